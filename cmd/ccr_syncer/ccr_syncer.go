@@ -3,16 +3,20 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/notify"
 	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
 	"github.com/selectdb/ccr_syncer/pkg/service"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/utils"
@@ -38,13 +42,22 @@ type Syncer struct {
 }
 
 var (
-	dbPath       string
-	syncer       Syncer
-	printVersion bool
+	dbPath          string
+	raftDir         string
+	raftAddr        string
+	raftPeers       string
+	syncer          Syncer
+	printVersion    bool
+	rotateSecretKey bool
+	migrateOnly     bool
 )
 
 func init() {
 	flag.BoolVar(&printVersion, "version", false, "The program's version")
+	flag.BoolVar(&rotateSecretKey, "rotate_secret_key", false,
+		"Re-encrypt the passwords of all jobs with the current key of the secret provider, then exit")
+	flag.BoolVar(&migrateOnly, "migrate_only", false,
+		"Migrate the schema of the meta db to the version of this syncer, then exit")
 
 	flag.StringVar(&dbPath, "db_dir", "ccr.db", "sqlite3 db file")
 	flag.StringVar(&syncer.Db_type, "db_type", "sqlite3", "meta db type")
@@ -52,6 +65,10 @@ func init() {
 	flag.IntVar(&syncer.Db_port, "db_port", 3306, "meta db port")
 	flag.StringVar(&syncer.Db_user, "db_user", "root", "meta db user")
 	flag.StringVar(&syncer.Db_password, "db_password", "", "meta db password")
+	flag.StringVar(&raftDir, "raft_dir", "raft", "the dir of the raft log and snapshots, for db_type raft")
+	flag.StringVar(&raftAddr, "raft_addr", "127.0.0.1:9290", "the raft address of this syncer, for db_type raft")
+	flag.StringVar(&raftPeers, "raft_peers", "",
+		"the comma separated raft addresses of all syncers, only the connections from their hosts are accepted, for db_type raft")
 
 	flag.StringVar(&syncer.Host, "host", "127.0.0.1", "syncer host")
 	flag.IntVar(&syncer.Port, "port", 9190, "syncer port")
@@ -75,6 +92,9 @@ func main() {
 	if dbPath == "" {
 		log.Fatal("db_dir is empty")
 	}
+	if migrateOnly && syncer.Db_type == "raft" {
+		log.Fatal("migrate_only is not supported by db_type raft, the meta db of raft is rebuilt and migrated at start")
+	}
 	var db storage.DB
 	var err error
 	switch syncer.Db_type {
@@ -84,12 +104,46 @@ func main() {
 		db, err = storage.NewMysqlDB(syncer.Db_host, syncer.Db_port, syncer.Db_user, syncer.Db_password)
 	case "postgresql":
 		db, err = storage.NewPostgresqlDB(syncer.Db_host, syncer.Db_port, syncer.Db_user, syncer.Db_password)
+	case "raft":
+		var peers []string
+		for _, peer := range strings.Split(raftPeers, ",") {
+			if peer = strings.TrimSpace(peer); peer != "" {
+				peers = append(peers, peer)
+			}
+		}
+		db, err = storage.NewRaftDB(&storage.RaftConfig{Dir: raftDir, Addr: raftAddr, Peers: peers, TLS: service.PeerTLSOptions()})
 	default:
 		err = xerror.Wrap(err, xerror.Normal, "new meta db failed.")
 	}
 	if err != nil {
 		log.Fatalf("new meta db error: %+v", err)
 	}
+	if migrateOnly {
+		log.Infof("the meta db is migrated to schema version %d", storage.SchemaVersion)
+		os.Exit(0)
+	}
+
+	// Step 1.1: init secret cipher, encrypt the passwords of the jobs
+	if provider, err := secret.NewProviderFromFlags(); err != nil {
+		log.Fatalf("new secret provider error: %+v", err)
+	} else if provider != nil {
+		base.SetSecretCipher(secret.NewCipher(provider))
+	} else if rotateSecretKey {
+		log.Fatal("secret_provider is empty, the passwords could not be re-encrypted")
+	}
+	if rotateSecretKey {
+		num, err := ccr.ReencryptJobs(db)
+		if err != nil {
+			log.Fatalf("re-encrypt the passwords of jobs failed, %d jobs are re-encrypted: %+v", num, err)
+		}
+		log.Infof("the passwords of %d jobs are re-encrypted", num)
+		os.Exit(0)
+	}
+
+	// Step 1.2: init the tls config of the doris connections
+	if err := base.InitTLS(); err != nil {
+		log.Fatalf("init doris tls error: %+v", err)
+	}
 
 	// Step 2: init factory
 	factory := ccr.NewFactory(rpc.NewRpcFactory(), ccr.NewMetaFactory(), base.NewSpecerFactory(), ccr.DefaultThriftMetaFactory)
@@ -100,6 +154,13 @@ func main() {
 	httpService := service.NewHttpServer(syncer.Host, syncer.Port, db, jobManager)
 	checker := ccr.NewChecker(hostInfo, db, jobManager)
 
+	// Step 3.1: init the notifier of the alerts
+	notifier, err := notify.NewNotifierFromFlags(hostInfo)
+	if err != nil {
+		log.Fatalf("new notifier error: %+v", err)
+	}
+	notify.SetDefault(notifier)
+
 	// Step 4: http service start
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -126,6 +187,15 @@ func main() {
 		checker.Start()
 	}()
 
+	// Step 6.1: reconcile the jobs of the job config file, after the jobs of the dead syncers
+	// are rebalanced.
+	go func() {
+		<-checker.Ready()
+		if err := httpService.ReconcileJobConfig(); err != nil {
+			log.Errorf("reconcile job config failed: %+v", err)
+		}
+	}()
+
 	// Step 7: init metrics
 	sink, err := prometheus.NewPrometheusSink()
 	if err != nil {
@@ -152,10 +222,21 @@ func main() {
 			checker.Stop()
 			jobManager.Stop()
 			monitor.Stop()
+			notifier.Close()
+			if closer, ok := db.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					log.Errorf("close meta db failed: %+v", err)
+				}
+			}
 			log.Info("all service stop")
 			return true
 		case syscall.SIGHUP:
-			log.Infof("receive signal: %s", signal.String())
+			log.Infof("receive signal: %s, reconcile job config", signal.String())
+			go func() {
+				if err := httpService.ReconcileJobConfig(); err != nil {
+					log.Errorf("reconcile job config failed: %+v", err)
+				}
+			}()
 			return false
 		default:
 			log.Infof("receive signal: %s", signal.String())
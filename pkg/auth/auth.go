@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Role is the permission of a token, a role has all permissions of the lower roles.
+type Role int
+
+const (
+	RoleNone     Role = 0
+	RoleReadOnly Role = 1 // query the jobs
+	RoleOperator Role = 2 // operate the existing jobs, such as pause/resume
+	RoleAdmin    Role = 3 // create/delete/desync the jobs
+)
+
+// Role Stringer
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read_only"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+func ParseRole(role string) (Role, error) {
+	switch role {
+	case "read_only":
+		return RoleReadOnly, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return RoleNone, xerror.Errorf(xerror.Normal, "unknown role: %q", role)
+	}
+}
+
+// Token is a static bearer token.
+type Token struct {
+	// The name of the token owner, only for logging.
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Role  string `json:"role"`
+}
+
+// TokenFile is the content of the token file, for example:
+//
+//	{
+//	  "tokens": [
+//	    {"name": "dashboard", "token": "xxx", "role": "read_only"},
+//	    {"name": "ops", "token": "yyy", "role": "operator"},
+//	    {"name": "dba", "token": "zzz", "role": "admin"}
+//	  ]
+//	}
+type TokenFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+type tokenEntry struct {
+	name string
+	hash [sha256.Size]byte
+	role Role
+}
+
+// Authenticator authenticates the requests by the bearer tokens.
+type Authenticator struct {
+	tokens []tokenEntry
+}
+
+func NewAuthenticator(tokens []Token) (*Authenticator, error) {
+	a := &Authenticator{tokens: make([]tokenEntry, 0, len(tokens))}
+	for i, token := range tokens {
+		if token.Token == "" {
+			return nil, xerror.Errorf(xerror.Normal, "the token %d (%s) is empty", i, token.Name)
+		}
+		role, err := ParseRole(token.Role)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "the token %d (%s)", i, token.Name)
+		}
+		a.tokens = append(a.tokens, tokenEntry{
+			name: token.Name,
+			hash: sha256.Sum256([]byte(token.Token)),
+			role: role,
+		})
+	}
+	return a, nil
+}
+
+// LoadTokenFile creates the authenticator with the tokens in the json file.
+func LoadTokenFile(path string) (*Authenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read token file %s failed", path)
+	}
+
+	var tokenFile TokenFile
+	if err := json.Unmarshal(data, &tokenFile); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse token file %s failed", path)
+	}
+	if len(tokenFile.Tokens) == 0 {
+		return nil, xerror.Errorf(xerror.Normal, "no tokens in token file %s", path)
+	}
+	return NewAuthenticator(tokenFile.Tokens)
+}
+
+// Authenticate returns the name and the role of the token, the role is RoleNone if the token
+// is unknown.
+func (a *Authenticator) Authenticate(token string) (string, Role) {
+	// Compare the hashes in constant time, so the length of the tokens is not leaked.
+	hash := sha256.Sum256([]byte(token))
+	name, role := "", RoleNone
+	for _, entry := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], entry.hash[:]) == 1 {
+			name, role = entry.name, entry.role
+		}
+	}
+	return name, role
+}
+
+const wwwAuthenticate = `Bearer realm="ccr_syncer"`
+
+// bearerToken returns the token in the header "Authorization: Bearer ${token}".
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	return token, token != ""
+}
+
+// ErrorWriter writes the response of the rejected request with the status code.
+type ErrorWriter func(w http.ResponseWriter, code int, errMsg string)
+
+// writeError writes the error as the result of the legacy api.
+func writeError(w http.ResponseWriter, code int, errMsg string) {
+	result := struct {
+		Success  bool   `json:"success"`
+		ErrorMsg string `json:"error_msg,omitempty"`
+	}{Success: false, ErrorMsg: errMsg}
+	data, _ := json.Marshal(&result)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// Middleware requires the requests have a token with the role at least, it responds 401 if the
+// token is missing or unknown, and 403 if the role of the token is not enough. A nil
+// authenticator allows all requests.
+func (a *Authenticator) Middleware(role Role, next http.Handler) http.Handler {
+	return a.MiddlewareWithErrorWriter(role, next, writeError)
+}
+
+// MiddlewareWithErrorWriter is the same as Middleware, but the rejected requests are responded
+// by the error writer.
+func (a *Authenticator) MiddlewareWithErrorWriter(role Role, next http.Handler, writeError ErrorWriter) http.Handler {
+	if a == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			log.Warnf("unauthorized request %s from %s: missing bearer token", r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", wwwAuthenticate)
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		name, tokenRole := a.Authenticate(token)
+		if tokenRole == RoleNone {
+			log.Warnf("unauthorized request %s from %s: invalid token", r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", wwwAuthenticate)
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if tokenRole < role {
+			log.Warnf("forbidden request %s from %s, token: %s, role: %s, required role: %s",
+				r.URL.Path, r.RemoteAddr, name, tokenRole, role)
+			writeError(w, http.StatusForbidden, "permission denied, the "+role.String()+" role is required")
+			return
+		}
+
+		log.Debugf("request %s is authenticated, token: %s, role: %s", r.URL.Path, name, tokenRole)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	content := `{"tokens": [
+		{"name": "viewer", "token": "read-token", "role": "read_only"},
+		{"name": "ops", "token": "operator-token", "role": "operator"},
+		{"name": "dba", "token": "admin-token", "role": "admin"}
+	]}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write token file failed: %v", err)
+	}
+
+	authenticator, err := LoadTokenFile(path)
+	if err != nil {
+		t.Fatalf("load token file failed: %v", err)
+	}
+	return authenticator
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		role   Role
+		header string
+		code   int
+	}{
+		{RoleReadOnly, "", http.StatusUnauthorized},
+		{RoleReadOnly, "Basic cm9vdDo=", http.StatusUnauthorized},
+		{RoleReadOnly, "Bearer unknown-token", http.StatusUnauthorized},
+		{RoleReadOnly, "Bearer read-token", http.StatusOK},
+		{RoleReadOnly, "bearer admin-token", http.StatusOK},
+		{RoleOperator, "Bearer read-token", http.StatusForbidden},
+		{RoleOperator, "Bearer operator-token", http.StatusOK},
+		{RoleAdmin, "Bearer operator-token", http.StatusForbidden},
+		{RoleAdmin, "Bearer admin-token", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/pause", nil)
+		if test.header != "" {
+			request.Header.Set("Authorization", test.header)
+		}
+		recorder := httptest.NewRecorder()
+		authenticator.Middleware(test.role, handler).ServeHTTP(recorder, request)
+
+		if recorder.Code != test.code {
+			t.Errorf("role: %s, header: %q, code: %d, expect: %d", test.role, test.header, recorder.Code, test.code)
+		}
+		if test.code == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("role: %s, header: %q, WWW-Authenticate is missing", test.role, test.header)
+		}
+	}
+}
+
+func TestMiddlewareWithErrorWriter(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	writeError := func(w http.ResponseWriter, code int, errMsg string) {
+		w.WriteHeader(code)
+		w.Write([]byte(`{"error":{"message":"` + errMsg + `"}}`))
+	}
+
+	request := httptest.NewRequest(http.MethodDelete, "/api/v1/jobs/job", nil)
+	request.Header.Set("Authorization", "Bearer read-token")
+	recorder := httptest.NewRecorder()
+	authenticator.MiddlewareWithErrorWriter(RoleAdmin, handler, writeError).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("code: %d, expect: %d", recorder.Code, http.StatusForbidden)
+	}
+	expect := `{"error":{"message":"permission denied, the admin role is required"}}`
+	if body := recorder.Body.String(); body != expect {
+		t.Errorf("body: %s, expect: %s", body, expect)
+	}
+}
+
+func TestNilAuthenticator(t *testing.T) {
+	var authenticator *Authenticator
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	authenticator.Middleware(RoleAdmin, handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/delete", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("the nil authenticator should allow all requests, code: %d", recorder.Code)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	if _, err := NewAuthenticator([]Token{{Name: "empty", Token: "", Role: "admin"}}); err == nil {
+		t.Errorf("the empty token should be rejected")
+	}
+	if _, err := NewAuthenticator([]Token{{Name: "unknown", Token: "token", Role: "root"}}); err == nil {
+		t.Errorf("the unknown role should be rejected")
+	}
+}
@@ -2,9 +2,11 @@ package base
 
 import (
 	"database/sql"
+	"strings"
 	"sync"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 )
 
@@ -36,7 +38,7 @@ func GetMysqlDB(dsn string) (*sql.DB, error) {
 	}
 
 	if db, err := sql.Open("mysql", dsn); err != nil {
-		return nil, xerror.Wrapf(err, xerror.DB, "connect to mysql failed, dsn: %s", dsn)
+		return nil, xerror.Wrapf(err, xerror.DB, "connect to mysql failed, dsn: %s", redactDSN(dsn))
 	} else {
 		db.SetMaxOpenConns(MaxOpenConns)
 		db.SetMaxIdleConns(MaxIdleConns)
@@ -46,3 +48,13 @@ func GetMysqlDB(dsn string) (*sql.DB, error) {
 		return db, nil
 	}
 }
+
+// redactDSN hides the password of the dsn "user:password@tcp(host:port)/db".
+func redactDSN(dsn string) string {
+	colon := strings.Index(dsn, ":")
+	at := strings.LastIndex(dsn, "@tcp(")
+	if colon < 0 || at < colon {
+		return dsn
+	}
+	return dsn[:colon+1] + secret.Redacted + dsn[at:]
+}
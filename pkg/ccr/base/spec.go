@@ -221,8 +221,29 @@ type Spec struct {
 	TableId  int64  `json:"table_id"`
 
 	observers []utils.Observer[SpecEvent]
+
+	// For dry run, the modifying sqls are passed to the recorder instead of executing.
+	sqlRecorder func(sql string)
+}
+
+// SetSqlRecorder makes the spec record the modifying sqls instead of executing them, a nil
+// recorder restores the executing.
+func (s *Spec) SetSqlRecorder(recorder func(sql string)) {
+	s.sqlRecorder = recorder
+}
+
+// recordSql returns true if the sql is recorded and should not be executed.
+func (s *Spec) recordSql(sql string) bool {
+	if s.sqlRecorder == nil {
+		return false
+	}
+
+	log.Infof("dry run, record sql: %s", sql)
+	s.sqlRecorder(sql)
+	return true
 }
 
+// The password is not included.
 func (s *Spec) String() string {
 	return fmt.Sprintf("host: %s, port: %s, thrift_port: %s, user: %s, cluster: %s, database: %s, database id: %d, table: %s, table id: %d",
 		s.Host, s.Port, s.ThriftPort, s.User, s.Cluster, s.Database, s.DbId, s.Table, s.TableId)
@@ -269,12 +290,12 @@ func (s *Spec) connect(dsn string) (*sql.DB, error) {
 
 // create mysql connection from spec
 func (s *Spec) Connect() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/", s.User, s.Password, s.Host, s.Port)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", s.User, s.Password, s.Host, s.Port, dorisDSNParams)
 	return s.connect(dsn)
 }
 
 func (s *Spec) ConnectDB() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", s.User, s.Password, s.Host, s.Port, s.Database)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s%s", s.User, s.Password, s.Host, s.Port, s.Database, dorisDSNParams)
 	return s.connect(dsn)
 }
 
@@ -530,16 +551,20 @@ func (s *Spec) RenameTable(destTableName string, renameTable *record.RenameTable
 func (s *Spec) dropTable(table string, force bool) error {
 	log.Infof("drop table %s.%s", s.Database, table)
 
-	db, err := s.Connect()
-	if err != nil {
-		return err
-	}
-
 	suffix := ""
 	if force {
 		suffix = "FORCE"
 	}
 	sql := fmt.Sprintf("DROP TABLE %s.%s %s", utils.FormatKeywordName(s.Database), utils.FormatKeywordName(table), suffix)
+	if s.recordSql(sql) {
+		return nil
+	}
+
+	db, err := s.Connect()
+	if err != nil {
+		return err
+	}
+
 	_, err = db.Exec(sql)
 	if err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "drop table %s.%s failed, sql: %s", s.Database, table, sql)
@@ -550,12 +575,17 @@ func (s *Spec) dropTable(table string, force bool) error {
 func (s *Spec) ClearDB() error {
 	log.Infof("clear database %s", s.Database)
 
+	sql := fmt.Sprintf("DROP DATABASE %s", utils.FormatKeywordName(s.Database))
+	if s.recordSql(sql) {
+		s.recordSql("CREATE DATABASE " + utils.FormatKeywordName(s.Database))
+		return nil
+	}
+
 	db, err := s.Connect()
 	if err != nil {
 		return err
 	}
 
-	sql := fmt.Sprintf("DROP DATABASE %s", utils.FormatKeywordName(s.Database))
 	_, err = db.Exec(sql)
 	if err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "drop database %s failed", s.Database)
@@ -570,12 +600,17 @@ func (s *Spec) ClearDB() error {
 func (s *Spec) CreateDatabase() error {
 	log.Debug("create database")
 
+	sql := "CREATE DATABASE IF NOT EXISTS " + utils.FormatKeywordName(s.Database)
+	if s.recordSql(sql) {
+		return nil
+	}
+
 	db, err := s.Connect()
 	if err != nil {
 		return nil
 	}
 
-	if _, err = db.Exec("CREATE DATABASE IF NOT EXISTS " + utils.FormatKeywordName(s.Database)); err != nil {
+	if _, err = db.Exec(sql); err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "create database %s failed", s.Database)
 	}
 	return nil
@@ -677,6 +712,86 @@ func (s *Spec) CheckTableExistsByName(tableName string) (bool, error) {
 	return table != "", nil
 }
 
+// PartitionChecksum is the row count and the checksum of the data of a partition.
+type PartitionChecksum struct {
+	RowCount int64
+	Checksum string
+}
+
+func (s *Spec) CountTableRows(tableName string) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) AS row_count FROM %s.%s",
+		utils.FormatKeywordName(s.Database), utils.FormatKeywordName(tableName))
+	results, err := s.queryResult(query, "row_count", "COUNT ROWS")
+	if err != nil {
+		return 0, err
+	}
+	if len(results) != 1 {
+		return 0, xerror.Errorf(xerror.Normal, "count rows failed, unexpected result: %v, sql: %s", results, query)
+	}
+
+	rowCount, err := strconv.ParseInt(results[0], 10, 64)
+	if err != nil {
+		return 0, xerror.Wrapf(err, xerror.Normal, "parse row count failed, sql: %s", query)
+	}
+	return rowCount, nil
+}
+
+// ChecksumPartition scans the partition, and sums the hash of each row as the checksum, so the
+// checksum is independent of the order of rows.
+func (s *Spec) ChecksumPartition(tableName, partitionName string) (*PartitionChecksum, error) {
+	fullTableName := fmt.Sprintf("%s.%s", utils.FormatKeywordName(s.Database), utils.FormatKeywordName(tableName))
+	columns, err := s.queryResult(fmt.Sprintf("SHOW COLUMNS FROM %s", fullTableName), "Field", "SHOW COLUMNS")
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, xerror.Errorf(xerror.Normal, "table %s has no columns", fullTableName)
+	}
+
+	values := make([]string, 0, len(columns))
+	for _, column := range columns {
+		values = append(values, fmt.Sprintf("IFNULL(CAST(%s AS STRING), '\\\\N')", utils.FormatKeywordName(column)))
+	}
+	query := fmt.Sprintf("SELECT COUNT(*) AS row_count, "+
+		"CAST(IFNULL(SUM(CAST(murmur_hash3_64(%s) AS LARGEINT)), 0) AS STRING) AS checksum "+
+		"FROM %s PARTITION (%s)",
+		strings.Join(values, ", "), fullTableName, utils.FormatKeywordName(partitionName))
+
+	db, err := s.ConnectDB()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "checksum partition failed, sql: %s", query)
+	}
+	defer rows.Close()
+
+	var checksum *PartitionChecksum
+	for rows.Next() {
+		rowParser := utils.NewRowParser()
+		if err := rowParser.Parse(rows); err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, query)
+		}
+		rowCount, err := rowParser.GetInt64("row_count")
+		if err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, query)
+		}
+		sum, err := rowParser.GetString("checksum")
+		if err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, query)
+		}
+		checksum = &PartitionChecksum{RowCount: rowCount, Checksum: sum}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "checksum partition failed, sql: %s", query)
+	}
+	if checksum == nil {
+		return nil, xerror.Errorf(xerror.Normal, "checksum partition failed, no result, sql: %s", query)
+	}
+	return checksum, nil
+}
+
 func (s *Spec) CancelRestoreIfExists(snapshotName string) error {
 	log.Debugf("cancel restore %s, db name: %s", snapshotName, s.Database)
 
@@ -696,6 +811,9 @@ func (s *Spec) CancelRestoreIfExists(snapshotName string) error {
 
 	sql := fmt.Sprintf("CANCEL RESTORE FROM %s", utils.FormatKeywordName(s.Database))
 	log.Infof("cancel restore %s, sql: %s", snapshotName, sql)
+	if s.recordSql(sql) {
+		return nil
+	}
 	_, err = db.Exec(sql)
 	if err != nil {
 		return xerror.Wrapf(err, xerror.Normal, "cancel restore failed, sql: %s", sql)
@@ -1115,6 +1233,10 @@ func (s *Spec) WaitTransactionDone(txnId int64) {
 
 // Exec sql
 func (s *Spec) Exec(sql string) error {
+	if s.recordSql(sql) {
+		return nil
+	}
+
 	db, err := s.Connect()
 	if err != nil {
 		return err
@@ -1129,6 +1251,10 @@ func (s *Spec) Exec(sql string) error {
 
 // Db Exec sql
 func (s *Spec) DbExec(sql string) error {
+	if s.recordSql(sql) {
+		return nil
+	}
+
 	db, err := s.ConnectDB()
 	if err != nil {
 		return err
@@ -1381,6 +1507,76 @@ func (s *Spec) DesyncTables(tables ...string) error {
 	return nil
 }
 
+// EnableTableBinlog enables the binlog of the tables.
+func (s *Spec) EnableTableBinlog(tables ...string) error {
+	var err error
+
+	failedTables := []string{}
+	for _, table := range tables {
+		enableSql := fmt.Sprintf("ALTER TABLE %s SET (\"binlog.enable\" = \"true\")", utils.FormatKeywordName(table))
+		log.Debugf("db exec sql: %s", enableSql)
+		if err = s.DbExec(enableSql); err != nil {
+			failedTables = append(failedTables, table)
+		}
+	}
+
+	if len(failedTables) > 0 {
+		return xerror.Wrapf(err, xerror.FE, "failed tables: %s", strings.Join(failedTables, ","))
+	}
+
+	return nil
+}
+
+// EnableDatabaseBinlog enables the binlog of the database, the binlog of all tables in the
+// database must be enabled before.
+func (s *Spec) EnableDatabaseBinlog() error {
+	enableSql := fmt.Sprintf("ALTER DATABASE %s SET PROPERTIES (\"binlog.enable\" = \"true\")", utils.FormatKeywordName(s.Database))
+	log.Debugf("exec sql: %s", enableSql)
+	return s.Exec(enableSql)
+}
+
+// GetMasterJournalId returns the journal id replayed by the master FE. The commit seq of a
+// binlog is the journal id of the edit log, so all binlogs committed since now have a greater
+// commit seq.
+func (s *Spec) GetMasterJournalId() (int64, error) {
+	db, err := s.Connect()
+	if err != nil {
+		return 0, err
+	}
+
+	query := "select IsMaster, ReplayedJournalId from frontends()"
+	rows, err := db.Query(query)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.Normal, query)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rowParser := utils.NewRowParser()
+		if err := rowParser.Parse(rows); err != nil {
+			return 0, xerror.Wrap(err, xerror.Normal, query)
+		}
+		isMaster, err := rowParser.GetBool("IsMaster")
+		if err != nil {
+			return 0, xerror.Wrap(err, xerror.Normal, query)
+		}
+		if !isMaster {
+			continue
+		}
+		journalId, err := rowParser.GetInt64("ReplayedJournalId")
+		if err != nil {
+			return 0, xerror.Wrap(err, xerror.Normal, query)
+		}
+		return journalId, nil
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, xerror.Wrap(err, xerror.Normal, query)
+	}
+
+	return 0, xerror.Errorf(xerror.Normal, "master frontend not found, sql: %s", query)
+}
+
 // Determine whether the error are network related, eg connection refused, connection reset, exposed from net packages.
 func isNetworkRelated(err error) bool {
 	msg := err.Error()
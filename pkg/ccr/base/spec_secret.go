@@ -0,0 +1,52 @@
+package base
+
+import (
+	"encoding/json"
+
+	"github.com/selectdb/ccr_syncer/pkg/secret"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The password of the spec is encrypted by the cipher when the spec is marshaled, such as
+// persisting the job, and it is decrypted when unmarshaled. The password is stored in plaintext
+// if the cipher is not set.
+var secretCipher *secret.Cipher
+
+// SetSecretCipher set the cipher to encrypt the passwords of the specs.
+func SetSecretCipher(cipher *secret.Cipher) {
+	secretCipher = cipher
+}
+
+// plainSpec has the same fields with Spec, but without the json methods.
+type plainSpec Spec
+
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	spec := plainSpec(*s)
+	if secretCipher != nil {
+		password, err := secretCipher.Encrypt(s.Password)
+		if err != nil {
+			return nil, xerror.Wrap(err, xerror.Normal, "encrypt password failed")
+		}
+		spec.Password = password
+	}
+	return json.Marshal(&spec)
+}
+
+func (s *Spec) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainSpec)(s)); err != nil {
+		return err
+	}
+
+	if !secret.IsEncrypted(s.Password) {
+		return nil
+	}
+	if secretCipher == nil {
+		return xerror.New(xerror.Normal, "the password is encrypted, but the secret provider is not configured")
+	}
+	password, err := secretCipher.Decrypt(s.Password)
+	if err != nil {
+		return xerror.Wrap(err, xerror.Normal, "decrypt password failed")
+	}
+	s.Password = password
+	return nil
+}
@@ -0,0 +1,41 @@
+package base
+
+import (
+	"flag"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The name of the tls config registered to the mysql driver.
+const dorisTLSConfigName = "ccr_doris"
+
+var (
+	dorisTLS       utils.TLSOptions
+	dorisDSNParams string
+)
+
+func init() {
+	flag.BoolVar(&dorisTLS.Enable, "doris_tls", false, "Connect to the mysql port of the doris FE with tls")
+	flag.StringVar(&dorisTLS.CaFile, "doris_tls_ca_file", "",
+		"The CA file to verify the doris FE certificate, the system CAs are used if it is empty")
+	flag.StringVar(&dorisTLS.CertFile, "doris_tls_cert_file", "", "The client certificate file sent to the doris FE")
+	flag.StringVar(&dorisTLS.KeyFile, "doris_tls_key_file", "", "The client key file sent to the doris FE")
+	flag.BoolVar(&dorisTLS.SkipVerify, "doris_tls_skip_verify", false, "Skip the verification of the doris FE certificate")
+}
+
+// InitTLS registers the tls config of the doris connections, it must be called before any
+// spec connects to doris.
+func InitTLS() error {
+	config, err := dorisTLS.ClientConfig()
+	if err != nil || config == nil {
+		return err
+	}
+
+	if err := mysql.RegisterTLSConfig(dorisTLSConfigName, config); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "register doris tls config failed")
+	}
+	dorisDSNParams = "?tls=" + dorisTLSConfigName
+	return nil
+}
@@ -53,6 +53,12 @@ type Specer interface {
 	BuildIndex(tableAlias string, buildIndex *record.IndexChangeJob) error
 
 	DesyncTables(tables ...string) error
+	EnableTableBinlog(tables ...string) error
+	EnableDatabaseBinlog() error
+	GetMasterJournalId() (int64, error)
+
+	CountTableRows(tableName string) (int64, error)
+	ChecksumPartition(tableName, partitionName string) (*PartitionChecksum, error)
 
 	utils.Subject[SpecEvent]
 }
@@ -0,0 +1,177 @@
+package ccr
+
+import (
+	"sync"
+	"time"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// The max number of binlog batches cached by the fetcher.
+	BINLOG_FETCHER_CACHE_SIZE = 64
+	// The duration to cache the empty result (BINLOG_TOO_NEW_COMMIT_SEQ), to avoid polling the
+	// source cluster by each destination.
+	BINLOG_FETCHER_EMPTY_TTL = SYNC_DURATION
+)
+
+type fetchBinlogFunc func(commitSeq int64) (*festruct.TGetBinlogResult_, error)
+
+// slowestCommitSeqFunc returns the commit seq of the slowest destination, the batches before it
+// are consumed by all destinations.
+type slowestCommitSeqFunc func() int64
+
+type binlogBatch struct {
+	resp      *festruct.TGetBinlogResult_
+	fetchedAt time.Time
+}
+
+// covers returns true if the batch contains the binlogs after the commitSeq, the batch fetched
+// with a smaller commit seq serves the destinations which have consumed part of it.
+func (b *binlogBatch) covers(from, commitSeq int64) bool {
+	if b.resp.GetStatus().GetStatusCode() != tstatus.TStatusCode_OK {
+		return false
+	}
+	return from <= commitSeq && commitSeq < b.lastCommitSeq()
+}
+
+func (b *binlogBatch) lastCommitSeq() int64 {
+	binlogs := b.resp.GetBinlogs()
+	if len(binlogs) == 0 {
+		return 0
+	}
+	return binlogs[len(binlogs)-1].GetCommitSeq()
+}
+
+// after returns the result with the binlogs after the commitSeq.
+func (b *binlogBatch) after(commitSeq int64) *festruct.TGetBinlogResult_ {
+	binlogs := b.resp.GetBinlogs()
+	for i, binlog := range binlogs {
+		if binlog.GetCommitSeq() > commitSeq {
+			binlogs = binlogs[i:]
+			break
+		}
+	}
+	resp := *b.resp
+	resp.Binlogs = binlogs
+	return &resp
+}
+
+type binlogCall struct {
+	wg   sync.WaitGroup
+	resp *festruct.TGetBinlogResult_
+	err  error
+}
+
+// binlogFetcher shares the binlog batches fetched from the source cluster between the
+// destinations of a fan-out job, so a batch is fetched once no matter how many destinations
+// consume it. A destination which lags too much misses the cache and fetches by itself.
+type binlogFetcher struct {
+	fetch   fetchBinlogFunc
+	slowest slowestCommitSeqFunc // nil if unknown
+
+	lock     sync.Mutex
+	batches  map[int64]*binlogBatch // the requested commit seq => batch
+	inflight map[int64]*binlogCall
+}
+
+func newBinlogFetcher(fetch fetchBinlogFunc, slowest slowestCommitSeqFunc) *binlogFetcher {
+	return &binlogFetcher{
+		fetch:    fetch,
+		slowest:  slowest,
+		batches:  make(map[int64]*binlogBatch),
+		inflight: make(map[int64]*binlogCall),
+	}
+}
+
+// GetBinlog returns the binlogs after the commitSeq, the result is shared with the
+// concurrent and the following calls whose commitSeq is covered by it.
+func (f *binlogFetcher) GetBinlog(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+	f.lock.Lock()
+	if batch, ok := f.batches[commitSeq]; ok {
+		if !isEmptyBinlogResult(batch.resp) || time.Since(batch.fetchedAt) < BINLOG_FETCHER_EMPTY_TTL {
+			f.lock.Unlock()
+			log.Debugf("binlog fetcher hit cache, commit seq: %d", commitSeq)
+			return batch.resp, nil
+		}
+		delete(f.batches, commitSeq)
+	}
+	for from, batch := range f.batches {
+		if batch.covers(from, commitSeq) {
+			f.lock.Unlock()
+			log.Debugf("binlog fetcher hit cache, commit seq: %d, batch: %d", commitSeq, from)
+			return batch.after(commitSeq), nil
+		}
+	}
+	if call, ok := f.inflight[commitSeq]; ok {
+		f.lock.Unlock()
+		call.wg.Wait()
+		return call.resp, call.err
+	}
+
+	call := &binlogCall{}
+	call.wg.Add(1)
+	f.inflight[commitSeq] = call
+	f.lock.Unlock()
+
+	call.resp, call.err = f.fetch(commitSeq)
+
+	// The slowest commit seq is got without the fetcher lock, since it reads the destinations.
+	var slowestCommitSeq int64
+	if f.slowest != nil {
+		slowestCommitSeq = f.slowest()
+	}
+
+	f.lock.Lock()
+	delete(f.inflight, commitSeq)
+	if call.err == nil && isCacheableBinlogResult(call.resp) {
+		f.batches[commitSeq] = &binlogBatch{resp: call.resp, fetchedAt: time.Now()}
+		f.evict(slowestCommitSeq)
+	}
+	f.lock.Unlock()
+	call.wg.Done()
+
+	return call.resp, call.err
+}
+
+// evict the batches consumed by all destinations, then the batches with the smallest commit seq
+// if there are still too many, they are only used by the slowest destinations.
+func (f *binlogFetcher) evict(slowestCommitSeq int64) {
+	for from, batch := range f.batches {
+		if len(batch.resp.GetBinlogs()) > 0 && batch.lastCommitSeq() <= slowestCommitSeq {
+			delete(f.batches, from)
+		}
+	}
+
+	for len(f.batches) > BINLOG_FETCHER_CACHE_SIZE {
+		var minCommitSeq int64 = -1
+		for commitSeq := range f.batches {
+			if minCommitSeq == -1 || commitSeq < minCommitSeq {
+				minCommitSeq = commitSeq
+			}
+		}
+		delete(f.batches, minCommitSeq)
+	}
+}
+
+func isEmptyBinlogResult(resp *festruct.TGetBinlogResult_) bool {
+	return resp.GetStatus().GetStatusCode() == tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ
+}
+
+func isCacheableBinlogResult(resp *festruct.TGetBinlogResult_) bool {
+	if resp == nil || resp.GetStatus() == nil {
+		return false
+	}
+
+	switch resp.GetStatus().GetStatusCode() {
+	case tstatus.TStatusCode_OK, tstatus.TStatusCode_BINLOG_TOO_OLD_COMMIT_SEQ:
+		return len(resp.GetBinlogs()) > 0
+	case tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ:
+		return true
+	default:
+		return false
+	}
+}
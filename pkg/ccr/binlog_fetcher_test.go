@@ -0,0 +1,186 @@
+package ccr
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+)
+
+func newTestBinlogResult(code tstatus.TStatusCode, commitSeqs ...int64) *festruct.TGetBinlogResult_ {
+	status := tstatus.NewTStatus()
+	status.StatusCode = code
+	resp := festruct.NewTGetBinlogResult_()
+	resp.Status = status
+	for _, commitSeq := range commitSeqs {
+		seq := commitSeq
+		binlog := festruct.NewTBinlog()
+		binlog.SetCommitSeq(&seq)
+		resp.Binlogs = append(resp.Binlogs, binlog)
+	}
+	return resp
+}
+
+func TestBinlogFetcher_SharedBatch(t *testing.T) {
+	var fetched int32
+	fetcher := newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		atomic.AddInt32(&fetched, 1)
+		return newTestBinlogResult(tstatus.TStatusCode_OK, commitSeq+1, commitSeq+2), nil
+	}, nil)
+
+	// three dests consume the same batch concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := fetcher.GetBinlog(10)
+			if err != nil {
+				t.Errorf("GetBinlog() error = %v", err)
+				return
+			}
+			if len(resp.GetBinlogs()) != 2 || resp.GetBinlogs()[0].GetCommitSeq() != 11 {
+				t.Errorf("unexpected binlogs: %v", resp.GetBinlogs())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetched != 1 {
+		t.Errorf("fetched %d times, want 1", fetched)
+	}
+
+	if _, err := fetcher.GetBinlog(12); err != nil {
+		t.Fatalf("GetBinlog() error = %v", err)
+	}
+	if fetched != 2 {
+		t.Errorf("fetched %d times, want 2", fetched)
+	}
+}
+
+func TestBinlogFetcher_Evict(t *testing.T) {
+	var fetched int32
+	fetcher := newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		atomic.AddInt32(&fetched, 1)
+		return newTestBinlogResult(tstatus.TStatusCode_OK, commitSeq+1), nil
+	}, nil)
+
+	for i := int64(0); i <= BINLOG_FETCHER_CACHE_SIZE; i++ {
+		if _, err := fetcher.GetBinlog(i); err != nil {
+			t.Fatalf("GetBinlog() error = %v", err)
+		}
+	}
+	if len(fetcher.batches) != BINLOG_FETCHER_CACHE_SIZE {
+		t.Errorf("cached %d batches, want %d", len(fetcher.batches), BINLOG_FETCHER_CACHE_SIZE)
+	}
+
+	// the slowest batch is evicted, fetch it again
+	before := fetched
+	if _, err := fetcher.GetBinlog(0); err != nil {
+		t.Fatalf("GetBinlog() error = %v", err)
+	}
+	if fetched != before+1 {
+		t.Errorf("the evicted batch is not fetched again")
+	}
+}
+
+func TestBinlogFetcher_ErrorNotCached(t *testing.T) {
+	var fetched int32
+	fetcher := newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		atomic.AddInt32(&fetched, 1)
+		return newTestBinlogResult(tstatus.TStatusCode_BINLOG_DISABLE), nil
+	}, nil)
+
+	for i := 0; i < 2; i++ {
+		if _, err := fetcher.GetBinlog(1); err != nil {
+			t.Fatalf("GetBinlog() error = %v", err)
+		}
+	}
+	if fetched != 2 {
+		t.Errorf("fetched %d times, want 2", fetched)
+	}
+}
+
+func TestBinlogFetcher_CoveredBatch(t *testing.T) {
+	var fetched int32
+	fetcher := newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		atomic.AddInt32(&fetched, 1)
+		return newTestBinlogResult(tstatus.TStatusCode_OK, commitSeq+2, commitSeq+4, commitSeq+6), nil
+	}, nil)
+
+	if _, err := fetcher.GetBinlog(10); err != nil {
+		t.Fatalf("GetBinlog() error = %v", err)
+	}
+
+	// the dests which consumed part of the batch are served by it
+	tests := []struct {
+		commitSeq int64
+		first     int64
+		size      int
+	}{
+		{11, 12, 3},
+		{12, 14, 2},
+		{15, 16, 1},
+	}
+	for _, test := range tests {
+		resp, err := fetcher.GetBinlog(test.commitSeq)
+		if err != nil {
+			t.Fatalf("GetBinlog(%d) error = %v", test.commitSeq, err)
+		}
+		binlogs := resp.GetBinlogs()
+		if len(binlogs) != test.size || binlogs[0].GetCommitSeq() != test.first {
+			t.Errorf("GetBinlog(%d) binlogs = %v", test.commitSeq, binlogs)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("fetched %d times, want 1", fetched)
+	}
+
+	// the cached batch is not changed
+	resp, _ := fetcher.GetBinlog(10)
+	if len(resp.GetBinlogs()) != 3 {
+		t.Errorf("the cached batch is changed: %v", resp.GetBinlogs())
+	}
+
+	// the end of the batch is not covered
+	if _, err := fetcher.GetBinlog(16); err != nil {
+		t.Fatalf("GetBinlog() error = %v", err)
+	}
+	if fetched != 2 {
+		t.Errorf("fetched %d times, want 2", fetched)
+	}
+}
+
+func TestBinlogFetcher_EvictBySlowest(t *testing.T) {
+	var slowest int64
+	fetcher := newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		return newTestBinlogResult(tstatus.TStatusCode_OK, commitSeq+1), nil
+	}, func() int64 {
+		return atomic.LoadInt64(&slowest)
+	})
+
+	for i := int64(0); i < 10; i++ {
+		if _, err := fetcher.GetBinlog(i); err != nil {
+			t.Fatalf("GetBinlog() error = %v", err)
+		}
+	}
+	if len(fetcher.batches) != 10 {
+		t.Fatalf("cached %d batches, want 10", len(fetcher.batches))
+	}
+
+	// the batches consumed by the slowest dest are evicted
+	atomic.StoreInt64(&slowest, 5)
+	if _, err := fetcher.GetBinlog(10); err != nil {
+		t.Fatalf("GetBinlog() error = %v", err)
+	}
+	if len(fetcher.batches) != 6 {
+		t.Errorf("cached %d batches, want 6", len(fetcher.batches))
+	}
+	for from := range fetcher.batches {
+		if from < 5 {
+			t.Errorf("the consumed batch %d is not evicted", from)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ccr
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/sink"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BinlogSink consumes the binlogs of a job during the incremental sync.
+type BinlogSink interface {
+	// Sink handles the binlog, the job progress is committed after it returns without error.
+	Sink(binlog *festruct.TBinlog) error
+	Close() error
+}
+
+func NewBinlogSink(j *Job) (BinlogSink, error) {
+	if j.DryRun {
+		return &dryRunSink{job: j}, nil
+	}
+	if j.Sink.IsDoris() {
+		return &dorisSink{job: j}, nil
+	}
+
+	writer, err := sink.NewWriter(j.Sink)
+	if err != nil {
+		return nil, err
+	}
+	return &recordSink{job: j, writer: writer}, nil
+}
+
+// dorisSink replays the binlogs into the dest doris cluster.
+type dorisSink struct {
+	job *Job
+}
+
+func (s *dorisSink) Sink(binlog *festruct.TBinlog) error {
+	return s.job.handleBinlog(binlog)
+}
+
+func (s *dorisSink) Close() error {
+	return nil
+}
+
+// recordSink decodes the binlogs and publishes them as events via the writer.
+type recordSink struct {
+	job    *Job
+	writer sink.Writer
+}
+
+func (s *recordSink) Sink(binlog *festruct.TBinlog) error {
+	if binlog == nil || !binlog.IsSetCommitSeq() {
+		return xerror.Errorf(xerror.Normal, "invalid binlog: %v", binlog)
+	}
+
+	j := s.job
+	commitSeq := binlog.GetCommitSeq()
+	j.progress.StartHandle(commitSeq)
+	xmetrics.HandlingBinlog(j.Name, commitSeq)
+
+	if !s.isRelated(binlog) {
+		log.Debugf("skip the unrelated binlog, commit seq: %d, table ids: %v", commitSeq, binlog.GetTableIds())
+		return nil
+	}
+
+	data, err := decodeBinlogRecord(binlog)
+	if err != nil {
+		return err
+	}
+
+	event := &sink.Event{
+		JobName:   j.Name,
+		CommitSeq: commitSeq,
+		Timestamp: binlog.GetTimestamp(),
+		Type:      binlog.GetType().String(),
+		DbId:      binlog.GetDbId(),
+		TableIds:  binlog.GetTableIds(),
+		Record:    data,
+	}
+	log.Debugf("sink binlog event, type: %s, commit seq: %d", event.Type, commitSeq)
+	return s.writer.Write(event)
+}
+
+func (s *recordSink) Close() error {
+	return s.writer.Close()
+}
+
+// isRelated returns whether the binlog should be published, the binlogs of the tables not
+// belong to the job are skipped.
+func (s *recordSink) isRelated(binlog *festruct.TBinlog) bool {
+	j := s.job
+	tableIds := binlog.GetTableIds()
+	if len(tableIds) == 0 {
+		return true
+	}
+
+	for _, tableId := range tableIds {
+		switch j.SyncType {
+		case TableSync:
+			if tableId == j.Src.TableId {
+				return true
+			}
+		case DBSync:
+			if !j.isTableFiltered(tableId, "") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeBinlogRecord decodes the data of binlog into the record, the raw data is returned if
+// the binlog type has no record.
+func decodeBinlogRecord(binlog *festruct.TBinlog) (any, error) {
+	data := binlog.GetData()
+	switch binlog.GetType() {
+	case festruct.TBinlogType_UPSERT:
+		return record.NewUpsertFromJson(data)
+	case festruct.TBinlogType_ADD_PARTITION:
+		return record.NewAddPartitionFromJson(data)
+	case festruct.TBinlogType_CREATE_TABLE:
+		return record.NewCreateTableFromJson(data)
+	case festruct.TBinlogType_DROP_PARTITION:
+		return record.NewDropPartitionFromJson(data)
+	case festruct.TBinlogType_DROP_TABLE:
+		return record.NewDropTableFromJson(data)
+	case festruct.TBinlogType_ALTER_JOB:
+		return record.NewAlterJobV2FromJson(data)
+	case festruct.TBinlogType_MODIFY_TABLE_ADD_OR_DROP_COLUMNS:
+		return record.NewModifyTableAddOrDropColumnsFromJson(data)
+	case festruct.TBinlogType_RENAME_COLUMN:
+		return record.NewRenameColumnFromJson(data)
+	case festruct.TBinlogType_MODIFY_COMMENT:
+		return record.NewModifyCommentFromJson(data)
+	case festruct.TBinlogType_TRUNCATE_TABLE:
+		return record.NewTruncateTableFromJson(data)
+	case festruct.TBinlogType_RENAME_TABLE:
+		return record.NewRenameTableFromJson(data)
+	case festruct.TBinlogType_REPLACE_PARTITIONS:
+		return record.NewReplacePartitionFromJson(data)
+	case festruct.TBinlogType_REPLACE_TABLE:
+		return record.NewReplaceTableRecordFromJson(data)
+	case festruct.TBinlogType_MODIFY_VIEW_DEF:
+		return record.NewAlterViewFromJson(data)
+	case festruct.TBinlogType_MODIFY_TABLE_ADD_OR_DROP_INVERTED_INDICES:
+		return record.NewModifyTableAddOrDropInvertedIndicesFromJson(data)
+	case festruct.TBinlogType_INDEX_CHANGE_JOB:
+		return record.NewIndexChangeJobFromJson(data)
+	case festruct.TBinlogType_BARRIER:
+		return record.NewBarrierLogFromJson(data)
+	default:
+		return data, nil
+	}
+}
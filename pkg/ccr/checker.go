@@ -1,19 +1,49 @@
 package ccr
 
 import (
+	"flag"
 	"fmt"
 	"time"
 
+	"github.com/selectdb/ccr_syncer/pkg/notify"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	CHECK_DURATION = time.Second * 5
-	CHECK_TIMEOUT  = CHECK_DURATION*2 + time.Second*2
+	DEFAULT_CHECK_DURATION = time.Second * 5
 )
 
+var (
+	flagCheckInterval time.Duration
+	flagCheckTimeout  time.Duration
+)
+
+func init() {
+	flag.DurationVar(&flagCheckInterval, "check_interval", DEFAULT_CHECK_DURATION,
+		"The interval of the syncer heartbeat and checking the dead syncers")
+	flag.DurationVar(&flagCheckTimeout, "check_timeout", 0,
+		"The syncer is dead if its heartbeat is older than the timeout, 0 means 2 * check_interval + 2s")
+}
+
+// CheckInterval returns the interval of the syncer heartbeat.
+func CheckInterval() time.Duration {
+	if flagCheckInterval <= 0 {
+		return DEFAULT_CHECK_DURATION
+	}
+	return flagCheckInterval
+}
+
+// CheckTimeout returns the timeout to treat a syncer as dead, it must be larger than the check
+// interval, otherwise the default one is used.
+func CheckTimeout() time.Duration {
+	if flagCheckTimeout > CheckInterval() {
+		return flagCheckTimeout
+	}
+	return CheckInterval()*2 + time.Second*2
+}
+
 type CheckerState int
 
 const (
@@ -55,6 +85,10 @@ type Checker struct {
 	deadSyncers []string
 	err         error
 	stop        chan struct{}
+	// Closed after the first check, the dead syncers are rebalanced.
+	ready chan struct{}
+	// The last time the load is rebalanced, see maybeRebalanceLoad.
+	lastRebalance time.Time
 }
 
 func NewChecker(hostInfo string, db storage.DB, jm *JobManager) *Checker {
@@ -64,6 +98,9 @@ func NewChecker(hostInfo string, db storage.DB, jm *JobManager) *Checker {
 		db:         db,
 		jobManager: jm,
 		stop:       make(chan struct{}),
+		ready:      make(chan struct{}),
+		// Wait a interval for the other syncers joining after start.
+		lastRebalance: time.Now(),
 	}
 }
 
@@ -120,12 +157,17 @@ func (c *Checker) handleUpdate() {
 }
 
 func (c *Checker) handleCheck() {
-	c.deadSyncers, c.err = c.db.GetDeadSyncers(c.lastStamp - int64(CHECK_TIMEOUT.Nanoseconds()))
+	c.deadSyncers, c.err = c.db.GetDeadSyncers(c.lastStamp - CheckTimeout().Nanoseconds())
 }
 
 func (c *Checker) handleRebalance() {
 	log.Infof("rebalance dead syncers: %v", c.deadSyncers)
-	c.err = c.db.RebalanceLoadFromDeadSyncers(c.deadSyncers)
+	var removedSyncers []string
+	removedSyncers, c.err = c.db.RebalanceLoadFromDeadSyncers(c.deadSyncers)
+	// All live syncers find the dead syncers, only the one removes them alerts.
+	for _, deadSyncer := range removedSyncers {
+		notify.SyncerDead(deadSyncer)
+	}
 }
 
 func (c *Checker) check() error {
@@ -161,6 +203,12 @@ func (c *Checker) check() error {
 }
 
 func (c *Checker) Start() error {
+	if flagCheckTimeout != 0 && flagCheckTimeout <= CheckInterval() {
+		log.Warnf("check timeout %s is not larger than check interval %s, use %s instead",
+			flagCheckTimeout, CheckInterval(), CheckTimeout())
+	}
+	log.Infof("checker started, interval: %s, timeout: %s", CheckInterval(), CheckTimeout())
+
 	if err := c.db.AddSyncer(c.hostInfo); err != nil {
 		log.Errorf("add failed, host info: %s, err: %+v", c.hostInfo, err)
 		return err
@@ -169,16 +217,22 @@ func (c *Checker) Start() error {
 		log.Errorf("checker first failed, host info: %s, err: %+v", c.hostInfo, err)
 		return err
 	}
+	close(c.ready)
 	return c.run()
 }
 
+// Ready returns a channel which is closed after the first check of the checker.
+func (c *Checker) Ready() <-chan struct{} {
+	return c.ready
+}
+
 func (c *Checker) Stop() {
 	log.Info("checker stopping")
 	close(c.stop)
 }
 
 func (c *Checker) run() error {
-	ticker := time.NewTicker(CHECK_DURATION)
+	ticker := time.NewTicker(CheckInterval())
 	defer ticker.Stop()
 
 	for {
@@ -190,6 +244,9 @@ func (c *Checker) run() error {
 			if err := c.check(); err != nil {
 				log.Errorf("checker failed, host info: %s, err: %+v", c.hostInfo, err)
 			}
+			c.handleHandoffs()
+			c.maybeRebalanceLoad()
+			c.updateSyncerMetrics()
 		}
 	}
 }
@@ -5,6 +5,7 @@
 //
 //	mockgen -source=pkg/rpc/fe.go -destination=pkg/ccr/fe_mock.go -package=ccr
 //
+
 // Package ccr is a generated GoMock package.
 package ccr
 
@@ -12,6 +13,7 @@ import (
 	reflect "reflect"
 
 	base "github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	rpc "github.com/selectdb/ccr_syncer/pkg/rpc"
 	frontendservice "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
 	status "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
 	types "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
@@ -161,18 +163,18 @@ func (mr *MockIFeRpcMockRecorder) GetMasterToken(arg0 any) *gomock.Call {
 }
 
 // GetSnapshot mocks base method.
-func (m *MockIFeRpc) GetSnapshot(arg0 *base.Spec, arg1 string) (*frontendservice.TGetSnapshotResult_, error) {
+func (m *MockIFeRpc) GetSnapshot(arg0 *base.Spec, arg1 string, arg2 bool) (*frontendservice.TGetSnapshotResult_, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetSnapshot", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetSnapshot", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*frontendservice.TGetSnapshotResult_)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetSnapshot indicates an expected call of GetSnapshot.
-func (mr *MockIFeRpcMockRecorder) GetSnapshot(arg0, arg1 any) *gomock.Call {
+func (mr *MockIFeRpcMockRecorder) GetSnapshot(arg0, arg1, arg2 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).GetSnapshot), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).GetSnapshot), arg0, arg1, arg2)
 }
 
 // GetTableMeta mocks base method.
@@ -191,18 +193,18 @@ func (mr *MockIFeRpcMockRecorder) GetTableMeta(spec, tableIds any) *gomock.Call
 }
 
 // RestoreSnapshot mocks base method.
-func (m *MockIFeRpc) RestoreSnapshot(arg0 *base.Spec, arg1 []*frontendservice.TTableRef, arg2 string, arg3 *frontendservice.TGetSnapshotResult_) (*frontendservice.TRestoreSnapshotResult_, error) {
+func (m *MockIFeRpc) RestoreSnapshot(arg0 *base.Spec, arg1 *rpc.RestoreSnapshotRequest) (*frontendservice.TRestoreSnapshotResult_, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RestoreSnapshot", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "RestoreSnapshot", arg0, arg1)
 	ret0, _ := ret[0].(*frontendservice.TRestoreSnapshotResult_)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RestoreSnapshot indicates an expected call of RestoreSnapshot.
-func (mr *MockIFeRpcMockRecorder) RestoreSnapshot(arg0, arg1, arg2, arg3 any) *gomock.Call {
+func (mr *MockIFeRpcMockRecorder) RestoreSnapshot(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).RestoreSnapshot), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreSnapshot", reflect.TypeOf((*MockIFeRpc)(nil).RestoreSnapshot), arg0, arg1)
 }
 
 // RollbackTransaction mocks base method.
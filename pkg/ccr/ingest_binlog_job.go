@@ -460,6 +460,10 @@ func (j *IngestBinlogJob) prepareTable(tableRecord *record.TableRecord) {
 		destTableId = job.Dest.TableId
 	case DBSync:
 		srcTableId = tableRecord.Id
+		if mappingTableId, ok := j.tableMapping[srcTableId]; ok {
+			destTableId = mappingTableId
+			break
+		}
 		destTableId, err = job.getDestTableIdBySrc(tableRecord.Id)
 		if err != nil {
 			break
@@ -549,6 +553,11 @@ func (j *IngestBinlogJob) runTabletIngestJobs() {
 	log.Debugf("runTabletIngestJobs, job length: %d", len(j.tabletIngestJobs))
 
 	for _, tabletIngestJob := range j.tabletIngestJobs {
+		if !j.ccrJob.waitTabletRate() {
+			j.setError(xerror.Errorf(xerror.Normal, "job %s is stopped", j.ccrJob.Name))
+			break
+		}
+
 		j.wg.Add(1)
 		go func(tabletIngestJob *tabletIngestBinlogHandler) {
 			tabletIngestJob.handle()
@@ -635,3 +644,35 @@ func (j *IngestBinlogJob) Run() {
 		return
 	}
 }
+
+// IngestTabletPlan is a tablet would be ingested, see IngestBinlogJob.Plan.
+type IngestTabletPlan struct {
+	SrcTabletId     int64 `json:"src_tablet_id"`
+	DestTabletId    int64 `json:"dest_tablet_id"`
+	DestPartitionId int64 `json:"dest_partition_id"`
+	BinlogVersion   int64 `json:"binlog_version"`
+}
+
+// Plan resolves the tablets to ingest without ingesting them, for dry run.
+func (j *IngestBinlogJob) Plan() ([]*IngestTabletPlan, error) {
+	j.prepareMeta()
+	if err := j.Error(); err != nil {
+		return nil, err
+	}
+
+	j.prepareTabletIngestJobs()
+	if err := j.Error(); err != nil {
+		return nil, err
+	}
+
+	plan := make([]*IngestTabletPlan, 0, len(j.tabletIngestJobs))
+	for _, tabletIngestJob := range j.tabletIngestJobs {
+		plan = append(plan, &IngestTabletPlan{
+			SrcTabletId:     tabletIngestJob.srcTablet.Id,
+			DestTabletId:    tabletIngestJob.destTablet.Id,
+			DestPartitionId: tabletIngestJob.destPartitionId,
+			BinlogVersion:   tabletIngestJob.binlogVersion,
+		})
+	}
+	return plan, nil
+}
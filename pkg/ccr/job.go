@@ -16,7 +16,11 @@ import (
 
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/label"
+	"github.com/selectdb/ccr_syncer/pkg/notify"
 	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/sink"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
 	utils "github.com/selectdb/ccr_syncer/pkg/utils"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
@@ -94,8 +98,9 @@ func (s SyncType) String() string {
 type JobState int
 
 const (
-	JobRunning JobState = 0
-	JobPaused  JobState = 1
+	JobRunning   JobState = 0
+	JobPaused    JobState = 1
+	JobCompleted JobState = 2 // the stop target is reached, see job_stop_at.go
 )
 
 // JobState Stringer
@@ -105,6 +110,8 @@ func (j JobState) String() string {
 		return "running"
 	case JobPaused:
 		return "paused"
+	case JobCompleted:
+		return "completed"
 	default:
 		return "unknown"
 	}
@@ -122,6 +129,28 @@ type Job struct {
 	SkipError bool        `json:"skip_error"`
 	State     JobState    `json:"state"`
 
+	// Only for db sync, filter the tables to sync.
+	TableFilter *TableFilter `json:"table_filter,omitempty"`
+	// Where the binlogs are published, replay into the dest doris cluster by default.
+	Sink *sink.Config `json:"sink,omitempty"`
+	// For fan-out job, the binlogs are replayed into each of the dests, see job_fanout.go.
+	Dests []base.Spec `json:"dests,omitempty"`
+	// The job is paused outside the schedule automatically, see job_schedule.go.
+	Schedule         *schedule.Schedule `json:"schedule,omitempty"`
+	PausedBySchedule bool               `json:"paused_by_schedule,omitempty"`
+	// Limit the throughput of the incremental sync, see job_rate_limit.go.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+	// Record what the job would do without executing, see job_dry_run.go.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Verify the data periodically, see job_verify.go.
+	VerifyConfig *VerifyConfig `json:"verify,omitempty"`
+	// Stop the job once the target is reached, see job_stop_at.go.
+	StopAt *StopTarget `json:"stop_at,omitempty"`
+	// Reverse the replication direction, see job_switchover.go.
+	Switchover *Switchover `json:"switchover,omitempty"`
+	// The free-form labels to select the jobs in the batch operations, see job_label.go.
+	Labels map[string]string `json:"labels,omitempty"`
+
 	factory *Factory `json:"-"`
 
 	allowTableExists bool `json:"-"` // Only for FirstRun(), don't need to persist.
@@ -131,11 +160,32 @@ type Job struct {
 	db         storage.DB   `json:"-"`
 	jobFactory *JobFactory  `json:"-"`
 	rawStatus  RawJobStatus `json:"-"`
+	binlogSink BinlogSink   `json:"-"`
+
+	dryRunResults *dryRunResults `json:"-"`
+
+	verifyRequests chan *verifyRequest  `json:"-"`
+	lastVerifyAt   time.Time            `json:"-"`
+	verification   *pendingVerification `json:"-"` // owned by the job goroutine
+
+	switchoverRunning atomic.Bool  `json:"-"`
+	pendingUpdates    atomic.Int32 `json:"-"` // the updates waiting for the job lock, see UpdateRateLimit
 
 	stop      chan struct{} `json:"-"`
+	stopOnce  sync.Once     `json:"-"`
+	done      chan struct{} `json:"-"` // closed after the job is exited, see JobManager.runJob
 	isDeleted atomic.Bool   `json:"-"`
+	fence     *jobFence     `json:"-"` // nil if the job is not added into the job manager
 
 	concurrencyManager *rpc.ConcurrencyManager `json:"-"`
+	binlogLimiter      *utils.RateLimiter      `json:"-"`
+	tabletLimiter      *utils.RateLimiter      `json:"-"`
+
+	// For fan-out job
+	parent        *Job           `json:"-"` // the fan-out job of the child
+	destIndex     int            `json:"-"` // the index of the child in the parent dests
+	children      []*Job         `json:"-"`
+	binlogFetcher *binlogFetcher `json:"-"`
 
 	lock sync.Mutex `json:"-"`
 }
@@ -147,6 +197,15 @@ type jobContext struct {
 	db               storage.DB
 	skipError        bool
 	allowTableExists bool
+	tableFilter      *TableFilter
+	sink             *sink.Config
+	dests            []base.Spec
+	schedule         *schedule.Schedule
+	rateLimit        *RateLimit
+	dryRun           bool
+	verifyConfig     *VerifyConfig
+	stopAt           *StopTarget
+	labels           map[string]string
 	factory          *Factory
 }
 
@@ -162,6 +221,60 @@ func NewJobContext(src, dest base.Spec, skipError bool, allowTableExists bool, d
 	}
 }
 
+// WithTableFilter set the table filter of the db sync job.
+func (c *jobContext) WithTableFilter(filter *TableFilter) *jobContext {
+	c.tableFilter = filter
+	return c
+}
+
+// WithSink set where the binlogs of the job are published.
+func (c *jobContext) WithSink(config *sink.Config) *jobContext {
+	c.sink = config
+	return c
+}
+
+// WithDests set the dests of the fan-out job.
+func (c *jobContext) WithDests(dests []base.Spec) *jobContext {
+	c.dests = dests
+	return c
+}
+
+// WithSchedule set the schedule of the job.
+func (c *jobContext) WithSchedule(sched *schedule.Schedule) *jobContext {
+	c.schedule = sched
+	return c
+}
+
+// WithRateLimit set the rate limit of the job.
+func (c *jobContext) WithRateLimit(rateLimit *RateLimit) *jobContext {
+	c.rateLimit = rateLimit
+	return c
+}
+
+// WithDryRun set whether the job is a dry run job.
+func (c *jobContext) WithDryRun(dryRun bool) *jobContext {
+	c.dryRun = dryRun
+	return c
+}
+
+// WithVerifyConfig set the periodic verification of the job.
+func (c *jobContext) WithVerifyConfig(config *VerifyConfig) *jobContext {
+	c.verifyConfig = config
+	return c
+}
+
+// WithStopTarget set the point in time where the job stops.
+func (c *jobContext) WithStopTarget(target *StopTarget) *jobContext {
+	c.stopAt = target
+	return c
+}
+
+// WithLabels set the labels of the job.
+func (c *jobContext) WithLabels(labels map[string]string) *jobContext {
+	c.labels = labels
+	return c
+}
+
 // new job
 func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 	jobContext, ok := ctx.(*jobContext)
@@ -183,6 +296,16 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 		SkipError: jobContext.skipError,
 		State:     JobRunning,
 
+		TableFilter:  jobContext.tableFilter,
+		Sink:         jobContext.sink,
+		Dests:        jobContext.dests,
+		Schedule:     jobContext.schedule,
+		RateLimit:    jobContext.rateLimit,
+		DryRun:       jobContext.dryRun,
+		VerifyConfig: jobContext.verifyConfig,
+		StopAt:       jobContext.stopAt,
+		Labels:       jobContext.labels,
+
 		allowTableExists: jobContext.allowTableExists,
 		factory:          factory,
 		forceFullsync:    false,
@@ -191,6 +314,8 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 		db:       jobContext.db,
 		stop:     make(chan struct{}),
 
+		verifyRequests: make(chan *verifyRequest, VERIFY_REQUEST_CAPACITY),
+
 		concurrencyManager: rpc.NewConcurrencyManager(),
 	}
 
@@ -205,6 +330,7 @@ func NewJobFromService(name string, ctx context.Context) (*Job, error) {
 	}
 
 	job.jobFactory = NewJobFactory()
+	job.initRateLimiters()
 
 	return job, nil
 }
@@ -225,8 +351,10 @@ func NewJobFromJson(jsonData string, db storage.DB, factory *Factory) (*Job, err
 	job.progress = nil
 	job.db = db
 	job.stop = make(chan struct{})
+	job.verifyRequests = make(chan *verifyRequest, VERIFY_REQUEST_CAPACITY)
 	job.jobFactory = NewJobFactory()
 	job.concurrencyManager = rpc.NewConcurrencyManager()
+	job.initRateLimiters()
 	return &job, nil
 }
 
@@ -241,19 +369,78 @@ func (j *Job) valid() error {
 	if j.Name == "" {
 		return xerror.New(xerror.Normal, "name is empty")
 	}
+	if isFanoutChildName(j.Name) {
+		return xerror.Errorf(xerror.Normal, "job name %s is reserved for the dests of fan-out job", j.Name)
+	}
 
 	err = j.ISrc.Valid()
 	if err != nil {
 		return xerror.Wrap(err, xerror.Normal, "src spec is invalid")
 	}
 
-	err = j.IDest.Valid()
-	if err != nil {
-		return xerror.Wrap(err, xerror.Normal, "dest spec is invalid")
+	if err = j.Sink.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "sink is invalid")
+	}
+
+	if err = j.Schedule.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "schedule is invalid")
+	}
+
+	if err = j.RateLimit.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "rate limit is invalid")
+	}
+
+	if j.DryRun {
+		if err = j.validDryRun(); err != nil {
+			return err
+		}
+	}
+
+	if err = j.VerifyConfig.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "verify config is invalid")
+	}
+	if !j.VerifyConfig.IsEmpty() {
+		if err = j.validVerify(); err != nil {
+			return err
+		}
+	}
+
+	if err = j.StopAt.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "stop target is invalid")
+	}
+	if !j.StopAt.IsEmpty() {
+		if err = j.validStopTarget(); err != nil {
+			return err
+		}
 	}
 
-	if (j.Src.Table == "" && j.Dest.Table != "") || (j.Src.Table != "" && j.Dest.Table == "") {
-		return xerror.New(xerror.Normal, "src/dest are not both db or table sync")
+	if err = label.Valid(j.Labels); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "labels are invalid")
+	}
+
+	// The dest cluster is only required by the doris sink.
+	if j.IsFanout() {
+		if err = j.validFanout(); err != nil {
+			return err
+		}
+	} else if j.Sink.IsDoris() {
+		err = j.IDest.Valid()
+		if err != nil {
+			return xerror.Wrap(err, xerror.Normal, "dest spec is invalid")
+		}
+
+		if (j.Src.Table == "" && j.Dest.Table != "") || (j.Src.Table != "" && j.Dest.Table == "") {
+			return xerror.New(xerror.Normal, "src/dest are not both db or table sync")
+		}
+	}
+
+	if !j.TableFilter.IsEmpty() {
+		if j.Src.Table != "" {
+			return xerror.New(xerror.Normal, "table filter is only supported by db sync")
+		}
+		if err := j.TableFilter.Valid(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -313,18 +500,45 @@ func (j *Job) genExtraInfo() (*base.ExtraInfo, error) {
 }
 
 func (j *Job) isIncrementalSync() bool {
-	switch j.progress.SyncState {
-	case TableIncrementalSync, DBIncrementalSync, DBTablesIncrementalSync:
-		return true
-	default:
-		return false
-	}
+	return j.progress.SyncState.isIncrementalSync()
 }
 
 func (j *Job) isTableSyncWithAlias() bool {
 	return j.SyncType == TableSync && j.Src.Table != j.Dest.Table
 }
 
+func (j *Job) hasTableFilter() bool {
+	return j.SyncType == DBSync && !j.TableFilter.IsEmpty()
+}
+
+// isTableFiltered returns whether the upstream table is excluded by the table filter.
+//
+// The tableName is optional, if it is empty, the name is resolved from the table name
+// mapping or the upstream meta. A table whose name could not be resolved is not filtered.
+func (j *Job) isTableFiltered(tableId int64, tableName string) bool {
+	if !j.hasTableFilter() {
+		return false
+	}
+
+	if tableName == "" {
+		if name, ok := j.progress.TableNameMapping[tableId]; ok {
+			tableName = name
+		} else if table, err := j.srcMeta.GetTable(tableId); err != nil {
+			log.Warnf("get the name of table %d failed, treat it as not filtered, err: %+v", tableId, err)
+			return false
+		} else {
+			tableName = table.Name
+		}
+	}
+
+	if j.TableFilter.IsTableIncluded(tableName) {
+		return false
+	}
+
+	log.Debugf("table %s (id: %d) is filtered by the table filter", tableName, tableId)
+	return true
+}
+
 func (j *Job) isTableDropped(tableId int64) (bool, error) {
 	// Keep compatible with the old version, which doesn't have the table id in partial sync data.
 	if tableId == 0 {
@@ -362,17 +576,18 @@ func (j *Job) addExtraInfo(jobInfo []byte) ([]byte, error) {
 	return jobInfoBytes, nil
 }
 
+// partialSyncInMemoryData is the in memory data of the partial sync steps.
+type partialSyncInMemoryData struct {
+	SnapshotName      string                        `json:"snapshot_name"`
+	SnapshotResp      *festruct.TGetSnapshotResult_ `json:"snapshot_resp"`
+	TableCommitSeqMap map[int64]int64               `json:"table_commit_seq_map"`
+	TableNameMapping  map[int64]string              `json:"table_name_mapping"`
+	RestoreLabel      string                        `json:"restore_label"`
+	SnapshotTableId   int64                         `json:"snapshot_table_id"` // the table id included in the snapshot.
+}
+
 // Like fullSync, but only backup and restore partial of the partitions of a table.
 func (j *Job) partialSync() error {
-	type inMemoryData struct {
-		SnapshotName      string                        `json:"snapshot_name"`
-		SnapshotResp      *festruct.TGetSnapshotResult_ `json:"snapshot_resp"`
-		TableCommitSeqMap map[int64]int64               `json:"table_commit_seq_map"`
-		TableNameMapping  map[int64]string              `json:"table_name_mapping"`
-		RestoreLabel      string                        `json:"restore_label"`
-		SnapshotTableId   int64                         `json:"snapshot_table_id"` // the table id included in the snapshot.
-	}
-
 	if j.progress.PartialSyncData == nil {
 		return xerror.Errorf(xerror.Normal, "run partial sync but data is nil")
 	}
@@ -384,7 +599,7 @@ func (j *Job) partialSync() error {
 	case Done:
 		log.Infof("partial sync status: done")
 		withAlias := len(j.progress.TableAliases) > 0
-		if err := j.newPartialSnapshot(tableId, table, partitions, withAlias); err != nil {
+		if err := j.newPartialSnapshot(tableId, table, partitions, withAlias, "restart the partial sync"); err != nil {
 			return err
 		}
 
@@ -410,7 +625,7 @@ func (j *Job) partialSync() error {
 		if err != nil && err == base.ErrBackupPartitionNotFound {
 			log.Warnf("partial sync status: partition not found in the upstream, step to table partial sync")
 			replace := true // replace the old data to avoid blocking reading
-			return j.newPartialSnapshot(tableId, table, nil, replace)
+			return j.newPartialSnapshot(tableId, table, nil, replace, "partition not found in the upstream")
 		} else if err != nil && err == base.ErrBackupTableNotFound {
 			var nextCommitSeq int64
 			if dropped, err := j.isTableDropped(tableId); err != nil {
@@ -478,7 +693,7 @@ func (j *Job) partialSync() error {
 				utils.FirstOr(snapshotResp.Status.GetErrorMsgs(), "unknown"),
 				snapshotResp.Status.GetStatusCode())
 			replace := len(j.progress.TableAliases) > 0
-			return j.newPartialSnapshot(tableId, table, partitions, replace)
+			return j.newPartialSnapshot(tableId, table, partitions, replace, "the partial snapshot is not exist or expired")
 		} else if snapshotResp.Status.GetStatusCode() != tstatus.TStatusCode_OK {
 			err = xerror.Errorf(xerror.FE, "get snapshot failed, status: %v", snapshotResp.Status)
 			return err
@@ -507,7 +722,7 @@ func (j *Job) partialSync() error {
 			snapshotTableId = backupObject.Id
 		}
 
-		inMemoryData := &inMemoryData{
+		inMemoryData := &partialSyncInMemoryData{
 			SnapshotName:      snapshotName,
 			SnapshotResp:      snapshotResp,
 			TableCommitSeqMap: tableCommitSeqMap,
@@ -520,7 +735,7 @@ func (j *Job) partialSync() error {
 		// Step 4: Add extra info
 		log.Infof("partial sync status: add extra info")
 
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*partialSyncInMemoryData)
 		snapshotResp := inMemoryData.SnapshotResp
 		jobInfo := snapshotResp.GetJobInfo()
 
@@ -543,7 +758,7 @@ func (j *Job) partialSync() error {
 
 		if j.progress.InMemoryData == nil {
 			persistData := j.progress.PersistData
-			inMemoryData := &inMemoryData{}
+			inMemoryData := &partialSyncInMemoryData{}
 			if err := json.Unmarshal([]byte(persistData), inMemoryData); err != nil {
 				return xerror.Errorf(xerror.Normal, "unmarshal persistData failed, persistData: %s", persistData)
 			}
@@ -551,7 +766,7 @@ func (j *Job) partialSync() error {
 		}
 
 		// Step 5.1: try reuse the exists restore job.
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*partialSyncInMemoryData)
 		snapshotName := inMemoryData.SnapshotName
 		if featureReuseRunningBackupRestoreJob {
 			name, err := j.IDest.GetValidRestoreJob(snapshotName)
@@ -624,7 +839,7 @@ func (j *Job) partialSync() error {
 
 	case WaitRestoreDone:
 		// Step 6: Wait restore job done
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*partialSyncInMemoryData)
 		restoreSnapshotName := inMemoryData.RestoreLabel
 		snapshotResp := inMemoryData.SnapshotResp
 
@@ -634,7 +849,7 @@ func (j *Job) partialSync() error {
 				return err
 			}
 			replace := len(j.progress.TableAliases) > 0
-			return j.newPartialSnapshot(tableId, table, partitions, replace)
+			return j.newPartialSnapshot(tableId, table, partitions, replace, "the partial snapshot is expired before restore")
 		}
 
 		restoreFinished, err := j.IDest.CheckRestoreFinished(restoreSnapshotName)
@@ -733,20 +948,21 @@ func (j *Job) partialSync() error {
 	return j.partialSync()
 }
 
-func (j *Job) fullSync() error {
-	type inMemoryData struct {
-		SnapshotName      string                        `json:"snapshot_name"`
-		SnapshotResp      *festruct.TGetSnapshotResult_ `json:"snapshot_resp"`
-		TableCommitSeqMap map[int64]int64               `json:"table_commit_seq_map"`
-		TableNameMapping  map[int64]string              `json:"table_name_mapping"`
-		Views             []string                      `json:"views"`
-		RestoreLabel      string                        `json:"restore_label"`
-	}
+// fullSyncInMemoryData is the in memory data of the full sync steps.
+type fullSyncInMemoryData struct {
+	SnapshotName      string                        `json:"snapshot_name"`
+	SnapshotResp      *festruct.TGetSnapshotResult_ `json:"snapshot_resp"`
+	TableCommitSeqMap map[int64]int64               `json:"table_commit_seq_map"`
+	TableNameMapping  map[int64]string              `json:"table_name_mapping"`
+	Views             []string                      `json:"views"`
+	RestoreLabel      string                        `json:"restore_label"`
+}
 
+func (j *Job) fullSync() error {
 	switch j.progress.SubSyncState {
 	case Done:
 		log.Infof("fullsync status: done")
-		if err := j.newSnapshot(j.progress.CommitSeq); err != nil {
+		if err := j.newSnapshot(j.progress.CommitSeq, "restart the full sync"); err != nil {
 			return err
 		}
 
@@ -778,6 +994,18 @@ func (j *Job) fullSync() error {
 				log.Warnf("full sync but source db is empty! retry later")
 				return nil
 			}
+			if j.hasTableFilter() {
+				for _, table := range tables {
+					if j.TableFilter.IsTableIncluded(table.Name) {
+						backupTableList = append(backupTableList, table.Name)
+					}
+				}
+				if len(backupTableList) == 0 {
+					log.Warnf("full sync but no table in source db matches the table filter! retry later")
+					return nil
+				}
+				log.Infof("fullsync with table filter, %d of %d tables are included", len(backupTableList), len(tables))
+			}
 		case TableSync:
 			backupTableList = append(backupTableList, j.Src.Table)
 		default:
@@ -829,7 +1057,7 @@ func (j *Job) fullSync() error {
 			log.Warnf("get snapshot %s: %s (%s), retry with new full sync", snapshotName,
 				utils.FirstOr(snapshotResp.Status.GetErrorMsgs(), "unknown"),
 				snapshotResp.Status.GetStatusCode())
-			return j.newSnapshot(j.progress.CommitSeq)
+			return j.newSnapshot(j.progress.CommitSeq, "the snapshot is not exist or expired")
 		} else if snapshotResp.Status.GetStatusCode() != tstatus.TStatusCode_OK {
 			err = xerror.Errorf(xerror.FE, "get snapshot failed, status: %v", snapshotResp.Status)
 			return err
@@ -868,7 +1096,7 @@ func (j *Job) fullSync() error {
 			}
 		}
 
-		inMemoryData := &inMemoryData{
+		inMemoryData := &fullSyncInMemoryData{
 			SnapshotName:      snapshotName,
 			SnapshotResp:      snapshotResp,
 			TableCommitSeqMap: tableCommitSeqMap,
@@ -881,7 +1109,7 @@ func (j *Job) fullSync() error {
 		// Step 4: Add extra info
 		log.Infof("fullsync status: add extra info")
 
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*fullSyncInMemoryData)
 		snapshotResp := inMemoryData.SnapshotResp
 		jobInfo := snapshotResp.GetJobInfo()
 
@@ -903,7 +1131,7 @@ func (j *Job) fullSync() error {
 
 		if j.progress.InMemoryData == nil {
 			persistData := j.progress.PersistData
-			inMemoryData := &inMemoryData{}
+			inMemoryData := &fullSyncInMemoryData{}
 			if err := json.Unmarshal([]byte(persistData), inMemoryData); err != nil {
 				return xerror.Errorf(xerror.Normal, "unmarshal persistData failed, persistData: %s", persistData)
 			}
@@ -911,7 +1139,7 @@ func (j *Job) fullSync() error {
 		}
 
 		// Step 5.1: cancel the running restore job which by the former process, if exists
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*fullSyncInMemoryData)
 		snapshotName := inMemoryData.SnapshotName
 		if featureReuseRunningBackupRestoreJob {
 			restoreSnapshotName, err := j.IDest.GetValidRestoreJob(snapshotName)
@@ -994,7 +1222,8 @@ func (j *Job) fullSync() error {
 		if featureCleanTableAndPartitions {
 			// drop exists partitions, and drop tables if in db sync.
 			restoreReq.CleanPartitions = true
-			if j.SyncType == DBSync {
+			// The filtered tables are not in the snapshot, keep them in the dest.
+			if j.SyncType == DBSync && !j.hasTableFilter() {
 				restoreReq.CleanTables = true
 			}
 		}
@@ -1016,7 +1245,7 @@ func (j *Job) fullSync() error {
 
 	case WaitRestoreDone:
 		// Step 6: Wait restore job done
-		inMemoryData := j.progress.InMemoryData.(*inMemoryData)
+		inMemoryData := j.progress.InMemoryData.(*fullSyncInMemoryData)
 		restoreSnapshotName := inMemoryData.RestoreLabel
 		tableNameMapping := inMemoryData.TableNameMapping
 		snapshotResp := inMemoryData.SnapshotResp
@@ -1026,7 +1255,7 @@ func (j *Job) fullSync() error {
 			if err := j.IDest.CancelRestoreIfExists(restoreSnapshotName); err != nil {
 				return err
 			}
-			return j.newSnapshot(j.progress.CommitSeq)
+			return j.newSnapshot(j.progress.CommitSeq, "the snapshot is expired before restore")
 		}
 
 		for {
@@ -1206,12 +1435,16 @@ func (j *Job) fullSync() error {
 }
 
 func (j *Job) persistJob() error {
+	if j.parent != nil {
+		return j.parent.persistFanoutDest(j.destIndex, j.Dest)
+	}
+
 	data, err := json.Marshal(j)
 	if err != nil {
 		return xerror.Errorf(xerror.Normal, "marshal job failed, job: %v", j)
 	}
 
-	if err := j.db.UpdateJob(j.Name, string(data)); err != nil {
+	if err := j.fence.updateJob(j.db, j.Name, string(data)); err != nil {
 		return err
 	}
 
@@ -1295,6 +1528,10 @@ func (j *Job) getDbSyncTableRecords(upsert *record.Upsert) []*record.TableRecord
 	tableRecords := make([]*record.TableRecord, 0, len(upsert.TableRecords))
 
 	for tableId, tableRecord := range upsert.TableRecords {
+		if j.isTableFiltered(tableId, "") {
+			continue
+		}
+
 		// DBIncrementalSync
 		if tableCommitSeqMap == nil {
 			tableRecords = append(tableRecords, tableRecord)
@@ -1439,6 +1676,9 @@ func (j *Job) handleUpsert(binlog *festruct.TBinlog) error {
 		}
 
 		log.Debugf("tableRecords: %v", tableRecords)
+		if j.DryRun {
+			return j.planIngestBinlog(tableRecords)
+		}
 		destTableIds := make([]int64, 0, len(tableRecords))
 		if j.SyncType == DBSync {
 			for _, tableRecord := range tableRecords {
@@ -1607,6 +1847,10 @@ func (j *Job) handleAddPartition(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(addPartition.TableId, "") {
+		return nil
+	}
+
 	if addPartition.IsTemp {
 		log.Infof("skip add temporary partition because backup/restore table with temporary partitions is not supported yet")
 		return nil
@@ -1650,6 +1894,10 @@ func (j *Job) handleDropPartition(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(dropPartition.TableId, "") {
+		return nil
+	}
+
 	var destTableName string
 	if j.SyncType == TableSync {
 		destTableName = j.Dest.Table
@@ -1687,6 +1935,19 @@ func (j *Job) handleCreateTable(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(createTable.TableId, createTable.TableName) {
+		// Keep the name of the filtered table, it is used to filter the following binlogs.
+		if createTable.TableName != "" {
+			if j.progress.TableNameMapping == nil {
+				j.progress.TableNameMapping = make(map[int64]string)
+			}
+			j.progress.TableNameMapping[createTable.TableId] = createTable.TableName
+		}
+		log.Infof("skip create table %s (id: %d), it is filtered by the table filter",
+			createTable.TableName, createTable.TableId)
+		return nil
+	}
+
 	if featureCreateViewDropExists {
 		viewRegex := regexp.MustCompile(`(?i)^CREATE(\s+)VIEW`)
 		isCreateView := viewRegex.MatchString(createTable.Sql)
@@ -1753,6 +2014,13 @@ func (j *Job) handleDropTable(binlog *festruct.TBinlog) error {
 		return err
 	}
 
+	if j.isTableFiltered(dropTable.TableId, dropTable.TableName) {
+		log.Infof("skip drop table %s (id: %d), it is filtered by the table filter",
+			dropTable.TableName, dropTable.TableId)
+		delete(j.progress.TableNameMapping, dropTable.TableId)
+		return nil
+	}
+
 	if !dropTable.IsView {
 		if _, ok := j.progress.TableMapping[dropTable.TableId]; !ok {
 			log.Warnf("the dest table is not found, skip drop table binlog, src table id: %d, commit seq: %d",
@@ -1806,7 +2074,7 @@ func (j *Job) handleDummy(binlog *festruct.TBinlog) error {
 
 	log.Infof("handle dummy binlog, need full sync. SyncType: %v, seq: %v", j.SyncType, dummyCommitSeq)
 
-	return j.newSnapshot(dummyCommitSeq)
+	return j.newSnapshot(dummyCommitSeq, "receive dummy binlog")
 }
 
 // handleAlterJob
@@ -1825,6 +2093,10 @@ func (j *Job) handleAlterJob(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(alterJob.TableId, alterJob.TableName) {
+		return nil
+	}
+
 	if !alterJob.IsFinished() {
 		switch alterJob.JobState {
 		case record.ALTER_JOB_STATE_PENDING:
@@ -1862,7 +2134,7 @@ func (j *Job) handleAlterJob(binlog *festruct.TBinlog) error {
 		}
 
 		replaceTable := true
-		return j.newPartialSnapshot(alterJob.TableId, alterJob.TableName, nil, replaceTable)
+		return j.newPartialSnapshot(alterJob.TableId, alterJob.TableName, nil, replaceTable, "schema change")
 	}
 
 	var allViewDeleted bool = false
@@ -1894,7 +2166,7 @@ func (j *Job) handleAlterJob(binlog *festruct.TBinlog) error {
 		}
 	}
 
-	return j.newSnapshot(j.progress.CommitSeq)
+	return j.newSnapshot(j.progress.CommitSeq, "the dest table is dropped by alter job")
 }
 
 // handleLightningSchemaChange
@@ -1912,6 +2184,10 @@ func (j *Job) handleLightningSchemaChange(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(lightningSchemaChange.TableId, "") {
+		return nil
+	}
+
 	tableAlias := ""
 	if j.isTableSyncWithAlias() {
 		tableAlias = j.Dest.Table
@@ -1938,6 +2214,10 @@ func (j *Job) handleRenameColumnRecord(commitSeq int64, renameColumn *record.Ren
 		return nil
 	}
 
+	if j.isTableFiltered(renameColumn.TableId, "") {
+		return nil
+	}
+
 	destTableId, err := j.getDestTableIdBySrc(renameColumn.TableId)
 	if err != nil {
 		return err
@@ -1965,6 +2245,10 @@ func (j *Job) handleModifyComment(binlog *festruct.TBinlog) error {
 		return err
 	}
 
+	if j.isTableFiltered(modifyComment.TblId, "") {
+		return nil
+	}
+
 	destTableId, err := j.getDestTableIdBySrc(modifyComment.TblId)
 	if err != nil {
 		return err
@@ -1995,6 +2279,10 @@ func (j *Job) handleTruncateTable(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(truncateTable.TableId, truncateTable.TableName) {
+		return nil
+	}
+
 	var destTableName string
 	switch j.SyncType {
 	case DBSync:
@@ -2028,14 +2316,18 @@ func (j *Job) handleReplacePartitions(binlog *festruct.TBinlog) error {
 		return nil
 	}
 
+	if j.isTableFiltered(replacePartition.TableId, replacePartition.TableName) {
+		return nil
+	}
+
 	if !replacePartition.StrictRange {
 		log.Warnf("replacing partitions with non strict range is not supported yet, replace partition record: %s", string(data))
-		return j.newSnapshot(j.progress.CommitSeq)
+		return j.newSnapshot(j.progress.CommitSeq, "replace partitions with non strict range")
 	}
 
 	if replacePartition.UseTempName {
 		log.Warnf("replacing partitions with use tmp name is not supported yet, replace partition record: %s", string(data))
-		return j.newSnapshot(j.progress.CommitSeq)
+		return j.newSnapshot(j.progress.CommitSeq, "replace partitions with temp name")
 	}
 
 	oldPartitions := strings.Join(replacePartition.Partitions, ",")
@@ -2048,7 +2340,7 @@ func (j *Job) handleReplacePartitions(binlog *festruct.TBinlog) error {
 		partitions = replacePartition.TempPartitions
 	}
 
-	return j.newPartialSnapshot(replacePartition.TableId, replacePartition.TableName, partitions, false)
+	return j.newPartialSnapshot(replacePartition.TableId, replacePartition.TableName, partitions, false, "replace partitions")
 }
 
 func (j *Job) handleModifyPartitions(binlog *festruct.TBinlog) error {
@@ -2084,6 +2376,24 @@ func (j *Job) handleRenameTableRecord(commitSeq int64, renameTable *record.Renam
 		return nil
 	}
 
+	if j.isTableFiltered(renameTable.TableId, renameTable.OldTableName) {
+		if j.progress.TableNameMapping == nil {
+			j.progress.TableNameMapping = make(map[int64]string)
+		}
+		j.progress.TableNameMapping[renameTable.TableId] = renameTable.NewTableName
+		if j.isTableFiltered(renameTable.TableId, renameTable.NewTableName) {
+			log.Infof("skip rename table %s to %s, both are filtered by the table filter",
+				renameTable.OldTableName, renameTable.NewTableName)
+			return nil
+		}
+
+		// The table is not exists in the dest cluster, sync it via partial snapshot.
+		log.Infof("the table %s is renamed to %s which is included by the table filter, sync it via partial snapshot",
+			renameTable.OldTableName, renameTable.NewTableName)
+		replace := false
+		return j.newPartialSnapshot(renameTable.TableId, renameTable.NewTableName, nil, replace, "an excluded table is renamed to an included one")
+	}
+
 	destTableId, err := j.getDestTableIdBySrc(renameTable.TableId)
 	if err != nil {
 		return err
@@ -2115,6 +2425,13 @@ func (j *Job) handleRenameTableRecord(commitSeq int64, renameTable *record.Renam
 	}
 	j.progress.TableNameMapping[renameTable.TableId] = renameTable.NewTableName
 
+	if j.isTableFiltered(renameTable.TableId, renameTable.NewTableName) {
+		// The table is excluded since now, stop syncing it.
+		log.Infof("the table %s is renamed to %s which is excluded by the table filter, stop syncing it",
+			renameTable.OldTableName, renameTable.NewTableName)
+		delete(j.progress.TableMapping, renameTable.TableId)
+	}
+
 	return nil
 }
 
@@ -2139,6 +2456,14 @@ func (j *Job) handleReplaceTableRecord(commitSeq int64, record *record.ReplaceTa
 		return xerror.Errorf(xerror.Normal, "replace table is not supported when table sync, consider rebuilding this job instead")
 	}
 
+	if j.hasTableFilter() {
+		originIncluded := j.TableFilter.IsTableIncluded(record.OriginTableName)
+		newIncluded := j.TableFilter.IsTableIncluded(record.NewTableName)
+		if !originIncluded || !newIncluded {
+			return j.handleFilteredReplaceTableRecord(commitSeq, record, originIncluded, newIncluded)
+		}
+	}
+
 	if j.isBinlogCommitted(record.OriginTableId, commitSeq) {
 		if !featureReplayReplaceTableIdempotent {
 			return nil
@@ -2200,7 +2525,7 @@ func (j *Job) handleReplaceTableRecord(commitSeq int64, record *record.ReplaceTa
 				log.Warnf("the new table %s not found in dest cluster, rebuild via partial snapshot, src table id: %d",
 					record.NewTableName, record.OriginTableId)
 				replace := true
-				return j.newPartialSnapshot(record.OriginTableId, record.NewTableName, nil, replace)
+				return j.newPartialSnapshot(record.OriginTableId, record.NewTableName, nil, replace, "the new table of replace table not found in dest")
 			}
 
 			destTableId, ok = j.progress.TableMapping[record.NewTableId]
@@ -2214,7 +2539,7 @@ func (j *Job) handleReplaceTableRecord(commitSeq int64, record *record.ReplaceTa
 				log.Warnf("the origin table %s not found in dest cluster, rebuild via partial snapshot, src table id: %d",
 					record.OriginTableName, record.NewTableId)
 				replace := true
-				return j.newPartialSnapshot(record.NewTableId, record.OriginTableName, nil, replace)
+				return j.newPartialSnapshot(record.NewTableId, record.OriginTableName, nil, replace, "the origin table of replace table not found in dest")
 			}
 		} else {
 			// The origin table (id, not name) must be dropped, if the origin table still
@@ -2258,6 +2583,68 @@ func (j *Job) handleReplaceTableRecord(commitSeq int64, record *record.ReplaceTa
 	return nil
 }
 
+// handleFilteredReplaceTableRecord handles the replace table record, which at least one of
+// the tables is excluded by the table filter.
+func (j *Job) handleFilteredReplaceTableRecord(commitSeq int64, record *record.ReplaceTableRecord,
+	originIncluded, newIncluded bool) error {
+	updateTableNameMapping := func() {
+		if j.progress.TableNameMapping == nil {
+			j.progress.TableNameMapping = make(map[int64]string)
+		}
+		j.progress.TableNameMapping[record.NewTableId] = record.OriginTableName
+		if record.SwapTable {
+			j.progress.TableNameMapping[record.OriginTableId] = record.NewTableName
+		} else {
+			delete(j.progress.TableNameMapping, record.OriginTableId)
+		}
+	}
+
+	if !originIncluded && !newIncluded {
+		log.Infof("skip replace table %s with %s, both are filtered by the table filter",
+			record.OriginTableName, record.NewTableName)
+		updateTableNameMapping()
+		return nil
+	}
+
+	// Only one of the tables is included, find the upstream table which holds the included
+	// name after replacing.
+	var includedName string
+	var prevTableId, tableId int64
+	if originIncluded {
+		includedName = record.OriginTableName
+		prevTableId = record.OriginTableId
+		tableId = record.NewTableId
+	} else {
+		includedName = record.NewTableName
+		prevTableId = record.NewTableId
+		if record.SwapTable {
+			tableId = record.OriginTableId
+		}
+	}
+
+	if tableId != 0 && j.isBinlogCommitted(tableId, commitSeq) {
+		return nil
+	}
+
+	updateTableNameMapping()
+	delete(j.progress.TableMapping, prevTableId)
+
+	if tableId == 0 {
+		// The included table is replaced by an excluded table, drop it.
+		log.Infof("the table %s is replaced with an excluded table, drop it", includedName)
+		if err := j.IDest.DropTable(includedName, true); err != nil {
+			return err
+		}
+		j.destMeta.ClearTablesCache()
+		return nil
+	}
+
+	log.Infof("the table %s is replaced with the data of an excluded table, sync it via partial snapshot, table id: %d",
+		includedName, tableId)
+	replace := true
+	return j.newPartialSnapshot(tableId, includedName, nil, replace, "an included table is replaced with an excluded one")
+}
+
 func (j *Job) handleModifyTableAddOrDropInvertedIndices(binlog *festruct.TBinlog) error {
 	log.Infof("handle modify table add or drop inverted indices binlog, prevCommitSeq: %d, commitSeq: %d",
 		j.progress.PrevCommitSeq, j.progress.CommitSeq)
@@ -2276,6 +2663,10 @@ func (j *Job) handleModifyTableAddOrDropInvertedIndicesRecord(commitSeq int64, r
 		return nil
 	}
 
+	if j.isTableFiltered(record.TableId, "") {
+		return nil
+	}
+
 	tableAlias := ""
 	if j.isTableSyncWithAlias() {
 		tableAlias = j.Dest.Table
@@ -2309,6 +2700,10 @@ func (j *Job) handleIndexChangeJobRecord(commitSeq int64, indexChangeJob *record
 		return nil
 	}
 
+	if j.isTableFiltered(indexChangeJob.TableId, indexChangeJob.TableName) {
+		return nil
+	}
+
 	if indexChangeJob.JobState != record.INDEX_CHANGE_JOB_STATE_FINISHED ||
 		indexChangeJob.IsDropOp {
 		log.Debugf("skip index change job binlog, job state: %s, is drop op: %t",
@@ -2391,6 +2786,10 @@ func (j *Job) handleAlterViewDef(binlog *festruct.TBinlog) error {
 		return err
 	}
 
+	if j.isTableFiltered(alterView.TableId, "") {
+		return nil
+	}
+
 	viewName, err := j.getDestTableNameBySrcId(alterView.TableId)
 	if err != nil {
 		return err
@@ -2404,20 +2803,41 @@ func (j *Job) handleBinlogs(binlogs []*festruct.TBinlog) (error, bool) {
 	log.Infof("handle binlogs, binlogs size: %d", len(binlogs))
 
 	for _, binlog := range binlogs {
-		// Step 1: dispatch handle binlog
-		if err := j.handleBinlog(binlog); err != nil {
+		if !j.waitBinlogRate() {
+			log.Infof("job %s is stopped while waiting for the binlog rate limit", j.Name)
+			return nil, true
+		}
+
+		if j.hasPendingUpdate() {
+			log.Infof("job %s has pending updates, back to run loop", j.Name)
+			return nil, true
+		}
+
+		if j.isStopping() {
+			return nil, true
+		}
+
+		// Step 1: stop before the binlog beyond the stop target
+		if reached, err := j.checkStopTargetByBinlog(binlog); err != nil {
+			return err, false
+		} else if reached {
+			return nil, true
+		}
+
+		// Step 2: dispatch handle binlog
+		if err := j.binlogSink.Sink(binlog); err != nil {
 			log.Errorf("handle binlog failed, prevCommitSeq: %d, commitSeq: %d, binlog type: %s, binlog data: %s",
 				j.progress.PrevCommitSeq, j.progress.CommitSeq, binlog.GetType(), binlog.GetData())
 			return err, false
 		}
 
-		// Step 2: check job state, if not incrementalSync, such as DBPartialSync, break
+		// Step 3: check job state, if not incrementalSync, such as DBPartialSync, break
 		if !j.isIncrementalSync() {
 			log.Debugf("job state is not incremental sync, back to run loop, job state: %s", j.progress.SyncState)
 			return nil, true
 		}
 
-		// Step 3: update progress
+		// Step 4: update progress
 		commitSeq := binlog.GetCommitSeq()
 		if j.SyncType == DBSync && j.progress.TableCommitSeqMap != nil {
 			// when all table commit seq > commitSeq, it's true
@@ -2435,10 +2855,16 @@ func (j *Job) handleBinlogs(binlogs []*festruct.TBinlog) (error, bool) {
 			}
 		}
 
-		// Step 4: update progress to db
+		// Step 5: update progress to db
 		if !j.progress.IsDone() {
 			j.progress.Done()
 		}
+		j.recordBinlogSynced()
+
+		// Step 6: go back to compare the dest once it reaches the pinned commit seq of the verification
+		if j.countVerifyBinlog(commitSeq) {
+			return nil, true
+		}
 	}
 	return nil, false
 }
@@ -2534,18 +2960,38 @@ func (j *Job) incrementalSync() error {
 
 	// Step 2: handle all binlog
 	for {
+		if j.isStopping() {
+			// Back to run loop to stop the job.
+			return nil
+		}
+
 		if j.forceFullsync {
 			log.Warnf("job is forced to step fullsync by user")
 			j.forceFullsync = false
-			_ = j.newSnapshot(j.progress.CommitSeq)
+			_ = j.newSnapshot(j.progress.CommitSeq, "forced by user")
 			return nil
 		}
 
+		if j.hasPendingVerify() {
+			log.Infof("job %s has pending verify requests, back to run loop", j.Name)
+			return nil
+		}
+
+		if j.hasPendingUpdate() {
+			log.Infof("job %s has pending updates, back to run loop", j.Name)
+			return nil
+		}
+
+		j.syncStopTarget()
+		if reached, err := j.checkStopTargetByProgress(false); err != nil || reached {
+			return err
+		}
+
 		// The CommitSeq is equals to PrevCommitSeq in here.
 		commitSeq := j.progress.CommitSeq
 		log.Debugf("src: %s, commitSeq: %v", src, commitSeq)
 
-		getBinlogResp, err := srcRpc.GetBinlog(src, commitSeq)
+		getBinlogResp, err := j.getBinlog(srcRpc, commitSeq)
 		if err != nil {
 			return err
 		}
@@ -2557,7 +3003,9 @@ func (j *Job) incrementalSync() error {
 		case tstatus.TStatusCode_OK:
 		case tstatus.TStatusCode_BINLOG_TOO_OLD_COMMIT_SEQ:
 		case tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ:
-			return nil
+			// No more binlogs for now.
+			_, err := j.checkStopTargetByProgress(true)
+			return err
 		case tstatus.TStatusCode_BINLOG_DISABLE:
 			return xerror.Errorf(xerror.Normal, "binlog is disabled")
 		case tstatus.TStatusCode_BINLOG_NOT_FOUND_DB:
@@ -2590,6 +3038,7 @@ func (j *Job) recoverJobProgress() error {
 		log.Errorf("parse job progress failed, job: %s, err: %+v", j.Name, err)
 		return err
 	} else {
+		progress.fence = j.fence
 		j.progress = progress
 		return nil
 	}
@@ -2669,14 +3118,16 @@ func (j *Job) handleError(err error) error {
 	}
 
 	xmetrics.AddError(xerr)
+	j.recordEvent(JobEventError, "%s error: %v", xerr.Category().Name(), err)
 	if xerr.IsPanic() {
 		log.Errorf("job panic, job: %s, err: %+v", j.Name, err)
+		notify.JobPanic(j.Name, err)
 		return err
 	}
 
 	if xerr.Category() == xerror.Meta {
 		log.Warnf("receive meta category error, make new snapshot, job: %s, err: %v", j.Name, err)
-		_ = j.newSnapshot(j.progress.CommitSeq)
+		_ = j.newSnapshot(j.progress.CommitSeq, fmt.Sprintf("meta error: %v", err))
 	}
 	return nil
 }
@@ -2695,6 +3146,12 @@ func (j *Job) run() {
 			return
 		}
 
+		if j.fence.IsLost() {
+			gls.DeleteGls(gls.GoID())
+			log.Infof("job stopped, it is dispatched to another syncer, job: %s", j.Name)
+			return
+		}
+
 		select {
 		case <-j.stop:
 			gls.DeleteGls(gls.GoID())
@@ -2702,6 +3159,10 @@ func (j *Job) run() {
 			return
 
 		case <-ticker.C:
+			j.applySchedule()
+			j.handleVerify()
+			j.refreshLag()
+
 			// loop to print error, not panic, waiting for user to pause/stop/remove Job
 			if j.getJobState() != JobRunning {
 				break
@@ -2714,17 +3175,30 @@ func (j *Job) run() {
 
 			err := j.sync()
 			if err == nil {
+				j.recordSyncSuccess()
 				break
 			}
 
 			log.Warnf("job sync failed, job: %s, err: %+v", j.Name, err)
+			j.recordSyncError(err)
 			panicError = j.handleError(err)
 		}
 	}
 }
 
-func (j *Job) newSnapshot(commitSeq int64) error {
-	log.Infof("new snapshot, commitSeq: %d", commitSeq)
+func (j *Job) newSnapshot(commitSeq int64, reason string) error {
+	if !j.Sink.IsDoris() {
+		return j.newSinkIncrementalSync(commitSeq)
+	}
+	if j.DryRun {
+		return j.dryRunSnapshot(commitSeq, fmt.Sprintf("full sync, reason: %s", reason))
+	}
+
+	log.Infof("new snapshot, commitSeq: %d, reason: %s", commitSeq, reason)
+	j.recordEvent(JobEventFullSync, "new snapshot, commit seq: %d, reason: %s", commitSeq, reason)
+	if j.isIncrementalSync() {
+		notify.FullSync(j.Name, reason)
+	}
 
 	j.progress.PartialSyncData = nil
 	j.progress.TableAliases = nil
@@ -2743,12 +3217,32 @@ func (j *Job) newSnapshot(commitSeq int64) error {
 	}
 }
 
+// The snapshot could not be restored into sinks other than doris (or in dry run), so publish
+// the binlogs since the commitSeq directly.
+func (j *Job) newSinkIncrementalSync(commitSeq int64) error {
+	log.Infof("start incremental sync without snapshot, job: %s, commitSeq: %d", j.Name, commitSeq)
+
+	j.progress.PartialSyncData = nil
+	j.progress.TableAliases = nil
+	j.progress.TableCommitSeqMap = nil
+	switch j.SyncType {
+	case TableSync:
+		j.progress.NextWithPersist(commitSeq, TableIncrementalSync, Done, "")
+		return nil
+	case DBSync:
+		j.progress.NextWithPersist(commitSeq, DBIncrementalSync, Done, "")
+		return nil
+	default:
+		return xerror.Panicf(xerror.Normal, "unknown table sync type: %v", j.SyncType)
+	}
+}
+
 // New partial snapshot, with the source cluster table name and the partitions to sync.
 // A empty partitions means to sync the whole table.
 //
 // If the replace is true, the restore task will load data into a new table and replaces the old
 // one when restore finished. So replace requires whole table partial sync.
-func (j *Job) newPartialSnapshot(tableId int64, table string, partitions []string, replace bool) error {
+func (j *Job) newPartialSnapshot(tableId int64, table string, partitions []string, replace bool, reason string) error {
 	if j.SyncType == TableSync && table != j.Src.Table {
 		return xerror.Errorf(xerror.Normal,
 			"partial sync table name is not equals to the source name %s, table: %s, sync type: table", j.Src.Table, table)
@@ -2761,6 +3255,12 @@ func (j *Job) newPartialSnapshot(tableId int64, table string, partitions []strin
 
 	// The binlog of commitSeq will be skipped once the partial snapshot finished.
 	commitSeq := j.progress.CommitSeq
+	if j.DryRun {
+		return j.dryRunSnapshot(commitSeq, fmt.Sprintf("partial sync table %s, partitions: %v, replace: %v, reason: %s",
+			table, partitions, replace, reason))
+	}
+	j.recordEvent(JobEventPartialSync, "new partial snapshot, commit seq: %d, table: %s, partitions: %v, replace: %v, reason: %s",
+		commitSeq, table, partitions, replace, reason)
 
 	syncData := &JobPartialSyncData{
 		TableId:    tableId,
@@ -2797,6 +3297,13 @@ func (j *Job) newPartialSnapshot(tableId int64, table string, partitions []strin
 
 // run job
 func (j *Job) Run() error {
+	jobWatch.updateState(j.Name, j.getJobState())
+	defer jobWatch.remove(j.Name)
+
+	if j.IsFanout() {
+		return j.runFanout()
+	}
+
 	gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
 	gls.Set("job", j.Name)
 
@@ -2815,6 +3322,23 @@ func (j *Job) Run() error {
 		return err
 	}
 
+	if j.DryRun {
+		if err := j.initDryRun(); err != nil {
+			return err
+		}
+	}
+
+	binlogSink, err := NewBinlogSink(j)
+	if err != nil {
+		return err
+	}
+	j.binlogSink = binlogSink
+	defer func() {
+		if err := j.binlogSink.Close(); err != nil {
+			log.Warnf("close binlog sink failed, job: %s, err: %+v", j.Name, err)
+		}
+	}()
+
 	if isProgressExist {
 		if err := j.recoverJobProgress(); err != nil {
 			log.Errorf("recover job %s progress failed: %+v", j.Name, err)
@@ -2822,15 +3346,22 @@ func (j *Job) Run() error {
 		}
 	} else {
 		j.progress = NewJobProgress(j.Name, j.SyncType, j.db)
-		if err := j.newSnapshot(0); err != nil {
+		j.progress.fence = j.fence
+		if err := j.newSnapshot(0, "the job is created"); err != nil {
 			return err
 		}
 	}
+	jobWatch.updateProgress(j.progress)
+	j.lock.Lock()
+	j.syncStopTarget()
+	j.lock.Unlock()
 
 	// Hack: for drop table
 	if j.SyncType == DBSync {
 		j.srcMeta.ClearTablesCache()
-		j.destMeta.ClearTablesCache()
+		if j.Sink.IsDoris() {
+			j.destMeta.ClearTablesCache()
+		}
 	}
 
 	j.run()
@@ -2864,6 +3395,14 @@ func (j *Job) desyncDB() error {
 }
 
 func (j *Job) Desync() error {
+	if j.IsFanout() {
+		return j.fanoutDesync()
+	}
+
+	if !j.Sink.IsDoris() {
+		return nil
+	}
+
 	if j.SyncType == DBSync {
 		return j.desyncDB()
 	} else {
@@ -2872,6 +3411,16 @@ func (j *Job) Desync() error {
 }
 
 func (j *Job) UpdateSkipError(skipError bool) error {
+	if err := j.updateSkipError(skipError); err != nil {
+		return err
+	}
+	if j.IsFanout() {
+		j.fanoutUpdateSkipError(skipError)
+	}
+	return nil
+}
+
+func (j *Job) updateSkipError(skipError bool) error {
 	j.lock.Lock()
 	defer j.lock.Unlock()
 
@@ -2885,19 +3434,20 @@ func (j *Job) UpdateSkipError(skipError bool) error {
 		j.SkipError = originSkipError
 		return err
 	} else {
+		j.recordEvent(JobEventUpdate, "update skip error from %v to %v", originSkipError, skipError)
 		return nil
 	}
 }
 
 // stop job
 func (j *Job) Stop() {
-	close(j.stop)
+	j.stopOnce.Do(func() { close(j.stop) })
 }
 
 // delete job
 func (j *Job) Delete() {
 	j.isDeleted.Store(true)
-	close(j.stop)
+	j.stopOnce.Do(func() { close(j.stop) })
 }
 
 func (j *Job) maybeDeleted() bool {
@@ -2915,7 +3465,7 @@ func (j *Job) maybeDeleted() bool {
 
 func (j *Job) updateFrontends() error {
 	if frontends, err := j.srcMeta.GetFrontends(); err != nil {
-		log.Warnf("get src frontends failed, fe: %+v", j.Src)
+		log.Warnf("get src frontends failed, fe: %s", &j.Src)
 		return err
 	} else {
 		for _, frontend := range frontends {
@@ -2924,8 +3474,12 @@ func (j *Job) updateFrontends() error {
 	}
 	log.Debugf("src frontends %+v", j.Src.Frontends)
 
+	if !j.Sink.IsDoris() {
+		return nil
+	}
+
 	if frontends, err := j.destMeta.GetFrontends(); err != nil {
-		log.Warnf("get dest frontends failed, fe: %+v", j.Dest)
+		log.Warnf("get dest frontends failed, fe: %s", &j.Dest)
 		return err
 	} else {
 		for _, frontend := range frontends {
@@ -2938,6 +3492,10 @@ func (j *Job) updateFrontends() error {
 }
 
 func (j *Job) FirstRun() error {
+	if j.IsFanout() {
+		return j.fanoutFirstRun()
+	}
+
 	log.Infof("first run check job, src: %s, dest: %s", &j.Src, &j.Dest)
 
 	// Step 0: get all frontends
@@ -2949,8 +3507,10 @@ func (j *Job) FirstRun() error {
 	if err := j.srcMeta.CheckBinlogFeature(); err != nil {
 		return err
 	}
-	if err := j.destMeta.CheckBinlogFeature(); err != nil {
-		return err
+	if j.Sink.IsDoris() {
+		if err := j.destMeta.CheckBinlogFeature(); err != nil {
+			return err
+		}
 	}
 
 	// Step 2: check src database
@@ -2995,6 +3555,12 @@ func (j *Job) FirstRun() error {
 
 	// Step 4: check dest database && table exists
 	// if dest database && table exists, return err
+	if !j.Sink.IsDoris() {
+		return nil
+	}
+	if j.DryRun {
+		return j.dryRunFirstRun()
+	}
 	dest_db_exists, err := j.IDest.CheckDatabaseExists()
 	if err != nil {
 		return err
@@ -3023,26 +3589,43 @@ func (j *Job) FirstRun() error {
 }
 
 func (j *Job) GetLag() (int64, error) {
+	if j.IsFanout() {
+		return j.fanoutGetLag()
+	}
+
 	j.lock.Lock()
-	defer j.lock.Unlock()
+	commitSeq := j.progress.CommitSeq
+	j.lock.Unlock()
+
+	return j.getLag(commitSeq)
+}
 
+// getLag gets the number of the binlogs after the commit seq in the source. The src spec is only
+// changed by the first run before the job loop, so it is read without the job lock, which is held
+// by sync() during the incremental sync.
+func (j *Job) getLag(commitSeq int64) (int64, error) {
 	srcSpec := &j.Src
 	rpc, err := j.factory.NewFeRpc(srcSpec)
 	if err != nil {
 		return 0, err
 	}
 
-	commitSeq := j.progress.CommitSeq
 	resp, err := rpc.GetBinlogLag(srcSpec, commitSeq)
 	if err != nil {
 		return 0, err
 	}
 
 	log.Debugf("resp: %v, lag: %d", resp, resp.GetLag())
+	j.recordLag(resp.GetLag())
 	return resp.GetLag(), nil
 }
 
 func (j *Job) getJobState() JobState {
+	// The child of fan-out job follows the state of the parent.
+	if j.parent != nil {
+		return j.parent.getJobState()
+	}
+
 	j.lock.Lock()
 	defer j.lock.Unlock()
 
@@ -3053,21 +3636,43 @@ func (j *Job) changeJobState(state JobState) error {
 	j.lock.Lock()
 	defer j.lock.Unlock()
 
-	if j.State == state {
+	if j.State == JobCompleted {
+		return xerror.Errorf(xerror.Normal, "job %s is completed", j.Name)
+	}
+	if state == JobRunning && j.Switchover.blocksResume() {
+		return xerror.Errorf(xerror.Normal, "job %s is switched over to %s", j.Name, j.Switchover.ReverseName)
+	}
+
+	// The job paused by schedule is paused by user since now, don't resume it automatically.
+	if j.State == state && !j.PausedBySchedule {
 		log.Debugf("job %s state is already %s", j.Name, state)
 		return nil
 	}
 
 	originState := j.State
+	originPausedBySchedule := j.PausedBySchedule
 	j.State = state
+	j.PausedBySchedule = false
 	if err := j.persistJob(); err != nil {
 		j.State = originState
+		j.PausedBySchedule = originPausedBySchedule
 		return err
 	}
 	log.Debugf("change job %s state from %s to %s", j.Name, originState, state)
+	j.notifyWatchState()
+	j.recordStateEvent(state, "by user")
 	return nil
 }
 
+func (j *Job) recordStateEvent(state JobState, by string) {
+	switch state {
+	case JobPaused:
+		j.recordEvent(JobEventPause, "pause job %s", by)
+	case JobRunning:
+		j.recordEvent(JobEventResume, "resume job %s", by)
+	}
+}
+
 func (j *Job) Pause() error {
 	log.Infof("pause job %s", j.Name)
 
@@ -3083,6 +3688,11 @@ func (j *Job) Resume() error {
 func (j *Job) ForceFullsync() {
 	log.Infof("force job %s step full sync", j.Name)
 
+	if j.IsFanout() {
+		j.fanoutForceFullsync()
+		return
+	}
+
 	j.lock.Lock()
 	defer j.lock.Unlock()
 	j.forceFullsync = true
@@ -3091,29 +3701,58 @@ func (j *Job) ForceFullsync() {
 type RawJobStatus struct {
 	state         int32
 	progressState int32
+	completedAt   int64
+
+	// The status details, see job_status.go.
+	lock   sync.Mutex
+	detail JobStatusDetail
+	// Only accessed by the job goroutine.
+	lagRefreshedAt time.Time
+	// Whether the lag is being refreshed in background.
+	lagRefreshing int32
 }
 
 func (j *Job) updateJobStatus() {
-	atomic.StoreInt32(&j.rawStatus.state, int32(j.State))
+	state := j.State
+	if j.parent != nil {
+		state = j.parent.getJobState()
+	}
+	atomic.StoreInt32(&j.rawStatus.state, int32(state))
 	if j.progress != nil {
 		atomic.StoreInt32(&j.rawStatus.progressState, int32(j.progress.SyncState))
+		atomic.StoreInt64(&j.rawStatus.completedAt, j.progress.CompletedAt)
 	}
+	j.updateProgressStatus()
 }
 
 type JobStatus struct {
 	Name          string `json:"name"`
 	State         string `json:"state"`
 	ProgressState string `json:"progress_state"`
+	// When the stop target is reached, in unix seconds.
+	CompletedAt int64 `json:"completed_at,omitempty"`
+	// The details of the running job, see job_status.go.
+	JobStatusDetail
+
+	// The status of each dest of the fan-out job.
+	Dests []*JobStatus `json:"dests,omitempty"`
 }
 
 func (j *Job) Status() *JobStatus {
+	if j.IsFanout() {
+		return j.fanoutStatus()
+	}
+
 	state := JobState(atomic.LoadInt32(&j.rawStatus.state)).String()
 	progressState := SyncState(atomic.LoadInt32(&j.rawStatus.progressState)).String()
+	completedAt := atomic.LoadInt64(&j.rawStatus.completedAt)
 
 	return &JobStatus{
-		Name:          j.Name,
-		State:         state,
-		ProgressState: progressState,
+		Name:            j.Name,
+		State:           state,
+		ProgressState:   progressState,
+		CompletedAt:     completedAt,
+		JobStatusDetail: j.getStatusDetail(),
 	}
 }
 
@@ -0,0 +1,279 @@
+package ccr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A dry run job fetches and decodes the binlogs, resolves the table/partition/tablet mappings
+// of the dest cluster, and records what it would do for each binlog: the sqls to execute, the
+// tablets to ingest and the snapshots to make. Nothing is executed in the dest cluster.
+
+// The max number of the dry run results kept in memory, the oldest ones are dropped.
+const DRY_RUN_RESULT_CAPACITY = 1024
+
+type DryRunResult struct {
+	CommitSeq  int64               `json:"commit_seq"`
+	BinlogType string              `json:"binlog_type"`
+	TableIds   []int64             `json:"table_ids,omitempty"`
+	HandledAt  int64               `json:"handled_at"` // unix milliseconds
+	Sqls       []string            `json:"sqls,omitempty"`
+	IngestPlan []*IngestTabletPlan `json:"ingest_plan,omitempty"`
+	Snapshots  []string            `json:"snapshots,omitempty"` // the full/partial snapshots would be made
+	Error      string              `json:"error,omitempty"`
+}
+
+type dryRunResults struct {
+	lock    sync.Mutex
+	results []*DryRunResult
+	current *DryRunResult
+}
+
+func newDryRunResults() *dryRunResults {
+	return &dryRunResults{
+		results: make([]*DryRunResult, 0),
+	}
+}
+
+func (r *dryRunResults) begin(binlog *festruct.TBinlog) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.current = &DryRunResult{
+		CommitSeq:  binlog.GetCommitSeq(),
+		BinlogType: binlog.GetType().String(),
+		TableIds:   binlog.GetTableIds(),
+		HandledAt:  time.Now().UnixMilli(),
+	}
+}
+
+func (r *dryRunResults) end(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.current == nil {
+		return
+	}
+	if err != nil {
+		r.current.Error = err.Error()
+	}
+
+	r.results = append(r.results, r.current)
+	if len(r.results) > DRY_RUN_RESULT_CAPACITY {
+		r.results = r.results[len(r.results)-DRY_RUN_RESULT_CAPACITY:]
+	}
+	r.current = nil
+}
+
+// update the handling result, it is ignored if no binlog is handling.
+func (r *dryRunResults) update(fn func(result *DryRunResult)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.current != nil {
+		fn(r.current)
+	}
+}
+
+func (r *dryRunResults) recordSql(sql string) {
+	r.update(func(result *DryRunResult) {
+		result.Sqls = append(result.Sqls, sql)
+	})
+}
+
+func (r *dryRunResults) recordSnapshot(snapshot string) {
+	r.update(func(result *DryRunResult) {
+		result.Snapshots = append(result.Snapshots, snapshot)
+	})
+}
+
+// get the results of the commit seq, all results are returned if the commit seq is 0.
+func (r *dryRunResults) get(commitSeq int64) []*DryRunResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	results := make([]*DryRunResult, 0)
+	for _, result := range r.results {
+		if commitSeq == 0 || result.CommitSeq == commitSeq {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+type sqlRecorderSetter interface {
+	SetSqlRecorder(recorder func(sql string))
+}
+
+func (j *Job) validDryRun() error {
+	if !j.Sink.IsDoris() {
+		return xerror.New(xerror.Normal, "dry run only supports the doris sink")
+	}
+	if j.IsFanout() {
+		return xerror.New(xerror.Normal, "dry run does not support fan-out job")
+	}
+	return nil
+}
+
+// initDryRun makes the dest specer record the sqls instead of executing them.
+func (j *Job) initDryRun() error {
+	setter, ok := j.IDest.(sqlRecorderSetter)
+	if !ok {
+		return xerror.Errorf(xerror.Normal, "dest specer %T does not support dry run", j.IDest)
+	}
+
+	j.lock.Lock()
+	if j.dryRunResults == nil {
+		j.dryRunResults = newDryRunResults()
+	}
+	results := j.dryRunResults
+	j.lock.Unlock()
+
+	setter.SetSqlRecorder(results.recordSql)
+	return nil
+}
+
+// dryRunFirstRun checks the dest without modifying it, the dest database must exist since the
+// mappings are resolved with it.
+func (j *Job) dryRunFirstRun() error {
+	if exists, err := j.IDest.CheckDatabaseExists(); err != nil {
+		return err
+	} else if !exists {
+		return xerror.Errorf(xerror.Normal, "dest database %s not exists, which is required by dry run", j.Dest.Database)
+	}
+	if destDbId, err := j.destMeta.GetDbId(); err != nil {
+		return err
+	} else {
+		j.Dest.DbId = destDbId
+	}
+
+	if j.SyncType == TableSync {
+		if exists, err := j.IDest.CheckTableExists(); err != nil {
+			return err
+		} else if !exists {
+			log.Warnf("dest table %s.%s not exists, the upsert binlogs could not be planned in dry run",
+				j.Dest.Database, j.Dest.Table)
+			return nil
+		}
+		if destTableId, err := j.destMeta.GetTableId(j.Dest.Table); err != nil {
+			return err
+		} else {
+			j.Dest.TableId = destTableId
+		}
+	}
+	return nil
+}
+
+// dryRunSnapshot records the snapshot would be made, and starts the incremental sync if the
+// job is not in incremental sync yet.
+func (j *Job) dryRunSnapshot(commitSeq int64, snapshot string) error {
+	log.Infof("dry run, record snapshot: %s", snapshot)
+	j.dryRunResults.recordSnapshot(snapshot)
+
+	if j.isIncrementalSync() {
+		return nil
+	}
+	return j.newSinkIncrementalSync(commitSeq)
+}
+
+// dryRunTableMapping resolves the dest tables of the table records. A dry run never runs the
+// full sync, so the TableMapping might be missing, the dest tables are resolved by the names of
+// the source tables, without touching the progress.
+func (j *Job) dryRunTableMapping(tableRecords []*record.TableRecord) (map[int64]int64, error) {
+	tableMapping := make(map[int64]int64, len(tableRecords))
+	if j.SyncType != DBSync {
+		return tableMapping, nil
+	}
+
+	for _, tableRecord := range tableRecords {
+		if destTableId, ok := j.progress.TableMapping[tableRecord.Id]; ok {
+			tableMapping[tableRecord.Id] = destTableId
+			continue
+		}
+
+		srcTableName, err := j.srcMeta.GetTableNameById(tableRecord.Id)
+		if err != nil {
+			return nil, err
+		}
+		destTableId, err := j.destMeta.GetTableId(srcTableName)
+		if err != nil {
+			return nil, err
+		}
+		tableMapping[tableRecord.Id] = destTableId
+	}
+	return tableMapping, nil
+}
+
+// planIngestBinlog resolves the tablets to ingest of the table records, without beginning the
+// transaction.
+func (j *Job) planIngestBinlog(tableRecords []*record.TableRecord) error {
+	tableMapping, err := j.dryRunTableMapping(tableRecords)
+	if err != nil {
+		return err
+	}
+
+	job, err := j.jobFactory.CreateJob(NewIngestContext(0, tableRecords, tableMapping), j, "IngestBinlog")
+	if err != nil {
+		return err
+	}
+
+	ingestBinlogJob, ok := job.(*IngestBinlogJob)
+	if !ok {
+		return xerror.Errorf(xerror.Normal, "invalid job type, job: %+v", job)
+	}
+
+	plan, err := ingestBinlogJob.Plan()
+	if err != nil {
+		return err
+	}
+	j.dryRunResults.update(func(result *DryRunResult) {
+		result.IngestPlan = append(result.IngestPlan, plan...)
+	})
+	return nil
+}
+
+func (j *Job) GetDryRunResults(commitSeq int64) ([]*DryRunResult, error) {
+	if !j.DryRun {
+		return nil, xerror.Errorf(xerror.Normal, "job %s is not a dry run job", j.Name)
+	}
+
+	j.lock.Lock()
+	results := j.dryRunResults
+	j.lock.Unlock()
+
+	if results == nil {
+		return []*DryRunResult{}, nil
+	}
+	return results.get(commitSeq), nil
+}
+
+// dryRunSink handles the binlogs in dry run mode. The failure of a binlog is recorded in the
+// result instead of retrying, since nothing is executed.
+type dryRunSink struct {
+	job *Job
+}
+
+func (s *dryRunSink) Sink(binlog *festruct.TBinlog) error {
+	j := s.job
+	j.dryRunResults.begin(binlog)
+
+	err := j.handleBinlog(binlog)
+	if err != nil {
+		log.Warnf("dry run binlog failed, commit seq: %d, err: %+v", binlog.GetCommitSeq(), err)
+		j.progress.NextSubVolatile(Done, nil)
+	}
+
+	j.dryRunResults.end(err)
+	return nil
+}
+
+func (s *dryRunSink) Close() error {
+	return nil
+}
@@ -0,0 +1,290 @@
+package ccr
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"go.uber.org/mock/gomock"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+	"github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/types"
+)
+
+const (
+	dryRunSrcTableId  = int64(1)
+	dryRunDestTableId = int64(2)
+)
+
+// newDryRunTestJob returns a db sync dry run job without the table mapping, the src table 1 and
+// the dest table 2 are both named tbl.
+func newDryRunTestJob(t *testing.T, ctrl *gomock.Controller) *Job {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	if err := db.AddJob("job", "{}", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+
+	fe := NewMockIFeRpc(ctrl)
+	fe.EXPECT().GetBackends(gomock.Any()).Return(&festruct.TGetBackendMetaResult_{
+		Status:   &tstatus.TStatus{StatusCode: tstatus.TStatusCode_OK},
+		Backends: []*types.TBackend{},
+	}, nil).AnyTimes()
+	fe.EXPECT().GetTableMeta(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(spec *base.Spec, tableIds []int64) (*festruct.TGetMetaResult_, error) {
+			return dryRunTableMeta(tableIds[0]), nil
+		}).AnyTimes()
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(fe, nil).AnyTimes()
+
+	srcMeta := NewMockMetaer(ctrl)
+	srcMeta.EXPECT().GetTableNameById(dryRunSrcTableId).Return("tbl", nil).AnyTimes()
+	destMeta := NewMockMetaer(ctrl)
+	destMeta.EXPECT().GetTableId("tbl").Return(dryRunDestTableId, nil).AnyTimes()
+
+	progress := NewJobProgress("job", DBSync, db)
+	progress.NextWithPersist(10, DBIncrementalSync, Done, "")
+	return &Job{
+		Name:          "job",
+		SyncType:      DBSync,
+		DryRun:        true,
+		State:         JobRunning,
+		Src:           base.Spec{Database: "src_db"},
+		Dest:          base.Spec{Database: "dest_db"},
+		IDest:         &base.Spec{Database: "dest_db"},
+		srcMeta:       srcMeta,
+		destMeta:      destMeta,
+		db:            db,
+		factory:       NewFactory(rpcFactory, nil, nil, DefaultThriftMetaFactory),
+		jobFactory:    NewJobFactory(),
+		progress:      progress,
+		dryRunResults: newDryRunResults(),
+	}
+}
+
+// dryRunTableMeta returns the meta of the table named tbl, with one partition, one base index
+// and one tablet. The src ids are 10, 100 and 1000, the dest ids are 21, 201 and 2001.
+func dryRunTableMeta(tableId int64) *festruct.TGetMetaResult_ {
+	id := func(srcId int64) *int64 {
+		if tableId == dryRunSrcTableId {
+			return utils.ThriftValueWrapper(srcId)
+		}
+		return utils.ThriftValueWrapper(srcId*2 + 1)
+	}
+	name := "tbl"
+	partitionRange := "range"
+	return &festruct.TGetMetaResult_{
+		Status: &tstatus.TStatus{StatusCode: tstatus.TStatusCode_OK},
+		DbMeta: &festruct.TGetMetaDBMeta{
+			Id: utils.ThriftValueWrapper(tableId * 100),
+			Tables: []*festruct.TGetMetaTableMeta{{
+				Id:   &tableId,
+				Name: &name,
+				Partitions: []*festruct.TGetMetaPartitionMeta{{
+					Id:    id(10),
+					Range: &partitionRange,
+					Indexes: []*festruct.TGetMetaIndexMeta{{
+						Id:      id(100),
+						Name:    &name,
+						Tablets: []*festruct.TGetMetaTabletMeta{{Id: id(1000)}},
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+func dryRunUpsertBinlog(commitSeq int64) *festruct.TBinlog {
+	binlogType := festruct.TBinlogType_UPSERT
+	data := fmt.Sprintf(`{"commitSeq":%d,"tableRecords":{"%d":{"partitionRecords":[{"partitionId":10,"range":"range","version":5}],"indexIds":[100]}}}`,
+		commitSeq, dryRunSrcTableId)
+	return &festruct.TBinlog{CommitSeq: &commitSeq, Type: &binlogType, Data: &data, TableIds: []int64{dryRunSrcTableId}}
+}
+
+func TestDryRunResults(t *testing.T) {
+	results := newDryRunResults()
+
+	// Nothing is recorded if no binlog is handling.
+	results.recordSql("DROP TABLE tbl")
+	if got := results.get(0); len(got) != 0 {
+		t.Fatalf("results = %v, expect empty", got)
+	}
+
+	for commitSeq := int64(1); commitSeq <= DRY_RUN_RESULT_CAPACITY+1; commitSeq++ {
+		results.begin(upsertBinlog(commitSeq, 1000))
+		results.recordSql(fmt.Sprintf("sql %d", commitSeq))
+		results.recordSnapshot(fmt.Sprintf("snapshot %d", commitSeq))
+		var err error
+		if commitSeq%2 == 0 {
+			err = fmt.Errorf("error %d", commitSeq)
+		}
+		results.end(err)
+	}
+
+	// The oldest result is dropped.
+	if got := results.get(0); len(got) != DRY_RUN_RESULT_CAPACITY || got[0].CommitSeq != 2 {
+		t.Fatalf("results size = %d, first commit seq = %d", len(got), got[0].CommitSeq)
+	}
+	if got := results.get(1); len(got) != 0 {
+		t.Errorf("the dropped result is returned: %v", got)
+	}
+
+	got := results.get(2)
+	if len(got) != 1 {
+		t.Fatalf("results of commit seq 2 = %v", got)
+	}
+	expect := &DryRunResult{
+		CommitSeq:  2,
+		BinlogType: festruct.TBinlogType_UPSERT.String(),
+		HandledAt:  got[0].HandledAt,
+		Sqls:       []string{"sql 2"},
+		Snapshots:  []string{"snapshot 2"},
+		Error:      "error 2",
+	}
+	if !reflect.DeepEqual(got[0], expect) {
+		t.Errorf("result = %+v, expect %+v", got[0], expect)
+	}
+	if got := results.get(3); len(got) != 1 || got[0].Error != "" {
+		t.Errorf("results of commit seq 3 = %+v", got)
+	}
+}
+
+// The dest spec records the modifying sqls into the handling result instead of executing them.
+func TestJobInitDryRun_RecordSql(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	job := newDryRunTestJob(t, ctrl)
+	job.dryRunResults = nil
+	if err := job.initDryRun(); err != nil {
+		t.Fatalf("init dry run failed: %v", err)
+	}
+
+	spec := job.IDest.(*base.Spec)
+	job.dryRunResults.begin(upsertBinlog(11, 1000))
+	for _, fn := range []func() error{
+		spec.CreateDatabase,
+		spec.ClearDB,
+		func() error { return spec.Exec("ALTER TABLE tbl ADD COLUMN c INT") },
+	} {
+		if err := fn(); err != nil {
+			t.Fatalf("the recorded sql should not fail: %v", err)
+		}
+	}
+	job.dryRunResults.end(nil)
+
+	results, err := job.GetDryRunResults(11)
+	if err != nil {
+		t.Fatalf("get dry run results failed: %v", err)
+	}
+	expect := []string{
+		"CREATE DATABASE IF NOT EXISTS `dest_db`",
+		"DROP DATABASE `dest_db`",
+		"CREATE DATABASE `dest_db`",
+		"ALTER TABLE tbl ADD COLUMN c INT",
+	}
+	if len(results) != 1 || !reflect.DeepEqual(results[0].Sqls, expect) {
+		t.Errorf("results = %+v, expect sqls %v", results, expect)
+	}
+
+	// The specer which could not record sqls does not support dry run.
+	job.IDest = NewMockSpecer(ctrl)
+	if err := job.initDryRun(); err == nil {
+		t.Errorf("init dry run with the mock specer should fail")
+	}
+}
+
+func TestJobGetDryRunResults(t *testing.T) {
+	job := &Job{Name: "job"}
+	if _, err := job.GetDryRunResults(0); err == nil {
+		t.Errorf("get dry run results of the normal job should fail")
+	}
+
+	job.DryRun = true
+	if results, err := job.GetDryRunResults(0); err != nil || len(results) != 0 {
+		t.Errorf("results = %v, err = %v, expect empty", results, err)
+	}
+}
+
+// A db sync dry run never runs the full sync, the upserts are planned with the dest tables
+// resolved by name, without touching the table mapping of the progress.
+func TestDryRunSink_PlanUpsert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	job := newDryRunTestJob(t, ctrl)
+	sink := &dryRunSink{job: job}
+
+	if err := sink.Sink(dryRunUpsertBinlog(11)); err != nil {
+		t.Fatalf("sink failed: %v", err)
+	}
+
+	results, _ := job.GetDryRunResults(11)
+	if len(results) != 1 || results[0].Error != "" {
+		t.Fatalf("results = %+v", results)
+	}
+	expect := []*IngestTabletPlan{{
+		SrcTabletId:     1000,
+		DestTabletId:    2001,
+		DestPartitionId: 21,
+		BinlogVersion:   5,
+	}}
+	if !reflect.DeepEqual(results[0].IngestPlan, expect) {
+		t.Errorf("ingest plan = %+v, expect %+v", results[0].IngestPlan, expect)
+	}
+	if job.progress.TableMapping != nil {
+		t.Errorf("the table mapping is changed by dry run: %v", job.progress.TableMapping)
+	}
+}
+
+// The failure of a binlog is recorded in the result instead of retrying.
+func TestDryRunSink_RecordError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	job := newDryRunTestJob(t, ctrl)
+	destMeta := NewMockMetaer(ctrl)
+	destMeta.EXPECT().GetTableId("tbl").Return(int64(0), fmt.Errorf("table tbl not found"))
+	job.destMeta = destMeta
+	sink := &dryRunSink{job: job}
+
+	if err := sink.Sink(dryRunUpsertBinlog(11)); err != nil {
+		t.Fatalf("sink failed: %v", err)
+	}
+
+	results, _ := job.GetDryRunResults(11)
+	if len(results) != 1 || results[0].Error == "" || len(results[0].IngestPlan) != 0 {
+		t.Errorf("results = %+v, expect the error", results)
+	}
+	if job.progress.SubSyncState != Done {
+		t.Errorf("sub sync state = %v, expect done", job.progress.SubSyncState)
+	}
+}
+
+func TestJobPlanIngestBinlog_TableMapping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	job := newDryRunTestJob(t, ctrl)
+
+	// The table mapping of the progress is preferred, the src meta is not asked.
+	srcMeta := NewMockMetaer(ctrl)
+	job.srcMeta = srcMeta
+	job.progress.TableMapping = map[int64]int64{dryRunSrcTableId: dryRunDestTableId}
+
+	tableRecords := []*record.TableRecord{{
+		Id:               dryRunSrcTableId,
+		PartitionRecords: []record.PartitionRecord{{Id: 10, Range: "range", Version: 7}},
+		IndexIds:         []int64{100},
+	}}
+	job.dryRunResults.begin(dryRunUpsertBinlog(12))
+	if err := job.planIngestBinlog(tableRecords); err != nil {
+		t.Fatalf("plan ingest binlog failed: %v", err)
+	}
+	job.dryRunResults.end(nil)
+
+	results, _ := job.GetDryRunResults(12)
+	if len(results) != 1 || len(results[0].IngestPlan) != 1 || results[0].IngestPlan[0].BinlogVersion != 7 {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,260 @@
+package ccr
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/rpc"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+
+	"github.com/modern-go/gls"
+	log "github.com/sirupsen/logrus"
+)
+
+// A fan-out job replays the binlogs of the src into each of the Dests. Each dest is synced by
+// a child job, which has its own progress, so a slow dest only lags itself. The binlogs are
+// fetched from the src once per batch and shared by the children.
+
+// The names of the children are reserved, a job with such name shares the progress with a child.
+var fanoutChildNameRegexp = regexp.MustCompile(`_dest[0-9]+$`)
+
+// FanoutChildName returns the name of the child job, which is the key of its progress.
+func FanoutChildName(name string, index int) string {
+	return fmt.Sprintf("%s_dest%d", name, index)
+}
+
+func isFanoutChildName(name string) bool {
+	return fanoutChildNameRegexp.MatchString(name)
+}
+
+func (j *Job) IsFanout() bool {
+	return len(j.Dests) > 0
+}
+
+func (j *Job) validFanout() error {
+	if j.Dest.Host != "" || j.Dest.Database != "" {
+		return xerror.New(xerror.Normal, "dest and dests are both specified")
+	}
+	if !j.Sink.IsDoris() {
+		return xerror.New(xerror.Normal, "fan-out job only supports the doris sink")
+	}
+
+	for i := range j.Dests {
+		dest := &j.Dests[i]
+		if err := j.factory.NewSpecer(dest).Valid(); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "dests[%d] spec is invalid", i)
+		}
+		if (j.Src.Table == "") != (dest.Table == "") {
+			return xerror.Errorf(xerror.Normal, "src/dests[%d] are not both db or table sync", i)
+		}
+		childName := FanoutChildName(j.Name, i)
+		if exist, err := j.db.IsJobExist(childName); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "check job exist failed")
+		} else if exist {
+			return xerror.Errorf(xerror.Normal, "job %s already exist, which is the name of dests[%d]", childName, i)
+		}
+	}
+	return nil
+}
+
+func (j *Job) newFanoutChild(index int) *Job {
+	child := &Job{
+		SyncType:    j.SyncType,
+		Name:        FanoutChildName(j.Name, index),
+		Src:         j.Src,
+		Dest:        j.Dests[index],
+		SkipError:   j.SkipError,
+		State:       j.State,
+		TableFilter: j.TableFilter,
+
+		factory:          j.factory,
+		allowTableExists: j.allowTableExists,
+
+		db:         j.db,
+		jobFactory: j.jobFactory,
+		stop:       make(chan struct{}),
+		fence:      j.fence,
+
+		concurrencyManager: rpc.NewConcurrencyManager(),
+
+		parent:    j,
+		destIndex: index,
+	}
+	child.ISrc = j.factory.NewSpecer(&child.Src)
+	child.srcMeta = j.factory.NewMeta(&child.Src)
+	child.IDest = j.factory.NewSpecer(&child.Dest)
+	child.destMeta = j.factory.NewMeta(&child.Dest)
+	return child
+}
+
+func (j *Job) fanoutFirstRun() error {
+	for i := range j.Dests {
+		child := j.newFanoutChild(i)
+		if err := child.FirstRun(); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "first run dests[%d]", i)
+		}
+		j.Src = child.Src
+		j.Dests[i] = child.Dest
+	}
+	return nil
+}
+
+func (j *Job) runFanout() error {
+	gls.ResetGls(gls.GoID(), map[interface{}]interface{}{})
+	gls.Set("job", j.Name)
+
+	src := &j.Src
+	srcRpc, err := j.factory.NewFeRpc(src)
+	if err != nil {
+		return err
+	}
+	j.binlogFetcher = newBinlogFetcher(func(commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+		return srcRpc.GetBinlog(src, commitSeq)
+	}, j.slowestChildCommitSeq)
+
+	children := make([]*Job, 0, len(j.Dests))
+	for i := range j.Dests {
+		children = append(children, j.newFanoutChild(i))
+	}
+	j.lock.Lock()
+	j.children = children
+	j.lock.Unlock()
+
+	log.Infof("run fan-out job %s with %d dests", j.Name, len(children))
+
+	var wg sync.WaitGroup
+	for _, child := range children {
+		wg.Add(1)
+		go func(child *Job) {
+			defer wg.Done()
+			if err := child.Run(); err != nil {
+				log.Errorf("fan-out child job run failed, job: %s, err: %+v", child.Name, err)
+			}
+		}(child)
+	}
+
+	select {
+	case <-j.stop:
+	case <-j.fence.Lost():
+		// The children stop themselves.
+	}
+	for _, child := range children {
+		if j.isDeleted.Load() {
+			child.Delete()
+		} else {
+			child.Stop()
+		}
+	}
+	wg.Wait()
+
+	j.maybeDeleted()
+	log.Infof("fan-out job stopped, job: %s", j.Name)
+	return nil
+}
+
+// getBinlog fetches the binlogs after the commitSeq, the child of a fan-out job shares the
+// binlogs with its siblings.
+func (j *Job) getBinlog(srcRpc rpc.IFeRpc, commitSeq int64) (*festruct.TGetBinlogResult_, error) {
+	if j.parent != nil && j.parent.binlogFetcher != nil {
+		return j.parent.binlogFetcher.GetBinlog(commitSeq)
+	}
+	return srcRpc.GetBinlog(&j.Src, commitSeq)
+}
+
+// slowestChildCommitSeq returns the commit seq of the slowest child, it is read from the status
+// of the children, which might be a little behind the progress.
+func (j *Job) slowestChildCommitSeq() int64 {
+	var slowest int64 = -1
+	for _, child := range j.getChildren() {
+		commitSeq := child.getStatusDetail().CommitSeq
+		if slowest == -1 || commitSeq < slowest {
+			slowest = commitSeq
+		}
+	}
+	if slowest == -1 {
+		return 0
+	}
+	return slowest
+}
+
+// persistFanoutDest saves the dest spec updated by the child job.
+func (j *Job) persistFanoutDest(index int, dest base.Spec) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.Dests[index] = dest
+	return j.persistJob()
+}
+
+func (j *Job) getChildren() []*Job {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.children
+}
+
+// NOTE: the parent lock must not be held when calling the children, since the child
+// acquires the parent lock to persist the dest.
+
+func (j *Job) fanoutForceFullsync() {
+	for _, child := range j.getChildren() {
+		child.ForceFullsync()
+	}
+}
+
+func (j *Job) fanoutUpdateSkipError(skipError bool) {
+	for _, child := range j.getChildren() {
+		child.lock.Lock()
+		child.SkipError = skipError
+		child.lock.Unlock()
+	}
+}
+
+func (j *Job) fanoutGetLag() (int64, error) {
+	var lag int64
+	for _, child := range j.getChildren() {
+		childLag, err := child.GetLag()
+		if err != nil {
+			return 0, xerror.Wrapf(err, xerror.Normal, "get lag of %s", child.Name)
+		}
+		if childLag > lag {
+			lag = childLag
+		}
+	}
+	return lag, nil
+}
+
+func (j *Job) fanoutDesync() error {
+	for _, child := range j.getChildren() {
+		if err := child.Desync(); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "desync %s", child.Name)
+		}
+	}
+	return nil
+}
+
+func (j *Job) fanoutStatus() *JobStatus {
+	status := &JobStatus{
+		Name:  j.Name,
+		State: j.getJobState().String(),
+	}
+	for _, child := range j.getChildren() {
+		childStatus := child.Status()
+		if status.ProgressState == "" {
+			status.ProgressState = childStatus.ProgressState
+		}
+		// The fan-out job is as slow as the slowest dest.
+		if childStatus.Lag > status.Lag {
+			status.Lag = childStatus.Lag
+		}
+		if childStatus.ConsecutiveFailures > status.ConsecutiveFailures {
+			status.ConsecutiveFailures = childStatus.ConsecutiveFailures
+		}
+		status.Dests = append(status.Dests, childStatus)
+	}
+	return status
+}
@@ -0,0 +1,69 @@
+package ccr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIsFanoutChildName(t *testing.T) {
+	tests := []struct {
+		name  string
+		child bool
+	}{
+		{"job", false},
+		{"job_dest", false},
+		{"job_destination", false},
+		{"job_dest0x", false},
+		{"job_dest0", true},
+		{"job_dest12", true},
+		{FanoutChildName("job_dest", 3), true},
+	}
+
+	for _, test := range tests {
+		if got := isFanoutChildName(test.name); got != test.child {
+			t.Errorf("isFanoutChildName(%q) = %v, expect %v", test.name, got, test.child)
+		}
+	}
+}
+
+// The children store their progress under the child names, which must not be used by other jobs.
+func TestJobValid_FanoutChildName(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+
+	job := &Job{Name: "job_dest0", db: db}
+	if err := job.valid(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("the reserved name should be rejected, err: %v", err)
+	}
+
+	ctrl := gomock.NewController(t)
+	specer := NewMockSpecer(ctrl)
+	specer.EXPECT().Valid().Return(nil).AnyTimes()
+	specFactory := NewMockSpecerFactory(ctrl)
+	specFactory.EXPECT().NewSpecer(gomock.Any()).Return(specer).AnyTimes()
+
+	job = &Job{
+		Name:    "job",
+		Src:     base.Spec{Database: "db"},
+		Dests:   []base.Spec{{Database: "db"}, {Database: "db"}},
+		db:      db,
+		factory: NewFactory(nil, nil, specFactory, nil),
+	}
+	if err := job.validFanout(); err != nil {
+		t.Fatalf("valid fan-out failed: %v", err)
+	}
+
+	if err := db.AddJob(FanoutChildName("job", 1), "{}", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+	if err := job.validFanout(); err == nil || !strings.Contains(err.Error(), "dests[1]") {
+		t.Errorf("the fan-out job colliding with job_dest1 should be rejected, err: %v", err)
+	}
+}
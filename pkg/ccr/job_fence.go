@@ -0,0 +1,88 @@
+package ccr
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	log "github.com/sirupsen/logrus"
+)
+
+// jobFence is the ownership epoch of the job when it is loaded by this syncer. The job and its
+// progress are persisted only if the epoch in the jobs table is not changed, once the job is
+// dispatched to another syncer (e.g. this syncer is considered dead after a long GC pause), the
+// persist fails and the job stops itself, so two syncers never replay the same job.
+//
+// The children of a fan-out job share the fence of the parent.
+type jobFence struct {
+	// The job in the jobs table.
+	jobName string
+	epoch   int64
+
+	lostOnce sync.Once
+	lost     chan struct{}
+}
+
+func newJobFence(jobName string, epoch int64) *jobFence {
+	return &jobFence{
+		jobName: jobName,
+		epoch:   epoch,
+		lost:    make(chan struct{}),
+	}
+}
+
+// check marks the fence lost if the err is storage.ErrJobFenced.
+func (f *jobFence) check(err error) {
+	if f == nil || !errors.Is(err, storage.ErrJobFenced) {
+		return
+	}
+
+	f.lostOnce.Do(func() {
+		log.Warnf("job %s lost the ownership of epoch %d, it is dispatched to another syncer, stop it",
+			f.jobName, f.epoch)
+		close(f.lost)
+	})
+}
+
+// Lost returns a channel closed once the job lost the ownership, nil for the job without fence.
+func (f *jobFence) Lost() <-chan struct{} {
+	if f == nil {
+		return nil
+	}
+	return f.lost
+}
+
+func (f *jobFence) IsLost() bool {
+	if f == nil {
+		return false
+	}
+
+	select {
+	case <-f.lost:
+		return true
+	default:
+		return false
+	}
+}
+
+// updateJob persists the job info, conditioned on the epoch if the fence is set.
+func (f *jobFence) updateJob(db storage.DB, jobName string, jobInfo string) error {
+	if f == nil {
+		return db.UpdateJob(jobName, jobInfo)
+	}
+
+	err := db.UpdateJobWithEpoch(jobName, jobInfo, f.epoch)
+	f.check(err)
+	return err
+}
+
+// updateProgress persists the job progress, conditioned on the epoch if the fence is set.
+func (f *jobFence) updateProgress(db storage.DB, jobName string, progress string) error {
+	if f == nil {
+		return db.UpdateProgress(jobName, progress)
+	}
+
+	err := db.UpdateProgressWithEpoch(jobName, progress, f.jobName, f.epoch)
+	f.check(err)
+	return err
+}
@@ -0,0 +1,78 @@
+package ccr
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+)
+
+// The job is dispatched from syncer a to syncer b while a still runs it, the progress of a is
+// fenced and a stops the job.
+func TestJobFenceTwoSyncers(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	syncerA, syncerB := "127.0.0.1:9190", "127.0.0.1:9191"
+	for _, syncer := range []string{syncerA, syncerB} {
+		if err := db.AddSyncer(syncer); err != nil {
+			t.Fatalf("add syncer failed: %v", err)
+		}
+	}
+	if err := db.AddJob("job", "{}", syncerA); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+
+	fenceA := newJobFence("job", storage.InitialJobEpoch)
+	progressA := NewJobProgress("job", TableSync, db)
+	progressA.fence = fenceA
+	progressA.NextWithPersist(10, TableIncrementalSync, Done, "")
+	if fenceA.IsLost() {
+		t.Fatalf("syncer a lost the job before rebalance")
+	}
+
+	if _, err := db.RebalanceLoadFromDeadSyncers([]string{syncerA}); err != nil {
+		t.Fatalf("rebalance failed: %v", err)
+	}
+	belong, epoch, err := db.GetJobOwnership("job")
+	if err != nil || belong != syncerB {
+		t.Fatalf("job belongs to %s, err: %v", belong, err)
+	}
+
+	// Syncer b recovers the job and makes progress.
+	fenceB := newJobFence("job", epoch)
+	progressB, err := NewJobProgressFromJson("job", db)
+	if err != nil {
+		t.Fatalf("recover progress failed: %v", err)
+	}
+	progressB.fence = fenceB
+	progressB.Done()
+	progressB.StartHandle(20)
+	progressB.Done()
+
+	// Syncer a is paused and wakes up, the persist returns without retrying.
+	progressA.StartHandle(11)
+	if !fenceA.IsLost() {
+		t.Fatalf("syncer a is not fenced")
+	}
+	select {
+	case <-fenceA.Lost():
+	default:
+		t.Fatalf("the lost channel of syncer a is not closed")
+	}
+	if err := fenceA.updateJob(db, "job", "{\"stale\": true}"); err == nil {
+		t.Fatalf("syncer a updates the job after fenced")
+	}
+
+	progress, err := NewJobProgressFromJson("job", db)
+	if err != nil {
+		t.Fatalf("get progress failed: %v", err)
+	}
+	if progress.CommitSeq != 20 {
+		t.Errorf("commit seq = %d, expect the progress of syncer b", progress.CommitSeq)
+	}
+	if fenceB.IsLost() {
+		t.Errorf("syncer b is fenced")
+	}
+}
@@ -0,0 +1,274 @@
+package ccr
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	flagRebalanceInterval     time.Duration
+	flagRebalanceMaxMoves     int
+	flagRebalanceDBSyncWeight int
+)
+
+func init() {
+	flag.DurationVar(&flagRebalanceInterval, "rebalance_interval", 5*time.Minute,
+		"The interval to rebalance the jobs across the live syncers by load, 0 to disable")
+	flag.IntVar(&flagRebalanceMaxMoves, "rebalance_max_moves", 4,
+		"The max jobs moved in each load rebalance")
+	flag.IntVar(&flagRebalanceDBSyncWeight, "rebalance_db_sync_weight", 1,
+		"The load weight of a db sync job in the load rebalance, the weight of a table sync job is 1")
+}
+
+// The load rebalance is planned by the leader only, it is the live syncer with the least host
+// info, so the syncers don't make conflict plans.
+//
+// The handoff of a job:
+//  1. the leader (or /drain) adds the handoff into the meta db,
+//  2. the checker of the owner finds it, stops the job after the binlog in handling, the
+//     progress is persisted in each step,
+//  3. the owner transfers the job to the target and increases its epoch, the target refreshes
+//     failed since its timestamp is updated, then it recovers the job from the progress.
+
+// jobWeight returns the load weight of the job.
+func jobWeight(db storage.DB, jobName string) int {
+	if flagRebalanceDBSyncWeight <= 1 {
+		return 1
+	}
+
+	jobInfo, err := db.GetJobInfo(jobName)
+	if err != nil {
+		log.Warnf("get job %s info for the load weight failed: %+v", jobName, err)
+		return 1
+	}
+	var job struct {
+		SyncType SyncType `json:"sync_type"`
+	}
+	if err := json.Unmarshal([]byte(jobInfo), &job); err != nil {
+		log.Warnf("unmarshal job %s info for the load weight failed: %+v", jobName, err)
+		return 1
+	}
+	if job.SyncType == DBSync {
+		return flagRebalanceDBSyncWeight
+	}
+	return 1
+}
+
+// clusterLoad is the jobs and syncers of the cluster used to plan the handoffs.
+type clusterLoad struct {
+	jobs []storage.JobLoad
+	// The live syncers could accept jobs, sorted.
+	targets []string
+	// The live syncers, sorted.
+	live []string
+}
+
+func getClusterLoad(db storage.DB) (*clusterLoad, error) {
+	syncers, err := GetSyncers(db)
+	if err != nil {
+		return nil, err
+	}
+	pendingHandoffs, err := db.GetJobHandoffs("")
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]*storage.JobHandoff)
+	for _, handoff := range pendingHandoffs {
+		pending[handoff.JobName] = handoff
+	}
+
+	// The jobs of the dead syncers are rebalanced by the checker.
+	load := &clusterLoad{}
+	for _, syncer := range syncers {
+		if !syncer.Alive {
+			continue
+		}
+		load.live = append(load.live, syncer.Host)
+		if !syncer.Draining {
+			load.targets = append(load.targets, syncer.Host)
+		}
+
+		for _, name := range syncer.Jobs {
+			job := storage.JobLoad{JobName: name, Syncer: syncer.Host, Weight: jobWeight(db, name)}
+			if handoff, ok := pending[name]; ok && handoff.From == syncer.Host {
+				job.Syncer = handoff.To
+				job.Pinned = true
+			}
+			load.jobs = append(load.jobs, job)
+		}
+	}
+	return load, nil
+}
+
+func addJobHandoffs(db storage.DB, handoffs []*storage.JobHandoff) error {
+	now := time.Now().UnixMilli()
+	for _, handoff := range handoffs {
+		handoff.Timestamp = now
+		log.Infof("hand off job %s from syncer %s to %s", handoff.JobName, handoff.From, handoff.To)
+	}
+	return db.AddJobHandoffs(handoffs)
+}
+
+// RebalanceLoad plans the handoffs to balance the load of the live syncers if the syncer is the
+// leader, and moves the jobs out of the draining syncers.
+func RebalanceLoad(db storage.DB, hostInfo string) ([]*storage.JobHandoff, error) {
+	load, err := getClusterLoad(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(load.live) == 0 || load.live[0] != hostInfo {
+		log.Debugf("skip load rebalance, syncer %s is not the leader", hostInfo)
+		return nil, nil
+	}
+
+	handoffs := storage.PlanJobHandoffs(load.jobs, load.targets, flagRebalanceMaxMoves)
+	if len(handoffs) == 0 {
+		return handoffs, nil
+	}
+	return handoffs, addJobHandoffs(db, handoffs)
+}
+
+// DrainSyncer marks the syncer draining and hands off all its jobs to the other live syncers,
+// the draining syncer accepts no jobs until it is restarted or undrained.
+func DrainSyncer(db storage.DB, hostInfo string) ([]*storage.JobHandoff, error) {
+	if err := db.DrainSyncer(hostInfo, true); err != nil {
+		return nil, err
+	}
+
+	load, err := getClusterLoad(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(load.targets) == 0 {
+		return nil, xerror.Errorf(xerror.Normal, "no live syncer to accept the jobs of syncer %s", hostInfo)
+	}
+
+	// Only the jobs of the draining syncer are moved, the others are balanced by the leader.
+	handoffs := make([]*storage.JobHandoff, 0)
+	for _, handoff := range storage.PlanJobHandoffs(load.jobs, load.targets, 0) {
+		if handoff.From == hostInfo {
+			handoffs = append(handoffs, handoff)
+		}
+	}
+	if len(handoffs) == 0 {
+		return handoffs, nil
+	}
+	return handoffs, addJobHandoffs(db, handoffs)
+}
+
+// isStopping returns true if the job is stopped (e.g. handing off) or lost the ownership, the
+// sync should back to the run loop after the binlog in handling.
+func (j *Job) isStopping() bool {
+	if j.fence.IsLost() {
+		return true
+	}
+
+	select {
+	case <-j.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// HandoffJob stops the job after the binlog in handling and transfers it to the target syncer
+// asynchronously.
+func (jm *JobManager) HandoffJob(handoff *storage.JobHandoff) {
+	jm.lock.Lock()
+	job, ok := jm.jobs[handoff.JobName]
+	if ok && jm.handoffs[handoff.JobName] {
+		jm.lock.Unlock()
+		return
+	}
+	if ok {
+		jm.handoffs[handoff.JobName] = true
+	}
+	jm.lock.Unlock()
+
+	if !ok {
+		jm.handoffIdleJob(handoff)
+		return
+	}
+
+	go func() {
+		defer func() {
+			jm.lock.Lock()
+			delete(jm.handoffs, handoff.JobName)
+			jm.lock.Unlock()
+		}()
+
+		log.Infof("stop job %s to hand off to syncer %s", job.Name, handoff.To)
+		job.Stop()
+		<-job.done
+
+		err := jm.db.TransferJob(job.Name, job.fence.epoch, handoff.To)
+		if err == nil || errors.Is(err, storage.ErrJobFenced) {
+			if err == nil {
+				log.Infof("job %s is handed off to syncer %s", job.Name, handoff.To)
+				recordJobEvent(jm.db, job.Name, JobEventHandoff, "hand off from syncer %s to %s", jm.hostInfo, handoff.To)
+			} else {
+				log.Warnf("job %s is dispatched to another syncer during hand off", job.Name)
+			}
+			jm.removeStoppedJob(job)
+			return
+		}
+
+		// Run it again in this syncer.
+		log.Errorf("hand off job %s to syncer %s failed, recover it: %+v", job.Name, handoff.To, err)
+		jm.removeStoppedJob(job)
+		if err := jm.Recover([]string{job.Name}); err != nil {
+			log.Errorf("recover job %s after hand off failed: %+v", job.Name, err)
+		}
+	}()
+}
+
+// handoffIdleJob handles the handoff of the job not running in this syncer.
+func (jm *JobManager) handoffIdleJob(handoff *storage.JobHandoff) {
+	belong, epoch, err := jm.db.GetJobOwnership(handoff.JobName)
+	if err != nil || belong != jm.hostInfo {
+		// The job is removed or dispatched to another syncer.
+		log.Infof("remove the stale handoff of job %s, belong: %s, err: %v", handoff.JobName, belong, err)
+		if err := jm.db.RemoveJobHandoff(handoff.JobName); err != nil {
+			log.Warnf("remove the handoff of job %s failed: %+v", handoff.JobName, err)
+		}
+		return
+	}
+
+	if err := jm.db.TransferJob(handoff.JobName, epoch, handoff.To); err != nil {
+		log.Warnf("hand off job %s to syncer %s failed: %+v", handoff.JobName, handoff.To, err)
+	} else {
+		log.Infof("job %s is handed off to syncer %s", handoff.JobName, handoff.To)
+	}
+}
+
+// handleHandoffs hands off the jobs requested to move out of this syncer.
+func (c *Checker) handleHandoffs() {
+	handoffs, err := c.db.GetJobHandoffs(c.hostInfo)
+	if err != nil {
+		log.Warnf("get job handoffs of syncer %s failed: %+v", c.hostInfo, err)
+		return
+	}
+	for _, handoff := range handoffs {
+		c.jobManager.HandoffJob(handoff)
+	}
+}
+
+// maybeRebalanceLoad rebalances the load of the live syncers each rebalance_interval.
+func (c *Checker) maybeRebalanceLoad() {
+	if flagRebalanceInterval <= 0 || time.Since(c.lastRebalance) < flagRebalanceInterval {
+		return
+	}
+	c.lastRebalance = time.Now()
+
+	if handoffs, err := RebalanceLoad(c.db, c.hostInfo); err != nil {
+		log.Warnf("rebalance load failed: %+v", err)
+	} else if len(handoffs) > 0 {
+		log.Infof("rebalance load, %d jobs are handing off", len(handoffs))
+	}
+}
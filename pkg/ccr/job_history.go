@@ -0,0 +1,97 @@
+package ccr
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The types of the job history events.
+const (
+	JobEventStateChange = "state_change" // the sync state of the progress is changed
+	JobEventFullSync    = "full_sync"    // a new snapshot is made
+	JobEventPartialSync = "partial_sync" // a new partial snapshot is made
+	JobEventRollback    = "rollback"     // the handling binlog is rollback
+	JobEventSkipBinlog  = "skip_binlog"  // the handling binlog is skipped since skip_error is set
+	JobEventPause       = "pause"
+	JobEventResume      = "resume"
+	JobEventUpdate      = "update" // the job config is updated
+	JobEventError       = "error"
+	JobEventVerify      = "verify"     // the data of the job is verified
+	JobEventComplete    = "complete"   // the stop target is reached
+	JobEventSwitchover  = "switchover" // the replication direction is reversed
+	JobEventHandoff     = "handoff"    // the job is handed off to another syncer
+)
+
+// The max length of the message, the error message with stack might be very long.
+const maxJobEventMessageLength = 4096
+
+var (
+	flagJobHistoryRetention time.Duration
+)
+
+func init() {
+	flag.DurationVar(&flagJobHistoryRetention, "job_history_retention", 7*24*time.Hour,
+		"The retention of the job history events and verify reports, 0 means keep forever")
+}
+
+// recordJobEvent appends a event into the job history, the failure is ignored since the
+// history is only for troubleshooting.
+func recordJobEvent(db storage.DB, jobName string, eventType string, format string, args ...interface{}) {
+	if db == nil {
+		return
+	}
+
+	message := fmt.Sprintf(format, args...)
+	if len(message) > maxJobEventMessageLength {
+		message = message[:maxJobEventMessageLength]
+	}
+
+	event := &storage.JobEvent{
+		JobName:   jobName,
+		Type:      eventType,
+		Timestamp: time.Now().UnixMilli(),
+		Message:   message,
+	}
+	if err := db.AddJobEvent(event); err != nil {
+		log.Warnf("add job event failed, job: %s, type: %s, message: %s, err: %+v",
+			jobName, eventType, message, err)
+	}
+}
+
+func (j *Job) recordEvent(eventType string, format string, args ...interface{}) {
+	recordJobEvent(j.db, j.Name, eventType, format, args...)
+}
+
+// cleanJobHistory removes the expired job events and verify reports periodically until the
+// stop chan closed.
+func cleanJobHistory(db storage.DB, stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if flagJobHistoryRetention > 0 {
+			expiredAt := time.Now().Add(-flagJobHistoryRetention).UnixMilli()
+			if num, err := db.RemoveJobEventsBefore(expiredAt); err != nil {
+				log.Warnf("remove expired job events failed, err: %+v", err)
+			} else if num > 0 {
+				log.Infof("remove %d expired job events", num)
+			}
+			if num, err := db.RemoveVerifyReportsBefore(expiredAt); err != nil {
+				log.Warnf("remove expired verify reports failed, err: %+v", err)
+			} else if num > 0 {
+				log.Infof("remove %d expired verify reports", num)
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ccr
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/label"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GetLabels returns a copy of the labels of the job.
+func (j *Job) GetLabels() map[string]string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	labels := make(map[string]string, len(j.Labels))
+	for key, value := range j.Labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// UpdateLabels replaces the labels of the job, the empty labels remove all labels.
+func (j *Job) UpdateLabels(labels map[string]string) error {
+	if err := label.Valid(labels); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "labels are invalid")
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	originLabels := j.Labels
+	j.Labels = labels
+	if err := j.persistJob(); err != nil {
+		j.Labels = originLabels
+		return err
+	}
+
+	log.Infof("update job %s labels to %s", j.Name, label.String(labels))
+	j.recordEvent(JobEventUpdate, "update labels to %s", label.String(labels))
+	return nil
+}
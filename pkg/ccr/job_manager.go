@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/selectdb/ccr_syncer/pkg/label"
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/verify"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
 	log "github.com/sirupsen/logrus"
@@ -22,6 +26,9 @@ type JobManager struct {
 	hostInfo string
 	stop     chan struct{}
 	wg       sync.WaitGroup
+
+	// The jobs handing off to another syncer.
+	handoffs map[string]bool
 }
 
 func NewJobManager(db storage.DB, factory *Factory, hostInfo string) *JobManager {
@@ -31,6 +38,7 @@ func NewJobManager(db storage.DB, factory *Factory, hostInfo string) *JobManager
 		factory:  factory,
 		hostInfo: hostInfo,
 		stop:     make(chan struct{}),
+		handoffs: make(map[string]bool),
 	}
 }
 
@@ -40,6 +48,11 @@ func (jm *JobManager) GetFactory() *Factory {
 
 // add job to job manager && run job
 func (jm *JobManager) AddJob(job *Job) error {
+	return jm.addJob(job, nil)
+}
+
+// addJob adds the job, beforeRun is called after the job is saved and before it runs.
+func (jm *JobManager) addJob(job *Job, beforeRun func()) error {
 	log.Infof("add job: %s", job.Name)
 
 	jm.lock.Lock()
@@ -64,7 +77,12 @@ func (jm *JobManager) AddJob(job *Job) error {
 		return err
 	}
 
+	if beforeRun != nil {
+		beforeRun()
+	}
+
 	// Step 4: run job
+	job.fence = newJobFence(job.Name, storage.InitialJobEpoch)
 	jm.jobs[job.Name] = job
 	jm.runJob(job)
 
@@ -88,11 +106,23 @@ func (jm *JobManager) Recover(jobNames []string) error {
 
 		log.Infof("recover job: %s", jobName)
 
+		// The epoch is read before the job info, if the job is dispatched again after that, the
+		// job will be fenced in the first persist.
+		belong, epoch, err := jm.db.GetJobOwnership(jobName)
+		if err != nil {
+			return err
+		}
+		if belong != jm.hostInfo {
+			log.Infof("skip to recover job %s, it is dispatched to syncer %s", jobName, belong)
+			continue
+		}
+
 		if jobInfo, err := jm.db.GetJobInfo(jobName); err != nil {
 			return err
 		} else if job, err := NewJobFromJson(jobInfo, jm.db, jm.factory); err != nil {
 			return err
 		} else {
+			job.fence = newJobFence(jobName, epoch)
 			jobs = append(jobs, job)
 		}
 	}
@@ -100,6 +130,11 @@ func (jm *JobManager) Recover(jobNames []string) error {
 	for _, job := range jobs {
 		jm.jobs[job.Name] = job
 		jm.runJob(job)
+
+		// The syncer might crash during switchover.
+		if job.GetSwitchover().IsPending() {
+			go jm.resumeSwitchover(job)
+		}
 	}
 	return nil
 }
@@ -137,6 +172,12 @@ func (jm *JobManager) Start() error {
 	}
 	jm.lock.RUnlock()
 
+	jm.wg.Add(1)
+	go func() {
+		defer jm.wg.Done()
+		cleanJobHistory(jm.db, jm.stop)
+	}()
+
 	<-jm.stop
 	return nil
 }
@@ -161,16 +202,33 @@ func (jm *JobManager) Stop() error {
 // run job loop in job manager
 func (jm *JobManager) runJob(job *Job) {
 	jm.wg.Add(1)
+	job.done = make(chan struct{})
 
 	go func() {
 		err := job.Run()
 		if err != nil {
 			log.Errorf("job run failed, job name: %s, error: %+v", job.Name, err)
 		}
+		if job.fence.IsLost() {
+			jm.removeStoppedJob(job)
+		}
+		close(job.done)
 		jm.wg.Done()
 	}()
 }
 
+// removeStoppedJob removes the job stopped by losing its ownership or handing off, without
+// touching the db, so it could be recovered again once it is dispatched back to this syncer.
+func (jm *JobManager) removeStoppedJob(job *Job) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	if jm.jobs[job.Name] == job {
+		log.Infof("remove the stopped job %s from job manager", job.Name)
+		delete(jm.jobs, job.Name)
+	}
+}
+
 func (jm *JobManager) GetLag(jobName string) (int64, error) {
 	jm.lock.RLock()
 	defer jm.lock.RUnlock()
@@ -255,3 +313,115 @@ func (jm *JobManager) UpdateJobSkipError(jobName string, skipError bool) error {
 		return xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
 	}
 }
+
+// ValidJobUpdate checks the updates of the job before applying any of them, so a request with
+// several updates is not applied partially.
+func (jm *JobManager) ValidJobUpdate(jobName string, rateLimit *RateLimit, config *VerifyConfig, target *StopTarget, labels map[string]string) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		if err := rateLimit.Valid(); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "rate limit is invalid")
+		}
+		if err := config.Valid(); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "verify config is invalid")
+		}
+		if !config.IsEmpty() {
+			if err := job.validVerify(); err != nil {
+				return err
+			}
+		}
+		if err := target.Valid(); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "stop target is invalid")
+		}
+		if !target.IsEmpty() {
+			if err := job.validStopTarget(); err != nil {
+				return err
+			}
+		}
+		if err := label.Valid(labels); err != nil {
+			return xerror.Wrap(err, xerror.Normal, "labels are invalid")
+		}
+		return nil
+	})
+}
+
+func (jm *JobManager) UpdateJobRateLimit(jobName string, rateLimit *RateLimit) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		return job.UpdateRateLimit(rateLimit)
+	})
+}
+
+func (jm *JobManager) GetJobSchedule(jobName string) (*JobSchedule, error) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	if job, ok := jm.jobs[jobName]; ok {
+		return job.GetSchedule()
+	} else {
+		return nil, xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+}
+
+func (jm *JobManager) UpdateJobSchedule(jobName string, sched *schedule.Schedule) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		return job.UpdateSchedule(sched)
+	})
+}
+
+func (jm *JobManager) GetJobHistory(filter *storage.JobEventFilter) ([]*storage.JobEvent, error) {
+	return jm.db.GetJobEvents(filter)
+}
+
+func (jm *JobManager) GetDryRunResults(jobName string, commitSeq int64) ([]*DryRunResult, error) {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	if job, ok := jm.jobs[jobName]; ok {
+		return job.GetDryRunResults(commitSeq)
+	} else {
+		return nil, xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+}
+
+func (jm *JobManager) Verify(jobName string, checksum bool) (*verify.Report, error) {
+	// Don't hold the lock while verifying, it might take a long time.
+	jm.lock.Lock()
+	job, ok := jm.jobs[jobName]
+	jm.lock.Unlock()
+
+	if !ok {
+		return nil, xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+	return job.Verify(checksum)
+}
+
+func (jm *JobManager) UpdateJobVerifyConfig(jobName string, config *VerifyConfig) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		return job.UpdateVerifyConfig(config)
+	})
+}
+
+func (jm *JobManager) UpdateJobStopTarget(jobName string, target *StopTarget) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		return job.UpdateStopTarget(target)
+	})
+}
+
+func (jm *JobManager) UpdateJobLabels(jobName string, labels map[string]string) error {
+	return jm.dealJob(jobName, func(job *Job) error {
+		return job.UpdateLabels(labels)
+	})
+}
+
+func (jm *JobManager) GetVerifyReports(jobName string, limit int) ([]*storage.VerifyReport, error) {
+	return jm.db.GetVerifyReports(jobName, limit)
+}
+
+// Watch registers the observer of the state and progress changes of the jobs running in this
+// syncer, see JobWatchEvent. The observer must not block.
+func (jm *JobManager) Watch(observer utils.Observer[*JobWatchEvent]) {
+	jobWatch.Register(observer)
+}
+
+func (jm *JobManager) Unwatch(observer utils.Observer[*JobWatchEvent]) {
+	jobWatch.Unregister(observer)
+}
@@ -2,6 +2,7 @@ package ccr
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -60,6 +61,15 @@ func (s SyncState) String() string {
 	}
 }
 
+func (s SyncState) isIncrementalSync() bool {
+	switch s {
+	case TableIncrementalSync, DBIncrementalSync, DBTablesIncrementalSync:
+		return true
+	default:
+		return false
+	}
+}
+
 type BinlogType int
 
 const (
@@ -145,6 +155,7 @@ type JobPartialSyncData struct {
 type JobProgress struct {
 	JobName string     `json:"job_name"`
 	db      storage.DB `json:"-"`
+	fence   *jobFence  `json:"-"`
 
 	// Table/DB big sync state machine states
 	SyncState SyncState `json:"sync_state"`
@@ -176,6 +187,10 @@ type JobProgress struct {
 	FullSyncStartAt        int64 `json:"full_sync_start_at,omitempty"`
 	IncrementalSyncStartAt int64 `json:"incremental_sync_start_at,omitempty"`
 	IngestBinlogAt         int64 `json:"ingest_binlog_at,omitempty"`
+	CompletedAt            int64 `json:"completed_at,omitempty"`
+
+	// The stop target of the job, see job_stop_at.go.
+	StopAt *StopTarget `json:"stop_at,omitempty"`
 }
 
 func (j *JobProgress) String() string {
@@ -319,6 +334,11 @@ func (j *JobProgress) NextWithPersist(commitSeq int64, syncState SyncState, subS
 		j.IngestBinlogAt = 0
 	}
 
+	if j.SyncState != syncState || j.SubSyncState != subSyncState {
+		recordJobEvent(j.db, j.JobName, JobEventStateChange, "from %s/%s to %s/%s, commit seq: %d",
+			j.SyncState, j.SubSyncState, syncState, subSyncState, commitSeq)
+	}
+
 	j.CommitSeq = commitSeq
 	if subSyncState == Done {
 		j.PrevCommitSeq = commitSeq
@@ -349,6 +369,14 @@ func (j *JobProgress) Done() {
 func (j *JobProgress) Rollback(skipError bool) {
 	log.Debugf("job %s step rollback", j.JobName)
 
+	if skipError {
+		recordJobEvent(j.db, j.JobName, JobEventSkipBinlog, "skip binlog, commit seq: %d, sub sync state: %s",
+			j.CommitSeq, j.SubSyncState)
+	} else {
+		recordJobEvent(j.db, j.JobName, JobEventRollback, "rollback binlog, commit seq: %d, sub sync state: %s",
+			j.CommitSeq, j.SubSyncState)
+	}
+
 	j.SubSyncState = Done
 	// if rollback, then prev commit seq is the last commit seq
 	// but if skip error, we can consume the binlog then prev commit seq is the last commit seq
@@ -376,8 +404,12 @@ func (j *JobProgress) Persist() {
 		}
 
 		// Step 2: write to db
-		err = j.db.UpdateProgress(j.JobName, string(jsonBytes))
-		if err != nil {
+		err = j.fence.updateProgress(j.db, j.JobName, string(jsonBytes))
+		if errors.Is(err, storage.ErrJobFenced) {
+			// The job is dispatched to another syncer, the progress is owned by it now.
+			log.Warnf("skip to update job progress, job: %s, error: %v", j.JobName, err)
+			return
+		} else if err != nil {
 			log.Errorf("update job progress failed, error: %+v", err)
 			time.Sleep(UPDATE_JOB_PROGRESS_DURATION)
 			continue
@@ -385,6 +417,7 @@ func (j *JobProgress) Persist() {
 
 		break
 	}
+	jobWatch.updateProgress(j)
 
 	log.Tracef("update job progress done, state: %s, subState: %s, commitSeq: %d, prevCommitSeq: %d",
 		j.SyncState, j.SubSyncState, j.CommitSeq, j.PrevCommitSeq)
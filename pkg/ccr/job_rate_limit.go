@@ -0,0 +1,149 @@
+package ccr
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	flagMaxBinlogsPerSecond       float64
+	flagMaxIngestTabletsPerSecond float64
+
+	globalRateLimitersOnce sync.Once
+	globalBinlogLimiter    *utils.RateLimiter
+	globalTabletLimiter    *utils.RateLimiter
+)
+
+func init() {
+	flag.Float64Var(&flagMaxBinlogsPerSecond, "max_binlogs_per_second", 0,
+		"The max binlogs handled per second of all jobs, 0 means unlimited")
+	flag.Float64Var(&flagMaxIngestTabletsPerSecond, "max_ingest_tablets_per_second", 0,
+		"The max tablets ingested per second of all jobs, 0 means unlimited")
+}
+
+// The global limiters are created lazily, since the flags are parsed after init.
+func globalRateLimiters() (*utils.RateLimiter, *utils.RateLimiter) {
+	globalRateLimitersOnce.Do(func() {
+		globalBinlogLimiter = utils.NewRateLimiter(flagMaxBinlogsPerSecond)
+		globalTabletLimiter = utils.NewRateLimiter(flagMaxIngestTabletsPerSecond)
+	})
+	return globalBinlogLimiter, globalTabletLimiter
+}
+
+// RateLimit limits the throughput of the incremental sync of a job, 0 means unlimited.
+type RateLimit struct {
+	BinlogsPerSecond float64 `json:"binlogs_per_second,omitempty"`
+	TabletsPerSecond float64 `json:"tablets_per_second,omitempty"`
+}
+
+func (r *RateLimit) IsEmpty() bool {
+	return r == nil || (r.BinlogsPerSecond == 0 && r.TabletsPerSecond == 0)
+}
+
+func (r *RateLimit) Valid() error {
+	if r == nil {
+		return nil
+	}
+	if r.BinlogsPerSecond < 0 || r.TabletsPerSecond < 0 {
+		return xerror.Errorf(xerror.Normal, "rate limit must not be negative, binlogs: %v, tablets: %v",
+			r.BinlogsPerSecond, r.TabletsPerSecond)
+	}
+	return nil
+}
+
+func (r *RateLimit) binlogsPerSecond() float64 {
+	if r == nil {
+		return 0
+	}
+	return r.BinlogsPerSecond
+}
+
+func (r *RateLimit) tabletsPerSecond() float64 {
+	if r == nil {
+		return 0
+	}
+	return r.TabletsPerSecond
+}
+
+func (j *Job) initRateLimiters() {
+	j.binlogLimiter = utils.NewRateLimiter(j.RateLimit.binlogsPerSecond())
+	j.tabletLimiter = utils.NewRateLimiter(j.RateLimit.tabletsPerSecond())
+	xmetrics.RateLimit(j.Name, j.RateLimit.binlogsPerSecond(), j.RateLimit.tabletsPerSecond())
+}
+
+// rateLimitOwner returns the job which owns the limiters, the children of a fan-out job
+// share the limiters of the parent.
+func (j *Job) rateLimitOwner() *Job {
+	if j.parent != nil {
+		return j.parent
+	}
+	return j
+}
+
+// waitBinlogRate blocks until the next binlog is allowed to handle, it returns false if
+// the job is stopped while waiting.
+func (j *Job) waitBinlogRate() bool {
+	globalLimiter, _ := globalRateLimiters()
+	return j.rateLimitOwner().binlogLimiter.Wait(1, j.stop) && globalLimiter.Wait(1, j.stop)
+}
+
+// waitTabletRate blocks until the next tablet is allowed to ingest, it returns false if
+// the job is stopped while waiting.
+func (j *Job) waitTabletRate() bool {
+	_, globalLimiter := globalRateLimiters()
+	if !j.rateLimitOwner().tabletLimiter.Wait(1, j.stop) || !globalLimiter.Wait(1, j.stop) {
+		return false
+	}
+	xmetrics.IngestTablet(j.rateLimitOwner().Name)
+	return true
+}
+
+// hasPendingUpdate returns true if a update is waiting for the job lock, the job loop should
+// go back to release the lock.
+func (j *Job) hasPendingUpdate() bool {
+	return j.rateLimitOwner().pendingUpdates.Load() > 0
+}
+
+// UpdateRateLimit changes the rate limit of the job at runtime, a empty one removes it.
+//
+// The limiters are changed before taking the job lock, since the job loop might hold the
+// lock while waiting for the limiters with the old rate.
+func (j *Job) UpdateRateLimit(rateLimit *RateLimit) error {
+	if err := rateLimit.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "rate limit is invalid")
+	}
+	if rateLimit.IsEmpty() {
+		rateLimit = nil
+	}
+
+	j.pendingUpdates.Add(1)
+	originBinlogRate, originTabletRate := j.binlogLimiter.Rate(), j.tabletLimiter.Rate()
+	j.setRateLimiters(rateLimit.binlogsPerSecond(), rateLimit.tabletsPerSecond())
+	j.lock.Lock()
+	j.pendingUpdates.Add(-1)
+	defer j.lock.Unlock()
+
+	originRateLimit := j.RateLimit
+	j.RateLimit = rateLimit
+	if err := j.persistJob(); err != nil {
+		j.RateLimit = originRateLimit
+		j.setRateLimiters(originBinlogRate, originTabletRate)
+		return err
+	}
+
+	log.Infof("update job %s rate limit to %+v", j.Name, rateLimit)
+	j.recordEvent(JobEventUpdate, "update rate limit to %+v", rateLimit)
+	return nil
+}
+
+func (j *Job) setRateLimiters(binlogsPerSecond, tabletsPerSecond float64) {
+	j.binlogLimiter.SetRate(binlogsPerSecond)
+	j.tabletLimiter.SetRate(tabletsPerSecond)
+	xmetrics.RateLimit(j.Name, binlogsPerSecond, tabletsPerSecond)
+}
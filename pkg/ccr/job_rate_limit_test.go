@@ -0,0 +1,86 @@
+package ccr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+)
+
+// The job loop waits for the limiters while holding the job lock, the update must wake it up
+// instead of waiting for the lock.
+func TestJobUpdateRateLimit_WhileWaiting(t *testing.T) {
+	job := newStopAtTestJob(t)
+	job.RateLimit = &RateLimit{BinlogsPerSecond: 0.001}
+	job.initRateLimiters() // the bucket starts empty
+
+	waiting := make(chan struct{})
+	loopDone := make(chan struct{})
+	go func() {
+		defer close(loopDone)
+		job.lock.Lock()
+		defer job.lock.Unlock()
+		close(waiting)
+		job.waitBinlogRate()
+		if !job.hasPendingUpdate() {
+			t.Errorf("the job loop should see the pending update")
+		}
+	}()
+	<-waiting
+
+	updateDone := make(chan error)
+	go func() {
+		updateDone <- job.UpdateRateLimit(nil)
+	}()
+
+	select {
+	case err := <-updateDone:
+		if err != nil {
+			t.Fatalf("update rate limit failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("update rate limit is blocked by the job loop")
+	}
+	<-loopDone
+
+	if job.RateLimit != nil || job.binlogLimiter.Rate() != 0 {
+		t.Errorf("rate limit is not removed, %+v, rate %v", job.RateLimit, job.binlogLimiter.Rate())
+	}
+	if job.hasPendingUpdate() {
+		t.Errorf("the pending update is not cleared")
+	}
+}
+
+// A request with several updates is validated as a whole before any of them is applied.
+func TestJobManagerValidJobUpdate(t *testing.T) {
+	job := newStopAtTestJob(t)
+	job.Dests = []base.Spec{{}, {}}
+	jm := &JobManager{jobs: map[string]*Job{job.Name: job}}
+
+	tests := []struct {
+		name      string
+		rateLimit *RateLimit
+		target    *StopTarget
+		labels    map[string]string
+		valid     bool
+	}{
+		{"empty", nil, nil, nil, true},
+		{"valid", &RateLimit{BinlogsPerSecond: 10}, &StopTarget{}, map[string]string{"env": "prod"}, true},
+		{"negative rate limit", &RateLimit{BinlogsPerSecond: -1}, nil, nil, false},
+		{"stop target of fan-out job", nil, &StopTarget{CommitSeq: 10}, nil, false},
+		{"invalid label", nil, nil, map[string]string{"": "prod"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := jm.ValidJobUpdate(job.Name, test.rateLimit, nil, test.target, test.labels)
+			if (err == nil) != test.valid {
+				t.Errorf("valid = %v, expect %v, err: %v", err == nil, test.valid, err)
+			}
+		})
+	}
+
+	if err := jm.ValidJobUpdate("unknown", nil, nil, nil, nil); err == nil {
+		t.Errorf("the update of the unknown job should be invalid")
+	}
+}
@@ -0,0 +1,124 @@
+package ccr
+
+import (
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type JobSchedule struct {
+	Schedule         *schedule.Schedule `json:"schedule"`
+	PausedBySchedule bool               `json:"paused_by_schedule"`
+	ShouldRun        bool               `json:"should_run"`
+}
+
+// applySchedule pauses the job outside the schedule and resumes it inside the schedule,
+// only the job paused by the schedule will be resumed automatically.
+func (j *Job) applySchedule() {
+	// The child of fan-out job follows the schedule of the parent.
+	if j.parent != nil {
+		j.parent.applySchedule()
+		return
+	}
+
+	j.lock.Lock()
+	sched := j.Schedule
+	state := j.State
+	pausedBySchedule := j.PausedBySchedule
+	j.lock.Unlock()
+
+	if sched.IsEmpty() {
+		return
+	}
+
+	shouldRun, err := sched.ShouldRun(time.Now())
+	if err != nil {
+		log.Warnf("evaluate the schedule of job %s failed, err: %+v", j.Name, err)
+		return
+	}
+
+	if !shouldRun && state == JobRunning {
+		log.Infof("job %s is out of the schedule, pause it", j.Name)
+		if err := j.changeJobStateBySchedule(JobPaused, true); err != nil {
+			log.Warnf("pause job %s by schedule failed, err: %+v", j.Name, err)
+		}
+	} else if shouldRun && state == JobPaused && pausedBySchedule {
+		log.Infof("job %s is in the schedule, resume it", j.Name)
+		if err := j.changeJobStateBySchedule(JobRunning, false); err != nil {
+			log.Warnf("resume job %s by schedule failed, err: %+v", j.Name, err)
+		}
+	}
+}
+
+func (j *Job) changeJobStateBySchedule(state JobState, pausedBySchedule bool) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	originState := j.State
+	originPausedBySchedule := j.PausedBySchedule
+	j.State = state
+	j.PausedBySchedule = pausedBySchedule
+	if err := j.persistJob(); err != nil {
+		j.State = originState
+		j.PausedBySchedule = originPausedBySchedule
+		return err
+	}
+	j.notifyWatchState()
+	j.recordStateEvent(state, "by schedule")
+	return nil
+}
+
+func (j *Job) GetSchedule() (*JobSchedule, error) {
+	j.lock.Lock()
+	sched := j.Schedule
+	pausedBySchedule := j.PausedBySchedule
+	j.lock.Unlock()
+
+	shouldRun, err := sched.ShouldRun(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &JobSchedule{
+		Schedule:         sched,
+		PausedBySchedule: pausedBySchedule,
+		ShouldRun:        shouldRun,
+	}, nil
+}
+
+// UpdateSchedule replaces the schedule of the job, a empty schedule removes it.
+func (j *Job) UpdateSchedule(sched *schedule.Schedule) error {
+	if err := sched.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "schedule is invalid")
+	}
+	if sched.IsEmpty() {
+		sched = nil
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	originSchedule := j.Schedule
+	originState := j.State
+	originPausedBySchedule := j.PausedBySchedule
+
+	j.Schedule = sched
+	if sched == nil && j.PausedBySchedule {
+		// No schedule to resume it any more.
+		j.State = JobRunning
+		j.PausedBySchedule = false
+	}
+	if err := j.persistJob(); err != nil {
+		j.Schedule = originSchedule
+		j.State = originState
+		j.PausedBySchedule = originPausedBySchedule
+		return err
+	}
+
+	log.Infof("update job %s schedule to %+v", j.Name, sched)
+	j.recordEvent(JobEventUpdate, "update schedule to %+v", sched)
+	return nil
+}
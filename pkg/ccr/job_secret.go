@@ -0,0 +1,47 @@
+package ccr
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ReencryptJobs re-encrypts the passwords of all jobs with the current key of the secret
+// provider, it returns the number of the re-encrypted jobs. The passwords are decrypted when
+// the jobs are unmarshaled, and encrypted again when marshaled, see base.SetSecretCipher.
+func ReencryptJobs(db storage.DB) (int, error) {
+	data, err := db.GetAllData()
+	if err != nil {
+		return 0, err
+	}
+
+	num := 0
+	for _, jobData := range data["jobs"] {
+		// The job data is formatted as "${job_name}, ${belong_to}".
+		jobName := strings.TrimSpace(strings.Split(jobData, ",")[0])
+		jobInfo, err := db.GetJobInfo(jobName)
+		if err != nil {
+			return num, err
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(jobInfo), &job); err != nil {
+			return num, xerror.Wrapf(err, xerror.Normal, "unmarshal job %s failed", jobName)
+		}
+		reencrypted, err := json.Marshal(&job)
+		if err != nil {
+			return num, xerror.Wrapf(err, xerror.Normal, "marshal job %s failed", jobName)
+		}
+		if err := db.UpdateJob(jobName, string(reencrypted)); err != nil {
+			return num, err
+		}
+
+		log.Infof("re-encrypt the passwords of job %s", jobName)
+		num++
+	}
+	return num, nil
+}
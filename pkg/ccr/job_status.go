@@ -0,0 +1,194 @@
+package ccr
+
+import (
+	"errors"
+	"flag"
+	"sync/atomic"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/notify"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var jobStatusLagInterval time.Duration
+
+func init() {
+	flag.DurationVar(&jobStatusLagInterval, "job_status_lag_interval", time.Minute,
+		"The interval to refresh the lag in the job status, 0 means the lag is only refreshed by get_lag")
+}
+
+// JobError is the last error of the job sync.
+type JobError struct {
+	Message  string `json:"message"`
+	Category string `json:"category"`
+	Panic    bool   `json:"panic,omitempty"`
+	// When the error occurs, in unix seconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// JobStatusDetail is the status of the running job, it is updated by the job goroutine and read
+// by the status api without holding the job lock. All timestamps are in unix seconds.
+type JobStatusDetail struct {
+	SubSyncState  string `json:"sub_sync_state,omitempty"`
+	PrevCommitSeq int64  `json:"prev_commit_seq"`
+	CommitSeq     int64  `json:"commit_seq"`
+
+	// The lag is cached when it is refreshed, see the flag job_status_lag_interval.
+	Lag          int64 `json:"lag"`
+	LagUpdatedAt int64 `json:"lag_updated_at,omitempty"`
+
+	// When the last binlog is synced successfully.
+	LastBinlogAt int64 `json:"last_binlog_at,omitempty"`
+
+	LastError *JobError `json:"last_error,omitempty"`
+	// The number of the consecutive failed syncs, it is reset once the job syncs successfully.
+	ConsecutiveFailures int64 `json:"consecutive_failures"`
+	// When the first of the consecutive failed syncs occurs.
+	FailingSince int64 `json:"failing_since,omitempty"`
+
+	// The label of the snapshot in the source and the restore job in the dest, during the
+	// full/partial sync.
+	SnapshotLabel string `json:"snapshot_label,omitempty"`
+	RestoreLabel  string `json:"restore_label,omitempty"`
+}
+
+func (j *Job) updateStatusDetail(update func(detail *JobStatusDetail)) {
+	j.rawStatus.lock.Lock()
+	defer j.rawStatus.lock.Unlock()
+
+	update(&j.rawStatus.detail)
+}
+
+func (j *Job) getStatusDetail() JobStatusDetail {
+	j.rawStatus.lock.Lock()
+	defer j.rawStatus.lock.Unlock()
+
+	detail := j.rawStatus.detail
+	if detail.LastError != nil {
+		lastError := *detail.LastError
+		detail.LastError = &lastError
+	}
+	return detail
+}
+
+// updateProgressStatus copies the progress into the status, it must be called in the job
+// goroutine.
+func (j *Job) updateProgressStatus() {
+	if j.progress == nil {
+		return
+	}
+
+	subSyncState := j.progress.SubSyncState.String()
+	prevCommitSeq := j.progress.PrevCommitSeq
+	commitSeq := j.progress.CommitSeq
+	snapshotLabel, restoreLabel := j.progressSyncLabels()
+	j.updateStatusDetail(func(detail *JobStatusDetail) {
+		detail.SubSyncState = subSyncState
+		detail.PrevCommitSeq = prevCommitSeq
+		detail.CommitSeq = commitSeq
+		detail.SnapshotLabel = snapshotLabel
+		detail.RestoreLabel = restoreLabel
+	})
+}
+
+// progressSyncLabels returns the snapshot and the restore label of the full/partial sync. The
+// labels are saved as the persist data, or the in memory data of the sync steps.
+func (j *Job) progressSyncLabels() (string, string) {
+	if j.isIncrementalSync() {
+		return "", ""
+	}
+
+	switch j.progress.SubSyncState {
+	case WaitBackupDone:
+		snapshotLabel, _ := j.progress.InMemoryData.(string)
+		return snapshotLabel, ""
+	case GetSnapshotInfo:
+		return j.progress.PersistData, ""
+	case PersistRestoreInfo:
+		return "", j.progress.PersistData
+	}
+
+	switch data := j.progress.InMemoryData.(type) {
+	case *fullSyncInMemoryData:
+		return data.SnapshotName, data.RestoreLabel
+	case *partialSyncInMemoryData:
+		return data.SnapshotName, data.RestoreLabel
+	default:
+		return "", ""
+	}
+}
+
+func (j *Job) recordBinlogSynced() {
+	now := time.Now().Unix()
+	j.updateStatusDetail(func(detail *JobStatusDetail) {
+		detail.LastBinlogAt = now
+	})
+}
+
+func (j *Job) recordSyncSuccess() {
+	j.updateStatusDetail(func(detail *JobStatusDetail) {
+		detail.ConsecutiveFailures = 0
+		detail.FailingSince = 0
+	})
+}
+
+func (j *Job) recordSyncError(err error) {
+	jobError := &JobError{
+		Message:   err.Error(),
+		Category:  "unknown",
+		Timestamp: time.Now().Unix(),
+	}
+	var xerr *xerror.XError
+	if errors.As(err, &xerr) {
+		jobError.Category = xerr.Category().Name()
+		jobError.Panic = xerr.IsPanic()
+	}
+
+	var failingSince int64
+	j.updateStatusDetail(func(detail *JobStatusDetail) {
+		detail.LastError = jobError
+		detail.ConsecutiveFailures++
+		if detail.FailingSince == 0 {
+			detail.FailingSince = jobError.Timestamp
+		}
+		failingSince = detail.FailingSince
+	})
+	notify.JobFailing(j.Name, time.Unix(failingSince, 0), err)
+}
+
+func (j *Job) recordLag(lag int64) {
+	now := time.Now().Unix()
+	j.updateStatusDetail(func(detail *JobStatusDetail) {
+		detail.Lag = lag
+		detail.LagUpdatedAt = now
+	})
+	notify.Lag(j.Name, lag)
+}
+
+// refreshLag refreshes the cached lag if it is older than the job_status_lag_interval. The rpc
+// is sent in background, so the slow frontend does not block the job goroutine.
+func (j *Job) refreshLag() {
+	if jobStatusLagInterval <= 0 {
+		return
+	}
+
+	// The failed refresh is retried in the next interval too.
+	if time.Since(j.rawStatus.lagRefreshedAt) < jobStatusLagInterval {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&j.rawStatus.lagRefreshing, 0, 1) {
+		return
+	}
+	j.rawStatus.lagRefreshedAt = time.Now()
+
+	commitSeq := j.progress.CommitSeq
+	go func() {
+		defer atomic.StoreInt32(&j.rawStatus.lagRefreshing, 0)
+
+		if _, err := j.getLag(commitSeq); err != nil {
+			log.Warnf("refresh the lag of job %s failed: %+v", j.Name, err)
+		}
+	}()
+}
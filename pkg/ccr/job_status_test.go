@@ -0,0 +1,198 @@
+package ccr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"go.uber.org/mock/gomock"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+)
+
+func newStatusTestJob(syncState SyncState, subSyncState SubSyncState) *Job {
+	progress := NewJobProgress("job", DBSync, nil)
+	progress.SyncState = syncState
+	progress.SubSyncState = subSyncState
+	return &Job{
+		Name:     "job",
+		SyncType: DBSync,
+		State:    JobRunning,
+		progress: progress,
+	}
+}
+
+func TestJobStatus_SyncLabels(t *testing.T) {
+	tests := []struct {
+		name          string
+		syncState     SyncState
+		subSyncState  SubSyncState
+		persistData   string
+		inMemoryData  any
+		snapshotLabel string
+		restoreLabel  string
+	}{
+		{
+			name:         "incremental sync",
+			syncState:    DBIncrementalSync,
+			subSyncState: Done,
+			inMemoryData: &fullSyncInMemoryData{SnapshotName: "snapshot", RestoreLabel: "restore"},
+		},
+		{
+			name:          "wait backup done",
+			syncState:     DBFullSync,
+			subSyncState:  WaitBackupDone,
+			inMemoryData:  "snapshot",
+			snapshotLabel: "snapshot",
+		},
+		{
+			name:          "get snapshot info",
+			syncState:     DBFullSync,
+			subSyncState:  GetSnapshotInfo,
+			persistData:   "snapshot",
+			snapshotLabel: "snapshot",
+		},
+		{
+			name:         "persist restore info",
+			syncState:    DBFullSync,
+			subSyncState: PersistRestoreInfo,
+			persistData:  "restore",
+			restoreLabel: "restore",
+		},
+		{
+			name:          "full sync",
+			syncState:     DBFullSync,
+			subSyncState:  WaitRestoreDone,
+			inMemoryData:  &fullSyncInMemoryData{SnapshotName: "snapshot", RestoreLabel: "restore"},
+			snapshotLabel: "snapshot",
+			restoreLabel:  "restore",
+		},
+		{
+			name:          "partial sync",
+			syncState:     DBPartialSync,
+			subSyncState:  RestoreSnapshot,
+			inMemoryData:  &partialSyncInMemoryData{SnapshotName: "snapshot", RestoreLabel: "restore"},
+			snapshotLabel: "snapshot",
+			restoreLabel:  "restore",
+		},
+		{
+			name:         "not loaded",
+			syncState:    DBFullSync,
+			subSyncState: WaitRestoreDone,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			job := newStatusTestJob(test.syncState, test.subSyncState)
+			job.progress.PersistData = test.persistData
+			job.progress.InMemoryData = test.inMemoryData
+			job.updateJobStatus()
+
+			status := job.Status()
+			if status.SnapshotLabel != test.snapshotLabel || status.RestoreLabel != test.restoreLabel {
+				t.Errorf("labels = (%q, %q), expect (%q, %q)", status.SnapshotLabel, status.RestoreLabel,
+					test.snapshotLabel, test.restoreLabel)
+			}
+		})
+	}
+}
+
+func TestJobStatus_Failures(t *testing.T) {
+	job := newStatusTestJob(DBIncrementalSync, Done)
+	job.progress.PrevCommitSeq = 9
+	job.progress.CommitSeq = 10
+	job.updateJobStatus()
+
+	status := job.Status()
+	if status.State != JobRunning.String() || status.ProgressState != DBIncrementalSync.String() {
+		t.Errorf("state = (%s, %s), expect (%s, %s)", status.State, status.ProgressState, JobRunning, DBIncrementalSync)
+	}
+	if status.SubSyncState != Done.String() || status.PrevCommitSeq != 9 || status.CommitSeq != 10 {
+		t.Errorf("progress = (%s, %d, %d), expect (%s, 9, 10)", status.SubSyncState, status.PrevCommitSeq,
+			status.CommitSeq, Done)
+	}
+	if status.LastError != nil || status.ConsecutiveFailures != 0 {
+		t.Errorf("the new job has failures: %+v", status.JobStatusDetail)
+	}
+
+	job.recordSyncError(xerror.New(xerror.Meta, "table not found"))
+	job.recordSyncError(errors.New("unknown error"))
+	status = job.Status()
+	if status.ConsecutiveFailures != 2 || status.FailingSince == 0 {
+		t.Errorf("failures = (%d, %d), expect 2 failures", status.ConsecutiveFailures, status.FailingSince)
+	}
+	if status.LastError == nil || status.LastError.Message != "unknown error" || status.LastError.Category != "unknown" {
+		t.Errorf("last error = %+v, expect the unknown error", status.LastError)
+	}
+
+	job.recordSyncError(xerror.New(xerror.Meta, "table not found"))
+	if lastError := job.Status().LastError; lastError.Category != xerror.Meta.Name() {
+		t.Errorf("last error category = %s, expect %s", lastError.Category, xerror.Meta.Name())
+	}
+
+	// The last error is kept to find out why the job failed, only the failures are reset.
+	job.recordSyncSuccess()
+	status = job.Status()
+	if status.ConsecutiveFailures != 0 || status.FailingSince != 0 {
+		t.Errorf("failures = (%d, %d), expect reset", status.ConsecutiveFailures, status.FailingSince)
+	}
+	if status.LastError == nil {
+		t.Errorf("last error is reset")
+	}
+
+	// The status is a copy.
+	status.LastError.Message = "changed"
+	if job.Status().LastError.Message == "changed" {
+		t.Errorf("the status shares the last error with the job")
+	}
+}
+
+func TestJobStatus_RefreshLag(t *testing.T) {
+	origin := jobStatusLagInterval
+	jobStatusLagInterval = time.Hour
+	t.Cleanup(func() { jobStatusLagInterval = origin })
+
+	ctrl := gomock.NewController(t)
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	fe := NewMockIFeRpc(ctrl)
+	job := newStatusTestJob(DBIncrementalSync, Done)
+	job.progress.CommitSeq = 10
+	job.factory = NewFactory(rpcFactory, nil, nil, nil)
+
+	// The rpc is blocked, the job goroutine is not.
+	unblock := make(chan struct{})
+	lag := int64(5)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(fe, nil).Times(1)
+	fe.EXPECT().GetBinlogLag(gomock.Any(), int64(10)).DoAndReturn(
+		func(_ any, _ int64) (*festruct.TGetBinlogLagResult_, error) {
+			<-unblock
+			return &festruct.TGetBinlogLagResult_{Lag: &lag}, nil
+		}).Times(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.refreshLag()
+		// Refreshed in the interval.
+		job.refreshLag()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("refresh lag blocks the job goroutine")
+	}
+
+	close(unblock)
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status().LagUpdatedAt == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the lag is not refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := job.Status(); status.Lag != lag {
+		t.Errorf("lag = %d, expect %d", status.Lag, lag)
+	}
+}
@@ -0,0 +1,203 @@
+package ccr
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/record"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StopTarget stops the job at a point in time: the job replays the binlogs until the target,
+// and then transits to the terminal state JobCompleted. The job stops at the first reached
+// target if both are set.
+type StopTarget struct {
+	// Replay the binlogs whose commit seq is not greater than it.
+	CommitSeq int64 `json:"commit_seq,omitempty"`
+	// Stop before the first upsert binlog committed after it, in unix milliseconds.
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+func (t *StopTarget) IsEmpty() bool {
+	return t == nil || (t.CommitSeq == 0 && t.Timestamp == 0)
+}
+
+func (t *StopTarget) Valid() error {
+	if t == nil {
+		return nil
+	}
+	if t.CommitSeq < 0 || t.Timestamp < 0 {
+		return xerror.Errorf(xerror.Normal, "stop target must not be negative, commit seq: %d, timestamp: %d",
+			t.CommitSeq, t.Timestamp)
+	}
+	return nil
+}
+
+func (t *StopTarget) String() string {
+	if t == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("StopTarget{CommitSeq: %d, Timestamp: %d}", t.CommitSeq, t.Timestamp)
+}
+
+// reachedBy returns whether the binlog is beyond the target, the binlog should not be replayed
+// if so.
+func (t *StopTarget) reachedBy(binlog *festruct.TBinlog) (bool, string, error) {
+	if t.IsEmpty() {
+		return false, "", nil
+	}
+
+	commitSeq := binlog.GetCommitSeq()
+	if t.CommitSeq > 0 && commitSeq > t.CommitSeq {
+		return true, fmt.Sprintf("binlog %d is after the target commit seq %d", commitSeq, t.CommitSeq), nil
+	}
+
+	if t.Timestamp > 0 && binlog.GetType() == festruct.TBinlogType_UPSERT {
+		upsert, err := record.NewUpsertFromJson(binlog.GetData())
+		if err != nil {
+			return false, "", err
+		}
+		if upsert.TimeStamp > t.Timestamp {
+			return true, fmt.Sprintf("binlog %d is committed at %d, after the target timestamp %d",
+				commitSeq, upsert.TimeStamp, t.Timestamp), nil
+		}
+	}
+	return false, "", nil
+}
+
+// reachedByProgress returns whether the target is reached without the next binlog, caughtUp
+// means there are no more binlogs in the source for now.
+func (t *StopTarget) reachedByProgress(commitSeq int64, caughtUp bool) (bool, string) {
+	if t.IsEmpty() {
+		return false, ""
+	}
+
+	if t.CommitSeq > 0 && commitSeq >= t.CommitSeq {
+		return true, fmt.Sprintf("commit seq %d reaches the target commit seq %d", commitSeq, t.CommitSeq)
+	}
+
+	// All binlogs committed before the target timestamp have been replayed.
+	if now := time.Now().UnixMilli(); caughtUp && t.Timestamp > 0 && now > t.Timestamp {
+		return true, fmt.Sprintf("all binlogs are replayed and now %d is after the target timestamp %d", now, t.Timestamp)
+	}
+	return false, ""
+}
+
+func (j *Job) validStopTarget() error {
+	if j.IsFanout() {
+		return xerror.New(xerror.Normal, "stop target does not support fan-out job")
+	}
+	return nil
+}
+
+// syncStopTarget copies the stop target of the job into the progress, the target is enforced
+// with the progress. The progress is owned by the job goroutine, so the target updated by user
+// is copied in the job loop, the job lock must be held.
+func (j *Job) syncStopTarget() {
+	if j.progress == nil {
+		return
+	}
+
+	target := j.StopAt
+	if j.progress.StopAt.IsEmpty() && target.IsEmpty() {
+		return
+	}
+	if !j.progress.StopAt.IsEmpty() && !target.IsEmpty() && *j.progress.StopAt == *target {
+		return
+	}
+
+	log.Infof("job %s stop target changes from %s to %s", j.Name, j.progress.StopAt, target)
+	j.progress.StopAt = target
+	j.progress.Persist()
+}
+
+// checkStopTargetByBinlog completes the job if the binlog is beyond the stop target, it must
+// be called before the binlog is handled.
+func (j *Job) checkStopTargetByBinlog(binlog *festruct.TBinlog) (bool, error) {
+	reached, reason, err := j.progress.StopAt.reachedBy(binlog)
+	if err != nil || !reached {
+		return false, err
+	}
+	return true, j.complete(reason)
+}
+
+// checkStopTargetByProgress completes the job if the progress reaches the stop target.
+func (j *Job) checkStopTargetByProgress(caughtUp bool) (bool, error) {
+	if !j.progress.IsDone() {
+		return false, nil
+	}
+
+	reached, reason := j.progress.StopAt.reachedByProgress(j.progress.CommitSeq, caughtUp)
+	if !reached {
+		return false, nil
+	}
+	return true, j.complete(reason)
+}
+
+// complete transits the job to the terminal state JobCompleted, the job lock must be held, it is
+// held by sync() in the job loop.
+func (j *Job) complete(reason string) error {
+	log.Infof("job %s is completed at commit seq %d, %s", j.Name, j.progress.CommitSeq, reason)
+
+	originState := j.State
+	originPausedBySchedule := j.PausedBySchedule
+	j.State = JobCompleted
+	j.PausedBySchedule = false
+	if err := j.persistJob(); err != nil {
+		j.State = originState
+		j.PausedBySchedule = originPausedBySchedule
+		return err
+	}
+
+	j.notifyWatchState()
+	j.progress.CompletedAt = time.Now().Unix()
+	j.progress.Persist()
+
+	xmetrics.JobCompleted(j.Name, j.progress.CommitSeq)
+	j.recordEvent(JobEventComplete, "job is completed at commit seq %d, %s", j.progress.CommitSeq, reason)
+	return nil
+}
+
+func (j *Job) GetStopTarget() *StopTarget {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.StopAt
+}
+
+// UpdateStopTarget replaces the stop target of the job, a empty target removes it. The target
+// of a completed job could not be changed. The new target takes effect when the job loop copies
+// it into the progress, see syncStopTarget.
+func (j *Job) UpdateStopTarget(target *StopTarget) error {
+	if err := target.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "stop target is invalid")
+	}
+	if target.IsEmpty() {
+		target = nil
+	} else if err := j.validStopTarget(); err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.State == JobCompleted {
+		return xerror.Errorf(xerror.Normal, "job %s is completed", j.Name)
+	}
+
+	originTarget := j.StopAt
+	j.StopAt = target
+	if err := j.persistJob(); err != nil {
+		j.StopAt = originTarget
+		return err
+	}
+
+	log.Infof("update job %s stop target to %s", j.Name, target)
+	j.recordEvent(JobEventUpdate, "update stop target to %s", target)
+	return nil
+}
@@ -0,0 +1,221 @@
+package ccr
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"go.uber.org/mock/gomock"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+	tstatus "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/status"
+)
+
+func upsertBinlog(commitSeq int64, timestamp int64) *festruct.TBinlog {
+	binlogType := festruct.TBinlogType_UPSERT
+	data := fmt.Sprintf(`{"commitSeq":%d,"timeStamp":%d}`, commitSeq, timestamp)
+	return &festruct.TBinlog{CommitSeq: &commitSeq, Type: &binlogType, Data: &data}
+}
+
+func ddlBinlog(commitSeq int64) *festruct.TBinlog {
+	binlogType := festruct.TBinlogType_ALTER_JOB
+	data := "{}"
+	return &festruct.TBinlog{CommitSeq: &commitSeq, Type: &binlogType, Data: &data}
+}
+
+func TestStopTarget_ReachedBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  *StopTarget
+		binlog  *festruct.TBinlog
+		reached bool
+	}{
+		{"nil target", nil, upsertBinlog(100, 1000), false},
+		{"empty target", &StopTarget{}, upsertBinlog(100, 1000), false},
+		{"before commit seq", &StopTarget{CommitSeq: 100}, upsertBinlog(99, 1000), false},
+		// The binlog at the target is replayed, the job stops exactly at it.
+		{"at commit seq", &StopTarget{CommitSeq: 100}, upsertBinlog(100, 1000), false},
+		{"after commit seq", &StopTarget{CommitSeq: 100}, upsertBinlog(101, 1000), true},
+		{"ddl after commit seq", &StopTarget{CommitSeq: 100}, ddlBinlog(101), true},
+		{"before timestamp", &StopTarget{Timestamp: 1000}, upsertBinlog(100, 999), false},
+		{"at timestamp", &StopTarget{Timestamp: 1000}, upsertBinlog(100, 1000), false},
+		{"after timestamp", &StopTarget{Timestamp: 1000}, upsertBinlog(100, 1001), true},
+		// Only the upserts carry the commit time.
+		{"ddl with timestamp", &StopTarget{Timestamp: 1000}, ddlBinlog(100), false},
+		{"commit seq reached first", &StopTarget{CommitSeq: 100, Timestamp: 2000}, upsertBinlog(101, 1500), true},
+		{"timestamp reached first", &StopTarget{CommitSeq: 200, Timestamp: 1000}, upsertBinlog(101, 1500), true},
+		{"neither reached", &StopTarget{CommitSeq: 200, Timestamp: 2000}, upsertBinlog(101, 1500), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reached, reason, err := test.target.reachedBy(test.binlog)
+			if err != nil {
+				t.Fatalf("reached by failed: %v", err)
+			}
+			if reached != test.reached {
+				t.Errorf("reached = %v, expect %v, reason: %s", reached, test.reached, reason)
+			}
+			if reached && reason == "" {
+				t.Errorf("reason is empty")
+			}
+		})
+	}
+
+	invalid := "invalid"
+	binlogType := festruct.TBinlogType_UPSERT
+	commitSeq := int64(1)
+	binlog := &festruct.TBinlog{CommitSeq: &commitSeq, Type: &binlogType, Data: &invalid}
+	if _, _, err := (&StopTarget{Timestamp: 1000}).reachedBy(binlog); err == nil {
+		t.Errorf("reached by the invalid upsert should fail")
+	}
+}
+
+func TestStopTarget_ReachedByProgress(t *testing.T) {
+	past := time.Now().Add(-time.Hour).UnixMilli()
+	future := time.Now().Add(time.Hour).UnixMilli()
+	tests := []struct {
+		name      string
+		target    *StopTarget
+		commitSeq int64
+		caughtUp  bool
+		reached   bool
+	}{
+		{"nil target", nil, 100, true, false},
+		{"before commit seq", &StopTarget{CommitSeq: 100}, 99, true, false},
+		{"at commit seq", &StopTarget{CommitSeq: 100}, 100, false, true},
+		{"after commit seq", &StopTarget{CommitSeq: 100}, 101, false, true},
+		{"past timestamp but not caught up", &StopTarget{Timestamp: past}, 100, false, false},
+		{"past timestamp and caught up", &StopTarget{Timestamp: past}, 100, true, true},
+		// More binlogs before the target might be committed later.
+		{"future timestamp and caught up", &StopTarget{Timestamp: future}, 100, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reached, reason := test.target.reachedByProgress(test.commitSeq, test.caughtUp)
+			if reached != test.reached {
+				t.Errorf("reached = %v, expect %v, reason: %s", reached, test.reached, reason)
+			}
+		})
+	}
+}
+
+func newStopAtTestJob(t *testing.T) *Job {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	if err := db.AddJob("job", "{}", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+
+	// There are no more binlogs after the commit seq 10.
+	ctrl := gomock.NewController(t)
+	fe := NewMockIFeRpc(ctrl)
+	fe.EXPECT().GetBinlog(gomock.Any(), int64(10)).Return(&festruct.TGetBinlogResult_{
+		Status: &tstatus.TStatus{StatusCode: tstatus.TStatusCode_BINLOG_TOO_NEW_COMMIT_SEQ},
+	}, nil).AnyTimes()
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(fe, nil).AnyTimes()
+
+	progress := NewJobProgress("job", TableSync, db)
+	progress.NextWithPersist(10, TableIncrementalSync, Done, "")
+	return &Job{
+		Name:     "job",
+		SyncType: TableSync,
+		State:    JobRunning,
+		db:       db,
+		factory:  NewFactory(rpcFactory, nil, nil, nil),
+		progress: progress,
+	}
+}
+
+// The job goroutine completes the job while the user operates it, run with -race. The job loop
+// holds the job lock in sync(), so the operations must not deadlock with it.
+func TestJobStopTarget_Concurrent(t *testing.T) {
+	job := newStopAtTestJob(t)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = job.Pause()
+			_ = job.Resume()
+			_ = job.UpdateSchedule(nil)
+			_ = job.GetStopTarget()
+			_ = job.Status()
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		// The job loop, see run.
+		for i := 0; i < 100 && job.getJobState() != JobCompleted; i++ {
+			if err := job.sync(); err != nil {
+				done <- err
+				return
+			}
+			if i == 50 {
+				_ = job.UpdateStopTarget(&StopTarget{CommitSeq: 10})
+			}
+			time.Sleep(time.Millisecond)
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("sync failed: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the job loop is deadlocked")
+	}
+	close(stop)
+	wg.Wait()
+
+	if job.getJobState() != JobCompleted {
+		t.Fatalf("job state = %s, expect completed", job.getJobState())
+	}
+	if err := job.Resume(); err == nil {
+		t.Errorf("the completed job is resumed")
+	}
+	if job.progress.CompletedAt == 0 {
+		t.Errorf("completed at is not set")
+	}
+}
+
+func TestJobStopTarget_UpdateNotPersistProgress(t *testing.T) {
+	job := newStopAtTestJob(t)
+
+	if err := job.UpdateStopTarget(&StopTarget{CommitSeq: 100}); err != nil {
+		t.Fatalf("update stop target failed: %v", err)
+	}
+	if !job.progress.StopAt.IsEmpty() {
+		t.Fatalf("the progress is updated outside of the job loop")
+	}
+
+	if err := job.sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	progress, err := NewJobProgressFromJson("job", job.db)
+	if err != nil {
+		t.Fatalf("get progress failed: %v", err)
+	}
+	if progress.StopAt == nil || progress.StopAt.CommitSeq != 100 {
+		t.Errorf("persisted stop target = %s, expect commit seq 100", progress.StopAt)
+	}
+	if job.getJobState() != JobRunning {
+		t.Errorf("job state = %s, expect running", job.getJobState())
+	}
+}
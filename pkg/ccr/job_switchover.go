@@ -0,0 +1,416 @@
+package ccr
+
+import (
+	"flag"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The switchover reverses the replication direction of a job without full sync:
+//
+//  1. wait for the dest catching up with the source, and pause the job,
+//  2. desync the tables of the dest,
+//  3. enable the binlog of the dest, and record the commit seq of the dest,
+//  4. create the reverse job, which starts incremental sync from the recorded commit seq.
+//
+// The switchover is persisted with the job after each step, so it is resumed from the last
+// finished step if the syncer crashes. The writes into the former dest before the switchover
+// is done are not replicated back, and the writes into the former source should be stopped
+// before the switchover.
+
+const SWITCHOVER_CHECK_DURATION = 3 * time.Second
+
+var (
+	flagSwitchoverTimeout time.Duration
+)
+
+func init() {
+	flag.DurationVar(&flagSwitchoverTimeout, "switchover_timeout", 10*time.Minute,
+		"The timeout of waiting for the job catching up with the source before switchover")
+}
+
+type SwitchoverStep int
+
+const (
+	SwitchoverWaitLag       SwitchoverStep = 0
+	SwitchoverPaused        SwitchoverStep = 1
+	SwitchoverDesynced      SwitchoverStep = 2
+	SwitchoverBinlogEnabled SwitchoverStep = 3
+	SwitchoverDone          SwitchoverStep = 4
+)
+
+// SwitchoverStep Stringer
+func (s SwitchoverStep) String() string {
+	switch s {
+	case SwitchoverWaitLag:
+		return "wait_lag"
+	case SwitchoverPaused:
+		return "paused"
+	case SwitchoverDesynced:
+		return "desynced"
+	case SwitchoverBinlogEnabled:
+		return "binlog_enabled"
+	case SwitchoverDone:
+		return "done"
+	default:
+		return fmt.Sprintf("unknown switchover step: %d", s)
+	}
+}
+
+type Switchover struct {
+	ReverseName string         `json:"reverse_name"`
+	Step        SwitchoverStep `json:"step"`
+	// The commit seq of the former dest where the reverse job starts from.
+	ReverseCommitSeq int64 `json:"reverse_commit_seq,omitempty"`
+	// The unix epoch time of the key timepoint.
+	StartAt  int64 `json:"start_at"`
+	FinishAt int64 `json:"finish_at,omitempty"`
+	// The error of the last failed step, it is cleared once the step is finished.
+	Error string `json:"error,omitempty"`
+}
+
+func (s *Switchover) IsPending() bool {
+	return s != nil && s.Step != SwitchoverDone
+}
+
+// blocksResume returns whether the job must not be resumed, the dest has been changed since
+// the job is paused by the switchover.
+func (s *Switchover) blocksResume() bool {
+	return s != nil && s.Step >= SwitchoverPaused
+}
+
+func (j *Job) validSwitchover() error {
+	if !j.Sink.IsDoris() {
+		return xerror.New(xerror.Normal, "switchover only supports the doris sink")
+	}
+	if j.IsFanout() {
+		return xerror.New(xerror.Normal, "switchover does not support fan-out job")
+	}
+	if j.DryRun {
+		return xerror.New(xerror.Normal, "switchover does not support dry run job")
+	}
+	return nil
+}
+
+func (j *Job) GetSwitchover() *Switchover {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.Switchover == nil {
+		return nil
+	}
+	switchover := *j.Switchover
+	return &switchover
+}
+
+// updateSwitchover persists the switchover with the job, a nil switchover removes it. Nothing
+// is persisted if the switchover is not changed.
+func (j *Job) updateSwitchover(switchover *Switchover) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.Switchover != nil && switchover != nil && *j.Switchover == *switchover {
+		return nil
+	}
+
+	originSwitchover := j.Switchover
+	j.Switchover = switchover
+	if err := j.persistJob(); err != nil {
+		j.Switchover = originSwitchover
+		return err
+	}
+	return nil
+}
+
+// changeSwitchoverState changes the job state and the switchover in one persist, so the user
+// could not resume the job between the switchover pauses it and the step is persisted.
+func (j *Job) changeSwitchoverState(state JobState, switchover *Switchover) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.State == JobCompleted {
+		return xerror.Errorf(xerror.Normal, "job %s is completed", j.Name)
+	}
+
+	originState := j.State
+	originPausedBySchedule := j.PausedBySchedule
+	originSwitchover := j.Switchover
+	j.State = state
+	j.PausedBySchedule = false
+	j.Switchover = switchover
+	if err := j.persistJob(); err != nil {
+		j.State = originState
+		j.PausedBySchedule = originPausedBySchedule
+		j.Switchover = originSwitchover
+		return err
+	}
+	if originState != state {
+		log.Debugf("change job %s state from %s to %s", j.Name, originState, state)
+		j.notifyWatchState()
+		j.recordStateEvent(state, "by switchover")
+	}
+	return nil
+}
+
+// caughtUp returns whether the dest has replayed all binlogs of the source. The job lock is held
+// by sync() during the incremental sync, so the progress is read from the status published by
+// the job goroutine, see updateJobStatus.
+func (j *Job) caughtUp() (bool, error) {
+	progressState := SyncState(atomic.LoadInt32(&j.rawStatus.progressState))
+	detail := j.getStatusDetail()
+	if !progressState.isIncrementalSync() || detail.SubSyncState != Done.String() ||
+		detail.PrevCommitSeq != detail.CommitSeq {
+		return false, nil
+	}
+
+	lag, err := j.getLag(detail.CommitSeq)
+	if err != nil {
+		return false, err
+	}
+	return lag == 0, nil
+}
+
+// pauseCaughtUp waits for the dest catching up with the source, pauses the job and persists
+// the paused step of the switchover.
+func (j *Job) pauseCaughtUp(timeout time.Duration, waiting, paused *Switchover) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if caughtUp, err := j.caughtUp(); err != nil {
+			return err
+		} else if caughtUp {
+			if err := j.changeSwitchoverState(JobPaused, paused); err != nil {
+				return err
+			}
+
+			// The binlogs might be committed before the job is paused.
+			caughtUp, checkErr := j.caughtUp()
+			if checkErr == nil && caughtUp {
+				return nil
+			}
+
+			log.Infof("job %s has new binlogs after paused, resume it and wait again", j.Name)
+			if err := j.changeSwitchoverState(JobRunning, waiting); err != nil {
+				return err
+			}
+			if checkErr != nil {
+				return checkErr
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return xerror.Errorf(xerror.Normal, "wait for job %s catching up timeout", j.Name)
+		}
+
+		select {
+		case <-j.stop:
+			return xerror.Errorf(xerror.Normal, "job %s is stopped", j.Name)
+		case <-time.After(SWITCHOVER_CHECK_DURATION):
+		}
+	}
+}
+
+// enableDestBinlog enables the binlog of the dest, and returns the commit seq of the dest since
+// which the binlogs are replicated back.
+func (j *Job) enableDestBinlog() (int64, error) {
+	if j.SyncType == DBSync {
+		tables, err := j.destMeta.GetTables()
+		if err != nil {
+			return 0, err
+		}
+
+		tableNames := []string{}
+		for _, tableMeta := range tables {
+			if tableMeta.Type == TABLE_TYPE_OLAP {
+				tableNames = append(tableNames, tableMeta.Name)
+			}
+		}
+		if err := j.IDest.EnableTableBinlog(tableNames...); err != nil {
+			return 0, err
+		}
+		if err := j.IDest.EnableDatabaseBinlog(); err != nil {
+			return 0, err
+		}
+	} else {
+		tableName, err := j.destMeta.GetTableNameById(j.Dest.TableId)
+		if err != nil {
+			return 0, err
+		}
+		if err := j.IDest.EnableTableBinlog(tableName); err != nil {
+			return 0, err
+		}
+	}
+
+	return j.IDest.GetMasterJournalId()
+}
+
+// newReverseJobContext returns the context of the reverse job, and the table mapping from the
+// dest to the source.
+func (j *Job) newReverseJobContext(db storage.DB, factory *Factory) (*jobContext, map[int64]int64) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	src, dest := j.Dest, j.Src
+	// The tables of the former source are exists.
+	ctx := NewJobContext(src, dest, j.SkipError, true, db, factory).
+		WithTableFilter(j.TableFilter).
+		WithRateLimit(j.RateLimit)
+
+	var tableMapping map[int64]int64
+	if j.progress != nil && j.progress.TableMapping != nil {
+		tableMapping = make(map[int64]int64, len(j.progress.TableMapping))
+		for srcTableId, destTableId := range j.progress.TableMapping {
+			tableMapping[destTableId] = srcTableId
+		}
+	}
+	return ctx, tableMapping
+}
+
+// Switchover reverses the replication direction of the job, or resumes the unfinished
+// switchover. The reverse job is named as ${name}_reverse if the reverseName is empty.
+func (jm *JobManager) Switchover(jobName string, reverseName string) (*Switchover, error) {
+	jm.lock.Lock()
+	job, ok := jm.jobs[jobName]
+	jm.lock.Unlock()
+
+	if !ok {
+		return nil, xerror.Errorf(xerror.Normal, "job not exist: %s", jobName)
+	}
+	return jm.switchover(job, reverseName)
+}
+
+// resumeSwitchover resumes the unfinished switchover of the recovered job.
+func (jm *JobManager) resumeSwitchover(job *Job) {
+	log.Infof("resume the switchover of job %s", job.Name)
+
+	if _, err := jm.switchover(job, ""); err != nil {
+		log.Warnf("resume the switchover of job %s failed, err: %+v", job.Name, err)
+	}
+}
+
+func (jm *JobManager) switchover(job *Job, reverseName string) (*Switchover, error) {
+	if err := job.validSwitchover(); err != nil {
+		return nil, err
+	}
+	if !job.switchoverRunning.CompareAndSwap(false, true) {
+		return nil, xerror.Errorf(xerror.Normal, "the switchover of job %s is running", job.Name)
+	}
+	defer job.switchoverRunning.Store(false)
+
+	switchover := job.GetSwitchover()
+	if switchover == nil {
+		if job.getJobState() == JobCompleted {
+			return nil, xerror.Errorf(xerror.Normal, "job %s is completed", job.Name)
+		}
+		if reverseName == "" {
+			reverseName = fmt.Sprintf("%s_reverse", job.Name)
+		}
+		if jm.isJobExist(reverseName) {
+			return nil, xerror.Errorf(xerror.Normal, "the reverse job %s already exists", reverseName)
+		}
+		switchover = &Switchover{
+			ReverseName: reverseName,
+			Step:        SwitchoverWaitLag,
+			StartAt:     time.Now().Unix(),
+		}
+		if err := job.updateSwitchover(switchover); err != nil {
+			return nil, err
+		}
+		log.Infof("start switchover of job %s, reverse job: %s", job.Name, reverseName)
+		job.recordEvent(JobEventSwitchover, "start switchover, reverse job: %s", reverseName)
+	} else if reverseName != "" && reverseName != switchover.ReverseName {
+		return nil, xerror.Errorf(xerror.Normal, "job %s is switching over to %s", job.Name, switchover.ReverseName)
+	}
+
+	for switchover.IsPending() {
+		var err error
+		next := *switchover
+		switch switchover.Step {
+		case SwitchoverWaitLag:
+			next.Step = SwitchoverPaused
+			next.Error = ""
+			if err = job.pauseCaughtUp(flagSwitchoverTimeout, switchover, &next); err != nil {
+				// Nothing is changed, abort the switchover.
+				log.Warnf("switchover of job %s is aborted, err: %+v", job.Name, err)
+				job.recordEvent(JobEventSwitchover, "switchover is aborted, err: %v", err)
+				if err := job.updateSwitchover(nil); err != nil {
+					log.Warnf("remove the switchover of job %s failed, err: %+v", job.Name, err)
+				}
+				return nil, err
+			}
+		case SwitchoverPaused:
+			err = job.Desync()
+			next.Step = SwitchoverDesynced
+		case SwitchoverDesynced:
+			next.ReverseCommitSeq, err = job.enableDestBinlog()
+			next.Step = SwitchoverBinlogEnabled
+		case SwitchoverBinlogEnabled:
+			err = jm.addReverseJob(job, switchover)
+			next.Step = SwitchoverDone
+			next.FinishAt = time.Now().Unix()
+		default:
+			err = xerror.Errorf(xerror.Normal, "unknown switchover step: %d", switchover.Step)
+		}
+
+		if err != nil {
+			log.Warnf("switchover of job %s failed at step %s, err: %+v", job.Name, switchover.Step, err)
+			failed := *switchover
+			failed.Error = err.Error()
+			if err := job.updateSwitchover(&failed); err != nil {
+				log.Warnf("update the switchover of job %s failed, err: %+v", job.Name, err)
+			}
+			job.recordEvent(JobEventSwitchover, "switchover failed at step %s, err: %v", switchover.Step, err)
+			return &failed, err
+		}
+
+		next.Error = ""
+		if err := job.updateSwitchover(&next); err != nil {
+			return switchover, err
+		}
+		log.Infof("switchover of job %s step %s is done", job.Name, switchover.Step)
+		job.recordEvent(JobEventSwitchover, "switchover step %s is done, next step: %s", switchover.Step, next.Step)
+		switchover = &next
+	}
+
+	return switchover, nil
+}
+
+// addReverseJob creates the reverse job, which starts incremental sync from the commit seq of
+// the former dest without full sync.
+func (jm *JobManager) addReverseJob(job *Job, switchover *Switchover) error {
+	if jm.isJobExist(switchover.ReverseName) {
+		log.Infof("reverse job %s is added before", switchover.ReverseName)
+		return nil
+	}
+
+	ctx, tableMapping := job.newReverseJobContext(jm.db, jm.factory)
+	reverseJob, err := NewJobFromService(switchover.ReverseName, ctx)
+	if err != nil {
+		return err
+	}
+
+	// The progress is persisted after the job is saved and before it runs, so the job never makes
+	// snapshot. If the syncer crashes in between, the recovered job makes a full sync.
+	return jm.addJob(reverseJob, func() {
+		progress := NewJobProgress(reverseJob.Name, reverseJob.SyncType, jm.db)
+		progress.TableMapping = tableMapping
+		if reverseJob.SyncType == DBSync {
+			progress.NextWithPersist(switchover.ReverseCommitSeq, DBIncrementalSync, Done, "")
+		} else {
+			progress.NextWithPersist(switchover.ReverseCommitSeq, TableIncrementalSync, Done, "")
+		}
+	})
+}
+
+func (jm *JobManager) isJobExist(jobName string) bool {
+	jm.lock.Lock()
+	defer jm.lock.Unlock()
+
+	_, ok := jm.jobs[jobName]
+	return ok
+}
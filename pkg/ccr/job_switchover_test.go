@@ -0,0 +1,312 @@
+package ccr
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/test_util"
+	"go.uber.org/mock/gomock"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+)
+
+const switchoverTestReverseCommitSeq int64 = 1000
+
+var errInjected = errors.New("injected error")
+
+// persistedJob is the part of the job persisted by the switchover.
+type persistedJob struct {
+	State      JobState    `json:"state"`
+	Switchover *Switchover `json:"switchover"`
+}
+
+type switchoverTest struct {
+	job        *Job
+	jm         *JobManager
+	db         *test_util.MockDB
+	dest       *MockSpecer
+	destMeta   *MockMetaer
+	rpcFactory *MockIRpcFactory
+
+	lock      sync.Mutex
+	persisted []persistedJob
+}
+
+func newSwitchoverTest(t *testing.T, state JobState, switchover *Switchover) *switchoverTest {
+	ctrl := gomock.NewController(t)
+	test := &switchoverTest{
+		db:         test_util.NewMockDB(ctrl),
+		dest:       NewMockSpecer(ctrl),
+		destMeta:   NewMockMetaer(ctrl),
+		rpcFactory: NewMockIRpcFactory(ctrl),
+	}
+	test.db.EXPECT().UpdateJob("job", gomock.Any()).DoAndReturn(func(_ string, jobInfo string) error {
+		var persisted persistedJob
+		if err := json.Unmarshal([]byte(jobInfo), &persisted); err != nil {
+			t.Errorf("unmarshal the persisted job failed: %v", err)
+		}
+		test.lock.Lock()
+		defer test.lock.Unlock()
+		test.persisted = append(test.persisted, persisted)
+		return nil
+	}).AnyTimes()
+	test.db.EXPECT().AddJobEvent(gomock.Any()).Return(nil).AnyTimes()
+	test.destMeta.EXPECT().GetTableNameById(int64(20)).Return("tbl", nil).AnyTimes()
+
+	factory := NewFactory(test.rpcFactory, nil, nil, nil)
+	test.jm = NewJobManager(test.db, factory, "127.0.0.1:9190")
+	// Creating the reverse job requires the fe, it is added before the crash, see addReverseJob.
+	test.jm.jobs["job_reverse"] = &Job{Name: "job_reverse"}
+
+	progress := NewJobProgress("job", TableSync, test.db)
+	progress.SyncState = TableIncrementalSync
+	progress.SubSyncState = Done
+	progress.PrevCommitSeq = 10
+	progress.CommitSeq = 10
+	test.job = &Job{
+		Name:       "job",
+		SyncType:   TableSync,
+		Src:        base.Spec{Database: "src_db", Table: "tbl", TableId: 10},
+		Dest:       base.Spec{Database: "dest_db", Table: "tbl", TableId: 20},
+		IDest:      test.dest,
+		destMeta:   test.destMeta,
+		State:      state,
+		Switchover: switchover,
+		factory:    factory,
+		progress:   progress,
+		db:         test.db,
+		stop:       make(chan struct{}),
+	}
+	test.job.updateJobStatus()
+	test.jm.jobs["job"] = test.job
+	return test
+}
+
+// expectCaughtUp expects the lag of the job is queried, and returns 0.
+func (test *switchoverTest) expectCaughtUp(ctrl *gomock.Controller) {
+	fe := NewMockIFeRpc(ctrl)
+	lag := int64(0)
+	fe.EXPECT().GetBinlogLag(gomock.Any(), int64(10)).Return(&festruct.TGetBinlogLagResult_{Lag: &lag}, nil).MinTimes(1)
+	test.rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(fe, nil).MinTimes(1)
+}
+
+func (test *switchoverTest) persistedSteps() []SwitchoverStep {
+	test.lock.Lock()
+	defer test.lock.Unlock()
+
+	steps := make([]SwitchoverStep, 0, len(test.persisted))
+	for _, persisted := range test.persisted {
+		steps = append(steps, persisted.Switchover.Step)
+	}
+	return steps
+}
+
+// The switchover is resumed from each persisted step after the syncer crashes, the finished
+// steps are not repeated.
+func TestSwitchover_ResumeFromPersistedStep(t *testing.T) {
+	tests := []struct {
+		name string
+		step SwitchoverStep
+		// The reverse commit seq recorded by the step enabling binlog.
+		reverseCommitSeq int64
+		paused           bool
+		desync           bool
+		enableBinlog     bool
+		persisted        []SwitchoverStep
+	}{
+		{
+			name:         "wait lag",
+			step:         SwitchoverWaitLag,
+			paused:       true,
+			desync:       true,
+			enableBinlog: true,
+			persisted:    []SwitchoverStep{SwitchoverPaused, SwitchoverDesynced, SwitchoverBinlogEnabled, SwitchoverDone},
+		},
+		{
+			name:         "paused",
+			step:         SwitchoverPaused,
+			desync:       true,
+			enableBinlog: true,
+			persisted:    []SwitchoverStep{SwitchoverDesynced, SwitchoverBinlogEnabled, SwitchoverDone},
+		},
+		{
+			name:         "desynced",
+			step:         SwitchoverDesynced,
+			enableBinlog: true,
+			persisted:    []SwitchoverStep{SwitchoverBinlogEnabled, SwitchoverDone},
+		},
+		{
+			name:             "binlog enabled",
+			step:             SwitchoverBinlogEnabled,
+			reverseCommitSeq: switchoverTestReverseCommitSeq,
+			persisted:        []SwitchoverStep{SwitchoverDone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := JobPaused
+			if tt.step == SwitchoverWaitLag {
+				state = JobRunning
+			}
+			test := newSwitchoverTest(t, state, &Switchover{
+				ReverseName:      "job_reverse",
+				Step:             tt.step,
+				ReverseCommitSeq: tt.reverseCommitSeq,
+				StartAt:          1,
+			})
+
+			// Without the expectations, gomock fails the repeated steps.
+			ctrl := gomock.NewController(t)
+			if tt.paused {
+				test.expectCaughtUp(ctrl)
+			}
+			if tt.desync {
+				test.dest.EXPECT().DesyncTables("tbl").Return(nil).Times(1)
+			}
+			if tt.enableBinlog {
+				test.dest.EXPECT().EnableTableBinlog("tbl").Return(nil).Times(1)
+				test.dest.EXPECT().GetMasterJournalId().Return(switchoverTestReverseCommitSeq, nil).Times(1)
+			}
+
+			test.jm.resumeSwitchover(test.job)
+
+			switchover := test.job.GetSwitchover()
+			if switchover == nil || switchover.Step != SwitchoverDone {
+				t.Fatalf("switchover = %+v, expect done", switchover)
+			}
+			if switchover.ReverseCommitSeq != switchoverTestReverseCommitSeq {
+				t.Errorf("reverse commit seq = %d, expect %d", switchover.ReverseCommitSeq, switchoverTestReverseCommitSeq)
+			}
+			if switchover.Error != "" {
+				t.Errorf("switchover error = %s", switchover.Error)
+			}
+			if steps := test.persistedSteps(); !equalSteps(steps, tt.persisted) {
+				t.Errorf("persisted steps = %v, expect %v", steps, tt.persisted)
+			}
+			if state := test.job.getJobState(); state != JobPaused {
+				t.Errorf("job state = %s, expect paused", state)
+			}
+			if err := test.job.Resume(); err == nil {
+				t.Errorf("the switched over job is resumed")
+			}
+		})
+	}
+}
+
+// The failed step is persisted with the error, and retried by the next resume.
+func TestSwitchover_RetryFailedStep(t *testing.T) {
+	test := newSwitchoverTest(t, JobPaused, &Switchover{ReverseName: "job_reverse", Step: SwitchoverPaused, StartAt: 1})
+
+	test.dest.EXPECT().DesyncTables("tbl").Return(nil).Times(1)
+	gomock.InOrder(
+		test.dest.EXPECT().EnableTableBinlog("tbl").Return(errInjected),
+		test.dest.EXPECT().EnableTableBinlog("tbl").Return(nil),
+	)
+	test.dest.EXPECT().GetMasterJournalId().Return(switchoverTestReverseCommitSeq, nil).Times(1)
+
+	test.jm.resumeSwitchover(test.job)
+	switchover := test.job.GetSwitchover()
+	if switchover.Step != SwitchoverDesynced || switchover.Error == "" {
+		t.Fatalf("switchover = %+v, expect failed at desynced", switchover)
+	}
+
+	test.jm.resumeSwitchover(test.job)
+	switchover = test.job.GetSwitchover()
+	if switchover.Step != SwitchoverDone || switchover.Error != "" {
+		t.Fatalf("switchover = %+v, expect done", switchover)
+	}
+}
+
+// The user pauses and resumes the job while the switchover pauses it, the job must never be
+// running once the paused step is persisted. Run with -race.
+func TestSwitchover_UserResumeRacing(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		test := newSwitchoverTest(t, JobRunning, &Switchover{ReverseName: "job_reverse", Step: SwitchoverWaitLag, StartAt: 1})
+		ctrl := gomock.NewController(t)
+		test.expectCaughtUp(ctrl)
+		test.dest.EXPECT().DesyncTables("tbl").Return(nil).Times(1)
+		test.dest.EXPECT().EnableTableBinlog("tbl").Return(nil).Times(1)
+		test.dest.EXPECT().GetMasterJournalId().Return(switchoverTestReverseCommitSeq, nil).Times(1)
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = test.job.Pause()
+				_ = test.job.Resume()
+			}
+		}()
+
+		test.jm.resumeSwitchover(test.job)
+		close(stop)
+		wg.Wait()
+
+		test.lock.Lock()
+		for _, persisted := range test.persisted {
+			if persisted.Switchover.blocksResume() && persisted.State != JobPaused {
+				t.Fatalf("the job is %s at switchover step %s", persisted.State, persisted.Switchover.Step)
+			}
+		}
+		test.lock.Unlock()
+		if state := test.job.getJobState(); state != JobPaused {
+			t.Fatalf("job state = %s, expect paused", state)
+		}
+		if switchover := test.job.GetSwitchover(); switchover.Step != SwitchoverDone {
+			t.Fatalf("switchover = %+v, expect done", switchover)
+		}
+	}
+}
+
+// The job lock is held by sync() during the incremental sync, the switchover checks the lag
+// without waiting for it.
+func TestSwitchover_CaughtUpWhileSyncing(t *testing.T) {
+	test := newSwitchoverTest(t, JobRunning, nil)
+	test.expectCaughtUp(gomock.NewController(t))
+
+	test.job.lock.Lock()
+	defer test.job.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if caughtUp, err := test.job.caughtUp(); err != nil || !caughtUp {
+			t.Errorf("caught up = %v, err: %v, expect caught up", caughtUp, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("caught up waits for the job lock")
+	}
+
+	// The published progress is not done.
+	test.job.progress.CommitSeq = 11
+	test.job.updateJobStatus()
+	if caughtUp, err := test.job.caughtUp(); err != nil || caughtUp {
+		t.Errorf("caught up = %v, err: %v, expect not caught up", caughtUp, err)
+	}
+}
+
+func equalSteps(a, b []SwitchoverStep) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
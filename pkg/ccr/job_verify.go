@@ -0,0 +1,382 @@
+package ccr
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/verify"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The verification is executed in the job goroutine between two binlogs. The source tables are
+// collected first, and the commit seq of the source is pinned by the lag, which is the number
+// of the binlogs not synced yet. The dest is compared once these binlogs are synced, so the dest
+// is at the same commit seq with the collected source. The source is consistent with the pinned
+// commit seq only if it has no new binlogs while collecting, see verify.Report.Consistent.
+
+// The max number of the pending verify requests of a job.
+const VERIFY_REQUEST_CAPACITY = 16
+
+var (
+	flagVerifyTimeout time.Duration
+)
+
+func init() {
+	flag.DurationVar(&flagVerifyTimeout, "verify_timeout", 30*time.Minute,
+		"The timeout of waiting for the verification requested by user")
+}
+
+// VerifyConfig verifies the data of the job periodically.
+type VerifyConfig struct {
+	// A standard 5 fields cron expression, in the local time zone of the syncer.
+	Cron string `json:"cron,omitempty"`
+	// Compare the checksums of the partitions, which scans all data of the tables.
+	Checksum bool `json:"checksum,omitempty"`
+}
+
+func (c *VerifyConfig) IsEmpty() bool {
+	return c == nil || c.Cron == ""
+}
+
+func (c *VerifyConfig) Valid() error {
+	if c.IsEmpty() {
+		return nil
+	}
+	_, err := schedule.ParseCron(c.Cron)
+	return err
+}
+
+type verifyResult struct {
+	report *verify.Report
+	err    error
+}
+
+type verifyRequest struct {
+	checksum bool
+	result   chan *verifyResult
+}
+
+// pendingVerification is a verification whose source is collected, the dest is compared after
+// the remaining binlogs are synced.
+type pendingVerification struct {
+	request  *verifyRequest // nil for the periodic verification
+	verifier *verify.Verifier
+	tables   []verify.TablePair
+	source   *verify.Source
+	// Whether the source has no new binlogs while collecting.
+	stable bool
+	// The binlogs to sync before the dest reaches the pinned commit seq of the source.
+	remaining     int64
+	lastCommitSeq int64
+	startAt       time.Time
+}
+
+// verifyMetaer gets the partition versions from the Metaer.
+type verifyMetaer struct {
+	meta Metaer
+}
+
+func (m *verifyMetaer) GetPartitionVersions(tableName string) (map[string]int64, error) {
+	tableId, err := m.meta.GetTableId(tableName)
+	if err != nil {
+		return nil, err
+	}
+	// The cached visible versions are stale.
+	if err := m.meta.UpdatePartitions(tableId); err != nil {
+		return nil, err
+	}
+	partitions, err := m.meta.GetPartitionIdMap(tableId)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]int64, len(partitions))
+	for _, partition := range partitions {
+		versions[partition.Name] = partition.VisibleVersion
+	}
+	return versions, nil
+}
+
+func (j *Job) validVerify() error {
+	if !j.Sink.IsDoris() {
+		return xerror.New(xerror.Normal, "verify only supports the doris sink")
+	}
+	if j.IsFanout() {
+		return xerror.New(xerror.Normal, "verify does not support fan-out job")
+	}
+	if j.DryRun {
+		return xerror.New(xerror.Normal, "verify does not support dry run job")
+	}
+	return nil
+}
+
+// Verify requests the job goroutine to verify the data, and waits for the report.
+func (j *Job) Verify(checksum bool) (*verify.Report, error) {
+	if err := j.validVerify(); err != nil {
+		return nil, err
+	}
+
+	request := &verifyRequest{
+		checksum: checksum,
+		result:   make(chan *verifyResult, 1),
+	}
+	select {
+	case j.verifyRequests <- request:
+	default:
+		return nil, xerror.Errorf(xerror.Normal, "job %s has too many pending verify requests", j.Name)
+	}
+
+	timer := time.NewTimer(flagVerifyTimeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-request.result:
+		return result.report, result.err
+	case <-j.stop:
+		return nil, xerror.Errorf(xerror.Normal, "job %s is stopped", j.Name)
+	case <-timer.C:
+		return nil, xerror.Errorf(xerror.Normal, "wait for the verification of job %s timeout", j.Name)
+	}
+}
+
+// hasPendingVerify returns true if the job loop should go back to serve the verification, the
+// job keeps syncing while the verification waits for the dest.
+func (j *Job) hasPendingVerify() bool {
+	if j.verification != nil {
+		return j.verification.remaining <= 0
+	}
+	return len(j.verifyRequests) > 0
+}
+
+// handleVerify serves the pending verify requests and the periodic verification, it must be
+// called in the job goroutine.
+func (j *Job) handleVerify() {
+	if j.verification != nil {
+		if !j.isIncrementalSync() {
+			j.abortVerify(xerror.Errorf(xerror.Normal, "job %s leaves the incremental sync, the verification is aborted", j.Name))
+		} else if time.Since(j.verification.startAt) > flagVerifyTimeout {
+			j.abortVerify(xerror.Errorf(xerror.Normal, "the dest of job %s does not reach the pinned commit seq in time", j.Name))
+		} else if j.verification.remaining <= 0 {
+			j.finishVerify()
+		}
+	}
+
+	for j.verification == nil && len(j.verifyRequests) > 0 {
+		request := <-j.verifyRequests
+		if err := j.startVerify(request.checksum, request); err != nil {
+			request.result <- &verifyResult{err: err}
+		}
+	}
+	if j.verification != nil {
+		return
+	}
+
+	j.lock.Lock()
+	config := j.VerifyConfig
+	j.lock.Unlock()
+	if config.IsEmpty() {
+		return
+	}
+
+	cron, err := schedule.ParseCron(config.Cron)
+	if err != nil {
+		log.Warnf("parse the verify cron of job %s failed, err: %+v", j.Name, err)
+		return
+	}
+
+	now := time.Now()
+	if j.lastVerifyAt.IsZero() {
+		// Don't verify once the job is started.
+		j.lastVerifyAt = now
+		return
+	}
+	if fireAt := cron.Prev(now); !fireAt.After(j.lastVerifyAt) {
+		return
+	}
+
+	if err := j.startVerify(config.Checksum, nil); err != nil {
+		log.Warnf("verify job %s failed, err: %+v", j.Name, err)
+	}
+}
+
+// startVerify collects the source tables and pins the commit seq of the source, the dest is
+// compared once it reaches the pinned commit seq.
+func (j *Job) startVerify(checksum bool, request *verifyRequest) error {
+	j.lastVerifyAt = time.Now()
+	if err := j.validVerify(); err != nil {
+		return err
+	}
+	if j.progress == nil || !j.isIncrementalSync() || !j.progress.IsDone() {
+		return xerror.Errorf(xerror.Normal, "job %s is not in incremental sync, the data is not ready to verify", j.Name)
+	}
+
+	tables, err := j.getVerifyTables()
+	if err != nil {
+		return err
+	}
+
+	commitSeq := j.progress.CommitSeq
+	lagBefore, err := j.getLag(commitSeq)
+	if err != nil {
+		return err
+	}
+	if lagBefore > 0 && j.getJobState() == JobPaused {
+		return xerror.Errorf(xerror.Normal, "job %s is paused with %d binlogs not synced, the dest could not reach the source", j.Name, lagBefore)
+	}
+
+	log.Infof("verify job %s, commit seq: %d, lag: %d, tables: %d, checksum: %v", j.Name, commitSeq, lagBefore, len(tables), checksum)
+	src := &verify.Cluster{Spec: j.ISrc, Meta: &verifyMetaer{meta: j.srcMeta}}
+	dest := &verify.Cluster{Spec: j.IDest, Meta: &verifyMetaer{meta: j.destMeta}}
+	verifier := verify.NewVerifier(src, dest, checksum)
+	source := verifier.CollectSource(tables)
+
+	lagAfter, err := j.getLag(commitSeq)
+	if err != nil {
+		return err
+	}
+
+	j.verification = &pendingVerification{
+		request:       request,
+		verifier:      verifier,
+		tables:        tables,
+		source:        source,
+		stable:        lagBefore == lagAfter,
+		remaining:     lagBefore,
+		lastCommitSeq: commitSeq,
+		startAt:       j.lastVerifyAt,
+	}
+	if lagBefore > 0 {
+		log.Infof("verify job %s, the source is collected, wait for %d binlogs to compare the dest", j.Name, lagBefore)
+		return nil
+	}
+	j.finishVerify()
+	return nil
+}
+
+// countVerifyBinlog counts the synced binlog for the pending verification, it returns true if
+// the dest reaches the pinned commit seq.
+func (j *Job) countVerifyBinlog(commitSeq int64) bool {
+	if j.verification == nil || commitSeq <= j.verification.lastCommitSeq {
+		return false
+	}
+	j.verification.lastCommitSeq = commitSeq
+	j.verification.remaining--
+	return j.verification.remaining <= 0
+}
+
+// finishVerify compares the dest with the collected source.
+func (j *Job) finishVerify() {
+	verification := j.verification
+	j.verification = nil
+
+	report := verification.verifier.Compare(verification.tables, verification.source)
+	report.JobName = j.Name
+	report.CommitSeq = j.progress.CommitSeq
+	report.Consistent = verification.stable
+	log.Infof("verify job %s finished, consistent: %v, matched: %v, mismatched tables: %d, partitions: %d, failed tables: %d",
+		j.Name, report.Consistent, report.Matched, report.MismatchedTables, report.MismatchedPartitions, report.FailedTables)
+
+	if report.Consistent {
+		xmetrics.Verify(j.Name, report.MismatchedTables, report.MismatchedPartitions)
+	}
+	j.saveVerifyReport(report)
+	if verification.request != nil {
+		verification.request.result <- &verifyResult{report: report}
+	}
+}
+
+func (j *Job) abortVerify(err error) {
+	verification := j.verification
+	j.verification = nil
+
+	log.Warnf("verify job %s failed, err: %+v", j.Name, err)
+	if verification.request != nil {
+		verification.request.result <- &verifyResult{err: err}
+	}
+}
+
+// getVerifyTables returns the tables to verify, the tables of db sync have the same names in
+// the source and the dest.
+func (j *Job) getVerifyTables() ([]verify.TablePair, error) {
+	if j.SyncType == TableSync {
+		return []verify.TablePair{{Src: j.Src.Table, Dest: j.Dest.Table}}, nil
+	}
+
+	tables, err := j.srcMeta.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]verify.TablePair, 0, len(tables))
+	for _, table := range tables {
+		if table.Type != TABLE_TYPE_OLAP || !j.TableFilter.IsTableIncluded(table.Name) {
+			continue
+		}
+		pairs = append(pairs, verify.TablePair{Src: table.Name, Dest: table.Name})
+	}
+	sort.Slice(pairs, func(i, k int) bool { return pairs[i].Src < pairs[k].Src })
+	return pairs, nil
+}
+
+// saveVerifyReport saves the report into db, the failure is ignored since the report is
+// returned to the requester too.
+func (j *Job) saveVerifyReport(report *verify.Report) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Warnf("marshal verify report of job %s failed, err: %+v", j.Name, err)
+		return
+	}
+
+	verifyReport := &storage.VerifyReport{
+		JobName:   j.Name,
+		CommitSeq: report.CommitSeq,
+		Timestamp: report.FinishAt,
+		Matched:   report.Matched,
+		Report:    string(data),
+	}
+	if err := j.db.AddVerifyReport(verifyReport); err != nil {
+		log.Warnf("save verify report of job %s failed, err: %+v", j.Name, err)
+	}
+	j.recordEvent(JobEventVerify, "verify at commit seq %d, consistent: %v, matched: %v, mismatched tables: %d, failed tables: %d",
+		report.CommitSeq, report.Consistent, report.Matched, report.MismatchedTables, report.FailedTables)
+}
+
+func (j *Job) GetVerifyConfig() *VerifyConfig {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.VerifyConfig
+}
+
+// UpdateVerifyConfig replaces the periodic verification config of the job, a empty one removes it.
+func (j *Job) UpdateVerifyConfig(config *VerifyConfig) error {
+	if err := config.Valid(); err != nil {
+		return xerror.Wrap(err, xerror.Normal, "verify config is invalid")
+	}
+	if config.IsEmpty() {
+		config = nil
+	} else if err := j.validVerify(); err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	originConfig := j.VerifyConfig
+	j.VerifyConfig = config
+	if err := j.persistJob(); err != nil {
+		j.VerifyConfig = originConfig
+		return err
+	}
+
+	log.Infof("update job %s verify config to %+v", j.Name, config)
+	j.recordEvent(JobEventUpdate, "update verify config to %+v", config)
+	return nil
+}
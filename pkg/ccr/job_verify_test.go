@@ -0,0 +1,179 @@
+package ccr
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"go.uber.org/mock/gomock"
+
+	festruct "github.com/selectdb/ccr_syncer/pkg/rpc/kitex_gen/frontendservice"
+)
+
+type verifyTest struct {
+	job *Job
+	// The lags returned by the source one by one, the last one is repeated.
+	lags []int64
+	// The row count of the dest table, the source table has 5 rows.
+	destRowCount atomic.Int64
+}
+
+func newVerifyTest(t *testing.T) *verifyTest {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	if err := db.AddJob("job", "{}", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+
+	test := &verifyTest{}
+	ctrl := gomock.NewController(t)
+	fe := NewMockIFeRpc(ctrl)
+	fe.EXPECT().GetBinlogLag(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(spec *base.Spec, commitSeq int64) (*festruct.TGetBinlogLagResult_, error) {
+			lag := test.lags[0]
+			if len(test.lags) > 1 {
+				test.lags = test.lags[1:]
+			}
+			return &festruct.TGetBinlogLagResult_{Lag: &lag}, nil
+		}).AnyTimes()
+	rpcFactory := NewMockIRpcFactory(ctrl)
+	rpcFactory.EXPECT().NewFeRpc(gomock.Any()).Return(fe, nil).AnyTimes()
+
+	src := NewMockSpecer(ctrl)
+	src.EXPECT().CountTableRows("tbl").Return(int64(5), nil).AnyTimes()
+	dest := NewMockSpecer(ctrl)
+	dest.EXPECT().CountTableRows("tbl").DoAndReturn(func(string) (int64, error) {
+		return test.destRowCount.Load(), nil
+	}).AnyTimes()
+
+	newMeta := func() Metaer {
+		meta := NewMockMetaer(ctrl)
+		meta.EXPECT().GetTableId("tbl").Return(int64(1), nil).AnyTimes()
+		meta.EXPECT().UpdatePartitions(int64(1)).Return(nil).AnyTimes()
+		meta.EXPECT().GetPartitionIdMap(int64(1)).Return(map[int64]*PartitionMeta{
+			1: {Id: 1, Name: "tbl", VisibleVersion: 2},
+		}, nil).AnyTimes()
+		return meta
+	}
+
+	progress := NewJobProgress("job", TableSync, db)
+	progress.NextWithPersist(10, TableIncrementalSync, Done, "")
+	test.job = &Job{
+		Name:     "job",
+		SyncType: TableSync,
+		State:    JobRunning,
+		Src:      base.Spec{Database: "db", Table: "tbl"},
+		Dest:     base.Spec{Database: "db", Table: "tbl"},
+		ISrc:     src,
+		IDest:    dest,
+		srcMeta:  newMeta(),
+		destMeta: newMeta(),
+		db:       db,
+		factory:  NewFactory(rpcFactory, nil, nil, nil),
+		progress: progress,
+	}
+	return test
+}
+
+// syncBinlogs simulates the binlogs synced by the job loop.
+func (test *verifyTest) syncBinlogs(commitSeqs ...int64) bool {
+	reached := false
+	for _, commitSeq := range commitSeqs {
+		test.job.progress.CommitSeq = commitSeq
+		reached = test.job.countVerifyBinlog(commitSeq)
+	}
+	return reached
+}
+
+func newTestVerifyRequest() *verifyRequest {
+	return &verifyRequest{result: make(chan *verifyResult, 1)}
+}
+
+// The source is collected with 2 binlogs not synced, the dest is compared after they are synced.
+func TestJobVerify_PinnedCommitSeq(t *testing.T) {
+	test := newVerifyTest(t)
+	test.lags = []int64{2}
+	test.destRowCount.Store(3)
+
+	request := newTestVerifyRequest()
+	if err := test.job.startVerify(false, request); err != nil {
+		t.Fatalf("start verify failed: %v", err)
+	}
+	if test.job.verification == nil || test.job.hasPendingVerify() {
+		t.Fatalf("the verification should wait for the dest")
+	}
+
+	if test.syncBinlogs(11, 11) {
+		t.Fatalf("the dest should not reach the pinned commit seq")
+	}
+	// The source has new binlogs, which do not affect the collected source.
+	test.destRowCount.Store(5)
+	if !test.syncBinlogs(12) || !test.job.hasPendingVerify() {
+		t.Fatalf("the dest should reach the pinned commit seq")
+	}
+
+	test.job.handleVerify()
+	if test.job.verification != nil {
+		t.Fatalf("the verification is not finished")
+	}
+	result := <-request.result
+	if result.err != nil {
+		t.Fatalf("verify failed: %v", result.err)
+	}
+	if !result.report.Matched || !result.report.Consistent || result.report.CommitSeq != 12 {
+		t.Errorf("report = %+v, expect matched and consistent at 12", result.report)
+	}
+}
+
+func TestJobVerify_SourceChanged(t *testing.T) {
+	test := newVerifyTest(t)
+	// A new binlog is committed while collecting the source.
+	test.lags = []int64{0, 1}
+	test.destRowCount.Store(5)
+
+	request := newTestVerifyRequest()
+	if err := test.job.startVerify(false, request); err != nil {
+		t.Fatalf("start verify failed: %v", err)
+	}
+	result := <-request.result
+	if result.err != nil || !result.report.Matched || result.report.Consistent {
+		t.Errorf("report = %+v, err = %v, expect matched but not consistent", result.report, result.err)
+	}
+}
+
+func TestJobVerify_PausedWithLag(t *testing.T) {
+	test := newVerifyTest(t)
+	test.lags = []int64{1}
+	test.job.State = JobPaused
+
+	if err := test.job.startVerify(false, newTestVerifyRequest()); err == nil {
+		t.Errorf("the paused job with lag could not be verified")
+	}
+	if test.job.verification != nil {
+		t.Errorf("the verification should not be pending")
+	}
+}
+
+func TestJobVerify_Aborted(t *testing.T) {
+	test := newVerifyTest(t)
+	test.lags = []int64{2}
+
+	request := newTestVerifyRequest()
+	if err := test.job.startVerify(false, request); err != nil {
+		t.Fatalf("start verify failed: %v", err)
+	}
+
+	// The job leaves the incremental sync before the dest reaches the pinned commit seq.
+	test.job.progress.SyncState = TablePartialSync
+	test.job.handleVerify()
+	if result := <-request.result; result.err == nil {
+		t.Errorf("the verification should be aborted")
+	}
+	if test.job.verification != nil {
+		t.Errorf("the aborted verification is not cleared")
+	}
+}
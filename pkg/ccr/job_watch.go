@@ -0,0 +1,146 @@
+package ccr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// JobWatchEvent is the snapshot of the job state and progress, it is emitted when the State,
+// SyncState, SubSyncState or PrevCommitSeq of the job changes.
+type JobWatchEvent struct {
+	JobName       string `json:"job_name"`
+	State         string `json:"state,omitempty"`
+	SyncState     string `json:"sync_state,omitempty"`
+	SubSyncState  string `json:"sub_sync_state,omitempty"`
+	PrevCommitSeq int64  `json:"prev_commit_seq"`
+	CommitSeq     int64  `json:"commit_seq"`
+	// The job is not running in this syncer anymore, it is deleted or stopped.
+	Removed bool `json:"removed,omitempty"`
+	// When the event is emitted, in unix milliseconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// jobWatchSubject merges the state and the progress of the jobs running in this syncer into the
+// snapshots, and notifies the observers when the snapshot changes.
+//
+// The observers are notified with the lock held to keep the events in order, so they must not
+// block.
+type jobWatchSubject struct {
+	lock      sync.Mutex
+	observers []utils.Observer[*JobWatchEvent]
+	jobs      map[string]*JobWatchEvent
+}
+
+var jobWatch = &jobWatchSubject{jobs: make(map[string]*JobWatchEvent)}
+
+// impl utils.Subject[*JobWatchEvent], the observer receives the snapshots of all jobs once it
+// is registered.
+func (s *jobWatchSubject) Register(observer utils.Observer[*JobWatchEvent]) {
+	log.Debugf("register job watch observer %v", observer)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.observers = append(s.observers, observer)
+	for _, snapshot := range s.jobs {
+		event := *snapshot
+		observer.Update(&event)
+	}
+}
+
+func (s *jobWatchSubject) Unregister(observer utils.Observer[*JobWatchEvent]) {
+	log.Debugf("unregister job watch observer %v", observer)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i, o := range s.observers {
+		if o == observer {
+			s.observers = append(s.observers[:i], s.observers[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *jobWatchSubject) Notify(event *JobWatchEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.notify(event)
+}
+
+// notify sends a copy of the event to each observer, the lock must be held.
+func (s *jobWatchSubject) notify(event *JobWatchEvent) {
+	for _, o := range s.observers {
+		copied := *event
+		o.Update(&copied)
+	}
+}
+
+// update applies the change to the snapshot of the job, and notifies the observers if the
+// snapshot is changed.
+func (s *jobWatchSubject) update(jobName string, change func(snapshot *JobWatchEvent)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot, ok := s.jobs[jobName]
+	if !ok {
+		snapshot = &JobWatchEvent{JobName: jobName}
+		s.jobs[jobName] = snapshot
+	}
+
+	origin := *snapshot
+	change(snapshot)
+	// The commit seq is carried but not watched, it changes for each binlog.
+	origin.CommitSeq = snapshot.CommitSeq
+	origin.Timestamp = snapshot.Timestamp
+	if ok && origin == *snapshot {
+		return
+	}
+
+	snapshot.Timestamp = time.Now().UnixMilli()
+	s.notify(snapshot)
+}
+
+func (s *jobWatchSubject) updateState(jobName string, state JobState) {
+	s.update(jobName, func(snapshot *JobWatchEvent) {
+		snapshot.State = state.String()
+	})
+}
+
+func (s *jobWatchSubject) updateProgress(progress *JobProgress) {
+	s.update(progress.JobName, func(snapshot *JobWatchEvent) {
+		snapshot.SyncState = progress.SyncState.String()
+		snapshot.SubSyncState = progress.SubSyncState.String()
+		snapshot.PrevCommitSeq = progress.PrevCommitSeq
+		snapshot.CommitSeq = progress.CommitSeq
+	})
+}
+
+func (s *jobWatchSubject) remove(jobName string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snapshot, ok := s.jobs[jobName]
+	if !ok {
+		return
+	}
+	delete(s.jobs, jobName)
+
+	snapshot.Removed = true
+	snapshot.Timestamp = time.Now().UnixMilli()
+	s.notify(snapshot)
+}
+
+// notifyWatchState notifies the state of the job, the children of the fan-out job follow the
+// state of the parent. The job lock must be held.
+func (j *Job) notifyWatchState() {
+	jobWatch.updateState(j.Name, j.State)
+	for _, child := range j.children {
+		jobWatch.updateState(child.Name, j.State)
+	}
+}
@@ -0,0 +1,56 @@
+package ccr
+
+import (
+	"testing"
+)
+
+type watchRecorder struct {
+	events []*JobWatchEvent
+}
+
+func (r *watchRecorder) Update(event *JobWatchEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestJobWatchSubject(t *testing.T) {
+	subject := &jobWatchSubject{jobs: make(map[string]*JobWatchEvent)}
+	subject.updateState("job1", JobRunning)
+
+	// The snapshot is sent once registered.
+	recorder := &watchRecorder{}
+	subject.Register(recorder)
+	if len(recorder.events) != 1 || recorder.events[0].State != "running" {
+		t.Fatalf("unexpected snapshot events: %v", recorder.events)
+	}
+
+	progress := &JobProgress{JobName: "job1", SyncState: TableFullSync, SubSyncState: BeginCreateSnapshot}
+	subject.updateProgress(progress)
+	if len(recorder.events) != 2 || recorder.events[1].SyncState != TableFullSync.String() {
+		t.Fatalf("unexpected progress events: %v", recorder.events)
+	}
+
+	// The commit seq is not watched.
+	progress.CommitSeq = 100
+	subject.updateProgress(progress)
+	subject.updateState("job1", JobRunning)
+	if len(recorder.events) != 2 {
+		t.Fatalf("unexpected events: %v", recorder.events)
+	}
+
+	progress.PrevCommitSeq = 100
+	subject.updateProgress(progress)
+	if len(recorder.events) != 3 || recorder.events[2].CommitSeq != 100 {
+		t.Fatalf("unexpected commit events: %v", recorder.events)
+	}
+
+	subject.remove("job1")
+	if len(recorder.events) != 4 || !recorder.events[3].Removed {
+		t.Fatalf("unexpected remove events: %v", recorder.events)
+	}
+
+	subject.Unregister(recorder)
+	subject.updateState("job2", JobPaused)
+	if len(recorder.events) != 4 {
+		t.Fatalf("unexpected events after unregister: %v", recorder.events)
+	}
+}
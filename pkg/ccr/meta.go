@@ -271,12 +271,19 @@ func (m *Meta) UpdatePartitions(tableId int64) error {
 		if err != nil {
 			return xerror.Wrapf(err, xerror.Normal, query)
 		}
+		// The visible version is only used by the verification, the partition is kept without it.
+		visibleVersion, err := rowParser.GetInt64("VisibleVersion")
+		if err != nil {
+			log.Warnf("parse the visible version of partition %d failed, skip it, err: %+v", partitionId, err)
+			visibleVersion = 0
+		}
 		log.Debugf("partitionId: %d, partitionName: %s", partitionId, partitionName)
 		partition := &PartitionMeta{
-			TableMeta: table,
-			Id:        partitionId,
-			Name:      partitionName,
-			Range:     partitionRange,
+			TableMeta:      table,
+			Id:             partitionId,
+			Name:           partitionName,
+			Range:          partitionRange,
+			VisibleVersion: visibleVersion,
 		}
 		partitions = append(partitions, partition)
 	}
@@ -1057,6 +1064,7 @@ func (m *Meta) GetTables() (map[int64]*TableMeta, error) {
 			DatabaseMeta:   &m.DatabaseMeta,
 			Id:             tableId,
 			Name:           tableName,
+			Type:           tableType,
 			PartitionIdMap: make(map[int64]*PartitionMeta),
 		}
 	}
@@ -18,6 +18,7 @@ type TableMeta struct {
 	Id                int64
 	BaseIndexId       int64
 	Name              string                    // maybe dirty, such after rename
+	Type              string                    // OLAP or VIEW, only set by GetTables
 	PartitionIdMap    map[int64]*PartitionMeta  // partitionId -> partitionMeta
 	PartitionRangeMap map[string]*PartitionMeta // partitionRange -> partitionMeta
 }
@@ -5,6 +5,7 @@
 //
 //	mockgen -source=pkg/ccr/metaer.go -destination=pkg/ccr/metaer_mock.go -package=ccr
 //
+
 // Package ccr is a generated GoMock package.
 package ccr
 
@@ -118,12 +119,13 @@ func (mr *MockIngestBinlogMetaerMockRecorder) GetIndexIdMap(tableId, partitionId
 }
 
 // GetIndexNameMap mocks base method.
-func (m *MockIngestBinlogMetaer) GetIndexNameMap(tableId, partitionId int64) (map[string]*IndexMeta, error) {
+func (m *MockIngestBinlogMetaer) GetIndexNameMap(tableId, partitionId int64) (map[string]*IndexMeta, *IndexMeta, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetIndexNameMap", tableId, partitionId)
 	ret0, _ := ret[0].(map[string]*IndexMeta)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(*IndexMeta)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetIndexNameMap indicates an expected call of GetIndexNameMap.
@@ -177,6 +179,48 @@ func (mr *MockIngestBinlogMetaerMockRecorder) GetTablets(tableId, partitionId, i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTablets", reflect.TypeOf((*MockIngestBinlogMetaer)(nil).GetTablets), tableId, partitionId, indexId)
 }
 
+// IsIndexDropped mocks base method.
+func (m *MockIngestBinlogMetaer) IsIndexDropped(indexId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsIndexDropped", indexId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsIndexDropped indicates an expected call of IsIndexDropped.
+func (mr *MockIngestBinlogMetaerMockRecorder) IsIndexDropped(indexId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsIndexDropped", reflect.TypeOf((*MockIngestBinlogMetaer)(nil).IsIndexDropped), indexId)
+}
+
+// IsPartitionDropped mocks base method.
+func (m *MockIngestBinlogMetaer) IsPartitionDropped(partitionId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPartitionDropped", partitionId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsPartitionDropped indicates an expected call of IsPartitionDropped.
+func (mr *MockIngestBinlogMetaerMockRecorder) IsPartitionDropped(partitionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPartitionDropped", reflect.TypeOf((*MockIngestBinlogMetaer)(nil).IsPartitionDropped), partitionId)
+}
+
+// IsTableDropped mocks base method.
+func (m *MockIngestBinlogMetaer) IsTableDropped(tableId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTableDropped", tableId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsTableDropped indicates an expected call of IsTableDropped.
+func (mr *MockIngestBinlogMetaerMockRecorder) IsTableDropped(tableId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTableDropped", reflect.TypeOf((*MockIngestBinlogMetaer)(nil).IsTableDropped), tableId)
+}
+
 // MockMetaer is a mock of Metaer interface.
 type MockMetaer struct {
 	ctrl     *gomock.Controller
@@ -238,18 +282,16 @@ func (mr *MockMetaerMockRecorder) ClearTable(dbName, tableName any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearTable", reflect.TypeOf((*MockMetaer)(nil).ClearTable), dbName, tableName)
 }
 
-// DbExec mocks base method.
-func (m *MockMetaer) DbExec(sql string) error {
+// ClearTablesCache mocks base method.
+func (m *MockMetaer) ClearTablesCache() {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DbExec", sql)
-	ret0, _ := ret[0].(error)
-	return ret0
+	m.ctrl.Call(m, "ClearTablesCache")
 }
 
-// DbExec indicates an expected call of DbExec.
-func (mr *MockMetaerMockRecorder) DbExec(sql any) *gomock.Call {
+// ClearTablesCache indicates an expected call of ClearTablesCache.
+func (mr *MockMetaerMockRecorder) ClearTablesCache() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DbExec", reflect.TypeOf((*MockMetaer)(nil).DbExec), sql)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearTablesCache", reflect.TypeOf((*MockMetaer)(nil).ClearTablesCache))
 }
 
 // DirtyGetTables mocks base method.
@@ -371,12 +413,13 @@ func (mr *MockMetaerMockRecorder) GetIndexIdMap(tableId, partitionId any) *gomoc
 }
 
 // GetIndexNameMap mocks base method.
-func (m *MockMetaer) GetIndexNameMap(tableId, partitionId int64) (map[string]*IndexMeta, error) {
+func (m *MockMetaer) GetIndexNameMap(tableId, partitionId int64) (map[string]*IndexMeta, *IndexMeta, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetIndexNameMap", tableId, partitionId)
 	ret0, _ := ret[0].(map[string]*IndexMeta)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(*IndexMeta)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetIndexNameMap indicates an expected call of GetIndexNameMap.
@@ -595,6 +638,48 @@ func (mr *MockMetaerMockRecorder) GetTablets(tableId, partitionId, indexId any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTablets", reflect.TypeOf((*MockMetaer)(nil).GetTablets), tableId, partitionId, indexId)
 }
 
+// IsIndexDropped mocks base method.
+func (m *MockMetaer) IsIndexDropped(indexId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsIndexDropped", indexId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsIndexDropped indicates an expected call of IsIndexDropped.
+func (mr *MockMetaerMockRecorder) IsIndexDropped(indexId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsIndexDropped", reflect.TypeOf((*MockMetaer)(nil).IsIndexDropped), indexId)
+}
+
+// IsPartitionDropped mocks base method.
+func (m *MockMetaer) IsPartitionDropped(partitionId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsPartitionDropped", partitionId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsPartitionDropped indicates an expected call of IsPartitionDropped.
+func (mr *MockMetaerMockRecorder) IsPartitionDropped(partitionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPartitionDropped", reflect.TypeOf((*MockMetaer)(nil).IsPartitionDropped), partitionId)
+}
+
+// IsTableDropped mocks base method.
+func (m *MockMetaer) IsTableDropped(tableId int64) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTableDropped", tableId)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsTableDropped indicates an expected call of IsTableDropped.
+func (mr *MockMetaerMockRecorder) IsTableDropped(tableId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTableDropped", reflect.TypeOf((*MockMetaer)(nil).IsTableDropped), tableId)
+}
+
 // UpdateBackends mocks base method.
 func (m *MockMetaer) UpdateBackends() error {
 	m.ctrl.T.Helper()
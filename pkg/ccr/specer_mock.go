@@ -9,10 +9,10 @@
 package ccr
 
 import (
-	sql "database/sql"
 	reflect "reflect"
 
 	base "github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	record "github.com/selectdb/ccr_syncer/pkg/ccr/record"
 	utils "github.com/selectdb/ccr_syncer/pkg/utils"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,6 +40,77 @@ func (m *MockSpecer) EXPECT() *MockSpecerMockRecorder {
 	return m.recorder
 }
 
+// AddPartition mocks base method.
+func (m *MockSpecer) AddPartition(destTableName string, addPartition *record.AddPartition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPartition", destTableName, addPartition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPartition indicates an expected call of AddPartition.
+func (mr *MockSpecerMockRecorder) AddPartition(destTableName, addPartition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPartition", reflect.TypeOf((*MockSpecer)(nil).AddPartition), destTableName, addPartition)
+}
+
+// AlterViewDef mocks base method.
+func (m *MockSpecer) AlterViewDef(viewName string, alterView *record.AlterView) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlterViewDef", viewName, alterView)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlterViewDef indicates an expected call of AlterViewDef.
+func (mr *MockSpecerMockRecorder) AlterViewDef(viewName, alterView any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlterViewDef", reflect.TypeOf((*MockSpecer)(nil).AlterViewDef), viewName, alterView)
+}
+
+// BuildIndex mocks base method.
+func (m *MockSpecer) BuildIndex(tableAlias string, buildIndex *record.IndexChangeJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BuildIndex", tableAlias, buildIndex)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BuildIndex indicates an expected call of BuildIndex.
+func (mr *MockSpecerMockRecorder) BuildIndex(tableAlias, buildIndex any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BuildIndex", reflect.TypeOf((*MockSpecer)(nil).BuildIndex), tableAlias, buildIndex)
+}
+
+// CancelRestoreIfExists mocks base method.
+func (m *MockSpecer) CancelRestoreIfExists(snapshotName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRestoreIfExists", snapshotName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRestoreIfExists indicates an expected call of CancelRestoreIfExists.
+func (mr *MockSpecerMockRecorder) CancelRestoreIfExists(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRestoreIfExists", reflect.TypeOf((*MockSpecer)(nil).CancelRestoreIfExists), snapshotName)
+}
+
+// CheckBackupFinished mocks base method.
+func (m *MockSpecer) CheckBackupFinished(snapshotName string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBackupFinished", snapshotName)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckBackupFinished indicates an expected call of CheckBackupFinished.
+func (mr *MockSpecerMockRecorder) CheckBackupFinished(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBackupFinished", reflect.TypeOf((*MockSpecer)(nil).CheckBackupFinished), snapshotName)
+}
+
 // CheckDatabaseExists mocks base method.
 func (m *MockSpecer) CheckDatabaseExists() (bool, error) {
 	m.ctrl.T.Helper()
@@ -85,48 +156,63 @@ func (mr *MockSpecerMockRecorder) CheckTableExists() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTableExists", reflect.TypeOf((*MockSpecer)(nil).CheckTableExists))
 }
 
-// ClearDB mocks base method.
-func (m *MockSpecer) ClearDB() error {
+// CheckTableExistsByName mocks base method.
+func (m *MockSpecer) CheckTableExistsByName(tableName string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ClearDB")
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "CheckTableExistsByName", tableName)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// ClearDB indicates an expected call of ClearDB.
-func (mr *MockSpecerMockRecorder) ClearDB() *gomock.Call {
+// CheckTableExistsByName indicates an expected call of CheckTableExistsByName.
+func (mr *MockSpecerMockRecorder) CheckTableExistsByName(tableName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearDB", reflect.TypeOf((*MockSpecer)(nil).ClearDB))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckTableExistsByName", reflect.TypeOf((*MockSpecer)(nil).CheckTableExistsByName), tableName)
 }
 
-// Connect mocks base method.
-func (m *MockSpecer) Connect() (*sql.DB, error) {
+// ChecksumPartition mocks base method.
+func (m *MockSpecer) ChecksumPartition(tableName, partitionName string) (*base.PartitionChecksum, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Connect")
-	ret0, _ := ret[0].(*sql.DB)
+	ret := m.ctrl.Call(m, "ChecksumPartition", tableName, partitionName)
+	ret0, _ := ret[0].(*base.PartitionChecksum)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Connect indicates an expected call of Connect.
-func (mr *MockSpecerMockRecorder) Connect() *gomock.Call {
+// ChecksumPartition indicates an expected call of ChecksumPartition.
+func (mr *MockSpecerMockRecorder) ChecksumPartition(tableName, partitionName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connect", reflect.TypeOf((*MockSpecer)(nil).Connect))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChecksumPartition", reflect.TypeOf((*MockSpecer)(nil).ChecksumPartition), tableName, partitionName)
 }
 
-// ConnectDB mocks base method.
-func (m *MockSpecer) ConnectDB() (*sql.DB, error) {
+// ClearDB mocks base method.
+func (m *MockSpecer) ClearDB() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ConnectDB")
-	ret0, _ := ret[0].(*sql.DB)
+	ret := m.ctrl.Call(m, "ClearDB")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearDB indicates an expected call of ClearDB.
+func (mr *MockSpecerMockRecorder) ClearDB() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearDB", reflect.TypeOf((*MockSpecer)(nil).ClearDB))
+}
+
+// CountTableRows mocks base method.
+func (m *MockSpecer) CountTableRows(tableName string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountTableRows", tableName)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ConnectDB indicates an expected call of ConnectDB.
-func (mr *MockSpecerMockRecorder) ConnectDB() *gomock.Call {
+// CountTableRows indicates an expected call of CountTableRows.
+func (mr *MockSpecerMockRecorder) CountTableRows(tableName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectDB", reflect.TypeOf((*MockSpecer)(nil).ConnectDB))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountTableRows", reflect.TypeOf((*MockSpecer)(nil).CountTableRows), tableName)
 }
 
 // CreateDatabase mocks base method.
@@ -143,61 +229,138 @@ func (mr *MockSpecerMockRecorder) CreateDatabase() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDatabase", reflect.TypeOf((*MockSpecer)(nil).CreateDatabase))
 }
 
-// CreateSnapshotAndWaitForDone mocks base method.
-func (m *MockSpecer) CreateSnapshotAndWaitForDone(tables []string) (string, error) {
+// CreatePartialSnapshot mocks base method.
+func (m *MockSpecer) CreatePartialSnapshot(snapshotName, table string, partitions []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSnapshotAndWaitForDone", tables)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "CreatePartialSnapshot", snapshotName, table, partitions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePartialSnapshot indicates an expected call of CreatePartialSnapshot.
+func (mr *MockSpecerMockRecorder) CreatePartialSnapshot(snapshotName, table, partitions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePartialSnapshot", reflect.TypeOf((*MockSpecer)(nil).CreatePartialSnapshot), snapshotName, table, partitions)
+}
+
+// CreateSnapshot mocks base method.
+func (m *MockSpecer) CreateSnapshot(snapshotName string, tables []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSnapshot", snapshotName, tables)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSnapshot indicates an expected call of CreateSnapshot.
+func (mr *MockSpecerMockRecorder) CreateSnapshot(snapshotName, tables any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshot", reflect.TypeOf((*MockSpecer)(nil).CreateSnapshot), snapshotName, tables)
+}
+
+// CreateTableOrView mocks base method.
+func (m *MockSpecer) CreateTableOrView(createTable *record.CreateTable, srcDatabase string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTableOrView", createTable, srcDatabase)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateTableOrView indicates an expected call of CreateTableOrView.
+func (mr *MockSpecerMockRecorder) CreateTableOrView(createTable, srcDatabase any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTableOrView", reflect.TypeOf((*MockSpecer)(nil).CreateTableOrView), createTable, srcDatabase)
+}
+
+// DesyncTables mocks base method.
+func (m *MockSpecer) DesyncTables(tables ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range tables {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DesyncTables", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DesyncTables indicates an expected call of DesyncTables.
+func (mr *MockSpecerMockRecorder) DesyncTables(tables ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DesyncTables", reflect.TypeOf((*MockSpecer)(nil).DesyncTables), tables...)
+}
+
+// DropPartition mocks base method.
+func (m *MockSpecer) DropPartition(destTableName string, dropPartition *record.DropPartition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DropPartition", destTableName, dropPartition)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DropPartition indicates an expected call of DropPartition.
+func (mr *MockSpecerMockRecorder) DropPartition(destTableName, dropPartition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropPartition", reflect.TypeOf((*MockSpecer)(nil).DropPartition), destTableName, dropPartition)
+}
+
+// DropTable mocks base method.
+func (m *MockSpecer) DropTable(tableName string, force bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DropTable", tableName, force)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// CreateSnapshotAndWaitForDone indicates an expected call of CreateSnapshotAndWaitForDone.
-func (mr *MockSpecerMockRecorder) CreateSnapshotAndWaitForDone(tables any) *gomock.Call {
+// DropTable indicates an expected call of DropTable.
+func (mr *MockSpecerMockRecorder) DropTable(tableName, force any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSnapshotAndWaitForDone", reflect.TypeOf((*MockSpecer)(nil).CreateSnapshotAndWaitForDone), tables)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropTable", reflect.TypeOf((*MockSpecer)(nil).DropTable), tableName, force)
 }
 
-// CreateTable mocks base method.
-func (m *MockSpecer) CreateTable(stmt string) error {
+// DropView mocks base method.
+func (m *MockSpecer) DropView(viewName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTable", stmt)
+	ret := m.ctrl.Call(m, "DropView", viewName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CreateTable indicates an expected call of CreateTable.
-func (mr *MockSpecerMockRecorder) CreateTable(stmt any) *gomock.Call {
+// DropView indicates an expected call of DropView.
+func (mr *MockSpecerMockRecorder) DropView(viewName any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTable", reflect.TypeOf((*MockSpecer)(nil).CreateTable), stmt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropView", reflect.TypeOf((*MockSpecer)(nil).DropView), viewName)
 }
 
-// DbExec mocks base method.
-func (m *MockSpecer) DbExec(sql string) error {
+// EnableDatabaseBinlog mocks base method.
+func (m *MockSpecer) EnableDatabaseBinlog() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DbExec", sql)
+	ret := m.ctrl.Call(m, "EnableDatabaseBinlog")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DbExec indicates an expected call of DbExec.
-func (mr *MockSpecerMockRecorder) DbExec(sql any) *gomock.Call {
+// EnableDatabaseBinlog indicates an expected call of EnableDatabaseBinlog.
+func (mr *MockSpecerMockRecorder) EnableDatabaseBinlog() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DbExec", reflect.TypeOf((*MockSpecer)(nil).DbExec), sql)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableDatabaseBinlog", reflect.TypeOf((*MockSpecer)(nil).EnableDatabaseBinlog))
 }
 
-// Exec mocks base method.
-func (m *MockSpecer) Exec(sql string) error {
+// EnableTableBinlog mocks base method.
+func (m *MockSpecer) EnableTableBinlog(tables ...string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Exec", sql)
+	varargs := []any{}
+	for _, a := range tables {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "EnableTableBinlog", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Exec indicates an expected call of Exec.
-func (mr *MockSpecerMockRecorder) Exec(sql any) *gomock.Call {
+// EnableTableBinlog indicates an expected call of EnableTableBinlog.
+func (mr *MockSpecerMockRecorder) EnableTableBinlog(tables ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockSpecer)(nil).Exec), sql)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTableBinlog", reflect.TypeOf((*MockSpecer)(nil).EnableTableBinlog), tables...)
 }
 
 // GetAllTables mocks base method.
@@ -215,6 +378,82 @@ func (mr *MockSpecerMockRecorder) GetAllTables() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllTables", reflect.TypeOf((*MockSpecer)(nil).GetAllTables))
 }
 
+// GetAllViewsFromTable mocks base method.
+func (m *MockSpecer) GetAllViewsFromTable(tableName string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllViewsFromTable", tableName)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllViewsFromTable indicates an expected call of GetAllViewsFromTable.
+func (mr *MockSpecerMockRecorder) GetAllViewsFromTable(tableName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllViewsFromTable", reflect.TypeOf((*MockSpecer)(nil).GetAllViewsFromTable), tableName)
+}
+
+// GetMasterJournalId mocks base method.
+func (m *MockSpecer) GetMasterJournalId() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMasterJournalId")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMasterJournalId indicates an expected call of GetMasterJournalId.
+func (mr *MockSpecerMockRecorder) GetMasterJournalId() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMasterJournalId", reflect.TypeOf((*MockSpecer)(nil).GetMasterJournalId))
+}
+
+// GetRestoreSignatureNotMatchedTableOrView mocks base method.
+func (m *MockSpecer) GetRestoreSignatureNotMatchedTableOrView(snapshotName string) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRestoreSignatureNotMatchedTableOrView", snapshotName)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetRestoreSignatureNotMatchedTableOrView indicates an expected call of GetRestoreSignatureNotMatchedTableOrView.
+func (mr *MockSpecerMockRecorder) GetRestoreSignatureNotMatchedTableOrView(snapshotName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRestoreSignatureNotMatchedTableOrView", reflect.TypeOf((*MockSpecer)(nil).GetRestoreSignatureNotMatchedTableOrView), snapshotName)
+}
+
+// GetValidBackupJob mocks base method.
+func (m *MockSpecer) GetValidBackupJob(snapshotNamePrefix string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetValidBackupJob", snapshotNamePrefix)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetValidBackupJob indicates an expected call of GetValidBackupJob.
+func (mr *MockSpecerMockRecorder) GetValidBackupJob(snapshotNamePrefix any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetValidBackupJob", reflect.TypeOf((*MockSpecer)(nil).GetValidBackupJob), snapshotNamePrefix)
+}
+
+// GetValidRestoreJob mocks base method.
+func (m *MockSpecer) GetValidRestoreJob(snapshotNamePrefix string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetValidRestoreJob", snapshotNamePrefix)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetValidRestoreJob indicates an expected call of GetValidRestoreJob.
+func (mr *MockSpecerMockRecorder) GetValidRestoreJob(snapshotNamePrefix any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetValidRestoreJob", reflect.TypeOf((*MockSpecer)(nil).GetValidRestoreJob), snapshotNamePrefix)
+}
+
 // IsDatabaseEnableBinlog mocks base method.
 func (m *MockSpecer) IsDatabaseEnableBinlog() (bool, error) {
 	m.ctrl.T.Helper()
@@ -230,6 +469,21 @@ func (mr *MockSpecerMockRecorder) IsDatabaseEnableBinlog() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDatabaseEnableBinlog", reflect.TypeOf((*MockSpecer)(nil).IsDatabaseEnableBinlog))
 }
 
+// IsEnableRestoreSnapshotCompression mocks base method.
+func (m *MockSpecer) IsEnableRestoreSnapshotCompression() (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsEnableRestoreSnapshotCompression")
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsEnableRestoreSnapshotCompression indicates an expected call of IsEnableRestoreSnapshotCompression.
+func (mr *MockSpecerMockRecorder) IsEnableRestoreSnapshotCompression() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEnableRestoreSnapshotCompression", reflect.TypeOf((*MockSpecer)(nil).IsEnableRestoreSnapshotCompression))
+}
+
 // IsTableEnableBinlog mocks base method.
 func (m *MockSpecer) IsTableEnableBinlog() (bool, error) {
 	m.ctrl.T.Helper()
@@ -245,6 +499,48 @@ func (mr *MockSpecerMockRecorder) IsTableEnableBinlog() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTableEnableBinlog", reflect.TypeOf((*MockSpecer)(nil).IsTableEnableBinlog))
 }
 
+// LightningIndexChange mocks base method.
+func (m *MockSpecer) LightningIndexChange(tableAlias string, changes *record.ModifyTableAddOrDropInvertedIndices) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LightningIndexChange", tableAlias, changes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LightningIndexChange indicates an expected call of LightningIndexChange.
+func (mr *MockSpecerMockRecorder) LightningIndexChange(tableAlias, changes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LightningIndexChange", reflect.TypeOf((*MockSpecer)(nil).LightningIndexChange), tableAlias, changes)
+}
+
+// LightningSchemaChange mocks base method.
+func (m *MockSpecer) LightningSchemaChange(srcDatabase, tableAlias string, changes *record.ModifyTableAddOrDropColumns) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LightningSchemaChange", srcDatabase, tableAlias, changes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LightningSchemaChange indicates an expected call of LightningSchemaChange.
+func (mr *MockSpecerMockRecorder) LightningSchemaChange(srcDatabase, tableAlias, changes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LightningSchemaChange", reflect.TypeOf((*MockSpecer)(nil).LightningSchemaChange), srcDatabase, tableAlias, changes)
+}
+
+// ModifyComment mocks base method.
+func (m *MockSpecer) ModifyComment(destTableName string, modifyComment *record.ModifyComment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModifyComment", destTableName, modifyComment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ModifyComment indicates an expected call of ModifyComment.
+func (mr *MockSpecerMockRecorder) ModifyComment(destTableName, modifyComment any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModifyComment", reflect.TypeOf((*MockSpecer)(nil).ModifyComment), destTableName, modifyComment)
+}
+
 // Notify mocks base method.
 func (m *MockSpecer) Notify(arg0 base.SpecEvent) {
 	m.ctrl.T.Helper()
@@ -269,6 +565,62 @@ func (mr *MockSpecerMockRecorder) Register(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockSpecer)(nil).Register), arg0)
 }
 
+// RenameColumn mocks base method.
+func (m *MockSpecer) RenameColumn(destTableName string, renameColumn *record.RenameColumn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameColumn", destTableName, renameColumn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameColumn indicates an expected call of RenameColumn.
+func (mr *MockSpecerMockRecorder) RenameColumn(destTableName, renameColumn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameColumn", reflect.TypeOf((*MockSpecer)(nil).RenameColumn), destTableName, renameColumn)
+}
+
+// RenameTable mocks base method.
+func (m *MockSpecer) RenameTable(destTableName string, renameTable *record.RenameTable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameTable", destTableName, renameTable)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenameTable indicates an expected call of RenameTable.
+func (mr *MockSpecerMockRecorder) RenameTable(destTableName, renameTable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameTable", reflect.TypeOf((*MockSpecer)(nil).RenameTable), destTableName, renameTable)
+}
+
+// ReplaceTable mocks base method.
+func (m *MockSpecer) ReplaceTable(fromName, toName string, swap bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceTable", fromName, toName, swap)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceTable indicates an expected call of ReplaceTable.
+func (mr *MockSpecerMockRecorder) ReplaceTable(fromName, toName, swap any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceTable", reflect.TypeOf((*MockSpecer)(nil).ReplaceTable), fromName, toName, swap)
+}
+
+// TruncateTable mocks base method.
+func (m *MockSpecer) TruncateTable(destTableName string, truncateTable *record.TruncateTable) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TruncateTable", destTableName, truncateTable)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TruncateTable indicates an expected call of TruncateTable.
+func (mr *MockSpecerMockRecorder) TruncateTable(destTableName, truncateTable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TruncateTable", reflect.TypeOf((*MockSpecer)(nil).TruncateTable), destTableName, truncateTable)
+}
+
 // Unregister mocks base method.
 func (m *MockSpecer) Unregister(arg0 utils.Observer[base.SpecEvent]) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,106 @@
+package ccr
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xmetrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// SyncerInfo is a member of the syncer cluster.
+type SyncerInfo struct {
+	Host string `json:"host"`
+	// The last heartbeat in unix milliseconds, 0 if the syncer is removed but its jobs are not
+	// rebalanced yet.
+	LastHeartbeat  int64    `json:"last_heartbeat"`
+	HeartbeatAgeMs int64    `json:"heartbeat_age_ms"`
+	Alive          bool     `json:"alive"`
+	Draining       bool     `json:"draining"`
+	Jobs           []string `json:"jobs"`
+}
+
+// splitDataRow splits the row "${key}, ${value}" of GetAllData.
+func splitDataRow(row string) (string, string, bool) {
+	i := strings.LastIndex(row, ", ")
+	if i < 0 {
+		return "", "", false
+	}
+	return row[:i], row[i+2:], true
+}
+
+// GetSyncers returns the syncers in the meta db and the jobs assigned to them, sorted by the
+// host info. The syncer is alive if its heartbeat is in the check timeout.
+func GetSyncers(db storage.DB) ([]*SyncerInfo, error) {
+	ans, err := db.GetAllData()
+	if err != nil {
+		return nil, err
+	}
+	drainingSyncers, err := db.GetDrainingSyncers()
+	if err != nil {
+		return nil, err
+	}
+	draining := make(map[string]bool)
+	for _, syncer := range drainingSyncers {
+		draining[syncer] = true
+	}
+
+	now := time.Now()
+	syncers := make(map[string]*SyncerInfo)
+	for _, row := range ans["syncers"] {
+		host, timestamp, ok := splitDataRow(row)
+		if !ok {
+			continue
+		}
+		syncer := &SyncerInfo{Host: host, Draining: draining[host], Jobs: make([]string, 0)}
+		// The timestamp is refreshed in unix nanoseconds, see Checker.
+		if nanos, err := strconv.ParseInt(timestamp, 10, 64); err == nil && nanos > 0 {
+			age := now.Sub(time.Unix(0, nanos))
+			syncer.LastHeartbeat = nanos / int64(time.Millisecond)
+			syncer.HeartbeatAgeMs = age.Milliseconds()
+			syncer.Alive = age <= CheckTimeout()
+		}
+		syncers[host] = syncer
+	}
+	for _, row := range ans["jobs"] {
+		name, belong, ok := splitDataRow(row)
+		if !ok {
+			continue
+		}
+		syncer, ok := syncers[belong]
+		if !ok {
+			syncer = &SyncerInfo{Host: belong, Draining: draining[belong], Jobs: make([]string, 0)}
+			syncers[belong] = syncer
+		}
+		syncer.Jobs = append(syncer.Jobs, name)
+	}
+
+	result := make([]*SyncerInfo, 0, len(syncers))
+	for _, syncer := range syncers {
+		sort.Strings(syncer.Jobs)
+		result = append(result, syncer)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Host < result[j].Host })
+	return result, nil
+}
+
+// updateSyncerMetrics exports the membership of the syncer cluster.
+func (c *Checker) updateSyncerMetrics() {
+	syncers, err := GetSyncers(c.db)
+	if err != nil {
+		log.Warnf("get syncers for metrics failed: %+v", err)
+		return
+	}
+
+	alive := 0
+	for _, syncer := range syncers {
+		if syncer.Alive {
+			alive++
+		}
+		xmetrics.Syncer(syncer.Host, syncer.HeartbeatAgeMs, syncer.Alive, len(syncer.Jobs))
+	}
+	xmetrics.SyncerNum(len(syncers), alive)
+}
@@ -0,0 +1,48 @@
+package ccr
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+)
+
+func TestGetSyncers(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	syncerA, syncerB, removed := "127.0.0.1:9190", "127.0.0.1:9191", "127.0.0.1:9192"
+	for _, syncer := range []string{syncerB, syncerA} {
+		if err := db.AddSyncer(syncer); err != nil {
+			t.Fatalf("add syncer failed: %v", err)
+		}
+	}
+	if err := db.DrainSyncer(syncerB, true); err != nil {
+		t.Fatalf("drain syncer failed: %v", err)
+	}
+	for name, belong := range map[string]string{"job2": syncerA, "job1": syncerA, "job3": removed} {
+		if err := db.AddJob(name, "{}", belong); err != nil {
+			t.Fatalf("add job failed: %v", err)
+		}
+	}
+
+	syncers, err := GetSyncers(db)
+	if err != nil {
+		t.Fatalf("get syncers failed: %v", err)
+	}
+	if len(syncers) != 3 {
+		t.Fatalf("expect 3 syncers, got %d", len(syncers))
+	}
+
+	a, b, c := syncers[0], syncers[1], syncers[2]
+	if a.Host != syncerA || !a.Alive || a.Draining || len(a.Jobs) != 2 || a.Jobs[0] != "job1" {
+		t.Errorf("unexpected syncer a: %+v", a)
+	}
+	if b.Host != syncerB || !b.Alive || !b.Draining || len(b.Jobs) != 0 {
+		t.Errorf("unexpected syncer b: %+v", b)
+	}
+	if c.Host != removed || c.Alive || c.LastHeartbeat != 0 || len(c.Jobs) != 1 {
+		t.Errorf("unexpected removed syncer: %+v", c)
+	}
+}
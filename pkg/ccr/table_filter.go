@@ -0,0 +1,101 @@
+package ccr
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// TableFilter decides which upstream tables are synced by a db sync job.
+//
+// Each pattern is either an exact table name or a regular expression, the regular
+// expression must match the whole table name. If IncludeTables is empty, all tables
+// are included. A table matched by ExcludeTables is always excluded.
+type TableFilter struct {
+	IncludeTables []string `json:"include_tables,omitempty"`
+	ExcludeTables []string `json:"exclude_tables,omitempty"`
+
+	once     sync.Once        `json:"-"`
+	includes []*regexp.Regexp `json:"-"`
+	excludes []*regexp.Regexp `json:"-"`
+}
+
+func NewTableFilter(includeTables, excludeTables []string) *TableFilter {
+	if len(includeTables) == 0 && len(excludeTables) == 0 {
+		return nil
+	}
+
+	return &TableFilter{
+		IncludeTables: includeTables,
+		ExcludeTables: excludeTables,
+	}
+}
+
+func (f *TableFilter) IsEmpty() bool {
+	return f == nil || (len(f.IncludeTables) == 0 && len(f.ExcludeTables) == 0)
+}
+
+// Valid checks whether all patterns are valid regular expressions.
+func (f *TableFilter) Valid() error {
+	if f == nil {
+		return nil
+	}
+
+	for _, patterns := range [][]string{f.IncludeTables, f.ExcludeTables} {
+		for _, pattern := range patterns {
+			if pattern == "" {
+				return xerror.New(xerror.Normal, "table filter pattern is empty")
+			}
+			if _, err := compileTablePattern(pattern); err != nil {
+				return xerror.Wrapf(err, xerror.Normal, "invalid table filter pattern: %s", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// IsTableIncluded returns whether the table with the name should be synced.
+func (f *TableFilter) IsTableIncluded(tableName string) bool {
+	if f.IsEmpty() {
+		return true
+	}
+
+	f.once.Do(f.compile)
+
+	if matchTablePatterns(tableName, f.ExcludeTables, f.excludes) {
+		return false
+	}
+	if len(f.IncludeTables) == 0 {
+		return true
+	}
+	return matchTablePatterns(tableName, f.IncludeTables, f.includes)
+}
+
+func (f *TableFilter) compile() {
+	// The invalid patterns are rejected by Valid(), ignore them here.
+	for _, pattern := range f.IncludeTables {
+		re, _ := compileTablePattern(pattern)
+		f.includes = append(f.includes, re)
+	}
+	for _, pattern := range f.ExcludeTables {
+		re, _ := compileTablePattern(pattern)
+		f.excludes = append(f.excludes, re)
+	}
+}
+
+func compileTablePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func matchTablePatterns(tableName string, patterns []string, regexps []*regexp.Regexp) bool {
+	for i, pattern := range patterns {
+		if pattern == tableName {
+			return true
+		}
+		if re := regexps[i]; re != nil && re.MatchString(tableName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package ccr
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTableFilter_IsTableIncluded(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		table   string
+		want    bool
+	}{
+		{"empty filter", nil, nil, "t1", true},
+		{"exact include", []string{"t1"}, nil, "t1", true},
+		{"exact include not matched", []string{"t1"}, nil, "t2", false},
+		{"regex include", []string{"order_.*"}, nil, "order_2024", true},
+		{"regex must match whole name", []string{"order_.*"}, nil, "my_order_2024", false},
+		{"exclude only", nil, []string{"tmp_.*"}, "tmp_1", false},
+		{"exclude only not matched", nil, []string{"tmp_.*"}, "t1", true},
+		{"exclude wins", []string{".*"}, []string{"t1"}, "t1", false},
+		{"exact name with regex meta chars", []string{"a.b"}, nil, "a.b", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewTableFilter(tt.include, tt.exclude)
+			if err := f.Valid(); err != nil {
+				t.Fatalf("Valid() error = %v", err)
+			}
+			if got := f.IsTableIncluded(tt.table); got != tt.want {
+				t.Errorf("IsTableIncluded(%s) = %v, want %v", tt.table, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableFilter_Valid(t *testing.T) {
+	if err := NewTableFilter([]string{"t(1"}, nil).Valid(); err == nil {
+		t.Errorf("Valid() expect error for invalid regex")
+	}
+	if err := NewTableFilter(nil, []string{""}).Valid(); err == nil {
+		t.Errorf("Valid() expect error for empty pattern")
+	}
+}
+
+func TestTableFilter_JSON(t *testing.T) {
+	f := NewTableFilter([]string{"t1", "order_.*"}, []string{"order_tmp"})
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("marshal error = %v", err)
+	}
+
+	var got TableFilter
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal error = %v", err)
+	}
+	if !got.IsTableIncluded("order_1") || got.IsTableIncluded("order_tmp") || got.IsTableIncluded("t2") {
+		t.Errorf("unexpected filter after unmarshal: %s", string(data))
+	}
+}
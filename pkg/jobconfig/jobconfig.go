@@ -0,0 +1,116 @@
+// Package jobconfig parses the declarative job config file, which lists the desired jobs of the
+// syncers, in YAML or JSON.
+//
+//	prune: false
+//	templates:
+//	  prod:
+//	    src: {host: 127.0.0.1, port: "9030", thrift_port: "9020", user: root, password: ""}
+//	    dest: {host: 127.0.0.1, port: "19030", thrift_port: "19020", user: root, password: ""}
+//	    labels: {env: prod}
+//	jobs:
+//	  - name: db1
+//	    template: prod
+//	    src: {database: db1}
+//	    dest: {database: db1}
+//
+// Each job is the request of create_ccr, it is merged into its template, the maps are merged
+// recursively and the other values of the job replace those of the template.
+package jobconfig
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	// Delete the jobs not in the config.
+	Prune bool
+	// The jobs merged with the templates, in the JSON of create_ccr request.
+	Jobs []*Job
+}
+
+type Job struct {
+	Name string
+	Data json.RawMessage
+}
+
+type file struct {
+	Prune     bool                      `yaml:"prune"`
+	Templates map[string]map[string]any `yaml:"templates"`
+	Jobs      []map[string]any          `yaml:"jobs"`
+}
+
+// Load reads and parses the config file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read job config file %s failed", path)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse job config file %s failed", path)
+	}
+	return config, nil
+}
+
+// Parse parses the config in YAML or JSON, JSON is a subset of YAML.
+func Parse(data []byte) (*Config, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "unmarshal job config failed")
+	}
+
+	config := &Config{Prune: f.Prune}
+	names := make(map[string]bool, len(f.Jobs))
+	for i, job := range f.Jobs {
+		name, _ := job["name"].(string)
+		if name == "" {
+			return nil, xerror.Errorf(xerror.Normal, "the name of the job %d is empty", i)
+		}
+		if names[name] {
+			return nil, xerror.Errorf(xerror.Normal, "duplicated job %s", name)
+		}
+		names[name] = true
+
+		if value, ok := job["template"]; ok {
+			templateName, _ := value.(string)
+			template, ok := f.Templates[templateName]
+			if !ok {
+				return nil, xerror.Errorf(xerror.Normal, "the template %v of job %s is not found", value, name)
+			}
+			delete(job, "template")
+			job = merge(template, job)
+		}
+
+		data, err := json.Marshal(job)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "marshal job %s failed", name)
+		}
+		config.Jobs = append(config.Jobs, &Job{Name: name, Data: data})
+	}
+	return config, nil
+}
+
+// merge returns the values of the override merged into the base, the maps are merged
+// recursively and the other values are replaced.
+func merge(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := merged[key].(map[string]any)
+		overrideMap, overrideIsMap := value.(map[string]any)
+		if baseIsMap && overrideIsMap {
+			merged[key] = merge(baseMap, overrideMap)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
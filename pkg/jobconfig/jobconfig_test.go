@@ -0,0 +1,74 @@
+package jobconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAML(t *testing.T) {
+	data := `
+prune: true
+templates:
+  prod:
+    src: {host: 127.0.0.1, port: "9030", user: root}
+    dest: {host: 127.0.0.2, port: "9030", user: root}
+    skip_error: true
+    labels: {env: prod, team: ads}
+jobs:
+  - name: db1
+    template: prod
+    src: {database: db1}
+    dest: {database: db1, user: admin}
+    labels: {team: bi}
+  - name: db2
+    src: {host: 127.0.0.3, database: db2}
+    dest: {host: 127.0.0.4, database: db2}
+    include_tables: [t1, "t2.*"]
+`
+	config, err := Parse([]byte(data))
+	assert.Nil(t, err)
+	assert.True(t, config.Prune)
+	assert.Equal(t, 2, len(config.Jobs))
+
+	var job1 map[string]any
+	assert.Nil(t, json.Unmarshal(config.Jobs[0].Data, &job1))
+	assert.Equal(t, "db1", config.Jobs[0].Name)
+	assert.Equal(t, map[string]any{"host": "127.0.0.1", "port": "9030", "user": "root", "database": "db1"}, job1["src"])
+	assert.Equal(t, map[string]any{"host": "127.0.0.2", "port": "9030", "user": "admin", "database": "db1"}, job1["dest"])
+	assert.Equal(t, map[string]any{"env": "prod", "team": "bi"}, job1["labels"])
+	assert.Equal(t, true, job1["skip_error"])
+	assert.Nil(t, job1["template"])
+
+	var job2 map[string]any
+	assert.Nil(t, json.Unmarshal(config.Jobs[1].Data, &job2))
+	assert.Equal(t, []any{"t1", "t2.*"}, job2["include_tables"])
+}
+
+func TestParseJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	data := `{"jobs": [{"name": "t1", "src": {"database": "db1", "table": "t1"}, "dest": {"database": "db1", "table": "t1"}}]}`
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0600))
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.False(t, config.Prune)
+	assert.Equal(t, 1, len(config.Jobs))
+	assert.Equal(t, "t1", config.Jobs[0].Name)
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"empty name":     `jobs: [{src: {database: db1}}]`,
+		"duplicated":     `jobs: [{name: a}, {name: a}]`,
+		"no template":    `jobs: [{name: a, template: prod}]`,
+		"invalid syntax": `jobs: [`,
+	}
+	for name, data := range tests {
+		_, err := Parse([]byte(data))
+		assert.NotNil(t, err, name)
+	}
+}
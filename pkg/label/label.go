@@ -0,0 +1,139 @@
+// Package label selects the jobs by the free-form labels, such as "env=prod,team!=ads".
+package label
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const (
+	maxKeyLength   = 128
+	maxValueLength = 256
+)
+
+var (
+	keyRegexp   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_./-]*[A-Za-z0-9])?$`)
+	valueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9_./-]*[A-Za-z0-9])?)?$`)
+)
+
+// Valid checks the keys and the values of the labels, the key is alphanumeric with '_', '.',
+// '/' and '-' inside, and so is the value but it could be empty.
+func Valid(labels map[string]string) error {
+	for key, value := range labels {
+		if len(key) > maxKeyLength || !keyRegexp.MatchString(key) {
+			return xerror.Errorf(xerror.Normal, "invalid label key %q", key)
+		}
+		if len(value) > maxValueLength || !valueRegexp.MatchString(value) {
+			return xerror.Errorf(xerror.Normal, "invalid value %q of label %s", value, key)
+		}
+	}
+	return nil
+}
+
+// String formats the labels as "k1=v1,k2=v2" in the order of the keys.
+func String(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+type operator int
+
+const (
+	opEquals operator = iota
+	opNotEquals
+	opExists
+	opNotExists
+)
+
+type requirement struct {
+	key   string
+	op    operator
+	value string
+}
+
+func (r *requirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	switch r.op {
+	case opEquals:
+		return ok && value == r.value
+	case opNotEquals:
+		return !ok || value != r.value
+	case opExists:
+		return ok
+	default:
+		return !ok
+	}
+}
+
+// Selector matches the labels if all requirements are matched, the requirements are separated
+// by ',', and each of them is one of:
+//
+//	key=value, key==value  the label exists and equals to the value
+//	key!=value             the label does not exist or not equals to the value
+//	key                    the label exists
+//	!key                   the label does not exist
+type Selector struct {
+	requirements []requirement
+}
+
+// ParseSelector parses the selector, the empty selector matches all labels.
+func ParseSelector(selector string) (*Selector, error) {
+	s := &Selector{}
+	for _, item := range strings.Split(selector, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var r requirement
+		if key, value, ok := strings.Cut(item, "!="); ok {
+			r = requirement{key: key, op: opNotEquals, value: value}
+		} else if key, value, ok := strings.Cut(item, "=="); ok {
+			r = requirement{key: key, op: opEquals, value: value}
+		} else if key, value, ok := strings.Cut(item, "="); ok {
+			r = requirement{key: key, op: opEquals, value: value}
+		} else if strings.HasPrefix(item, "!") {
+			r = requirement{key: item[1:], op: opNotExists}
+		} else {
+			r = requirement{key: item, op: opExists}
+		}
+
+		r.key = strings.TrimSpace(r.key)
+		r.value = strings.TrimSpace(r.value)
+		if err := Valid(map[string]string{r.key: r.value}); err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "invalid label selector %q", selector)
+		}
+		s.requirements = append(s.requirements, r)
+	}
+	return s, nil
+}
+
+func (s *Selector) IsEmpty() bool {
+	return s == nil || len(s.requirements) == 0
+}
+
+// Matches returns whether the labels match all requirements of the selector.
+func (s *Selector) Matches(labels map[string]string) bool {
+	if s == nil {
+		return true
+	}
+
+	for i := range s.requirements {
+		if !s.requirements[i].matches(labels) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package label
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.Nil(t, Valid(nil))
+	assert.Nil(t, Valid(map[string]string{"env": "prod", "team.io/owner": "ads-1", "empty": ""}))
+	assert.NotNil(t, Valid(map[string]string{"": "prod"}))
+	assert.NotNil(t, Valid(map[string]string{"env=": "prod"}))
+	assert.NotNil(t, Valid(map[string]string{"env": "prod,test"}))
+	assert.NotNil(t, Valid(map[string]string{"-env": "prod"}))
+}
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "team": "ads", "tier": ""}
+
+	tests := []struct {
+		selector string
+		matched  bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env==prod", true},
+		{"env=test", false},
+		{"env!=test", true},
+		{"owner!=bob", true},
+		{"env=prod, team=ads", true},
+		{"env=prod,team=bi", false},
+		{"tier", true},
+		{"tier=", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!env", false},
+	}
+	for _, test := range tests {
+		selector, err := ParseSelector(test.selector)
+		assert.Nil(t, err, test.selector)
+		assert.Equal(t, test.matched, selector.Matches(labels), test.selector)
+	}
+
+	for _, invalid := range []string{"=prod", "env=prod=test", "!", "env!=a b"} {
+		_, err := ParseSelector(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "", String(nil))
+	assert.Equal(t, "env=prod,team=ads", String(map[string]string{"team": "ads", "env": "prod"}))
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const (
+	// Post the alert as JSON.
+	WebhookGeneric  = "generic"
+	WebhookSlack    = "slack"
+	WebhookDingTalk = "dingtalk"
+	WebhookFeishu   = "feishu"
+)
+
+const (
+	defaultDedupMinutes       = 30
+	defaultRateLimitPerMinute = 20
+)
+
+type WebhookConfig struct {
+	Name string `json:"name"`
+	// generic, slack, dingtalk or feishu, the default is generic.
+	Type      string            `json:"type"`
+	Url       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	TimeoutMs int64             `json:"timeout_ms,omitempty"`
+	// The alert types sent to this webhook, empty means all.
+	Alerts []string `json:"alerts,omitempty"`
+	// The max alerts sent in a minute, the others are dropped. Zero means the default 20, and a
+	// negative value means unlimited.
+	RateLimitPerMinute int `json:"rate_limit_per_minute,omitempty"`
+}
+
+type Config struct {
+	Webhooks []*WebhookConfig `json:"webhooks"`
+
+	// Alert if the lag (the number of binlogs not synced) of a job exceeds it, zero disables it.
+	LagThreshold int64 `json:"lag_threshold,omitempty"`
+	// Alert if a job keeps failing for the minutes, zero disables it.
+	FailingMinutes int64 `json:"failing_minutes,omitempty"`
+	// The same alert of the same job or syncer is sent once in the minutes, zero means the
+	// default 30 minutes.
+	DedupMinutes int64 `json:"dedup_minutes,omitempty"`
+}
+
+func (c *WebhookConfig) Valid() error {
+	switch c.Type {
+	case "", WebhookGeneric, WebhookSlack, WebhookDingTalk, WebhookFeishu:
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown webhook type %s of webhook %s", c.Type, c.Name)
+	}
+	if c.Url == "" {
+		return xerror.Errorf(xerror.Normal, "the url of webhook %s is empty", c.Name)
+	}
+	if c.TimeoutMs < 0 {
+		return xerror.Errorf(xerror.Normal, "invalid timeout %d of webhook %s", c.TimeoutMs, c.Name)
+	}
+	for _, alertType := range c.Alerts {
+		if !isAlertType(alertType) {
+			return xerror.Errorf(xerror.Normal, "unknown alert type %s of webhook %s", alertType, c.Name)
+		}
+	}
+	return nil
+}
+
+func (c *Config) Valid() error {
+	if len(c.Webhooks) == 0 {
+		return xerror.New(xerror.Normal, "no webhook is configured")
+	}
+	for _, webhook := range c.Webhooks {
+		if err := webhook.Valid(); err != nil {
+			return err
+		}
+	}
+	if c.LagThreshold < 0 || c.FailingMinutes < 0 || c.DedupMinutes < 0 {
+		return xerror.New(xerror.Normal, "lag_threshold, failing_minutes and dedup_minutes must not be negative")
+	}
+	return nil
+}
+
+// LoadConfigFile reads the json config of the notifier.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read notify config file %s failed", path)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse notify config file %s failed", path)
+	}
+	if err := config.Valid(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	AlertJobPanic   = "job_panic"   // the job panics and stops syncing
+	AlertFullSync   = "full_sync"   // the job falls back to full sync
+	AlertLag        = "lag"         // the lag of the job exceeds the threshold
+	AlertSyncerDead = "syncer_dead" // the syncer is dead, its jobs are rebalanced
+	AlertJobFailing = "job_failing" // the job keeps failing for minutes
+)
+
+// The alerts queued but not sent yet, the new alerts are dropped if the queue is full.
+const alertQueueSize = 1024
+
+var flagConfigFile string
+
+func init() {
+	flag.StringVar(&flagConfigFile, "notify_config_file", "",
+		"The json config of the alert webhooks, the alerts are disabled if it is empty")
+}
+
+func isAlertType(alertType string) bool {
+	switch alertType {
+	case AlertJobPanic, AlertFullSync, AlertLag, AlertSyncerDead, AlertJobFailing:
+		return true
+	default:
+		return false
+	}
+}
+
+type Alert struct {
+	Type string `json:"type"`
+	// The job or the dead syncer the alert is about.
+	JobName    string `json:"job_name,omitempty"`
+	DeadSyncer string `json:"dead_syncer,omitempty"`
+	// The syncer sends the alert.
+	Syncer  string `json:"syncer"`
+	Message string `json:"message"`
+	// When the alert is raised, in unix seconds.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Text is the message of the chat webhooks.
+func (a *Alert) Text() string {
+	subject := a.JobName
+	if subject == "" {
+		subject = a.DeadSyncer
+	}
+	return fmt.Sprintf("[ccr_syncer %s] %s %s: %s", a.Syncer, a.Type, subject, a.Message)
+}
+
+// dedupKey is the key to deduplicate the same alert of the same job or syncer.
+func (a *Alert) dedupKey() string {
+	return strings.Join([]string{a.Type, a.JobName, a.DeadSyncer}, "/")
+}
+
+// Notifier sends the alerts to the webhooks asynchronously, the same alert is sent once in the
+// dedup interval and each webhook is rate limited. A nil notifier drops all alerts.
+type Notifier struct {
+	config   *Config
+	hostInfo string
+	webhooks []*webhook
+
+	lock     sync.Mutex
+	lastSent map[string]time.Time
+	closed   bool
+
+	queue chan *Alert
+	wg    sync.WaitGroup
+	now   func() time.Time // for test
+}
+
+func NewNotifier(config *Config, hostInfo string) (*Notifier, error) {
+	if err := config.Valid(); err != nil {
+		return nil, err
+	}
+
+	n := &Notifier{
+		config:   config,
+		hostInfo: hostInfo,
+		lastSent: make(map[string]time.Time),
+		queue:    make(chan *Alert, alertQueueSize),
+		now:      time.Now,
+	}
+	for _, webhookConfig := range config.Webhooks {
+		n.webhooks = append(n.webhooks, newWebhook(webhookConfig))
+	}
+
+	n.wg.Add(1)
+	go n.run()
+	return n, nil
+}
+
+// NewNotifierFromFlags creates the notifier from the flags, it returns nil if the notifier is
+// not configured.
+func NewNotifierFromFlags(hostInfo string) (*Notifier, error) {
+	if flagConfigFile == "" {
+		return nil, nil
+	}
+
+	config, err := LoadConfigFile(flagConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewNotifier(config, hostInfo)
+}
+
+func (n *Notifier) dedupInterval() time.Duration {
+	minutes := n.config.DedupMinutes
+	if minutes == 0 {
+		minutes = defaultDedupMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// Notify queues the alert unless the same alert is sent in the dedup interval, it never blocks.
+func (n *Notifier) Notify(alert *Alert) {
+	if n == nil {
+		return
+	}
+
+	now := n.now()
+	alert.Syncer = n.hostInfo
+	alert.Timestamp = now.Unix()
+
+	key := alert.dedupKey()
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.closed {
+		return
+	}
+	if lastSent, ok := n.lastSent[key]; ok && now.Sub(lastSent) < n.dedupInterval() {
+		log.Debugf("alert %s is deduplicated", key)
+		return
+	}
+	n.lastSent[key] = now
+
+	select {
+	case n.queue <- alert:
+	default:
+		log.Warnf("the alert queue is full, drop alert: %s", alert.Text())
+	}
+}
+
+func (n *Notifier) run() {
+	defer n.wg.Done()
+
+	for alert := range n.queue {
+		n.send(alert)
+	}
+}
+
+func (n *Notifier) send(alert *Alert) {
+	log.Infof("send alert: %s", alert.Text())
+	raisedAt := time.Unix(alert.Timestamp, 0)
+	for _, webhook := range n.webhooks {
+		if !webhook.accepts(alert) {
+			continue
+		}
+		if !webhook.allow(raisedAt) {
+			log.Warnf("the alerts to webhook %s are rate limited, drop alert: %s", webhook.config.Name, alert.Text())
+			continue
+		}
+		if err := webhook.send(alert); err != nil {
+			log.Warnf("send alert %s failed: %+v", alert.Type, err)
+		}
+	}
+}
+
+// Close sends the queued alerts and stops the notifier.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.lock.Unlock()
+	n.wg.Wait()
+}
+
+func (n *Notifier) JobPanic(jobName string, err error) {
+	n.Notify(&Alert{Type: AlertJobPanic, JobName: jobName, Message: fmt.Sprintf("job panics: %v", err)})
+}
+
+func (n *Notifier) FullSync(jobName string, reason string) {
+	n.Notify(&Alert{Type: AlertFullSync, JobName: jobName, Message: fmt.Sprintf("job falls back to full sync, reason: %s", reason)})
+}
+
+// Lag alerts if the lag exceeds the threshold.
+func (n *Notifier) Lag(jobName string, lag int64) {
+	if n == nil || n.config.LagThreshold <= 0 || lag <= n.config.LagThreshold {
+		return
+	}
+	n.Notify(&Alert{Type: AlertLag, JobName: jobName,
+		Message: fmt.Sprintf("lag %d exceeds the threshold %d", lag, n.config.LagThreshold)})
+}
+
+func (n *Notifier) SyncerDead(syncer string) {
+	n.Notify(&Alert{Type: AlertSyncerDead, DeadSyncer: syncer, Message: "syncer is dead, its jobs are rebalanced"})
+}
+
+// JobFailing alerts if the job keeps failing since the time longer than the failing minutes.
+func (n *Notifier) JobFailing(jobName string, since time.Time, err error) {
+	if n == nil || n.config.FailingMinutes <= 0 {
+		return
+	}
+	failing := n.now().Sub(since)
+	if failing < time.Duration(n.config.FailingMinutes)*time.Minute {
+		return
+	}
+	n.Notify(&Alert{Type: AlertJobFailing, JobName: jobName,
+		Message: fmt.Sprintf("job keeps failing for %s, last error: %v", failing.Truncate(time.Second), err)})
+}
+
+// The default notifier is used by the jobs and the checker, it is set once the syncer starts.
+var defaultNotifier *Notifier
+
+func SetDefault(n *Notifier) {
+	defaultNotifier = n
+}
+
+func JobPanic(jobName string, err error) {
+	defaultNotifier.JobPanic(jobName, err)
+}
+
+func FullSync(jobName string, reason string) {
+	defaultNotifier.FullSync(jobName, reason)
+}
+
+func Lag(jobName string, lag int64) {
+	defaultNotifier.Lag(jobName, lag)
+}
+
+func SyncerDead(syncer string) {
+	defaultNotifier.SyncerDead(syncer)
+}
+
+func JobFailing(jobName string, since time.Time, err error) {
+	defaultNotifier.JobFailing(jobName, since, err)
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookStub records the bodies posted to it.
+type webhookStub struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	bodies []map[string]any
+}
+
+func newWebhookStub(t *testing.T) *webhookStub {
+	stub := &webhookStub{}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("unmarshal body %s failed: %v", data, err)
+		}
+		stub.lock.Lock()
+		stub.bodies = append(stub.bodies, body)
+		stub.lock.Unlock()
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func (s *webhookStub) received() []map[string]any {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.bodies
+}
+
+func TestNotifierPayloads(t *testing.T) {
+	stubs := make(map[string]*webhookStub)
+	config := &Config{}
+	for _, webhookType := range []string{WebhookGeneric, WebhookSlack, WebhookDingTalk, WebhookFeishu} {
+		stub := newWebhookStub(t)
+		stubs[webhookType] = stub
+		config.Webhooks = append(config.Webhooks, &WebhookConfig{Name: webhookType, Type: webhookType, Url: stub.server.URL})
+	}
+
+	notifier, err := NewNotifier(config, "127.0.0.1:9190")
+	if err != nil {
+		t.Fatalf("NewNotifier() error = %v", err)
+	}
+	notifier.JobPanic("job1", errors.New("boom"))
+	notifier.Close()
+
+	for webhookType, stub := range stubs {
+		bodies := stub.received()
+		if len(bodies) != 1 {
+			t.Fatalf("webhook %s received %d alerts", webhookType, len(bodies))
+		}
+		body := bodies[0]
+		switch webhookType {
+		case WebhookGeneric:
+			if body["type"] != AlertJobPanic || body["job_name"] != "job1" || body["syncer"] != "127.0.0.1:9190" {
+				t.Errorf("unexpected generic payload: %v", body)
+			}
+		case WebhookSlack:
+			if body["text"] == nil {
+				t.Errorf("unexpected slack payload: %v", body)
+			}
+		case WebhookDingTalk:
+			if body["msgtype"] != "text" || body["text"].(map[string]any)["content"] == nil {
+				t.Errorf("unexpected dingtalk payload: %v", body)
+			}
+		case WebhookFeishu:
+			if body["msg_type"] != "text" || body["content"].(map[string]any)["text"] == nil {
+				t.Errorf("unexpected feishu payload: %v", body)
+			}
+		}
+	}
+}
+
+func TestNotifierDedupAndRateLimit(t *testing.T) {
+	stub := newWebhookStub(t)
+	config := &Config{
+		Webhooks:       []*WebhookConfig{{Name: "stub", Url: stub.server.URL, RateLimitPerMinute: 3}},
+		LagThreshold:   100,
+		FailingMinutes: 10,
+		DedupMinutes:   5,
+	}
+	notifier, err := NewNotifier(config, "127.0.0.1:9190")
+	if err != nil {
+		t.Fatalf("NewNotifier() error = %v", err)
+	}
+	now := time.Now()
+	notifier.now = func() time.Time { return now }
+
+	// Below the thresholds.
+	notifier.Lag("job1", 100)
+	notifier.JobFailing("job1", now.Add(-time.Minute), errors.New("failed"))
+
+	// Deduplicated.
+	notifier.Lag("job1", 101)
+	notifier.Lag("job1", 200)
+	notifier.JobFailing("job1", now.Add(-time.Hour), errors.New("failed"))
+
+	// Rate limited, at most 3 alerts in a minute.
+	notifier.SyncerDead("127.0.0.1:9191")
+	notifier.FullSync("job2", "binlog lost")
+
+	// Sent again after the dedup interval.
+	now = now.Add(6 * time.Minute)
+	notifier.Lag("job1", 101)
+	notifier.Close()
+
+	var types []string
+	for _, body := range stub.received() {
+		types = append(types, body["type"].(string))
+	}
+	expect := []string{AlertLag, AlertJobFailing, AlertSyncerDead, AlertLag}
+	if len(types) != len(expect) {
+		t.Fatalf("received alerts %v, expect %v", types, expect)
+	}
+	for i := range expect {
+		if types[i] != expect[i] {
+			t.Fatalf("received alerts %v, expect %v", types, expect)
+		}
+	}
+
+	// Notify after close is ignored.
+	notifier.SyncerDead("127.0.0.1:9192")
+}
+
+func TestConfigValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{"empty", &Config{}, true},
+		{"generic", &Config{Webhooks: []*WebhookConfig{{Url: "http://127.0.0.1"}}}, false},
+		{"without url", &Config{Webhooks: []*WebhookConfig{{Type: WebhookSlack}}}, true},
+		{"unknown type", &Config{Webhooks: []*WebhookConfig{{Type: "email", Url: "http://127.0.0.1"}}}, true},
+		{"unknown alert", &Config{Webhooks: []*WebhookConfig{{Url: "http://127.0.0.1", Alerts: []string{"oom"}}}}, true},
+		{"negative lag", &Config{Webhooks: []*WebhookConfig{{Url: "http://127.0.0.1"}}, LagThreshold: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
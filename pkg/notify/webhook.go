@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+type webhook struct {
+	config *WebhookConfig
+	client *http.Client
+	alerts map[string]bool
+
+	// The time of the alerts sent in the last minute, for rate limiting.
+	lock sync.Mutex
+	sent []time.Time
+}
+
+func newWebhook(config *WebhookConfig) *webhook {
+	timeout := defaultWebhookTimeout
+	if config.TimeoutMs > 0 {
+		timeout = time.Duration(config.TimeoutMs) * time.Millisecond
+	}
+
+	w := &webhook{
+		config: config,
+		client: &http.Client{Timeout: timeout},
+		alerts: make(map[string]bool, len(config.Alerts)),
+	}
+	for _, alertType := range config.Alerts {
+		w.alerts[alertType] = true
+	}
+	return w
+}
+
+func (w *webhook) accepts(alert *Alert) bool {
+	return len(w.alerts) == 0 || w.alerts[alert.Type]
+}
+
+// allow returns whether the alert is allowed to send now, it counts the alerts sent in the
+// last minute.
+func (w *webhook) allow(now time.Time) bool {
+	limit := w.config.RateLimitPerMinute
+	if limit == 0 {
+		limit = defaultRateLimitPerMinute
+	} else if limit < 0 {
+		return true
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	i := 0
+	for i < len(w.sent) && now.Sub(w.sent[i]) >= time.Minute {
+		i++
+	}
+	w.sent = w.sent[i:]
+	if len(w.sent) >= limit {
+		return false
+	}
+	w.sent = append(w.sent, now)
+	return true
+}
+
+// payload builds the body of the alert, in the format of the webhook type.
+func (w *webhook) payload(alert *Alert) ([]byte, error) {
+	var body any
+	switch w.config.Type {
+	case WebhookSlack:
+		body = map[string]any{"text": alert.Text()}
+	case WebhookDingTalk:
+		body = map[string]any{
+			"msgtype": "text",
+			"text":    map[string]any{"content": alert.Text()},
+		}
+	case WebhookFeishu:
+		body = map[string]any{
+			"msg_type": "text",
+			"content":  map[string]any{"text": alert.Text()},
+		}
+	default:
+		body = alert
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "marshal alert %s failed", alert.Type)
+	}
+	return data, nil
+}
+
+func (w *webhook) send(alert *Alert) error {
+	data, err := w.payload(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.config.Url, bytes.NewReader(data))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "new request of webhook %s failed", w.config.Name)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "post alert to webhook %s failed", w.config.Name)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return xerror.Errorf(xerror.Normal, "post alert to webhook %s failed, status: %s", w.config.Name, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The max duration to look back for the previous fire time of a cron expression.
+const cronLookback = 5 * 366 * 24 * time.Hour
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, 0 is sunday, 7 is also accepted as sunday
+}
+
+// Cron is a standard 5 fields cron expression: minute hour day-of-month month day-of-week.
+//
+// Each field supports `*`, numbers, ranges `a-b`, lists `a,b` and steps `*/n`, `a-b/n`.
+type Cron struct {
+	expr    string
+	minutes uint64
+	hours   uint64
+	days    uint64
+	months  uint64
+	weeks   uint64
+
+	// Like the vixie cron, if both day-of-month and day-of-week are restricted, the time
+	// matches if either of them matches.
+	daysStar  bool
+	weeksStar bool
+}
+
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, xerror.Errorf(xerror.Normal, "invalid cron %q, expect 5 fields but got %d", expr, len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "invalid cron %q", expr)
+		}
+		bits[i] = value
+	}
+
+	// 7 is sunday too
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = (bits[4] | 1) &^ (1 << 7)
+	}
+
+	return &Cron{
+		expr:      expr,
+		minutes:   bits[0],
+		hours:     bits[1],
+		days:      bits[2],
+		months:    bits[3],
+		weeks:     bits[4],
+		daysStar:  fields[2] == "*",
+		weeksStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, bound cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			s, err := strconv.Atoi(part[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, xerror.Errorf(xerror.Normal, "invalid step in %q", part)
+			}
+			step = s
+			part = part[:idx]
+		}
+
+		start, end := bound.min, bound.max
+		if bound.max == 6 {
+			end = 7 // day of week accepts 7 as sunday
+		}
+		switch {
+		case part == "*":
+			end = bound.max
+		case strings.Contains(part, "-"):
+			items := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(items[0])
+			end, err2 = strconv.Atoi(items[1])
+			if err1 != nil || err2 != nil {
+				return 0, xerror.Errorf(xerror.Normal, "invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, xerror.Errorf(xerror.Normal, "invalid value %q", part)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		max := bound.max
+		if bound.max == 6 {
+			max = 7
+		}
+		if start < bound.min || end > max || start > end {
+			return 0, xerror.Errorf(xerror.Normal, "value %q out of range [%d, %d]", part, bound.min, max)
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+	return bits, nil
+}
+
+func (c *Cron) String() string {
+	return c.expr
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dayMatched := c.days&(1<<uint(t.Day())) != 0
+	weekMatched := c.weeks&(1<<uint(t.Weekday())) != 0
+	if c.daysStar || c.weeksStar {
+		return dayMatched && weekMatched
+	}
+	return dayMatched || weekMatched
+}
+
+// Prev returns the latest fire time which is not after t, the zero time is returned if
+// the cron is not fired in the lookback duration.
+func (c *Cron) Prev(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	limit := t.Add(-cronLookback)
+
+	for t.After(limit) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			// the last minute of the previous month
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(-time.Minute)
+			continue
+		}
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(-time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
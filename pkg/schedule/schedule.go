@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a time range of the allowed weekdays, the time is formatted as "HH:MM".
+//
+// If End is not after Start, the window crosses the midnight and the weekdays refer to
+// the day the window starts. An empty weekdays means every day.
+type Window struct {
+	Weekdays []string `json:"weekdays,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+}
+
+func (w *Window) String() string {
+	days := "every day"
+	if len(w.Weekdays) > 0 {
+		days = strings.Join(w.Weekdays, ",")
+	}
+	return fmt.Sprintf("%s %s-%s", days, w.Start, w.End)
+}
+
+// Schedule decides when the job is allowed to sync, there are two kinds of schedule:
+//  1. windows, the job runs inside any of the windows and pauses outside them.
+//  2. cron, the job pauses at the PauseCron and resumes at the ResumeCron.
+//
+// All times are evaluated in the TimeZone, which is a IANA name like "Asia/Shanghai",
+// the local time zone is used if it is empty.
+type Schedule struct {
+	TimeZone   string   `json:"time_zone,omitempty"`
+	Windows    []Window `json:"windows,omitempty"`
+	PauseCron  string   `json:"pause_cron,omitempty"`
+	ResumeCron string   `json:"resume_cron,omitempty"`
+}
+
+func (s *Schedule) IsEmpty() bool {
+	return s == nil || (len(s.Windows) == 0 && s.PauseCron == "" && s.ResumeCron == "")
+}
+
+func (s *Schedule) Valid() error {
+	if s.IsEmpty() {
+		return nil
+	}
+
+	if _, err := s.location(); err != nil {
+		return err
+	}
+
+	hasCron := s.PauseCron != "" || s.ResumeCron != ""
+	if hasCron && len(s.Windows) > 0 {
+		return xerror.New(xerror.Normal, "schedule windows and cron are exclusive")
+	}
+
+	if hasCron {
+		if s.PauseCron == "" || s.ResumeCron == "" {
+			return xerror.New(xerror.Normal, "both pause_cron and resume_cron are required")
+		}
+		if _, err := ParseCron(s.PauseCron); err != nil {
+			return err
+		}
+		if _, err := ParseCron(s.ResumeCron); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	for i := range s.Windows {
+		window := &s.Windows[i]
+		if _, err := parseClock(window.Start); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "invalid window %s", window)
+		}
+		if _, err := parseClock(window.End); err != nil {
+			return xerror.Wrapf(err, xerror.Normal, "invalid window %s", window)
+		}
+		for _, day := range window.Weekdays {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return xerror.Errorf(xerror.Normal, "invalid weekday %s in window %s", day, window)
+			}
+		}
+	}
+	return nil
+}
+
+// ShouldRun returns whether the job is allowed to sync at the time.
+func (s *Schedule) ShouldRun(now time.Time) (bool, error) {
+	if s.IsEmpty() {
+		return true, nil
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return false, err
+	}
+	now = now.In(loc)
+
+	if s.PauseCron != "" || s.ResumeCron != "" {
+		return s.shouldRunByCron(now)
+	}
+	return s.shouldRunByWindows(now)
+}
+
+func (s *Schedule) shouldRunByCron(now time.Time) (bool, error) {
+	pauseCron, err := ParseCron(s.PauseCron)
+	if err != nil {
+		return false, err
+	}
+	resumeCron, err := ParseCron(s.ResumeCron)
+	if err != nil {
+		return false, err
+	}
+
+	// The latest fired one wins, resume if both are fired at the same time.
+	lastPause := pauseCron.Prev(now)
+	lastResume := resumeCron.Prev(now)
+	return !lastResume.Before(lastPause), nil
+}
+
+func (s *Schedule) shouldRunByWindows(now time.Time) (bool, error) {
+	minutes := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+
+	for i := range s.Windows {
+		window := &s.Windows[i]
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return false, err
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return false, err
+		}
+
+		if start < end {
+			if window.hasWeekday(today) && start <= minutes && minutes < end {
+				return true, nil
+			}
+			continue
+		}
+
+		// crosses the midnight, or the whole day if start == end.
+		if window.hasWeekday(today) && minutes >= start {
+			return true, nil
+		}
+		if window.hasWeekday(yesterday) && minutes < end {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (w *Window) hasWeekday(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, name := range w.Weekdays {
+		if weekdays[strings.ToLower(name)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.Local, nil
+	}
+
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "invalid time zone %s", s.TimeZone)
+	}
+	return loc, nil
+}
+
+// parseClock parses "HH:MM" and returns the minutes since the midnight.
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, xerror.Wrapf(err, xerror.Normal, "invalid clock %s, expect HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
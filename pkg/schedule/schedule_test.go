@@ -0,0 +1,131 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	ts, err := time.ParseInLocation("2006-01-02 15:04", value, time.UTC)
+	if err != nil {
+		t.Fatalf("parse time %s: %v", value, err)
+	}
+	return ts
+}
+
+func TestCron_Prev(t *testing.T) {
+	tests := []struct {
+		expr   string
+		now    string
+		expect string
+	}{
+		{"0 22 * * *", "2024-05-08 23:10", "2024-05-08 22:00"},
+		{"0 22 * * *", "2024-05-08 21:59", "2024-05-07 22:00"},
+		{"*/15 * * * *", "2024-05-08 10:44", "2024-05-08 10:30"},
+		{"30 1 * * 1-5", "2024-05-05 12:00", "2024-05-03 01:30"}, // sunday => friday
+		{"0 0 1 * *", "2024-05-08 00:00", "2024-05-01 00:00"},
+		{"0 0 * * 7", "2024-05-08 00:00", "2024-05-05 00:00"}, // 7 is sunday
+		{"0 12 29 2 *", "2024-05-08 00:00", "2024-02-29 12:00"},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if !assert.NoError(t, err, tt.expr) {
+			continue
+		}
+		assert.Equal(t, mustTime(t, tt.expect), cron.Prev(mustTime(t, tt.now)), tt.expr)
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedule_Windows(t *testing.T) {
+	s := &Schedule{
+		TimeZone: "UTC",
+		Windows: []Window{
+			// the nightly batch window is 01:00-06:00, so sync in the rest of the day.
+			{Start: "06:00", End: "01:00"},
+			// sync all the day in weekend.
+			{Weekdays: []string{"sat", "Sun"}, Start: "00:00", End: "00:00"},
+		},
+	}
+	assert.NoError(t, s.Valid())
+
+	tests := []struct {
+		now    string
+		expect bool
+	}{
+		{"2024-05-08 05:59", false}, // wednesday
+		{"2024-05-08 06:00", true},
+		{"2024-05-08 23:59", true},
+		{"2024-05-09 00:30", true},
+		{"2024-05-09 01:00", false},
+		{"2024-05-11 03:00", true}, // saturday
+	}
+	for _, tt := range tests {
+		got, err := s.ShouldRun(mustTime(t, tt.now))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expect, got, tt.now)
+	}
+}
+
+func TestSchedule_Cron(t *testing.T) {
+	s := &Schedule{
+		TimeZone:   "Asia/Shanghai",
+		PauseCron:  "0 1 * * *",
+		ResumeCron: "0 6 * * *",
+	}
+	assert.NoError(t, s.Valid())
+
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+
+	tests := []struct {
+		now    time.Time
+		expect bool
+	}{
+		{time.Date(2024, 5, 8, 0, 59, 0, 0, loc), true},
+		{time.Date(2024, 5, 8, 1, 0, 0, 0, loc), false},
+		{time.Date(2024, 5, 8, 5, 59, 0, 0, loc), false},
+		{time.Date(2024, 5, 8, 6, 0, 0, 0, loc), true},
+		// the same instant in UTC
+		{time.Date(2024, 5, 7, 18, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		got, err := s.ShouldRun(tt.now)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expect, got, tt.now.String())
+	}
+}
+
+func TestSchedule_Valid(t *testing.T) {
+	invalids := []*Schedule{
+		{TimeZone: "Mars/Base"},
+		{PauseCron: "0 1 * * *"},
+		{PauseCron: "0 1 * * *", ResumeCron: "0 6 * * *", Windows: []Window{{Start: "01:00", End: "02:00"}}},
+		{Windows: []Window{{Start: "1am", End: "02:00"}}},
+		{Windows: []Window{{Weekdays: []string{"monday"}, Start: "01:00", End: "02:00"}}},
+	}
+	for _, s := range invalids {
+		// TimeZone only schedule is empty
+		if s.IsEmpty() {
+			s.Windows = []Window{{Start: "01:00", End: "02:00"}}
+		}
+		assert.Error(t, s.Valid(), "%+v", s)
+	}
+
+	var empty *Schedule
+	assert.NoError(t, empty.Valid())
+	run, err := empty.ShouldRun(time.Now())
+	assert.NoError(t, err)
+	assert.True(t, run)
+}
@@ -0,0 +1,104 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The encrypted secret is formatted as "enc:v1:${key_id}:${base64(nonce + ciphertext)}".
+const encryptedPrefix = "enc:v1:"
+
+// IsEncrypted returns whether the secret is encrypted by the Cipher.
+func IsEncrypted(secret string) bool {
+	return strings.HasPrefix(secret, encryptedPrefix)
+}
+
+// Cipher encrypts the secrets with AES-256-GCM, the AES key is the SHA-256 of the key data.
+type Cipher struct {
+	provider Provider
+}
+
+func NewCipher(provider Provider) *Cipher {
+	return &Cipher{provider: provider}
+}
+
+func newAEAD(key *Key) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key.Data)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "new aes cipher failed")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "new gcm cipher failed")
+	}
+	return aead, nil
+}
+
+// Encrypt encrypts the secret with the current key, the empty and the encrypted secrets are
+// returned as it is.
+func (c *Cipher) Encrypt(secret string) (string, error) {
+	if secret == "" || IsEncrypted(secret) {
+		return secret, nil
+	}
+
+	key, err := c.provider.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", xerror.Wrap(err, xerror.Normal, "generate nonce failed")
+	}
+	// The key id is authenticated, so the secret could not be moved to another key.
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(key.Id))
+	return encryptedPrefix + key.Id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts the secret with the key encrypted it, the plaintext secret is returned as
+// it is.
+func (c *Cipher) Decrypt(secret string) (string, error) {
+	if !IsEncrypted(secret) {
+		return secret, nil
+	}
+
+	keyId, encoded, ok := strings.Cut(strings.TrimPrefix(secret, encryptedPrefix), ":")
+	if !ok {
+		return "", xerror.New(xerror.Normal, "invalid encrypted secret, key id not found")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", xerror.Wrap(err, xerror.Normal, "invalid encrypted secret")
+	}
+
+	key, err := c.provider.GetKey(keyId)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", xerror.New(xerror.Normal, "invalid encrypted secret, too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyId))
+	if err != nil {
+		return "", xerror.Wrapf(err, xerror.Normal, "decrypt secret with key %s failed", keyId)
+	}
+	return string(plaintext), nil
+}
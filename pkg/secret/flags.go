@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"flag"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+var (
+	flagProvider        string
+	flagKeyFile         string
+	flagKeyEnv          string
+	flagKeyringFile     string
+	flagPreviousKeyFile string
+)
+
+func init() {
+	flag.StringVar(&flagProvider, "secret_provider", "",
+		"Encrypt the cluster passwords with the key from the provider: file, env or keyring, the passwords are stored in plaintext if it is empty")
+	flag.StringVar(&flagKeyFile, "secret_key_file", "", "The key file of the file secret provider")
+	flag.StringVar(&flagKeyEnv, "secret_key_env", "CCR_SECRET_KEY", "The environment variable of the env secret provider")
+	flag.StringVar(&flagKeyringFile, "secret_keyring_file", "", "The keyring json file of the keyring secret provider")
+	flag.StringVar(&flagPreviousKeyFile, "secret_previous_key_file", "",
+		"The file of the previous key, to decrypt the passwords encrypted before the key is rotated")
+}
+
+// NewProviderFromFlags creates the secret provider from the flags, it returns nil if the
+// secret provider is not configured.
+func NewProviderFromFlags() (Provider, error) {
+	var provider Provider
+	var err error
+	switch flagProvider {
+	case "":
+		return nil, nil
+	case "file":
+		provider, err = NewFileProvider(flagKeyFile)
+	case "env":
+		provider, err = NewEnvProvider(flagKeyEnv)
+	case "keyring":
+		provider, err = NewKeyringProvider(flagKeyringFile)
+	default:
+		err = xerror.Errorf(xerror.Normal, "unknown secret provider: %s", flagProvider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if flagPreviousKeyFile != "" {
+		previous, err := NewFileProvider(flagPreviousKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		provider = NewChainProvider(provider, previous)
+	}
+	return provider, nil
+}
@@ -0,0 +1,165 @@
+package secret
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// Key encrypts and decrypts the secrets, the id of the key is saved with the encrypted secrets.
+type Key struct {
+	Id   string
+	Data []byte
+}
+
+// newKey creates a key, the id is derived from the key data.
+func newKey(data []byte) (*Key, error) {
+	data = []byte(strings.TrimSpace(string(data)))
+	if len(data) == 0 {
+		return nil, xerror.New(xerror.Normal, "the secret key is empty")
+	}
+
+	sum := sha256.Sum256(data)
+	return &Key{Id: hex.EncodeToString(sum[:4]), Data: data}, nil
+}
+
+// Provider provides the keys of the secrets.
+type Provider interface {
+	// CurrentKey returns the key to encrypt the secrets.
+	CurrentKey() (*Key, error)
+	// GetKey returns the key to decrypt the secrets encrypted by it.
+	GetKey(id string) (*Key, error)
+}
+
+// singleKeyProvider provides only one key.
+type singleKeyProvider struct {
+	key *Key
+}
+
+func (p *singleKeyProvider) CurrentKey() (*Key, error) {
+	return p.key, nil
+}
+
+func (p *singleKeyProvider) GetKey(id string) (*Key, error) {
+	if id != p.key.Id {
+		return nil, xerror.Errorf(xerror.Normal, "secret key %s not found", id)
+	}
+	return p.key, nil
+}
+
+// NewFileProvider reads the key from the file.
+func NewFileProvider(path string) (Provider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read secret key file %s failed", path)
+	}
+
+	key, err := newKey(data)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "secret key file: %s", path)
+	}
+	return &singleKeyProvider{key: key}, nil
+}
+
+// NewEnvProvider reads the key from the environment variable.
+func NewEnvProvider(name string) (Provider, error) {
+	key, err := newKey([]byte(os.Getenv(name)))
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "secret key env: %s", name)
+	}
+	return &singleKeyProvider{key: key}, nil
+}
+
+// keyring is the content of the keyring file, for example:
+//
+//	{
+//	  "current": "2024",
+//	  "keys": {
+//	    "2023": "the old key",
+//	    "2024": "the new key"
+//	  }
+//	}
+type keyring struct {
+	Current string            `json:"current"`
+	Keys    map[string]string `json:"keys"`
+}
+
+type keyringProvider struct {
+	current string
+	keys    map[string]*Key
+}
+
+// NewKeyringProvider reads the keys from the keyring file, the secrets are encrypted by the
+// current key, and the other keys are kept to decrypt the secrets encrypted before rotation.
+func NewKeyringProvider(path string) (Provider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read secret keyring file %s failed", path)
+	}
+
+	var ring keyring
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "parse secret keyring file %s failed", path)
+	}
+
+	provider := &keyringProvider{
+		current: ring.Current,
+		keys:    make(map[string]*Key, len(ring.Keys)),
+	}
+	for id, value := range ring.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, xerror.Errorf(xerror.Normal, "invalid secret key id %q in keyring file %s", id, path)
+		}
+		key, err := newKey([]byte(value))
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "secret key %s in keyring file %s", id, path)
+		}
+		key.Id = id
+		provider.keys[id] = key
+	}
+	if _, ok := provider.keys[ring.Current]; !ok {
+		return nil, xerror.Errorf(xerror.Normal, "current secret key %q not found in keyring file %s", ring.Current, path)
+	}
+	return provider, nil
+}
+
+func (p *keyringProvider) CurrentKey() (*Key, error) {
+	return p.keys[p.current], nil
+}
+
+func (p *keyringProvider) GetKey(id string) (*Key, error) {
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+	return nil, xerror.Errorf(xerror.Normal, "secret key %s not found", id)
+}
+
+// chainProvider encrypts by the first provider, and decrypts by any of the providers.
+type chainProvider struct {
+	providers []Provider
+}
+
+// NewChainProvider chains the providers, the secrets are encrypted by the key of the first
+// provider. It is used to decrypt the secrets encrypted by the previous keys during rotation.
+func NewChainProvider(providers ...Provider) Provider {
+	return &chainProvider{providers: providers}
+}
+
+func (p *chainProvider) CurrentKey() (*Key, error) {
+	return p.providers[0].CurrentKey()
+}
+
+func (p *chainProvider) GetKey(id string) (*Key, error) {
+	var err error
+	for _, provider := range p.providers {
+		var key *Key
+		if key, err = provider.GetKey(id); err == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"encoding/json"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const Redacted = "******"
+
+// RedactPasswords replaces the values of all non-empty "password" fields in the json document,
+// and the values of the "headers" objects, e.g. the webhook headers, which usually carry tokens.
+func RedactPasswords(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "unmarshal json failed")
+	}
+
+	redacted, err := json.Marshal(redact(document))
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "marshal json failed")
+	}
+	return redacted, nil
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if password, ok := field.(string); ok && key == "password" && password != "" {
+				v[key] = Redacted
+			} else if headers, ok := field.(map[string]interface{}); ok && key == "headers" {
+				for name, header := range headers {
+					if value, ok := header.(string); ok && value != "" {
+						headers[name] = Redacted
+					}
+				}
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,165 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("write file %s failed: %v", path, err)
+	}
+	return path
+}
+
+func TestCipher(t *testing.T) {
+	provider, err := NewFileProvider(writeFile(t, "key", "the secret key\n"))
+	if err != nil {
+		t.Fatalf("new file provider failed: %v", err)
+	}
+	cipher := NewCipher(provider)
+
+	encrypted, err := cipher.Encrypt("root_password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "root_password") {
+		t.Fatalf("the secret is not encrypted: %s", encrypted)
+	}
+
+	if again, err := cipher.Encrypt("root_password"); err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	} else if again == encrypted {
+		t.Errorf("the nonce is reused")
+	}
+
+	if decrypted, err := cipher.Decrypt(encrypted); err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	} else if decrypted != "root_password" {
+		t.Errorf("decrypted secret is %s, expect root_password", decrypted)
+	}
+
+	// The empty and plaintext secrets are kept.
+	if encrypted, err := cipher.Encrypt(""); err != nil || encrypted != "" {
+		t.Errorf("encrypt empty secret: %q, %v", encrypted, err)
+	}
+	if decrypted, err := cipher.Decrypt("plaintext"); err != nil || decrypted != "plaintext" {
+		t.Errorf("decrypt plaintext secret: %q, %v", decrypted, err)
+	}
+
+	// Decrypt with a wrong key.
+	os.Setenv("CCR_SECRET_TEST_KEY", "another key")
+	defer os.Unsetenv("CCR_SECRET_TEST_KEY")
+	envProvider, err := NewEnvProvider("CCR_SECRET_TEST_KEY")
+	if err != nil {
+		t.Fatalf("new env provider failed: %v", err)
+	}
+	if _, err := NewCipher(envProvider).Decrypt(encrypted); err == nil {
+		t.Errorf("decrypt with a wrong key should fail")
+	}
+
+	// Tamper the key id.
+	keyId := strings.Split(encrypted, ":")[2]
+	tampered := strings.Replace(encrypted, keyId, "tampered", 1)
+	if _, err := cipher.Decrypt(tampered); err == nil {
+		t.Errorf("decrypt with a tampered key id should fail")
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldRing := writeFile(t, "old.json", `{"current": "k1", "keys": {"k1": "key one"}}`)
+	newRing := writeFile(t, "new.json", `{"current": "k2", "keys": {"k1": "key one", "k2": "key two"}}`)
+
+	oldProvider, err := NewKeyringProvider(oldRing)
+	if err != nil {
+		t.Fatalf("new keyring provider failed: %v", err)
+	}
+	encrypted, err := NewCipher(oldProvider).Encrypt("password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if !strings.HasPrefix(encrypted, "enc:v1:k1:") {
+		t.Fatalf("the secret is not encrypted by k1: %s", encrypted)
+	}
+
+	newProvider, err := NewKeyringProvider(newRing)
+	if err != nil {
+		t.Fatalf("new keyring provider failed: %v", err)
+	}
+	cipher := NewCipher(newProvider)
+	decrypted, err := cipher.Decrypt(encrypted)
+	if err != nil || decrypted != "password" {
+		t.Fatalf("decrypt with the new keyring: %q, %v", decrypted, err)
+	}
+	reencrypted, err := cipher.Encrypt(decrypted)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if !strings.HasPrefix(reencrypted, "enc:v1:k2:") {
+		t.Errorf("the secret is not encrypted by k2: %s", reencrypted)
+	}
+
+	if _, err := NewKeyringProvider(writeFile(t, "bad.json", `{"current": "k3", "keys": {"k1": "key one"}}`)); err == nil {
+		t.Errorf("the current key must be in the keyring")
+	}
+}
+
+func TestChainProvider(t *testing.T) {
+	oldProvider, err := NewFileProvider(writeFile(t, "old", "old key"))
+	if err != nil {
+		t.Fatalf("new file provider failed: %v", err)
+	}
+	newProvider, err := NewFileProvider(writeFile(t, "new", "new key"))
+	if err != nil {
+		t.Fatalf("new file provider failed: %v", err)
+	}
+
+	encrypted, err := NewCipher(oldProvider).Encrypt("password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if _, err := NewCipher(newProvider).Decrypt(encrypted); err == nil {
+		t.Fatalf("decrypt without the old key should fail")
+	}
+
+	cipher := NewCipher(NewChainProvider(newProvider, oldProvider))
+	if decrypted, err := cipher.Decrypt(encrypted); err != nil || decrypted != "password" {
+		t.Fatalf("decrypt with the chain provider: %q, %v", decrypted, err)
+	}
+	reencrypted, err := cipher.Encrypt("password")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if decrypted, err := NewCipher(newProvider).Decrypt(reencrypted); err != nil || decrypted != "password" {
+		t.Errorf("decrypt with the new key: %q, %v", decrypted, err)
+	}
+}
+
+func TestRedactPasswords(t *testing.T) {
+	data := `{"name":"job","src":{"user":"root","password":"p1"},"dest":{"password":""},"dests":[{"password":"p2"}]}`
+	redacted, err := RedactPasswords([]byte(data))
+	if err != nil {
+		t.Fatalf("redact failed: %v", err)
+	}
+
+	expect := `{"dest":{"password":""},"dests":[{"password":"******"}],"name":"job","src":{"password":"******","user":"root"}}`
+	if string(redacted) != expect {
+		t.Errorf("redacted: %s, expect: %s", redacted, expect)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	data := `{"name":"job","sink":{"type":"webhook","url":"http://127.0.0.1","headers":{"Authorization":"Bearer t","X-Empty":""}}}`
+	redacted, err := RedactPasswords([]byte(data))
+	if err != nil {
+		t.Fatalf("redact failed: %v", err)
+	}
+
+	expect := `{"name":"job","sink":{"headers":{"Authorization":"******","X-Empty":""},"type":"webhook","url":"http://127.0.0.1"}}`
+	if string(redacted) != expect {
+		t.Errorf("redacted: %s, expect: %s", redacted, expect)
+	}
+}
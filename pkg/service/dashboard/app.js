@@ -0,0 +1,206 @@
+"use strict";
+
+const REFRESH_INTERVAL_MS = 5000;
+const TOKEN_KEY = "ccr_syncer_token";
+
+let selectedJob = "";
+let lastJobs = [];
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+// The token is kept in the local storage of the browser, and sent as the bearer token.
+async function post(path, body) {
+  const headers = { "Content-Type": "application/json" };
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) {
+    headers["Authorization"] = "Bearer " + token;
+  }
+  const resp = await fetch(path, { method: "POST", headers: headers, body: JSON.stringify(body || {}) });
+  let result;
+  try {
+    result = await resp.json();
+  } catch (e) {
+    throw new Error(path + ": " + resp.status + " " + resp.statusText);
+  }
+  if (!resp.ok || result.success === false) {
+    const msg = result.error_msg || (result.error && result.error.message) || resp.statusText;
+    throw new Error(path + ": " + msg);
+  }
+  return result;
+}
+
+function showMessage(text, info) {
+  const message = $("message");
+  message.textContent = text;
+  message.classList.toggle("info", !!info);
+  message.classList.toggle("hidden", !text);
+}
+
+function formatTime(unixSeconds) {
+  if (!unixSeconds) {
+    return "-";
+  }
+  return new Date(unixSeconds * 1000).toLocaleString();
+}
+
+function cell(row, text, className) {
+  const td = document.createElement("td");
+  td.textContent = text === undefined || text === null || text === "" ? "-" : text;
+  if (className) {
+    td.className = className;
+  }
+  row.appendChild(td);
+  return td;
+}
+
+function renderSyncers(syncers) {
+  const tbody = $("syncers").querySelector("tbody");
+  tbody.replaceChildren();
+  for (const syncer of syncers || []) {
+    const row = document.createElement("tr");
+    cell(row, syncer.host);
+    cell(row, formatTime(syncer.last_heartbeat), syncer.last_heartbeat ? "" : "dead");
+    cell(row, syncer.error_msg, "error");
+    tbody.appendChild(row);
+  }
+}
+
+function actionButton(td, job, action, text) {
+  const button = document.createElement("button");
+  button.textContent = text;
+  button.addEventListener("click", async (event) => {
+    event.stopPropagation();
+    if (!confirm(text + " job " + job.name + "?")) {
+      return;
+    }
+    try {
+      await post("/dashboard/api/" + action, { name: job.name });
+      showMessage(text + " job " + job.name + " succeeded", true);
+    } catch (e) {
+      showMessage(e.message);
+    }
+    refresh();
+  });
+  td.appendChild(button);
+}
+
+function renderJobs(jobs) {
+  const filter = $("filter").value.trim();
+  const tbody = $("jobs").querySelector("tbody");
+  tbody.replaceChildren();
+  for (const job of jobs || []) {
+    if (filter && !job.name.includes(filter)) {
+      continue;
+    }
+    const status = job.status || {};
+    const row = document.createElement("tr");
+    if (job.name === selectedJob) {
+      row.className = "selected";
+    }
+    cell(row, job.name);
+    cell(row, job.syncer);
+    cell(row, status.state || "unknown", "state-" + (status.state || "unknown"));
+    cell(row, [status.progress_state, status.sub_sync_state].filter(Boolean).join(" / "));
+    cell(row, job.status ? status.lag : "");
+    cell(row, job.status ? status.commit_seq : "");
+    cell(row, formatTime(status.last_binlog_at));
+    cell(row, job.status ? status.consecutive_failures : "");
+    const error = cell(row, status.last_error ? status.last_error.message : "", "error");
+    if (status.last_error) {
+      error.title = status.last_error.message;
+    }
+
+    const actions = cell(row, "", "actions");
+    actions.textContent = "";
+    if (status.state === "running") {
+      actionButton(actions, job, "pause", "Pause");
+    } else if (status.state === "paused") {
+      actionButton(actions, job, "resume", "Resume");
+    }
+    if (status.state !== "completed") {
+      actionButton(actions, job, "force_fullsync", "Force fullsync");
+    }
+
+    row.addEventListener("click", () => selectJob(job.name));
+    tbody.appendChild(row);
+  }
+}
+
+function renderEvents(list, events) {
+  list.replaceChildren();
+  if (!events || events.length === 0) {
+    const item = document.createElement("li");
+    item.textContent = "No events";
+    list.appendChild(item);
+    return;
+  }
+  for (const event of events) {
+    const item = document.createElement("li");
+    const time = document.createElement("span");
+    time.className = "time";
+    time.textContent = new Date(event.timestamp).toLocaleString();
+    const type = document.createElement("span");
+    type.className = "type";
+    type.textContent = event.type;
+    item.append(time, type, document.createTextNode(event.message));
+    list.appendChild(item);
+  }
+}
+
+async function renderDetail() {
+  if (!selectedJob) {
+    return;
+  }
+  const job = lastJobs.find((job) => job.name === selectedJob);
+  $("detail").classList.remove("hidden");
+  $("detail-name").textContent = selectedJob;
+  $("detail-status").textContent = job && job.status ? JSON.stringify(job.status, null, 2) : "The syncer is unreachable";
+
+  // The history is saved in the meta db, so it is read from this syncer, the events are in
+  // descending order.
+  const [timeline, errors] = await Promise.all([
+    post("/job_history", { name: selectedJob, limit: 50 }),
+    post("/job_history", { name: selectedJob, types: ["error"], limit: 10 }),
+  ]);
+  renderEvents($("timeline"), timeline.events);
+  renderEvents($("errors"), errors.events);
+}
+
+function selectJob(name) {
+  selectedJob = name;
+  renderJobs(lastJobs);
+  renderDetail().catch((e) => showMessage(e.message));
+}
+
+async function refresh() {
+  try {
+    const result = await post("/dashboard/api/jobs");
+    lastJobs = result.jobs || [];
+    renderSyncers(result.syncers);
+    renderJobs(lastJobs);
+    await renderDetail();
+  } catch (e) {
+    showMessage(e.message);
+  }
+}
+
+function init() {
+  $("token").value = localStorage.getItem(TOKEN_KEY) || "";
+  $("save-token").addEventListener("click", () => {
+    localStorage.setItem(TOKEN_KEY, $("token").value);
+    showMessage("");
+    refresh();
+  });
+  $("refresh").addEventListener("click", refresh);
+  $("filter").addEventListener("input", () => renderJobs(lastJobs));
+  setInterval(() => {
+    if ($("auto-refresh").checked) {
+      refresh();
+    }
+  }, REFRESH_INTERVAL_MS);
+  refresh();
+}
+
+init();
@@ -0,0 +1,501 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/selectdb/ccr_syncer/pkg/auth"
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The versioned api models the jobs as resources:
+//
+//	GET    /api/v1/jobs                      list the jobs
+//	POST   /api/v1/jobs                      create a job
+//	GET    /api/v1/jobs/{name}               get the status of the job
+//	PATCH  /api/v1/jobs/{name}               update the job
+//	DELETE /api/v1/jobs/{name}               delete the job
+//	POST   /api/v1/jobs/{name}:{action}      pause, resume, force_fullsync, desync, switchover, verify
+//	GET    /api/v1/jobs/{name}/{resource}    lag, progress, detail, schedule, history, verify_reports, dry_run_results
+//	PUT    /api/v1/jobs/{name}/schedule      update the schedule of the job
+//
+// The errors are responded with the 4xx/5xx status codes and the error envelope
+// `{"error": {"code": "not_found", "message": "..."}}`. The requests of a job owned by another
+// syncer are redirected with 307, so the method and the body are kept.
+const (
+	apiV1Prefix     = "/api/v1"
+	apiV1JobsPrefix = apiV1Prefix + "/jobs/"
+)
+
+//go:embed openapi.json
+var openapiDoc []byte
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiErrorResult struct {
+	Error apiError `json:"error"`
+}
+
+func apiErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_argument"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "already_exists"
+	case http.StatusBadGateway:
+		return "cluster_error"
+	default:
+		return "internal"
+	}
+}
+
+// apiStatusOf returns the status code of the error, the errors of the requests are normal
+// errors, and the errors of the clusters are rpc, fe, be and meta errors.
+func apiStatusOf(err error) int {
+	if errors.Is(err, storage.ErrJobExists) {
+		return http.StatusConflict
+	}
+
+	var xerr *xerror.XError
+	if !errors.As(err, &xerr) {
+		return http.StatusInternalServerError
+	}
+	switch xerr.Category() {
+	case xerror.Normal:
+		return http.StatusBadRequest
+	case xerror.DB:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func writeApiJson(w http.ResponseWriter, status int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(&apiErrorResult{Error: apiError{Code: apiErrorCode(status), Message: err.Error()}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeApiError(w http.ResponseWriter, status int, errMsg string) {
+	writeApiJson(w, status, &apiErrorResult{Error: apiError{Code: apiErrorCode(status), Message: errMsg}})
+}
+
+// decodeApiBody decodes the json body, the empty body is allowed for the optional fields.
+func decodeApiBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+type apiJobHandler func(w http.ResponseWriter, r *http.Request, name string)
+
+type apiRoute struct {
+	role    auth.Role
+	handler apiJobHandler
+}
+
+// apiJobRoutes returns the routes of a job, the key is "${method} ${suffix}", the suffix is
+// empty for the job itself, ":${action}" for the actions and "/${resource}" for the sub
+// resources.
+func (s *HttpService) apiJobRoutes() map[string]apiRoute {
+	return map[string]apiRoute{
+		"GET ":                 {auth.RoleReadOnly, s.apiGetJob},
+		"PATCH ":               {auth.RoleOperator, s.apiUpdateJob},
+		"DELETE ":              {auth.RoleAdmin, s.apiDeleteJob},
+		"POST :pause":          {auth.RoleOperator, s.apiJobAction("pause", s.jobManager.Pause)},
+		"POST :resume":         {auth.RoleOperator, s.apiJobAction("resume", s.jobManager.Resume)},
+		"POST :force_fullsync": {auth.RoleOperator, s.apiJobAction("force fullsync", s.jobManager.ForceFullsync)},
+		"POST :desync":         {auth.RoleAdmin, s.apiJobAction("desync", s.jobManager.Desync)},
+		"POST :switchover":     {auth.RoleAdmin, s.apiSwitchover},
+		"POST :verify":         {auth.RoleOperator, s.apiVerify},
+		"GET /lag":             {auth.RoleReadOnly, s.apiGetLag},
+		"GET /progress":        {auth.RoleReadOnly, s.apiGetProgress},
+		"GET /detail":          {auth.RoleReadOnly, s.apiGetDetail},
+		"GET /schedule":        {auth.RoleReadOnly, s.apiGetSchedule},
+		"PUT /schedule":        {auth.RoleOperator, s.apiUpdateSchedule},
+		"GET /history":         {auth.RoleReadOnly, s.apiGetHistory},
+		"GET /verify_reports":  {auth.RoleReadOnly, s.apiGetVerifyReports},
+		"GET /dry_run_results": {auth.RoleReadOnly, s.apiGetDryRunResults},
+	}
+}
+
+func (s *HttpService) registerApiV1Handlers() {
+	routes := s.apiJobRoutes()
+	s.mux.Handle(apiV1Prefix+"/jobs", http.HandlerFunc(s.apiJobsHandler))
+	s.mux.Handle(apiV1JobsPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.apiJobHandler(routes, w, r)
+	}))
+	s.mux.Handle(apiV1Prefix+"/openapi.json", s.apiMiddleware(auth.RoleReadOnly, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapiDoc)
+	}))
+	s.mux.Handle(apiV1Prefix+"/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusNotFound, "unknown api "+r.URL.Path)
+	}))
+}
+
+func (s *HttpService) apiMiddleware(role auth.Role, handler http.HandlerFunc) http.Handler {
+	return s.authenticator.MiddlewareWithErrorWriter(role, handler, writeApiError)
+}
+
+// apiJobsHandler serves the collection of the jobs.
+func (s *HttpService) apiJobsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.apiMiddleware(auth.RoleReadOnly, s.apiListJobs).ServeHTTP(w, r)
+	case http.MethodPost:
+		s.apiMiddleware(auth.RoleAdmin, s.apiCreateJob).ServeHTTP(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeApiError(w, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed")
+	}
+}
+
+// apiJobHandler serves the job and its actions and sub resources.
+func (s *HttpService) apiJobHandler(routes map[string]apiRoute, w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), apiV1JobsPrefix)
+	name, suffix := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		name, suffix = path[:i], path[i:]
+	} else if i := strings.LastIndex(path, ":"); i >= 0 && hasApiSuffix(routes, path[i:]) {
+		// The job name might contain ':', so only the known actions are split.
+		name, suffix = path[:i], path[i:]
+	}
+
+	name, err := url.PathUnescape(name)
+	if err != nil || name == "" {
+		writeApiError(w, http.StatusBadRequest, "invalid job name")
+		return
+	}
+
+	route, ok := routes[r.Method+" "+suffix]
+	if !ok {
+		if allowed := allowedApiMethods(routes, suffix); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeApiError(w, http.StatusMethodNotAllowed, "method "+r.Method+" is not allowed")
+		} else {
+			writeApiError(w, http.StatusNotFound, "unknown api "+r.URL.Path)
+		}
+		return
+	}
+
+	s.apiMiddleware(route.role, func(w http.ResponseWriter, r *http.Request) {
+		if s.apiRedirect(name, w, r) {
+			return
+		}
+		route.handler(w, r, name)
+	}).ServeHTTP(w, r)
+}
+
+func hasApiSuffix(routes map[string]apiRoute, suffix string) bool {
+	return len(allowedApiMethods(routes, suffix)) > 0
+}
+
+func allowedApiMethods(routes map[string]apiRoute, suffix string) []string {
+	methods := make([]string, 0)
+	for key := range routes {
+		if method, routeSuffix, _ := strings.Cut(key, " "); routeSuffix == suffix {
+			methods = append(methods, method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// apiRedirect is the same as redirect, but responds the errors with the status codes.
+func (s *HttpService) apiRedirect(jobName string, w http.ResponseWriter, r *http.Request) bool {
+	if jobExist, err := s.db.IsJobExist(jobName); err != nil {
+		log.Warnf("get job %s exist failed: %+v, uri is %s", jobName, err, r.RequestURI)
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return true
+	} else if !jobExist {
+		writeApiError(w, http.StatusNotFound, "job "+jobName+" not exist")
+		return true
+	}
+
+	belongHost, err := s.db.GetJobBelong(jobName)
+	if err != nil {
+		log.Warnf("get job %s belong failed: %+v, uri is %s", jobName, err, r.RequestURI)
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return true
+	}
+
+	if belongHost == s.hostInfo {
+		return false
+	}
+
+	redirectUrl := redirectUrl(belongHost, r)
+	http.Redirect(w, r, redirectUrl, http.StatusTemporaryRedirect)
+	log.Infof("%s is located in syncer %s, the redirect url is %s", jobName, belongHost, redirectUrl)
+	return true
+}
+
+func (s *HttpService) apiListJobs(w http.ResponseWriter, r *http.Request) {
+	if jobs, err := s.listJobNames(); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, map[string]interface{}{
+			"jobs":     jobs,
+			"statuses": s.listJobStatuses(),
+		})
+	}
+}
+
+func (s *HttpService) apiCreateJob(w http.ResponseWriter, r *http.Request) {
+	var request CreateCcrRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Name == "" {
+		writeApiError(w, http.StatusBadRequest, "name is empty")
+		return
+	}
+
+	if exist, err := s.db.IsJobExist(request.Name); err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if exist {
+		writeApiError(w, http.StatusConflict, "job "+request.Name+" already exists")
+		return
+	}
+
+	if err := createCcr(&request, s.db, s.jobManager); err != nil {
+		log.Warnf("create ccr failed: %+v", err)
+		writeApiError(w, apiStatusOf(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Location", apiV1JobsPrefix+url.PathEscape(request.Name))
+	writeApiJson(w, http.StatusCreated, map[string]string{"name": request.Name})
+}
+
+func (s *HttpService) apiGetJob(w http.ResponseWriter, r *http.Request, name string) {
+	if jobStatus, err := s.jobManager.GetJobStatus(name); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, jobStatus)
+	}
+}
+
+func (s *HttpService) apiUpdateJob(w http.ResponseWriter, r *http.Request, name string) {
+	var request UpdateJobRequest
+	if err := decodeApiBody(r, &request); err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	request.Name = name
+
+	if err := s.updateJob(&request); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *HttpService) apiDeleteJob(w http.ResponseWriter, r *http.Request, name string) {
+	if err := s.jobManager.RemoveJob(name); err != nil {
+		log.Warnf("delete job failed: %+v", err)
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// apiJobAction returns the handler of the action without the request body and the result.
+func (s *HttpService) apiJobAction(action string, do func(name string) error) apiJobHandler {
+	return func(w http.ResponseWriter, r *http.Request, name string) {
+		log.Infof("%s job %s", action, name)
+		if err := do(name); err != nil {
+			log.Warnf("%s job failed: %+v", action, err)
+			writeApiError(w, apiStatusOf(err), err.Error())
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func (s *HttpService) apiSwitchover(w http.ResponseWriter, r *http.Request, name string) {
+	var request SwitchoverRequest
+	if err := decodeApiBody(r, &request); err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if switchover, err := s.jobManager.Switchover(name, request.ReverseName); err != nil {
+		log.Warnf("switchover job failed: %+v", err)
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, switchover)
+	}
+}
+
+func (s *HttpService) apiVerify(w http.ResponseWriter, r *http.Request, name string) {
+	var request VerifyRequest
+	if err := decodeApiBody(r, &request); err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if report, err := s.jobManager.Verify(name, request.Checksum); err != nil {
+		log.Warnf("verify job failed: %+v", err)
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, report)
+	}
+}
+
+func (s *HttpService) apiGetLag(w http.ResponseWriter, r *http.Request, name string) {
+	if lag, err := s.jobManager.GetLag(name); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, map[string]int64{"lag": lag})
+	}
+}
+
+func (s *HttpService) apiGetProgress(w http.ResponseWriter, r *http.Request, name string) {
+	if progress, err := s.db.GetProgress(name); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, json.RawMessage(progress))
+	}
+}
+
+func (s *HttpService) apiGetDetail(w http.ResponseWriter, r *http.Request, name string) {
+	jobDetail, err := s.db.GetJobInfo(name)
+	if err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+		return
+	}
+
+	// The passwords are never returned, even if they are encrypted.
+	if redacted, err := secret.RedactPasswords([]byte(jobDetail)); err != nil {
+		writeApiError(w, http.StatusInternalServerError, err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, json.RawMessage(redacted))
+	}
+}
+
+func (s *HttpService) apiGetSchedule(w http.ResponseWriter, r *http.Request, name string) {
+	if jobSchedule, err := s.jobManager.GetJobSchedule(name); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, jobSchedule)
+	}
+}
+
+// apiUpdateSchedule replaces the schedule of the job, a null or empty body removes it.
+func (s *HttpService) apiUpdateSchedule(w http.ResponseWriter, r *http.Request, name string) {
+	var jobSchedule *schedule.Schedule
+	if err := decodeApiBody(r, &jobSchedule); err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.jobManager.UpdateJobSchedule(name, jobSchedule); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// apiQueryInt returns the int value of the query parameter, zero if it is absent.
+func apiQueryInt(r *http.Request, key string) (int64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, xerror.Errorf(xerror.Normal, "invalid query parameter %s: %s", key, value)
+	}
+	return v, nil
+}
+
+// apiGetHistory accepts the query parameters types (comma separated), since, until and limit.
+func (s *HttpService) apiGetHistory(w http.ResponseWriter, r *http.Request, name string) {
+	filter := &storage.JobEventFilter{JobName: name}
+	if types := r.URL.Query().Get("types"); types != "" {
+		filter.Types = strings.Split(types, ",")
+	}
+	since, err := apiQueryInt(r, "since")
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := apiQueryInt(r, "until")
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := apiQueryInt(r, "limit")
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.Since, filter.Until, filter.Limit = since, until, int(limit)
+
+	if events, err := s.jobManager.GetJobHistory(filter); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, map[string]interface{}{"events": events})
+	}
+}
+
+func (s *HttpService) apiGetVerifyReports(w http.ResponseWriter, r *http.Request, name string) {
+	limit, err := apiQueryInt(r, "limit")
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if reports, err := s.getVerifyReports(name, int(limit)); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, map[string]interface{}{"reports": reports})
+	}
+}
+
+func (s *HttpService) apiGetDryRunResults(w http.ResponseWriter, r *http.Request, name string) {
+	commitSeq, err := apiQueryInt(r, "commit_seq")
+	if err != nil {
+		writeApiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if results, err := s.jobManager.GetDryRunResults(name, commitSeq); err != nil {
+		writeApiError(w, apiStatusOf(err), err.Error())
+	} else {
+		writeApiJson(w, http.StatusOK, map[string]interface{}{"results": results})
+	}
+}
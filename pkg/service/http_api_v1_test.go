@@ -0,0 +1,208 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/auth"
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// newApiTestService returns the service of testSyncer, with the jobs "db1" and "ns:job" owned by
+// it and "remote" owned by otherSyncer.
+func newApiTestService(t *testing.T) *HttpService {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	addTestJob(t, db, "db1", "db1", testSyncer)
+	addTestJob(t, db, "ns:job", "db2", testSyncer)
+	addTestJob(t, db, "remote", "db3", otherSyncer)
+	return NewHttpServer("127.0.0.1", 9190, db, ccr.NewJobManager(db, nil, testSyncer))
+}
+
+type apiTestResponse struct {
+	status int
+	header http.Header
+	error  apiError
+}
+
+func serveApi(t *testing.T, handler http.Handler, method, target string) apiTestResponse {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	resp := apiTestResponse{status: w.Code, header: w.Header()}
+	if w.Code >= http.StatusBadRequest {
+		var result apiErrorResult
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("%s %s: decode the error failed: %v", method, target, err)
+		}
+		resp.error = result.Error
+	}
+	return resp
+}
+
+func TestApiJobHandler_Routes(t *testing.T) {
+	s := newApiTestService(t)
+
+	var routed, routedName string
+	route := func(key string) apiRoute {
+		return apiRoute{auth.RoleReadOnly, func(w http.ResponseWriter, r *http.Request, name string) {
+			routed, routedName = key, name
+			w.WriteHeader(http.StatusNoContent)
+		}}
+	}
+	routes := map[string]apiRoute{
+		"GET ":        route("GET "),
+		"DELETE ":     route("DELETE "),
+		"POST :pause": route("POST :pause"),
+		"GET /lag":    route("GET /lag"),
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.apiJobHandler(routes, w, r)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		route  string
+		job    string
+		// The Allow header of 405, or the error code.
+		allow string
+		code  string
+	}{
+		{"get job", http.MethodGet, "/api/v1/jobs/db1", http.StatusNoContent, "GET ", "db1", "", ""},
+		{"delete job", http.MethodDelete, "/api/v1/jobs/db1", http.StatusNoContent, "DELETE ", "db1", "", ""},
+		{"action", http.MethodPost, "/api/v1/jobs/db1:pause", http.StatusNoContent, "POST :pause", "db1", "", ""},
+		{"sub resource", http.MethodGet, "/api/v1/jobs/db1/lag", http.StatusNoContent, "GET /lag", "db1", "", ""},
+		{"name with colon", http.MethodGet, "/api/v1/jobs/ns:job", http.StatusNoContent, "GET ", "ns:job", "", ""},
+		{"action of name with colon", http.MethodPost, "/api/v1/jobs/ns:job:pause", http.StatusNoContent, "POST :pause", "ns:job", "", ""},
+		{"escaped name", http.MethodGet, "/api/v1/jobs/ns%3Ajob/lag", http.StatusNoContent, "GET /lag", "ns:job", "", ""},
+		// The unknown action is a part of the name.
+		{"unknown action", http.MethodPost, "/api/v1/jobs/db1:unknown", http.StatusMethodNotAllowed, "", "", "DELETE, GET", "method_not_allowed"},
+		{"job not exist", http.MethodGet, "/api/v1/jobs/db1:unknown", http.StatusNotFound, "", "", "", "not_found"},
+		{"method not allowed", http.MethodPut, "/api/v1/jobs/db1", http.StatusMethodNotAllowed, "", "", "DELETE, GET", "method_not_allowed"},
+		{"action method not allowed", http.MethodGet, "/api/v1/jobs/db1:pause", http.StatusMethodNotAllowed, "", "", "POST", "method_not_allowed"},
+		{"unknown resource", http.MethodGet, "/api/v1/jobs/db1/unknown", http.StatusNotFound, "", "", "", "not_found"},
+		{"empty name", http.MethodGet, "/api/v1/jobs/", http.StatusBadRequest, "", "", "", "invalid_argument"},
+		{"empty name of action", http.MethodPost, "/api/v1/jobs/:pause", http.StatusBadRequest, "", "", "", "invalid_argument"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			routed, routedName = "", ""
+			resp := serveApi(t, handler, test.method, test.path)
+			if resp.status != test.status {
+				t.Fatalf("status = %d, expect %d, error: %+v", resp.status, test.status, resp.error)
+			}
+			if routed != test.route || routedName != test.job {
+				t.Errorf("routed to (%q, %q), expect (%q, %q)", routed, routedName, test.route, test.job)
+			}
+			if allow := resp.header.Get("Allow"); allow != test.allow {
+				t.Errorf("allow = %q, expect %q", allow, test.allow)
+			}
+			if resp.error.Code != test.code {
+				t.Errorf("error code = %q, expect %q", resp.error.Code, test.code)
+			}
+		})
+	}
+}
+
+// The requests of the job owned by the other syncer are redirected with 307, both the routes
+// and the unknown routes of the job are checked before redirecting.
+func TestApiRedirect(t *testing.T) {
+	s := newApiTestService(t)
+	s.registerApiV1Handlers()
+
+	tests := []struct {
+		method   string
+		path     string
+		status   int
+		location string
+	}{
+		{http.MethodGet, "/api/v1/jobs/remote", http.StatusTemporaryRedirect, "http://" + otherSyncer + "/api/v1/jobs/remote"},
+		{http.MethodPost, "/api/v1/jobs/remote:pause", http.StatusTemporaryRedirect, "http://" + otherSyncer + "/api/v1/jobs/remote:pause"},
+		{http.MethodGet, "/api/v1/jobs/remote/history?limit=10", http.StatusTemporaryRedirect, "http://" + otherSyncer + "/api/v1/jobs/remote/history?limit=10"},
+		{http.MethodPut, "/api/v1/jobs/remote", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/api/v1/jobs/missing", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/jobs/db1/detail", http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		resp := serveApi(t, s.mux, test.method, test.path)
+		if resp.status != test.status {
+			t.Errorf("%s %s: status = %d, expect %d, error: %+v", test.method, test.path, resp.status, test.status, resp.error)
+		}
+		if location := resp.header.Get("Location"); location != test.location {
+			t.Errorf("%s %s: location = %q, expect %q", test.method, test.path, location, test.location)
+		}
+	}
+}
+
+func TestApiV1_NotFoundAndMethodNotAllowed(t *testing.T) {
+	s := newApiTestService(t)
+	s.registerApiV1Handlers()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{http.MethodPut, "/api/v1/jobs", http.StatusMethodNotAllowed, "GET, POST"},
+		{http.MethodDelete, "/api/v1/jobs", http.StatusMethodNotAllowed, "GET, POST"},
+		{http.MethodGet, "/api/v1/unknown", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/jobs/db1/unknown", http.StatusNotFound, ""},
+		{http.MethodPost, "/api/v1/jobs/db1:unknown", http.StatusMethodNotAllowed, "DELETE, GET, PATCH"},
+	}
+	for _, test := range tests {
+		resp := serveApi(t, s.mux, test.method, test.path)
+		if resp.status != test.status {
+			t.Errorf("%s %s: status = %d, expect %d", test.method, test.path, resp.status, test.status)
+		}
+		if resp.error.Code != apiErrorCode(test.status) {
+			t.Errorf("%s %s: error code = %q, expect %q", test.method, test.path, resp.error.Code, apiErrorCode(test.status))
+		}
+		if allow := resp.header.Get("Allow"); allow != test.allow {
+			t.Errorf("%s %s: allow = %q, expect %q", test.method, test.path, allow, test.allow)
+		}
+	}
+}
+
+func TestApiStatusOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"job exists", storage.ErrJobExists, http.StatusConflict, "already_exists"},
+		{"wrapped job exists", xerror.Wrap(storage.ErrJobExists, xerror.DB, "add job"), http.StatusConflict, "already_exists"},
+		{"normal", xerror.New(xerror.Normal, "invalid request"), http.StatusBadRequest, "invalid_argument"},
+		{"db", xerror.New(xerror.DB, "meta db is down"), http.StatusInternalServerError, "internal"},
+		{"rpc", xerror.New(xerror.RPC, "rpc failed"), http.StatusBadGateway, "cluster_error"},
+		{"fe", xerror.New(xerror.FE, "fe failed"), http.StatusBadGateway, "cluster_error"},
+		{"be", xerror.New(xerror.BE, "be failed"), http.StatusBadGateway, "cluster_error"},
+		{"meta", xerror.New(xerror.Meta, "table not found"), http.StatusBadGateway, "cluster_error"},
+		{"not xerror", errors.New("unknown"), http.StatusInternalServerError, "internal"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := apiStatusOf(test.err)
+			if status != test.status {
+				t.Errorf("status = %d, expect %d", status, test.status)
+			}
+			if code := apiErrorCode(status); code != test.code {
+				t.Errorf("code = %s, expect %s", code, test.code)
+			}
+		})
+	}
+}
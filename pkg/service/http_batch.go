@@ -0,0 +1,340 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/label"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BATCH_FORWARD_TIMEOUT = 5 * time.Minute
+
+	// The batch request forwarded to the syncer owns the jobs, it is executed without selecting
+	// the jobs and forwarding again.
+	batchForwardedHeader = "X-Ccr-Batch-Forwarded"
+)
+
+// The batch operations select the jobs by the labels and the names, then fan out the request to
+// the syncers own the selected jobs.
+const (
+	batchPause         = "pause"
+	batchResume        = "resume"
+	batchDelete        = "delete"
+	batchForceFullsync = "force_fullsync"
+	batchUpdateJob     = "update_job"
+)
+
+type BatchRequest struct {
+	// Select the jobs by the labels, such as "env=prod,team!=ads", see pkg/label.
+	Selector string `json:"selector"`
+	// Select the jobs whose name matches the glob, such as "db1_*".
+	NameGlob string `json:"name_glob"`
+	// Select the jobs by the names.
+	Names []string `json:"names,omitempty"`
+	// The update of the batch update_job, the name is ignored.
+	Update *UpdateJobRequest `json:"update,omitempty"`
+}
+
+type BatchJobResult struct {
+	Name     string `json:"name"`
+	Syncer   string `json:"syncer"`
+	Success  bool   `json:"success"`
+	ErrorMsg string `json:"error_msg,omitempty"`
+}
+
+// selectJobs returns the selected jobs, grouped by the syncers own them.
+func (s *HttpService) selectJobs(request *BatchRequest) (map[string][]string, error) {
+	selector, err := label.ParseSelector(request.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if request.NameGlob != "" {
+		if _, err := path.Match(request.NameGlob, ""); err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "invalid name glob %q", request.NameGlob)
+		}
+	}
+	names := make(map[string]bool, len(request.Names))
+	for _, name := range request.Names {
+		names[name] = true
+	}
+
+	ans, err := s.db.GetAllData()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, eachJob := range ans["jobs"] {
+		// The format is "${job_name}, ${belong_to}", see GetAllData.
+		i := strings.LastIndex(eachJob, ", ")
+		if i < 0 {
+			continue
+		}
+		name, belong := eachJob[:i], eachJob[i+2:]
+
+		if len(names) > 0 && !names[name] {
+			continue
+		}
+		if request.NameGlob != "" {
+			if matched, _ := path.Match(request.NameGlob, name); !matched {
+				continue
+			}
+		}
+		if !selector.IsEmpty() {
+			labels, err := s.getJobLabels(name)
+			if err != nil {
+				return nil, err
+			}
+			if !selector.Matches(labels) {
+				continue
+			}
+		}
+		groups[belong] = append(groups[belong], name)
+	}
+	return groups, nil
+}
+
+// getJobLabels reads the labels from the job info, so the jobs of other syncers are selected too.
+func (s *HttpService) getJobLabels(jobName string) (map[string]string, error) {
+	jobInfo, err := s.db.GetJobInfo(jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	var job struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(jobInfo), &job); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "unmarshal job %s info failed", jobName)
+	}
+	return job.Labels, nil
+}
+
+func (s *HttpService) batchDo(action string, name string, update *UpdateJobRequest) error {
+	switch action {
+	case batchPause:
+		return s.jobManager.Pause(name)
+	case batchResume:
+		return s.jobManager.Resume(name)
+	case batchDelete:
+		return s.jobManager.RemoveJob(name)
+	case batchForceFullsync:
+		return s.jobManager.ForceFullsync(name)
+	case batchUpdateJob:
+		request := *update
+		request.Name = name
+		return s.updateJob(&request)
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown batch action %s", action)
+	}
+}
+
+// batchLocal executes the action on the jobs of this syncer one by one.
+func (s *HttpService) batchLocal(action string, names []string, update *UpdateJobRequest) []*BatchJobResult {
+	results := make([]*BatchJobResult, 0, len(names))
+	for _, name := range names {
+		result := &BatchJobResult{Name: name, Syncer: s.hostInfo, Success: true}
+		if err := s.batchDo(action, name, update); err != nil {
+			log.Warnf("batch %s job %s failed: %+v", action, name, err)
+			result.Success = false
+			result.ErrorMsg = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// newForwardHttpClient returns the client to forward the requests to the other syncers.
+func newForwardHttpClient(timeout time.Duration) (*http.Client, error) {
+	client := &http.Client{Timeout: timeout}
+	if options := PeerTLSOptions(); options != nil {
+		// The certificate of this syncer is sent, if the other syncers verify the client
+		// certificates.
+		config, err := options.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: config}
+	}
+	return client, nil
+}
+
+// newForwardRequest builds the POST request of the path to the syncer, the authorization of the
+// origin request is kept, so the request is authenticated by that syncer again.
+func newForwardRequest(syncer string, path string, body []byte, r *http.Request) (*http.Request, error) {
+	scheme := "http"
+	if tlsEnabled() {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, syncer, path)
+	forward, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "new request %s failed", url)
+	}
+	forward.Header.Set("Content-Type", "application/json")
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		forward.Header.Set("Authorization", authorization)
+	}
+	return forward, nil
+}
+
+// batchForward forwards the action on the names to the syncer owns them.
+func (s *HttpService) batchForward(client *http.Client, action string, syncer string, names []string,
+	update *UpdateJobRequest, r *http.Request) ([]*BatchJobResult, error) {
+	body, err := json.Marshal(&BatchRequest{Names: names, Update: update})
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.Normal, "marshal batch request failed")
+	}
+
+	forward, err := newForwardRequest(syncer, "/batch_"+action, body, r)
+	if err != nil {
+		return nil, err
+	}
+	forward.Header.Set(batchForwardedHeader, s.hostInfo)
+
+	resp, err := client.Do(forward)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "forward batch %s to syncer %s failed", action, syncer)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrorMsg string            `json:"error_msg"`
+		Results  []*BatchJobResult `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "decode batch %s result of syncer %s failed, status: %s",
+			action, syncer, resp.Status)
+	}
+	if result.Results == nil {
+		return nil, xerror.Errorf(xerror.Normal, "batch %s in syncer %s failed, status: %s, error: %s",
+			action, syncer, resp.Status, result.ErrorMsg)
+	}
+	return result.Results, nil
+}
+
+// batch executes the action on the selected jobs, the jobs of this syncer are executed locally
+// and the others are forwarded to their syncers concurrently.
+func (s *HttpService) batch(action string, request *BatchRequest, r *http.Request) ([]*BatchJobResult, error) {
+	groups, err := s.selectJobs(request)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newForwardHttpClient(BATCH_FORWARD_TIMEOUT)
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := make([]*BatchJobResult, 0)
+	for syncer, names := range groups {
+		wg.Add(1)
+		go func(syncer string, names []string) {
+			defer wg.Done()
+
+			var syncerResults []*BatchJobResult
+			if syncer == s.hostInfo {
+				syncerResults = s.batchLocal(action, names, request.Update)
+			} else if forwarded, err := s.batchForward(client, action, syncer, names, request.Update, r); err != nil {
+				log.Warnf("batch %s jobs %v in syncer %s failed: %+v", action, names, syncer, err)
+				for _, name := range names {
+					syncerResults = append(syncerResults, &BatchJobResult{
+						Name:     name,
+						Syncer:   syncer,
+						ErrorMsg: err.Error(),
+					})
+				}
+			} else {
+				syncerResults = forwarded
+			}
+
+			mu.Lock()
+			results = append(results, syncerResults...)
+			mu.Unlock()
+		}(syncer, names)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results, nil
+}
+
+func (s *HttpService) batchHandler(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Infof("batch %s jobs", action)
+
+		type result struct {
+			*defaultResult
+			Results []*BatchJobResult `json:"results"`
+		}
+		var batchResult *result
+		defer func() { writeJson(w, batchResult) }()
+
+		// Parse the JSON request body
+		var request BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			log.Warnf("batch %s jobs failed: %+v", action, err)
+
+			batchResult = &result{defaultResult: newErrorResult(err.Error())}
+			return
+		}
+
+		// Avoid operating all jobs by mistake.
+		if request.Selector == "" && request.NameGlob == "" && len(request.Names) == 0 {
+			log.Warnf("batch %s jobs failed: selector, name_glob and names are all empty", action)
+
+			batchResult = &result{defaultResult: newErrorResult("selector, name_glob and names are all empty")}
+			return
+		}
+		if action == batchUpdateJob && request.Update == nil {
+			log.Warnf("batch %s jobs failed: update is empty", action)
+
+			batchResult = &result{defaultResult: newErrorResult("update is empty")}
+			return
+		}
+
+		var results []*BatchJobResult
+		if forwardedBy := r.Header.Get(batchForwardedHeader); forwardedBy != "" {
+			log.Infof("batch %s jobs %v forwarded by syncer %s", action, request.Names, forwardedBy)
+			results = s.batchLocal(action, request.Names, request.Update)
+		} else if batchResults, err := s.batch(action, &request, r); err != nil {
+			log.Warnf("batch %s jobs failed: %+v", action, err)
+
+			batchResult = &result{defaultResult: newErrorResult(err.Error())}
+			return
+		} else {
+			results = batchResults
+		}
+
+		failed := 0
+		for _, jobResult := range results {
+			if !jobResult.Success {
+				failed++
+			}
+		}
+		if failed > 0 {
+			batchResult = &result{
+				defaultResult: newErrorResult(fmt.Sprintf("%d of %d jobs failed", failed, len(results))),
+				Results:       results,
+			}
+		} else {
+			batchResult = &result{
+				defaultResult: newSuccessResult(),
+				Results:       results,
+			}
+		}
+	}
+}
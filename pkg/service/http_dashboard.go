@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/auth"
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const DASHBOARD_FORWARD_TIMEOUT = 30 * time.Second
+
+// The single page dashboard, it only contains the static files, the data is loaded by the
+// authenticated apis, so the page itself is served without authentication.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+type DashboardSyncer struct {
+	Host string `json:"host"`
+	// The last heartbeat in the syncers table, in unix seconds.
+	LastHeartbeat int64 `json:"last_heartbeat"`
+	// The error of getting the job status from the syncer.
+	ErrorMsg string `json:"error_msg,omitempty"`
+}
+
+type DashboardJob struct {
+	Name   string `json:"name"`
+	Syncer string `json:"syncer"`
+	// Nil if the syncer is unreachable.
+	Status *ccr.JobStatus `json:"status,omitempty"`
+}
+
+// fetchJobStatuses gets the status of the jobs running in the syncer by /list_jobs.
+func (s *HttpService) fetchJobStatuses(client *http.Client, syncer string, r *http.Request) ([]*ccr.JobStatus, error) {
+	if syncer == s.hostInfo {
+		return s.listJobStatuses(), nil
+	}
+
+	forward, err := newForwardRequest(syncer, "/list_jobs", []byte("{}"), r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(forward)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "list jobs of syncer %s failed", syncer)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success  bool             `json:"success"`
+		ErrorMsg string           `json:"error_msg"`
+		Statuses []*ccr.JobStatus `json:"statuses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "decode jobs of syncer %s failed, status: %s", syncer, resp.Status)
+	}
+	if !result.Success {
+		return nil, xerror.Errorf(xerror.Normal, "list jobs of syncer %s failed, status: %s, error: %s",
+			syncer, resp.Status, result.ErrorMsg)
+	}
+	return result.Statuses, nil
+}
+
+// dashboardJobs lists the jobs of all syncers in the cluster, the status is fetched from the
+// syncer owns the job concurrently.
+func (s *HttpService) dashboardJobs(r *http.Request) ([]*DashboardSyncer, []*DashboardJob, error) {
+	ans, err := s.db.GetAllData()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncers := make(map[string]*DashboardSyncer)
+	for _, row := range ans["syncers"] {
+		host, timestamp, ok := splitAllDataRow(row)
+		if !ok {
+			continue
+		}
+		// The timestamp is refreshed in unix nanoseconds, see Checker.
+		nanos, _ := strconv.ParseInt(timestamp, 10, 64)
+		syncers[host] = &DashboardSyncer{Host: host, LastHeartbeat: nanos / int64(time.Second)}
+	}
+	jobs := make([]*DashboardJob, 0, len(ans["jobs"]))
+	for _, row := range ans["jobs"] {
+		name, belong, ok := splitAllDataRow(row)
+		if !ok {
+			continue
+		}
+		jobs = append(jobs, &DashboardJob{Name: name, Syncer: belong})
+		if _, ok := syncers[belong]; !ok {
+			// The syncer is dead and the job is not rebalanced yet.
+			syncers[belong] = &DashboardSyncer{Host: belong}
+		}
+	}
+
+	client, err := newForwardHttpClient(DASHBOARD_FORWARD_TIMEOUT)
+	if err != nil {
+		return nil, nil, err
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	statuses := make(map[string]*ccr.JobStatus)
+	for _, syncer := range syncers {
+		wg.Add(1)
+		go func(syncer *DashboardSyncer) {
+			defer wg.Done()
+
+			syncerStatuses, err := s.fetchJobStatuses(client, syncer.Host, r)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Warnf("dashboard get the jobs of syncer %s failed: %+v", syncer.Host, err)
+				syncer.ErrorMsg = err.Error()
+				return
+			}
+			for _, status := range syncerStatuses {
+				statuses[status.Name] = status
+			}
+		}(syncer)
+	}
+	wg.Wait()
+
+	for _, job := range jobs {
+		job.Status = statuses[job.Name]
+	}
+	syncerList := make([]*DashboardSyncer, 0, len(syncers))
+	for _, syncer := range syncers {
+		syncerList = append(syncerList, syncer)
+	}
+	sort.Slice(syncerList, func(i, j int) bool { return syncerList[i].Host < syncerList[j].Host })
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return syncerList, jobs, nil
+}
+
+func (s *HttpService) dashboardJobsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("dashboard list jobs")
+
+	type result struct {
+		*defaultResult
+		Syncers []*DashboardSyncer `json:"syncers,omitempty"`
+		Jobs    []*DashboardJob    `json:"jobs,omitempty"`
+	}
+	var jobsResult *result
+	defer func() { writeJson(w, jobsResult) }()
+
+	if syncers, jobs, err := s.dashboardJobs(r); err != nil {
+		log.Warnf("dashboard list jobs failed: %+v", err)
+		jobsResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		jobsResult = &result{
+			defaultResult: newSuccessResult(),
+			Syncers:       syncers,
+			Jobs:          jobs,
+		}
+	}
+}
+
+// dashboardActionHandler executes the handler in the syncer owns the job. The browser doesn't
+// keep the body of the POST request when it follows the redirect, so the request of the job in
+// other syncers is forwarded by this syncer instead.
+func (s *HttpService) dashboardActionHandler(path string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		var request CcrCommonRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		if request.Name == "" {
+			writeJson(w, newErrorResult("name is empty"))
+			return
+		}
+
+		belong, err := s.db.GetJobBelong(request.Name)
+		if err != nil {
+			log.Warnf("dashboard %s job %s failed: %+v", path, request.Name, err)
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		if belong == s.hostInfo {
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			handler(w, r)
+			return
+		}
+
+		log.Infof("dashboard forwards %s of job %s to syncer %s", path, request.Name, belong)
+		client, err := newForwardHttpClient(DASHBOARD_FORWARD_TIMEOUT)
+		if err != nil {
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		forward, err := newForwardRequest(belong, path, body, r)
+		if err != nil {
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		resp, err := client.Do(forward)
+		if err != nil {
+			log.Warnf("dashboard forwards %s of job %s to syncer %s failed: %+v", path, request.Name, belong, err)
+			writeJson(w, newErrorResult(err.Error()))
+			return
+		}
+		defer resp.Body.Close()
+
+		w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}
+}
+
+func (s *HttpService) registerDashboardHandlers() {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		// The embedded directory always exists.
+		panic(err)
+	}
+	s.mux.Handle("/dashboard/", http.StripPrefix("/dashboard/", http.FileServer(http.FS(files))))
+
+	s.handle("/dashboard/api/jobs", auth.RoleReadOnly, http.HandlerFunc(s.dashboardJobsHandler))
+	s.handle("/dashboard/api/pause", auth.RoleOperator, s.dashboardActionHandler("/pause", s.pauseHandler))
+	s.handle("/dashboard/api/resume", auth.RoleOperator, s.dashboardActionHandler("/resume", s.resumeHandler))
+	s.handle("/dashboard/api/force_fullsync", auth.RoleOperator,
+		s.dashboardActionHandler("/force_fullsync", s.forceFullsyncHandler))
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+)
+
+// deadSyncer is the syncer left in the db, nothing is listening on it.
+const deadSyncer = "127.0.0.1:1"
+
+type dashboardTestCluster struct {
+	db storage.DB
+	// The url of the syncer serving the dashboard.
+	url         string
+	hostInfo    string
+	otherServer string
+}
+
+// newDashboardTestCluster starts two syncers sharing the db, the job "local" is owned by the
+// syncer serving the dashboard, "remote" by the other one and "orphan" by deadSyncer.
+func newDashboardTestCluster(t *testing.T) *dashboardTestCluster {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	server, hostInfo := newAuthTestServer(t, db)
+	_, otherHostInfo := newAuthTestServer(t, db)
+	for _, syncer := range []string{hostInfo, otherHostInfo} {
+		if err := db.AddSyncer(syncer); err != nil {
+			t.Fatalf("add syncer %s failed: %v", syncer, err)
+		}
+	}
+	addTestJob(t, db, "local", "db1", hostInfo)
+	addTestJob(t, db, "remote", "db2", otherHostInfo)
+	addTestJob(t, db, "orphan", "db3", deadSyncer)
+	return &dashboardTestCluster{db: db, url: server.URL, hostInfo: hostInfo, otherServer: otherHostInfo}
+}
+
+// do sends the request with the token without following the redirects, it returns the status
+// and the body.
+func (c *dashboardTestCluster) do(t *testing.T, method, path, token, body string) (int, []byte) {
+	request, err := http.NewRequest(method, c.url+path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("new request failed: %v", err)
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: read body failed: %v", method, path, err)
+	}
+	return resp.StatusCode, data
+}
+
+// The static files are served without the token.
+func TestDashboard_StaticFiles(t *testing.T) {
+	c := newDashboardTestCluster(t)
+
+	tests := []struct {
+		path   string
+		status int
+		// The content of the file, empty if not checked.
+		contains string
+	}{
+		{"/dashboard/", http.StatusOK, "<title>CCR Syncer</title>"},
+		{"/dashboard/index.html", http.StatusMovedPermanently, ""},
+		{"/dashboard/app.js", http.StatusOK, "/dashboard/api/jobs"},
+		{"/dashboard/style.css", http.StatusOK, ""},
+		{"/dashboard/unknown.js", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		status, body := c.do(t, http.MethodGet, test.path, "", "")
+		if status != test.status {
+			t.Errorf("%s: status = %d, expect %d", test.path, status, test.status)
+		}
+		if !strings.Contains(string(body), test.contains) {
+			t.Errorf("%s: body does not contain %q", test.path, test.contains)
+		}
+	}
+}
+
+// The apis are authenticated, the actions require the operator role.
+func TestDashboard_Auth(t *testing.T) {
+	c := newDashboardTestCluster(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"jobs without token", "/dashboard/api/jobs", "", http.StatusUnauthorized},
+		{"jobs with unknown token", "/dashboard/api/jobs", "unknown", http.StatusUnauthorized},
+		{"jobs with read only token", "/dashboard/api/jobs", testReadOnlyToken, http.StatusOK},
+		{"pause without token", "/dashboard/api/pause", "", http.StatusUnauthorized},
+		{"pause with read only token", "/dashboard/api/pause", testReadOnlyToken, http.StatusForbidden},
+		{"resume with read only token", "/dashboard/api/resume", testReadOnlyToken, http.StatusForbidden},
+		{"force fullsync with read only token", "/dashboard/api/force_fullsync", testReadOnlyToken, http.StatusForbidden},
+		{"pause with operator token", "/dashboard/api/pause", testOperatorToken, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status, body := c.do(t, http.MethodPost, test.path, test.token, `{"name":"local"}`); status != test.status {
+				t.Errorf("status = %d, expect %d, body: %s", status, test.status, body)
+			}
+		})
+	}
+}
+
+// The jobs of all syncers are listed, the unreachable syncer is reported.
+func TestDashboard_Jobs(t *testing.T) {
+	c := newDashboardTestCluster(t)
+
+	status, body := c.do(t, http.MethodGet, "/dashboard/api/jobs", testReadOnlyToken, "")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, body: %s", status, body)
+	}
+	var result struct {
+		Success bool               `json:"success"`
+		Syncers []*DashboardSyncer `json:"syncers"`
+		Jobs    []*DashboardJob    `json:"jobs"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("decode the result failed: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("list jobs failed: %s", body)
+	}
+
+	syncers := make(map[string]*DashboardSyncer)
+	for _, syncer := range result.Syncers {
+		syncers[syncer.Host] = syncer
+	}
+	if len(syncers) != 3 {
+		t.Fatalf("syncers = %s, expect 3", body)
+	}
+	// The other syncer is asked with the forwarded token.
+	for _, host := range []string{c.hostInfo, c.otherServer} {
+		if syncer := syncers[host]; syncer == nil || syncer.LastHeartbeat == 0 || syncer.ErrorMsg != "" {
+			t.Errorf("syncer %s = %+v, expect alive", host, syncer)
+		}
+	}
+	if syncer := syncers[deadSyncer]; syncer == nil || syncer.LastHeartbeat != 0 || syncer.ErrorMsg == "" {
+		t.Errorf("syncer %s = %+v, expect unreachable", deadSyncer, syncer)
+	}
+
+	expect := []DashboardJob{{Name: "local", Syncer: c.hostInfo}, {Name: "orphan", Syncer: deadSyncer},
+		{Name: "remote", Syncer: c.otherServer}}
+	if len(result.Jobs) != len(expect) {
+		t.Fatalf("jobs = %s, expect %d jobs", body, len(expect))
+	}
+	for i, job := range result.Jobs {
+		// No job is running in the syncers, so there is no status.
+		if job.Name != expect[i].Name || job.Syncer != expect[i].Syncer || job.Status != nil {
+			t.Errorf("job %d = %+v, expect %+v", i, job, expect[i])
+		}
+	}
+}
+
+// The action of the job is executed in the syncer owns it, without redirecting the browser.
+func TestDashboard_Action(t *testing.T) {
+	c := newDashboardTestCluster(t)
+
+	tests := []struct {
+		name string
+		body string
+		// The error message of the result.
+		errMsg string
+	}{
+		{"local job", `{"name":"local"}`, "job not exist: local"},
+		{"remote job", `{"name":"remote"}`, "job not exist: remote"},
+		{"unreachable syncer", `{"name":"orphan"}`, deadSyncer},
+		{"unknown job", `{"name":"unknown"}`, "unknown"},
+		{"empty name", `{}`, "name is empty"},
+		{"invalid body", `{`, "unexpected end of JSON input"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The request redirected by the handler of the action is not followed.
+			status, body := c.do(t, http.MethodPost, "/dashboard/api/pause", testOperatorToken, test.body)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d, expect %d, body: %s", status, http.StatusOK, body)
+			}
+			var result defaultResult
+			if err := json.Unmarshal(body, &result); err != nil {
+				t.Fatalf("decode the result failed: %v, body: %s", err, body)
+			}
+			if result.Success || !strings.Contains(result.ErrorMsg, test.errMsg) {
+				t.Errorf("result = %+v, expect the error %q", result, test.errMsg)
+			}
+		})
+	}
+}
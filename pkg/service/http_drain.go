@@ -0,0 +1,105 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	log "github.com/sirupsen/logrus"
+)
+
+type DrainRequest struct {
+	// The host info of the syncer, this syncer if it is empty.
+	Syncer string `json:"syncer,omitempty"`
+	// Cancel the drain, the pending handoffs of the syncer are removed.
+	Cancel bool `json:"cancel,omitempty"`
+}
+
+// countSyncerJobs returns the number of the jobs belong to the syncer.
+func (s *HttpService) countSyncerJobs(hostInfo string) (int, error) {
+	ans, err := s.db.GetAllData()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, row := range ans["jobs"] {
+		if _, belong, ok := splitAllDataRow(row); ok && belong == hostInfo {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *HttpService) cancelDrain(hostInfo string) error {
+	if err := s.db.DrainSyncer(hostInfo, false); err != nil {
+		return err
+	}
+
+	handoffs, err := s.db.GetJobHandoffs(hostInfo)
+	if err != nil {
+		return err
+	}
+	for _, handoff := range handoffs {
+		if err := s.db.RemoveJobHandoff(handoff.JobName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainHandler hands off all jobs of the syncer to the other live syncers gracefully, the
+// syncer accepts no jobs until it is restarted or the drain is canceled. The jobs are moved
+// asynchronously, poll it until the remaining jobs is 0.
+func (s *HttpService) drainHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("drain syncer")
+
+	type result struct {
+		*defaultResult
+		Syncer   string                `json:"syncer,omitempty"`
+		Handoffs []*storage.JobHandoff `json:"handoffs,omitempty"`
+		// The number of the jobs still belong to the syncer.
+		RemainingJobs int `json:"remaining_jobs"`
+	}
+	var drainResult *result
+	defer func() { writeJson(w, drainResult) }()
+
+	var request DrainRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Warnf("drain syncer failed: %+v", err)
+		drainResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+	if request.Syncer == "" {
+		request.Syncer = s.hostInfo
+	}
+
+	var handoffs []*storage.JobHandoff
+	var err error
+	if request.Cancel {
+		log.Infof("cancel draining syncer %s", request.Syncer)
+		err = s.cancelDrain(request.Syncer)
+	} else {
+		log.Infof("drain syncer %s", request.Syncer)
+		handoffs, err = ccr.DrainSyncer(s.db, request.Syncer)
+	}
+	if err != nil {
+		log.Warnf("drain syncer %s failed: %+v", request.Syncer, err)
+		drainResult = &result{defaultResult: newErrorResult(err.Error()), Syncer: request.Syncer}
+		return
+	}
+
+	remaining, err := s.countSyncerJobs(request.Syncer)
+	if err != nil {
+		log.Warnf("count the jobs of syncer %s failed: %+v", request.Syncer, err)
+		drainResult = &result{defaultResult: newErrorResult(err.Error()), Syncer: request.Syncer}
+		return
+	}
+	drainResult = &result{
+		defaultResult: newSuccessResult(),
+		Syncer:        request.Syncer,
+		Handoffs:      handoffs,
+		RemainingJobs: remaining,
+	}
+}
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/jobconfig"
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/sink"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var flagJobConfigFile string
+
+func init() {
+	flag.StringVar(&flagJobConfigFile, "job_config_file", "",
+		"The YAML or JSON file of the desired jobs, which are reconciled on startup and SIGHUP")
+}
+
+// The actions of the job config plan.
+const (
+	planCreate = "create"
+	planUpdate = "update"
+	planDelete = "delete"
+	// The job is changed in the fields which could not be updated, it is not reconciled.
+	planConflict = "conflict"
+)
+
+type JobPlanAction struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// The syncer owns the job, empty for the job to create.
+	Syncer string `json:"syncer,omitempty"`
+	// The changed fields of the update or the conflict.
+	Changes []string `json:"changes,omitempty"`
+
+	create   *CreateCcrRequest
+	update   *UpdateJobRequest
+	schedule *schedule.Schedule
+}
+
+// JobPlan is the difference between the job config and the jobs in the meta db.
+type JobPlan struct {
+	Prune     bool             `json:"prune"`
+	Actions   []*JobPlanAction `json:"actions"`
+	Unchanged []string         `json:"unchanged,omitempty"`
+}
+
+// configuredJob is the fields of the persisted job compared with the job config.
+type configuredJob struct {
+	Src         base.Spec          `json:"src"`
+	Dest        base.Spec          `json:"dest"`
+	SkipError   bool               `json:"skip_error"`
+	TableFilter *ccr.TableFilter   `json:"table_filter"`
+	Sink        *sink.Config       `json:"sink"`
+	Dests       []base.Spec        `json:"dests"`
+	Schedule    *schedule.Schedule `json:"schedule"`
+	RateLimit   *ccr.RateLimit     `json:"rate_limit"`
+	DryRun      bool               `json:"dry_run"`
+	Verify      *ccr.VerifyConfig  `json:"verify"`
+	StopAt      *ccr.StopTarget    `json:"stop_at"`
+	Labels      map[string]string  `json:"labels"`
+}
+
+// jsonEqual compares the values by their JSON.
+func jsonEqual(a, b any) bool {
+	aData, _ := json.Marshal(a)
+	bData, _ := json.Marshal(b)
+	return bytes.Equal(aData, bData)
+}
+
+// specChanged compares the fields of the spec set by user, the frontend might be switched to
+// the current master.
+func specChanged(current, desired *base.Spec) bool {
+	return current.User != desired.User || current.Password != desired.Password ||
+		current.Cluster != desired.Cluster || current.Database != desired.Database ||
+		current.Table != desired.Table
+}
+
+// diffJob returns the action to reconcile the current job to the desired, nil if unchanged.
+func diffJob(name string, current *configuredJob, desired *CreateCcrRequest) *JobPlanAction {
+	var conflicts []string
+	if specChanged(&current.Src, &desired.Src) {
+		conflicts = append(conflicts, "src")
+	}
+	if specChanged(&current.Dest, &desired.Dest) {
+		conflicts = append(conflicts, "dest")
+	}
+	if len(current.Dests) != len(desired.Dests) {
+		conflicts = append(conflicts, "dests")
+	} else {
+		for i := range current.Dests {
+			if specChanged(&current.Dests[i], &desired.Dests[i]) {
+				conflicts = append(conflicts, "dests")
+				break
+			}
+		}
+	}
+	var includeTables, excludeTables []string
+	if current.TableFilter != nil {
+		includeTables, excludeTables = current.TableFilter.IncludeTables, current.TableFilter.ExcludeTables
+	}
+	if !jsonEqual(includeTables, desired.IncludeTables) || !jsonEqual(excludeTables, desired.ExcludeTables) {
+		conflicts = append(conflicts, "include_tables/exclude_tables")
+	}
+	if current.Sink.IsDoris() != desired.Sink.IsDoris() || (!current.Sink.IsDoris() && !jsonEqual(current.Sink, desired.Sink)) {
+		conflicts = append(conflicts, "sink")
+	}
+	if current.DryRun != desired.DryRun {
+		conflicts = append(conflicts, "dry_run")
+	}
+	if len(conflicts) > 0 {
+		return &JobPlanAction{Name: name, Action: planConflict, Changes: conflicts}
+	}
+
+	// The skip_error is always set, so it is not reset by the absent fields, see updateJob.
+	update := &UpdateJobRequest{Name: name, SkipError: &desired.SkipError}
+	var changes []string
+	if current.SkipError != desired.SkipError {
+		changes = append(changes, "skip_error")
+	}
+	if desired.RateLimit.IsEmpty() != current.RateLimit.IsEmpty() ||
+		(!desired.RateLimit.IsEmpty() && !jsonEqual(current.RateLimit, desired.RateLimit)) {
+		changes = append(changes, "rate_limit")
+		update.RateLimit = &ccr.RateLimit{}
+		if desired.RateLimit != nil {
+			update.RateLimit = desired.RateLimit
+		}
+	}
+	if desired.Verify.IsEmpty() != current.Verify.IsEmpty() ||
+		(!desired.Verify.IsEmpty() && !jsonEqual(current.Verify, desired.Verify)) {
+		changes = append(changes, "verify")
+		update.Verify = &ccr.VerifyConfig{}
+		if desired.Verify != nil {
+			update.Verify = desired.Verify
+		}
+	}
+	if desired.StopAt.IsEmpty() != current.StopAt.IsEmpty() ||
+		(!desired.StopAt.IsEmpty() && !jsonEqual(current.StopAt, desired.StopAt)) {
+		changes = append(changes, "stop_at")
+		update.StopAt = &ccr.StopTarget{}
+		if desired.StopAt != nil {
+			update.StopAt = desired.StopAt
+		}
+	}
+	if len(current.Labels) != len(desired.Labels) || (len(desired.Labels) > 0 && !reflect.DeepEqual(current.Labels, desired.Labels)) {
+		changes = append(changes, "labels")
+		update.Labels = map[string]string{}
+		if desired.Labels != nil {
+			update.Labels = desired.Labels
+		}
+	}
+	scheduleChanged := !jsonEqual(current.Schedule, desired.Schedule)
+	if scheduleChanged {
+		changes = append(changes, "schedule")
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	action := &JobPlanAction{Name: name, Action: planUpdate, Changes: changes, update: update}
+	if scheduleChanged {
+		// A nil schedule removes it.
+		action.schedule = desired.Schedule
+		if action.schedule == nil {
+			action.schedule = &schedule.Schedule{}
+		}
+	}
+	return action
+}
+
+// planJobConfig compares the job config with the jobs of all syncers in the meta db.
+func (s *HttpService) planJobConfig(config *jobconfig.Config) (*JobPlan, error) {
+	ans, err := s.db.GetAllData()
+	if err != nil {
+		return nil, err
+	}
+	belongs := make(map[string]string)
+	for _, row := range ans["jobs"] {
+		if name, belong, ok := splitAllDataRow(row); ok {
+			belongs[name] = belong
+		}
+	}
+
+	plan := &JobPlan{Prune: config.Prune, Actions: make([]*JobPlanAction, 0)}
+	desired := make(map[string]bool, len(config.Jobs))
+	for _, job := range config.Jobs {
+		desired[job.Name] = true
+
+		var request CreateCcrRequest
+		decoder := json.NewDecoder(bytes.NewReader(job.Data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "invalid job %s in job config", job.Name)
+		}
+
+		belong, ok := belongs[job.Name]
+		if !ok {
+			plan.Actions = append(plan.Actions, &JobPlanAction{Name: job.Name, Action: planCreate, create: &request})
+			continue
+		}
+
+		jobInfo, err := s.db.GetJobInfo(job.Name)
+		if err != nil {
+			return nil, err
+		}
+		var current configuredJob
+		if err := json.Unmarshal([]byte(jobInfo), &current); err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "unmarshal job %s info failed", job.Name)
+		}
+		if action := diffJob(job.Name, &current, &request); action != nil {
+			action.Syncer = belong
+			plan.Actions = append(plan.Actions, action)
+		} else {
+			plan.Unchanged = append(plan.Unchanged, job.Name)
+		}
+	}
+
+	if config.Prune {
+		for name, belong := range belongs {
+			if !desired[name] {
+				plan.Actions = append(plan.Actions, &JobPlanAction{Name: name, Action: planDelete, Syncer: belong})
+			}
+		}
+	}
+
+	sort.Slice(plan.Actions, func(i, j int) bool { return plan.Actions[i].Name < plan.Actions[j].Name })
+	sort.Strings(plan.Unchanged)
+	return plan, nil
+}
+
+func loadJobConfig() (*jobconfig.Config, error) {
+	if flagJobConfigFile == "" {
+		return nil, xerror.New(xerror.Normal, "job_config_file is not set")
+	}
+	return jobconfig.Load(flagJobConfigFile)
+}
+
+// applyJobPlanAction applies the action, the jobs are updated and deleted by the syncer owns
+// them, each syncer reconciles the same job config.
+func (s *HttpService) applyJobPlanAction(action *JobPlanAction) error {
+	if action.Syncer != "" && action.Syncer != s.hostInfo {
+		log.Infof("skip to %s job %s, it is reconciled by syncer %s", action.Action, action.Name, action.Syncer)
+		return nil
+	}
+
+	log.Infof("reconcile job config, %s job %s, changes: %v", action.Action, action.Name, action.Changes)
+	switch action.Action {
+	case planCreate:
+		// All syncers create the jobs of the config, the job is created by the first one.
+		if exists, err := s.db.IsJobExist(action.Name); err != nil {
+			return err
+		} else if exists {
+			log.Infof("skip to create job %s, it is created by the other syncer", action.Name)
+			return nil
+		}
+		err := createCcr(action.create, s.db, s.jobManager)
+		if errors.Is(err, storage.ErrJobExists) {
+			log.Infof("skip to create job %s, it is created by the other syncer", action.Name)
+			return nil
+		}
+		return err
+	case planUpdate:
+		if err := s.updateJob(action.update); err != nil {
+			return err
+		}
+		if action.schedule != nil {
+			return s.jobManager.UpdateJobSchedule(action.Name, action.schedule)
+		}
+		return nil
+	case planDelete:
+		return s.jobManager.RemoveJob(action.Name)
+	case planConflict:
+		log.Warnf("job %s is changed in %v which could not be updated, please recreate it", action.Name, action.Changes)
+		return nil
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown job plan action %s", action.Action)
+	}
+}
+
+// ReconcileJobConfig creates, updates and deletes the jobs to match the job config file, it
+// does nothing if the job_config_file is not set.
+func (s *HttpService) ReconcileJobConfig() error {
+	if flagJobConfigFile == "" {
+		return nil
+	}
+
+	s.reconcileLock.Lock()
+	defer s.reconcileLock.Unlock()
+
+	config, err := loadJobConfig()
+	if err != nil {
+		return err
+	}
+	plan, err := s.planJobConfig(config)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, action := range plan.Actions {
+		if err := s.applyJobPlanAction(action); err != nil {
+			log.Warnf("reconcile job config, %s job %s failed: %+v", action.Action, action.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return xerror.Errorf(xerror.Normal, "%d of %d job config actions failed", failed, len(plan.Actions))
+	}
+	log.Infof("reconcile job config %s done, %d actions, %d jobs unchanged",
+		flagJobConfigFile, len(plan.Actions), len(plan.Unchanged))
+	return nil
+}
+
+// planHandler reports the difference between the job config file and the jobs, without
+// applying it.
+func (s *HttpService) planHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("plan job config")
+
+	type result struct {
+		*defaultResult
+		Plan *JobPlan `json:"plan,omitempty"`
+	}
+	var planResult *result
+	defer func() { writeJson(w, planResult) }()
+
+	config, err := loadJobConfig()
+	if err != nil {
+		log.Warnf("plan job config failed: %+v", err)
+		planResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+	if plan, err := s.planJobConfig(config); err != nil {
+		log.Warnf("plan job config failed: %+v", err)
+		planResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		planResult = &result{
+			defaultResult: newSuccessResult(),
+			Plan:          plan,
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"go.uber.org/mock/gomock"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	testSyncer  = "127.0.0.1:9190"
+	otherSyncer = "127.0.0.1:9191"
+)
+
+func init() {
+	log.SetOutput(io.Discard)
+}
+
+const testJobConfig = `
+prune: true
+templates:
+  prod:
+    src: {host: 127.0.0.1, port: "9030", thrift_port: "9020", user: root, password: ""}
+    dest: {host: 127.0.0.1, port: "19030", thrift_port: "19020", user: root, password: ""}
+jobs:
+  - name: create_job
+    template: prod
+    src: {database: db_create}
+    dest: {database: db_create}
+  - name: update_job
+    template: prod
+    src: {database: db_update}
+    dest: {database: db_update}
+    skip_error: true
+    labels: {env: prod}
+  - name: conflict_job
+    template: prod
+    src: {database: db_conflict}
+    dest: {database: db_conflict_changed}
+  - name: unchanged_job
+    template: prod
+    src: {database: db_unchanged}
+    dest: {database: db_unchanged}
+`
+
+func newTestSpec(port, database string) base.Spec {
+	return base.Spec{Frontend: base.Frontend{Host: "127.0.0.1", Port: port}, User: "root", Database: database}
+}
+
+// addTestJob adds the job persisted by the syncer to the meta db.
+func addTestJob(t *testing.T, db storage.DB, name, database, belong string) {
+	job := &configuredJob{Src: newTestSpec("9030", database), Dest: newTestSpec("19030", database)}
+	data, err := json.Marshal(job)
+	if err != nil {
+		t.Fatalf("marshal job %s failed: %v", name, err)
+	}
+	if err := db.AddJob(name, string(data), belong); err != nil {
+		t.Fatalf("add job %s failed: %v", name, err)
+	}
+}
+
+func newJobConfigTestDB(t *testing.T, config string) storage.DB {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("write job config failed: %v", err)
+	}
+	origin := flagJobConfigFile
+	flagJobConfigFile = path
+	t.Cleanup(func() { flagJobConfigFile = origin })
+
+	db, err := storage.NewSQLiteDB(filepath.Join(dir, "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	for _, syncer := range []string{testSyncer, otherSyncer} {
+		if err := db.AddSyncer(syncer); err != nil {
+			t.Fatalf("add syncer failed: %v", err)
+		}
+	}
+	return db
+}
+
+func TestPlanHandler(t *testing.T) {
+	db := newJobConfigTestDB(t, testJobConfig)
+	addTestJob(t, db, "update_job", "db_update", testSyncer)
+	addTestJob(t, db, "conflict_job", "db_conflict", otherSyncer)
+	addTestJob(t, db, "unchanged_job", "db_unchanged", testSyncer)
+	addTestJob(t, db, "delete_job", "db_delete", otherSyncer)
+	s := NewHttpServer("127.0.0.1", 9190, db, ccr.NewJobManager(db, nil, testSyncer))
+
+	w := httptest.NewRecorder()
+	s.planHandler(w, httptest.NewRequest(http.MethodGet, "/plan", nil))
+
+	var result struct {
+		defaultResult
+		Plan *JobPlan `json:"plan"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("decode plan failed: %v", err)
+	}
+	if !result.Success || result.Plan == nil {
+		t.Fatalf("plan failed: %s", result.ErrorMsg)
+	}
+
+	expected := []JobPlanAction{
+		{Name: "conflict_job", Action: planConflict, Syncer: otherSyncer, Changes: []string{"dest"}},
+		{Name: "create_job", Action: planCreate},
+		{Name: "delete_job", Action: planDelete, Syncer: otherSyncer},
+		{Name: "update_job", Action: planUpdate, Syncer: testSyncer, Changes: []string{"skip_error", "labels"}},
+	}
+	if len(result.Plan.Actions) != len(expected) {
+		t.Fatalf("plan actions = %d, expect %d", len(result.Plan.Actions), len(expected))
+	}
+	for i, action := range result.Plan.Actions {
+		if !reflect.DeepEqual(*action, expected[i]) {
+			t.Errorf("action %d = %+v, expect %+v", i, *action, expected[i])
+		}
+	}
+	if !result.Plan.Prune || !reflect.DeepEqual(result.Plan.Unchanged, []string{"unchanged_job"}) {
+		t.Errorf("plan = %+v, expect prune and unchanged_job", result.Plan)
+	}
+
+	// The plan is a dry run.
+	if exists, err := db.IsJobExist("delete_job"); err != nil || !exists {
+		t.Errorf("the job is deleted by the plan, err: %v", err)
+	}
+	if exists, err := db.IsJobExist("create_job"); err != nil || exists {
+		t.Errorf("the job is created by the plan, err: %v", err)
+	}
+}
+
+func TestPlanHandler_WithoutJobConfig(t *testing.T) {
+	origin := flagJobConfigFile
+	flagJobConfigFile = ""
+	t.Cleanup(func() { flagJobConfigFile = origin })
+
+	s := NewHttpServer("127.0.0.1", 9190, nil, nil)
+	w := httptest.NewRecorder()
+	s.planHandler(w, httptest.NewRequest(http.MethodGet, "/plan", nil))
+
+	var result defaultResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("decode result failed: %v", err)
+	}
+	if result.Success {
+		t.Errorf("plan without job config succeeded")
+	}
+}
+
+// racingDB creates the job by the other syncer, after the syncer plans to create it.
+type racingDB struct {
+	storage.DB
+	t       *testing.T
+	jobName string
+	created bool
+}
+
+func (db *racingDB) IsJobExist(jobName string) (bool, error) {
+	if jobName == db.jobName && !db.created {
+		db.created = true
+		addTestJob(db.t, db.DB, jobName, "db_create", otherSyncer)
+	}
+	if jobName == db.jobName {
+		return false, nil
+	}
+	return db.DB.IsJobExist(jobName)
+}
+
+// All syncers reconcile the job config, the jobs owned by the other syncers are skipped, and the
+// job created by the other syncer is not a failure.
+func TestReconcileJobConfig_OtherSyncers(t *testing.T) {
+	db := newJobConfigTestDB(t, testJobConfig)
+	addTestJob(t, db, "update_job", "db_update", otherSyncer)
+	addTestJob(t, db, "conflict_job", "db_conflict", otherSyncer)
+	addTestJob(t, db, "unchanged_job", "db_unchanged", otherSyncer)
+	addTestJob(t, db, "delete_job", "db_delete", otherSyncer)
+
+	ctrl := gomock.NewController(t)
+	meta := ccr.NewMockMetaer(ctrl)
+	meta.EXPECT().GetFrontends().Return(nil, nil).AnyTimes()
+	meta.EXPECT().CheckBinlogFeature().Return(nil).AnyTimes()
+	meta.EXPECT().GetDbId().Return(int64(1), nil).AnyTimes()
+	metaFactory := ccr.NewMockMetaerFactory(ctrl)
+	metaFactory.EXPECT().NewMeta(gomock.Any()).Return(meta).AnyTimes()
+	spec := ccr.NewMockSpecer(ctrl)
+	spec.EXPECT().Valid().Return(nil).AnyTimes()
+	spec.EXPECT().CheckDatabaseExists().Return(true, nil).AnyTimes()
+	spec.EXPECT().IsDatabaseEnableBinlog().Return(true, nil).AnyTimes()
+	specFactory := ccr.NewMockSpecerFactory(ctrl)
+	specFactory.EXPECT().NewSpecer(gomock.Any()).Return(spec).AnyTimes()
+	factory := ccr.NewFactory(nil, metaFactory, specFactory, nil)
+
+	jm := ccr.NewJobManager(db, factory, testSyncer)
+	s := NewHttpServer("127.0.0.1", 9190, &racingDB{DB: db, t: t, jobName: "create_job"}, jm)
+	if err := s.ReconcileJobConfig(); err != nil {
+		t.Fatalf("reconcile job config failed: %v", err)
+	}
+
+	for _, name := range []string{"create_job", "update_job", "delete_job"} {
+		if belong, err := db.GetJobBelong(name); err != nil || belong != otherSyncer {
+			t.Errorf("job %s belongs to %s, expect %s, err: %v", name, belong, otherSyncer, err)
+		}
+	}
+	jobInfo, err := db.GetJobInfo("update_job")
+	if err != nil {
+		t.Fatalf("get job info failed: %v", err)
+	}
+	var job configuredJob
+	if err := json.Unmarshal([]byte(jobInfo), &job); err != nil {
+		t.Fatalf("unmarshal job info failed: %v", err)
+	}
+	if job.SkipError {
+		t.Errorf("the job of the other syncer is updated")
+	}
+}
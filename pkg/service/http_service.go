@@ -7,19 +7,64 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/selectdb/ccr_syncer/pkg/auth"
 	"github.com/selectdb/ccr_syncer/pkg/ccr"
 	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/selectdb/ccr_syncer/pkg/schedule"
+	"github.com/selectdb/ccr_syncer/pkg/secret"
+	"github.com/selectdb/ccr_syncer/pkg/sink"
 	"github.com/selectdb/ccr_syncer/pkg/storage"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/verify"
 	"github.com/selectdb/ccr_syncer/pkg/version"
 	"github.com/selectdb/ccr_syncer/pkg/xerror"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	flagAuthTokenFile   string
+	flagTLSCertFile     string
+	flagTLSKeyFile      string
+	flagTLSClientCaFile string
+	flagTLSPeerCaFile   string
+)
+
+func init() {
+	flag.StringVar(&flagAuthTokenFile, "auth_token_file", "",
+		"The json file of the bearer tokens to access the http api, the api is not authenticated if it is empty")
+	flag.StringVar(&flagTLSCertFile, "tls_cert_file", "", "The certificate file of the https api, the api is served in http if it is empty")
+	flag.StringVar(&flagTLSKeyFile, "tls_key_file", "", "The key file of the https api")
+	flag.StringVar(&flagTLSClientCaFile, "tls_client_ca_file", "",
+		"The CA file to verify the client certificates of the https api, the client certificates are not required if it is empty")
+	flag.StringVar(&flagTLSPeerCaFile, "tls_peer_ca_file", "",
+		"The CA file to verify the certificates of the other syncers when the requests are forwarded, the system CAs are used if it is empty")
+}
+
+func tlsEnabled() bool {
+	return flagTLSCertFile != ""
+}
+
+// PeerTLSOptions returns the tls options to connect to the other syncers, the certificate of this
+// syncer is sent as the client certificate. It returns nil if the tls is not enabled.
+func PeerTLSOptions() *utils.TLSOptions {
+	if !tlsEnabled() {
+		return nil
+	}
+	return &utils.TLSOptions{
+		Enable:   true,
+		CaFile:   flagTLSPeerCaFile,
+		CertFile: flagTLSCertFile,
+		KeyFile:  flagTLSKeyFile,
+	}
+}
+
 // TODO(Drogon): impl a generic http request handle parse json
 
 func writeJson(w http.ResponseWriter, data interface{}) {
@@ -61,6 +106,12 @@ type HttpService struct {
 
 	db         storage.DB
 	jobManager *ccr.JobManager
+
+	// Authenticate the requests if the auth_token_file is set.
+	authenticator *auth.Authenticator
+
+	// Only one reconciliation of the job config runs at the same time.
+	reconcileLock sync.Mutex
 }
 
 func NewHttpServer(host string, port int, db storage.DB, jobManager *ccr.JobManager) *HttpService {
@@ -82,11 +133,38 @@ type CreateCcrRequest struct {
 	SkipError bool      `json:"skip_error"`
 	// For table sync, allow to create ccr job even if the target table already exists.
 	AllowTableExists bool `json:"allow_table_exists"`
+	// For db sync, only sync the included tables and skip the excluded tables, both
+	// exact table names and regular expressions are supported.
+	IncludeTables []string `json:"include_tables,omitempty"`
+	ExcludeTables []string `json:"exclude_tables,omitempty"`
+	// Publish the binlogs to the sink instead of the dest cluster, eg. a local file or a webhook.
+	Sink *sink.Config `json:"sink,omitempty"`
+	// Replay the binlogs of src into each of the dests, the dest should be empty.
+	Dests []base.Spec `json:"dests,omitempty"`
+	// Only sync in the schedule windows, or pause/resume the job by the cron expressions.
+	Schedule *schedule.Schedule `json:"schedule,omitempty"`
+	// Limit the binlogs and ingested tablets per second of the incremental sync.
+	RateLimit *ccr.RateLimit `json:"rate_limit,omitempty"`
+	// Record the sqls and ingest plans of the binlogs without executing them in the dest.
+	DryRun bool `json:"dry_run"`
+	// Verify the data of the source and the dest periodically.
+	Verify *ccr.VerifyConfig `json:"verify,omitempty"`
+	// Replay the binlogs up to the commit seq or the timestamp, then complete the job.
+	StopAt *ccr.StopTarget `json:"stop_at,omitempty"`
+	// The free-form labels to select the jobs in the batch operations.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // Stringer
 func (r *CreateCcrRequest) String() string {
-	return fmt.Sprintf("name: %s, src: %v, dest: %v", r.Name, r.Src, r.Dest)
+	if len(r.Dests) > 0 {
+		dests := make([]string, 0, len(r.Dests))
+		for i := range r.Dests {
+			dests = append(dests, r.Dests[i].String())
+		}
+		return fmt.Sprintf("name: %s, src: %s, dests: [%s]", r.Name, &r.Src, strings.Join(dests, "; "))
+	}
+	return fmt.Sprintf("name: %s, src: %s, dest: %s", r.Name, &r.Src, &r.Dest)
 }
 
 // version Handler
@@ -111,7 +189,17 @@ func (s *HttpService) versionHandler(w http.ResponseWriter, r *http.Request) {
 func createCcr(request *CreateCcrRequest, db storage.DB, jobManager *ccr.JobManager) error {
 	log.Infof("create ccr %s", request)
 
-	ctx := ccr.NewJobContext(request.Src, request.Dest, request.SkipError, request.AllowTableExists, db, jobManager.GetFactory())
+	tableFilter := ccr.NewTableFilter(request.IncludeTables, request.ExcludeTables)
+	ctx := ccr.NewJobContext(request.Src, request.Dest, request.SkipError, request.AllowTableExists, db, jobManager.GetFactory()).
+		WithTableFilter(tableFilter).
+		WithSink(request.Sink).
+		WithDests(request.Dests).
+		WithSchedule(request.Schedule).
+		WithRateLimit(request.RateLimit).
+		WithDryRun(request.DryRun).
+		WithVerifyConfig(request.Verify).
+		WithStopTarget(request.StopAt).
+		WithLabels(request.Labels)
 	job, err := ccr.NewJobFromService(request.Name, ctx)
 	if err != nil {
 		return err
@@ -126,7 +214,8 @@ func createCcr(request *CreateCcrRequest, db storage.DB, jobManager *ccr.JobMana
 	return nil
 }
 
-// return exit(bool)
+// redirect redirects the request of the job to the syncer owns it with 307, so the method and
+// the body of the request are kept, return exit(bool)
 func (s *HttpService) redirect(jobName string, w http.ResponseWriter, r *http.Request) bool {
 	if jobExist, err := s.db.IsJobExist(jobName); err != nil {
 		log.Warnf("get job %s exist failed: %+v, uri is %s", jobName, err, r.RequestURI)
@@ -153,12 +242,21 @@ func (s *HttpService) redirect(jobName string, w http.ResponseWriter, r *http.Re
 	}
 
 	log.Infof("%s is located in syncer %s, please redirect to %s", jobName, belongHost, belongHost)
-	redirectUrl := fmt.Sprintf("http://%s", belongHost+r.RequestURI)
-	http.Redirect(w, r, redirectUrl, http.StatusSeeOther)
+	redirectUrl := redirectUrl(belongHost, r)
+	http.Redirect(w, r, redirectUrl, http.StatusTemporaryRedirect)
 	log.Infof("the redirect url is %s", redirectUrl)
 	return true
 }
 
+// redirectUrl returns the url of the request in the syncer.
+func redirectUrl(hostInfo string, r *http.Request) string {
+	scheme := "http"
+	if tlsEnabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, hostInfo+r.RequestURI)
+}
+
 // HttpServer serving /create_ccr by json http rpc
 func (s *HttpService) createHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("create ccr")
@@ -438,8 +536,17 @@ func (s *HttpService) desyncHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 type UpdateJobRequest struct {
-	Name      string `json:"name,required"`
-	SkipError bool   `json:"skip_error"`
+	Name string `json:"name,required"`
+	// The skip_error is reset to false if skip_error, rate_limit, verify, stop_at and labels are
+	// all absent.
+	SkipError *bool          `json:"skip_error"`
+	RateLimit *ccr.RateLimit `json:"rate_limit,omitempty"`
+	// A empty verify config (`{}`) removes the periodic verification.
+	Verify *ccr.VerifyConfig `json:"verify,omitempty"`
+	// A empty stop target (`{}`) removes it.
+	StopAt *ccr.StopTarget `json:"stop_at,omitempty"`
+	// Replace the labels, the empty labels (`{}`) remove all labels.
+	Labels map[string]string `json:"labels"`
 }
 
 func (s *HttpService) updateJobHandler(w http.ResponseWriter, r *http.Request) {
@@ -469,15 +576,415 @@ func (s *HttpService) updateJobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.jobManager.UpdateJobSkipError(request.Name, request.SkipError); err != nil {
-		log.Warnf("desync job failed: %+v", err)
-
+	if err := s.updateJob(&request); err != nil {
 		updateJobResult = newErrorResult(err.Error())
 	} else {
 		updateJobResult = newSuccessResult()
 	}
 }
 
+func (s *HttpService) updateJob(request *UpdateJobRequest) error {
+	if err := s.jobManager.ValidJobUpdate(request.Name, request.RateLimit, request.Verify, request.StopAt, request.Labels); err != nil {
+		log.Warnf("update job failed: %+v", err)
+		return err
+	}
+
+	if request.SkipError != nil || (request.RateLimit == nil && request.Verify == nil && request.StopAt == nil && request.Labels == nil) {
+		skipError := request.SkipError != nil && *request.SkipError
+		if err := s.jobManager.UpdateJobSkipError(request.Name, skipError); err != nil {
+			log.Warnf("update job skip error failed: %+v", err)
+			return err
+		}
+	}
+
+	if request.RateLimit != nil {
+		if err := s.jobManager.UpdateJobRateLimit(request.Name, request.RateLimit); err != nil {
+			log.Warnf("update job rate limit failed: %+v", err)
+			return err
+		}
+	}
+
+	if request.Verify != nil {
+		if err := s.jobManager.UpdateJobVerifyConfig(request.Name, request.Verify); err != nil {
+			log.Warnf("update job verify config failed: %+v", err)
+			return err
+		}
+	}
+
+	if request.StopAt != nil {
+		if err := s.jobManager.UpdateJobStopTarget(request.Name, request.StopAt); err != nil {
+			log.Warnf("update job stop target failed: %+v", err)
+			return err
+		}
+	}
+
+	if request.Labels != nil {
+		if err := s.jobManager.UpdateJobLabels(request.Name, request.Labels); err != nil {
+			log.Warnf("update job labels failed: %+v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *HttpService) getScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get job schedule")
+
+	type result struct {
+		*defaultResult
+		Schedule *ccr.JobSchedule `json:"schedule,omitempty"`
+	}
+
+	var scheduleResult *result
+	defer func() { writeJson(w, scheduleResult) }()
+
+	// Parse the JSON request body
+	var request CcrCommonRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get job schedule failed: %+v", err)
+
+		scheduleResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("get job schedule failed: name is empty")
+
+		scheduleResult = &result{defaultResult: newErrorResult("name is empty")}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	if jobSchedule, err := s.jobManager.GetJobSchedule(request.Name); err != nil {
+		log.Warnf("get job schedule failed: %+v", err)
+
+		scheduleResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		scheduleResult = &result{
+			defaultResult: newSuccessResult(),
+			Schedule:      jobSchedule,
+		}
+	}
+}
+
+type UpdateScheduleRequest struct {
+	Name string `json:"name,required"`
+	// A null or empty schedule removes the schedule of the job.
+	Schedule *schedule.Schedule `json:"schedule"`
+}
+
+func (s *HttpService) updateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("update job schedule")
+
+	var updateResult *defaultResult
+	defer func() { writeJson(w, updateResult) }()
+
+	// Parse the JSON request body
+	var request UpdateScheduleRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("update job schedule failed: %+v", err)
+
+		updateResult = newErrorResult(err.Error())
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("update job schedule failed: name is empty")
+
+		updateResult = newErrorResult("name is empty")
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	if err := s.jobManager.UpdateJobSchedule(request.Name, request.Schedule); err != nil {
+		log.Warnf("update job schedule failed: %+v", err)
+
+		updateResult = newErrorResult(err.Error())
+	} else {
+		updateResult = newSuccessResult()
+	}
+}
+
+type JobHistoryRequest struct {
+	// Empty means all jobs.
+	Name  string   `json:"name"`
+	Types []string `json:"types"`
+	// The time range in unix milliseconds, [since, until).
+	Since int64 `json:"since"`
+	Until int64 `json:"until"`
+	Limit int   `json:"limit"`
+}
+
+// The history is saved in the db shared by all syncers, so it is not required to redirect.
+func (s *HttpService) jobHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get job history")
+
+	type result struct {
+		*defaultResult
+		Events []*storage.JobEvent `json:"events,omitempty"`
+	}
+
+	var historyResult *result
+	defer func() { writeJson(w, historyResult) }()
+
+	// Parse the JSON request body
+	var request JobHistoryRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get job history failed: %+v", err)
+
+		historyResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	filter := &storage.JobEventFilter{
+		JobName: request.Name,
+		Types:   request.Types,
+		Since:   request.Since,
+		Until:   request.Until,
+		Limit:   request.Limit,
+	}
+	if events, err := s.jobManager.GetJobHistory(filter); err != nil {
+		log.Warnf("get job history failed: %+v", err)
+
+		historyResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		historyResult = &result{
+			defaultResult: newSuccessResult(),
+			Events:        events,
+		}
+	}
+}
+
+type DryRunResultRequest struct {
+	Name string `json:"name,required"`
+	// Zero means the results of all handled binlogs.
+	CommitSeq int64 `json:"commit_seq"`
+}
+
+func (s *HttpService) dryRunResultHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get dry run result")
+
+	type result struct {
+		*defaultResult
+		Results []*ccr.DryRunResult `json:"results,omitempty"`
+	}
+
+	var dryRunResult *result
+	defer func() { writeJson(w, dryRunResult) }()
+
+	// Parse the JSON request body
+	var request DryRunResultRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get dry run result failed: %+v", err)
+
+		dryRunResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("get dry run result failed: name is empty")
+
+		dryRunResult = &result{defaultResult: newErrorResult("name is empty")}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	if results, err := s.jobManager.GetDryRunResults(request.Name, request.CommitSeq); err != nil {
+		log.Warnf("get dry run result failed: %+v", err)
+
+		dryRunResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		dryRunResult = &result{
+			defaultResult: newSuccessResult(),
+			Results:       results,
+		}
+	}
+}
+
+type VerifyRequest struct {
+	Name string `json:"name,required"`
+	// Compare the checksums of the partitions, which scans all data of the tables.
+	Checksum bool `json:"checksum"`
+}
+
+// The verification is executed between two binlogs by the job, so the request waits until the
+// job is verified.
+func (s *HttpService) verifyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("verify job")
+
+	type result struct {
+		*defaultResult
+		Report *verify.Report `json:"report,omitempty"`
+	}
+
+	var verifyResult *result
+	defer func() { writeJson(w, verifyResult) }()
+
+	// Parse the JSON request body
+	var request VerifyRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("verify job failed: %+v", err)
+
+		verifyResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("verify job failed: name is empty")
+
+		verifyResult = &result{defaultResult: newErrorResult("name is empty")}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	if report, err := s.jobManager.Verify(request.Name, request.Checksum); err != nil {
+		log.Warnf("verify job failed: %+v", err)
+
+		verifyResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		verifyResult = &result{
+			defaultResult: newSuccessResult(),
+			Report:        report,
+		}
+	}
+}
+
+type VerifyReportsRequest struct {
+	Name  string `json:"name,required"`
+	Limit int    `json:"limit"`
+}
+
+// The reports are saved in the db shared by all syncers, so it is not required to redirect.
+func (s *HttpService) verifyReportsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("get verify reports")
+
+	type result struct {
+		*defaultResult
+		Reports []*verify.Report `json:"reports,omitempty"`
+	}
+
+	var reportsResult *result
+	defer func() { writeJson(w, reportsResult) }()
+
+	// Parse the JSON request body
+	var request VerifyReportsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("get verify reports failed: %+v", err)
+
+		reportsResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("get verify reports failed: name is empty")
+
+		reportsResult = &result{defaultResult: newErrorResult("name is empty")}
+		return
+	}
+
+	if reports, err := s.getVerifyReports(request.Name, request.Limit); err != nil {
+		reportsResult = &result{defaultResult: newErrorResult(err.Error())}
+	} else {
+		reportsResult = &result{
+			defaultResult: newSuccessResult(),
+			Reports:       reports,
+		}
+	}
+}
+
+func (s *HttpService) getVerifyReports(jobName string, limit int) ([]*verify.Report, error) {
+	verifyReports, err := s.jobManager.GetVerifyReports(jobName, limit)
+	if err != nil {
+		log.Warnf("get verify reports failed: %+v", err)
+		return nil, err
+	}
+
+	reports := make([]*verify.Report, 0, len(verifyReports))
+	for _, verifyReport := range verifyReports {
+		var report verify.Report
+		if err := json.Unmarshal([]byte(verifyReport.Report), &report); err != nil {
+			log.Warnf("get verify reports failed, unmarshal report %d: %+v", verifyReport.Id, err)
+			return nil, xerror.Wrapf(err, xerror.Normal, "unmarshal verify report %d failed", verifyReport.Id)
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+type SwitchoverRequest struct {
+	Name string `json:"name,required"`
+	// The name of the reverse job, ${name}_reverse by default.
+	ReverseName string `json:"reverse_name"`
+}
+
+// The request waits until the switchover is done or failed, a failed switchover is resumed from
+// the failed step by requesting again.
+func (s *HttpService) switchoverHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("switchover job")
+
+	type result struct {
+		*defaultResult
+		Switchover *ccr.Switchover `json:"switchover,omitempty"`
+	}
+
+	var switchoverResult *result
+	defer func() { writeJson(w, switchoverResult) }()
+
+	// Parse the JSON request body
+	var request SwitchoverRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		log.Warnf("switchover job failed: %+v", err)
+
+		switchoverResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+
+	if request.Name == "" {
+		log.Warnf("switchover job failed: name is empty")
+
+		switchoverResult = &result{defaultResult: newErrorResult("name is empty")}
+		return
+	}
+
+	if s.redirect(request.Name, w, r) {
+		return
+	}
+
+	if switchover, err := s.jobManager.Switchover(request.Name, request.ReverseName); err != nil {
+		log.Warnf("switchover job failed: %+v", err)
+
+		switchoverResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+			Switchover:    switchover,
+		}
+	} else {
+		switchoverResult = &result{
+			defaultResult: newSuccessResult(),
+			Switchover:    switchover,
+		}
+	}
+}
+
 // ListJobs service
 func (s *HttpService) listJobsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("list jobs")
@@ -485,33 +992,59 @@ func (s *HttpService) listJobsHandler(w http.ResponseWriter, r *http.Request) {
 	type result struct {
 		*defaultResult
 		Jobs []string `json:"jobs,omitempty"`
+		// The status of the jobs running in this syncer.
+		Statuses []*ccr.JobStatus `json:"statuses,omitempty"`
 	}
 
 	var jobResult *result
 	defer func() { writeJson(w, jobResult) }()
 
-	// use GetAllData to get all jobs
-	if ans, err := s.db.GetAllData(); err != nil {
-		log.Warnf("when list jobs, get all data failed: %+v", err)
-
+	if allJobs, err := s.listJobNames(); err != nil {
 		jobResult = &result{
 			defaultResult: newErrorResult(err.Error()),
 		}
 	} else {
-		var jobData []string
-		jobData = ans["jobs"]
-		allJobs := make([]string, 0)
-		for _, eachJob := range jobData {
-			allJobs = append(allJobs, strings.Trim(strings.Split(eachJob, ",")[0], " "))
-		}
-
 		jobResult = &result{
 			defaultResult: newSuccessResult(),
 			Jobs:          allJobs,
+			Statuses:      s.listJobStatuses(),
 		}
 	}
 }
 
+// listJobStatuses returns the status of the jobs running in this syncer, sorted by name.
+func (s *HttpService) listJobStatuses() []*ccr.JobStatus {
+	statuses := s.jobManager.ListJobs()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *HttpService) listJobNames() ([]string, error) {
+	// use GetAllData to get all jobs
+	ans, err := s.db.GetAllData()
+	if err != nil {
+		log.Warnf("when list jobs, get all data failed: %+v", err)
+		return nil, err
+	}
+
+	var jobData []string
+	jobData = ans["jobs"]
+	allJobs := make([]string, 0)
+	for _, eachJob := range jobData {
+		allJobs = append(allJobs, strings.Trim(strings.Split(eachJob, ",")[0], " "))
+	}
+	return allJobs, nil
+}
+
+// splitAllDataRow splits the row "${key}, ${value}" of GetAllData.
+func splitAllDataRow(row string) (string, string, bool) {
+	i := strings.LastIndex(row, ", ")
+	if i < 0 {
+		return "", "", false
+	}
+	return row[:i], row[i+2:], true
+}
+
 // get job progress
 func (s *HttpService) jobProgressHandler(w http.ResponseWriter, r *http.Request) {
 	log.Infof("get job progress")
@@ -600,16 +1133,27 @@ func (s *HttpService) jobDetailHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if jobDetail, err := s.db.GetJobInfo(request.Name); err != nil {
+	jobDetail, err := s.db.GetJobInfo(request.Name)
+	if err != nil {
 		log.Warnf("get job info failed: %+v", err)
 
+		jobResult = &result{
+			defaultResult: newErrorResult(err.Error()),
+		}
+		return
+	}
+
+	// The passwords are never returned, even if they are encrypted.
+	if redacted, err := secret.RedactPasswords([]byte(jobDetail)); err != nil {
+		log.Warnf("redact job info failed: %+v", err)
+
 		jobResult = &result{
 			defaultResult: newErrorResult(err.Error()),
 		}
 	} else {
 		jobResult = &result{
 			defaultResult: newSuccessResult(),
-			JobDetail:     jobDetail,
+			JobDetail:     string(redacted),
 		}
 	}
 }
@@ -680,32 +1224,84 @@ func (s *HttpService) featuresHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handle registers the handler, which requires the role at least if the authentication is
+// enabled. The request is authenticated before it is redirected to the syncer owns the job, and
+// the redirected request is authenticated by that syncer again, so all syncers should share the
+// same tokens, and the client should keep the Authorization header when it follows the redirect
+// to the other host, eg. curl --location-trusted.
+func (s *HttpService) handle(pattern string, role auth.Role, handler http.Handler) {
+	s.mux.Handle(pattern, s.authenticator.Middleware(role, handler))
+}
+
 func (s *HttpService) RegisterHandlers() {
-	s.mux.HandleFunc("/version", s.versionHandler)
-	s.mux.HandleFunc("/create_ccr", s.createHandler)
-	s.mux.HandleFunc("/get_lag", s.getLagHandler)
-	s.mux.HandleFunc("/pause", s.pauseHandler)
-	s.mux.HandleFunc("/resume", s.resumeHandler)
-	s.mux.HandleFunc("/delete", s.deleteHandler)
-	s.mux.HandleFunc("/job_status", s.statusHandler)
-	s.mux.HandleFunc("/desync", s.desyncHandler)
-	s.mux.HandleFunc("/update_job", s.updateJobHandler)
-	s.mux.HandleFunc("/list_jobs", s.listJobsHandler)
-	s.mux.HandleFunc("/job_detail", s.jobDetailHandler)
-	s.mux.HandleFunc("/job_progress", s.jobProgressHandler)
-	s.mux.HandleFunc("/force_fullsync", s.forceFullsyncHandler)
-	s.mux.HandleFunc("/features", s.featuresHandler)
-	s.mux.Handle("/metrics", promhttp.Handler())
+	// read only
+	s.handle("/version", auth.RoleReadOnly, http.HandlerFunc(s.versionHandler))
+	s.handle("/get_lag", auth.RoleReadOnly, http.HandlerFunc(s.getLagHandler))
+	s.handle("/job_status", auth.RoleReadOnly, http.HandlerFunc(s.statusHandler))
+	s.handle("/get_schedule", auth.RoleReadOnly, http.HandlerFunc(s.getScheduleHandler))
+	s.handle("/list_jobs", auth.RoleReadOnly, http.HandlerFunc(s.listJobsHandler))
+	s.handle("/job_history", auth.RoleReadOnly, http.HandlerFunc(s.jobHistoryHandler))
+	s.handle("/dry_run_result", auth.RoleReadOnly, http.HandlerFunc(s.dryRunResultHandler))
+	s.handle("/verify_reports", auth.RoleReadOnly, http.HandlerFunc(s.verifyReportsHandler))
+	s.handle("/job_detail", auth.RoleReadOnly, http.HandlerFunc(s.jobDetailHandler))
+	s.handle("/job_progress", auth.RoleReadOnly, http.HandlerFunc(s.jobProgressHandler))
+	s.handle("/features", auth.RoleReadOnly, http.HandlerFunc(s.featuresHandler))
+	s.handle("/watch", auth.RoleReadOnly, http.HandlerFunc(s.watchHandler))
+	s.handle("/plan", auth.RoleReadOnly, http.HandlerFunc(s.planHandler))
+	s.handle("/syncers", auth.RoleReadOnly, http.HandlerFunc(s.syncersHandler))
+	s.handle("/metrics", auth.RoleReadOnly, promhttp.Handler())
+
+	// operator
+	s.handle("/pause", auth.RoleOperator, http.HandlerFunc(s.pauseHandler))
+	s.handle("/resume", auth.RoleOperator, http.HandlerFunc(s.resumeHandler))
+	s.handle("/update_job", auth.RoleOperator, http.HandlerFunc(s.updateJobHandler))
+	s.handle("/update_schedule", auth.RoleOperator, http.HandlerFunc(s.updateScheduleHandler))
+	s.handle("/verify", auth.RoleOperator, http.HandlerFunc(s.verifyHandler))
+	s.handle("/force_fullsync", auth.RoleOperator, http.HandlerFunc(s.forceFullsyncHandler))
+	s.handle("/batch_pause", auth.RoleOperator, s.batchHandler(batchPause))
+	s.handle("/batch_resume", auth.RoleOperator, s.batchHandler(batchResume))
+	s.handle("/batch_force_fullsync", auth.RoleOperator, s.batchHandler(batchForceFullsync))
+	s.handle("/batch_update_job", auth.RoleOperator, s.batchHandler(batchUpdateJob))
+
+	// admin
+	s.handle("/create_ccr", auth.RoleAdmin, http.HandlerFunc(s.createHandler))
+	s.handle("/delete", auth.RoleAdmin, http.HandlerFunc(s.deleteHandler))
+	s.handle("/desync", auth.RoleAdmin, http.HandlerFunc(s.desyncHandler))
+	s.handle("/switchover", auth.RoleAdmin, http.HandlerFunc(s.switchoverHandler))
+	s.handle("/batch_delete", auth.RoleAdmin, s.batchHandler(batchDelete))
+	s.handle("/drain", auth.RoleAdmin, http.HandlerFunc(s.drainHandler))
+
+	// The versioned api authorizes each route by itself.
+	s.registerApiV1Handlers()
+
+	s.registerDashboardHandlers()
 }
 
 func (s *HttpService) Start() error {
 	addr := fmt.Sprintf(":%d", s.port)
 	log.Infof("Server listening on %s", addr)
 
+	if flagAuthTokenFile != "" {
+		authenticator, err := auth.LoadTokenFile(flagAuthTokenFile)
+		if err != nil {
+			return err
+		}
+		s.authenticator = authenticator
+		log.Infof("http api is authenticated by the tokens in %s", flagAuthTokenFile)
+	}
 	s.RegisterHandlers()
 
 	s.server = &http.Server{Addr: addr, Handler: s.mux}
-	err := s.server.ListenAndServe()
+	var err error
+	if tlsEnabled() {
+		if s.server.TLSConfig, err = utils.NewServerTLSConfig(flagTLSCertFile, flagTLSKeyFile, flagTLSClientCaFile); err != nil {
+			return err
+		}
+		log.Infof("http api is served in https, client certificates are verified: %t", flagTLSClientCaFile != "")
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
 	if err == nil {
 		return nil
 	} else if err == http.ErrServerClosed {
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/auth"
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	"github.com/selectdb/ccr_syncer/pkg/storage"
+)
+
+const (
+	testReadOnlyToken = "read-only-token"
+	testOperatorToken = "operator-token"
+)
+
+// newAuthTestServer starts the syncer authenticated by testReadOnlyToken and testOperatorToken
+// with the db.
+func newAuthTestServer(t *testing.T, db storage.DB) (*httptest.Server, string) {
+	server := httptest.NewUnstartedServer(nil)
+	hostInfo := server.Listener.Addr().String()
+
+	authenticator, err := auth.NewAuthenticator([]auth.Token{
+		{Name: "dashboard", Token: testReadOnlyToken, Role: "read_only"},
+		{Name: "ops", Token: testOperatorToken, Role: "operator"},
+	})
+	if err != nil {
+		t.Fatalf("new authenticator failed: %v", err)
+	}
+	s := &HttpService{
+		mux:           http.NewServeMux(),
+		hostInfo:      hostInfo,
+		db:            db,
+		jobManager:    ccr.NewJobManager(db, nil, hostInfo),
+		authenticator: authenticator,
+	}
+	s.RegisterHandlers()
+	server.Config.Handler = s.mux
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, hostInfo
+}
+
+// The request of the job owned by the other syncer is redirected with the method and the body,
+// and authenticated by that syncer with the same token.
+func TestRedirect_WithToken(t *testing.T) {
+	db, err := storage.NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	if err != nil {
+		t.Fatalf("new sqlite db failed: %v", err)
+	}
+	server, _ := newAuthTestServer(t, db)
+	otherServer, otherHostInfo := newAuthTestServer(t, db)
+	addTestJob(t, db, "remote", "db1", otherHostInfo)
+
+	pause := func(client *http.Client) (*http.Response, *defaultResult) {
+		request, err := http.NewRequest(http.MethodPost, server.URL+"/pause", bytes.NewBufferString(`{"name":"remote"}`))
+		if err != nil {
+			t.Fatalf("new request failed: %v", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+testOperatorToken)
+		resp, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("pause failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var result defaultResult
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatalf("decode the result failed: %v", err)
+			}
+		}
+		return resp, &result
+	}
+
+	// The job is not running in the other syncer, but the body of the redirected request is
+	// decoded there.
+	resp, result := pause(&http.Client{})
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Host != otherServer.Listener.Addr().String() {
+		t.Fatalf("status = %d, url = %s, expect redirected to %s", resp.StatusCode, resp.Request.URL, otherServer.URL)
+	}
+	if resp.Request.Method != http.MethodPost || !strings.Contains(result.ErrorMsg, "job not exist: remote") {
+		t.Errorf("method = %s, result = %+v, expect the pause handled by the other syncer", resp.Request.Method, result)
+	}
+
+	// The redirected request without the token is rejected by the other syncer.
+	stripToken := &http.Client{CheckRedirect: func(r *http.Request, via []*http.Request) error {
+		r.Header.Del("Authorization")
+		return nil
+	}}
+	if resp, _ := pause(stripToken); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, expect %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
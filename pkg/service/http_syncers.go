@@ -0,0 +1,35 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+	log "github.com/sirupsen/logrus"
+)
+
+// syncersHandler lists the syncers of the cluster with their heartbeat and jobs.
+func (s *HttpService) syncersHandler(w http.ResponseWriter, r *http.Request) {
+	log.Infof("list syncers")
+
+	type result struct {
+		*defaultResult
+		CheckIntervalMs int64             `json:"check_interval_ms"`
+		CheckTimeoutMs  int64             `json:"check_timeout_ms"`
+		Syncers         []*ccr.SyncerInfo `json:"syncers"`
+	}
+	var syncersResult *result
+	defer func() { writeJson(w, syncersResult) }()
+
+	syncers, err := ccr.GetSyncers(s.db)
+	if err != nil {
+		log.Warnf("list syncers failed: %+v", err)
+		syncersResult = &result{defaultResult: newErrorResult(err.Error())}
+		return
+	}
+	syncersResult = &result{
+		defaultResult:   newSuccessResult(),
+		CheckIntervalMs: ccr.CheckInterval().Milliseconds(),
+		CheckTimeoutMs:  ccr.CheckTimeout().Milliseconds(),
+		Syncers:         syncers,
+	}
+}
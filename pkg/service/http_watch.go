@@ -0,0 +1,133 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	WATCH_KEEPALIVE_INTERVAL = 15 * time.Second
+	// The events buffered for each watcher, the watch is closed if the client is too slow to
+	// consume them, and the client should watch again to get the latest snapshots.
+	WATCH_EVENT_BUFFER_SIZE = 1024
+)
+
+// jobWatcher is the observer of a /watch request.
+type jobWatcher struct {
+	// Empty means all jobs.
+	names    map[string]bool
+	events   chan *ccr.JobWatchEvent
+	overflow chan struct{}
+	once     sync.Once
+}
+
+func newJobWatcher(names []string) *jobWatcher {
+	watcher := &jobWatcher{
+		names:    make(map[string]bool, len(names)),
+		events:   make(chan *ccr.JobWatchEvent, WATCH_EVENT_BUFFER_SIZE),
+		overflow: make(chan struct{}),
+	}
+	for _, name := range names {
+		watcher.names[name] = true
+	}
+	return watcher
+}
+
+// impl utils.Observer[*ccr.JobWatchEvent], it must not block.
+func (w *jobWatcher) Update(event *ccr.JobWatchEvent) {
+	if len(w.names) > 0 && !w.names[event.JobName] {
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.once.Do(func() { close(w.overflow) })
+	}
+}
+
+// parseWatchNames parses the names from the query, such as "?name=a&name=b" or "?name=a,b".
+func parseWatchNames(r *http.Request) []string {
+	names := make([]string, 0)
+	for _, value := range r.URL.Query()["name"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func writeWatchEvent(w http.ResponseWriter, event string, data interface{}) error {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, bytes)
+	return err
+}
+
+// watchHandler streams the state and progress changes of the jobs as server-sent events. The
+// snapshots of the watched jobs are sent first, then an event is sent once the state, sync
+// state, sub sync state or prev commit seq of a job changes.
+//
+// Only the jobs running in this syncer are watched, the request of a single job is redirected
+// to the syncer owns it.
+func (s *HttpService) watchHandler(w http.ResponseWriter, r *http.Request) {
+	names := parseWatchNames(r)
+	if len(names) == 1 && s.redirect(names[0], w, r) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJson(w, newErrorResult("streaming is not supported"))
+		return
+	}
+
+	log.Infof("watch jobs %v from %s", names, r.RemoteAddr)
+	watcher := newJobWatcher(names)
+	s.jobManager.Watch(watcher)
+	defer s.jobManager.Unwatch(watcher)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(WATCH_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			log.Infof("watch jobs %v from %s is closed", names, r.RemoteAddr)
+			return
+		case <-watcher.overflow:
+			log.Warnf("watch jobs %v from %s is closed, the client is too slow", names, r.RemoteAddr)
+			writeWatchEvent(w, "overflow", newErrorResult("too many events are not consumed, please watch again"))
+			flusher.Flush()
+			return
+		case event := <-watcher.events:
+			err = writeWatchEvent(w, "job", event)
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		}
+		if err != nil {
+			log.Warnf("watch jobs %v from %s failed: %+v", names, r.RemoteAddr, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// FileWriter appends the events to a local file, one JSON object per line.
+type FileWriter struct {
+	path string
+	file *os.File
+	lock sync.Mutex
+}
+
+func NewFileWriter(path string) (*FileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "create dir of file sink %s failed", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "open file sink %s failed", path)
+	}
+
+	return &FileWriter{
+		path: path,
+		file: file,
+	}, nil
+}
+
+func (w *FileWriter) Write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "marshal event failed, commit seq: %d", event.CommitSeq)
+	}
+	data = append(data, '\n')
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, err := w.file.Write(data); err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "write file sink %s failed", w.path)
+	}
+	// The progress is persisted after the event is written, sync it to avoid losing events.
+	if err := w.file.Sync(); err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "sync file sink %s failed", w.path)
+	}
+	return nil
+}
+
+func (w *FileWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if err := w.file.Close(); err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "close file sink %s failed", w.path)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package sink
+
+import (
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const (
+	// Replay the binlogs into the dest doris cluster, it is the default sink.
+	SinkTypeDoris   = "doris"
+	SinkTypeFile    = "file"
+	SinkTypeWebhook = "webhook"
+)
+
+// Event is a decoded binlog record, it is written as a JSON line by the writers.
+type Event struct {
+	JobName   string  `json:"job_name"`
+	CommitSeq int64   `json:"commit_seq"`
+	Timestamp int64   `json:"timestamp"`
+	Type      string  `json:"type"`
+	DbId      int64   `json:"db_id"`
+	TableIds  []int64 `json:"table_ids,omitempty"`
+	Record    any     `json:"record"`
+}
+
+// Writer publishes the events to somewhere other than doris.
+type Writer interface {
+	Write(event *Event) error
+	Close() error
+}
+
+type Config struct {
+	Type string `json:"type"`
+
+	// For file sink, the path of the JSON lines file, the events are appended to it.
+	Path string `json:"path,omitempty"`
+
+	// For webhook sink, each event is posted to the url as a JSON line.
+	Url       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	TimeoutMs int64             `json:"timeout_ms,omitempty"`
+}
+
+// IsDoris returns whether the binlogs are replayed into the dest doris cluster.
+func (c *Config) IsDoris() bool {
+	return c == nil || c.Type == "" || c.Type == SinkTypeDoris
+}
+
+func (c *Config) Valid() error {
+	if c.IsDoris() {
+		return nil
+	}
+
+	switch c.Type {
+	case SinkTypeFile:
+		if c.Path == "" {
+			return xerror.New(xerror.Normal, "file sink path is empty")
+		}
+	case SinkTypeWebhook:
+		if c.Url == "" {
+			return xerror.New(xerror.Normal, "webhook sink url is empty")
+		}
+		if c.TimeoutMs < 0 {
+			return xerror.Errorf(xerror.Normal, "invalid webhook sink timeout: %d", c.TimeoutMs)
+		}
+	default:
+		return xerror.Errorf(xerror.Normal, "unknown sink type: %s", c.Type)
+	}
+	return nil
+}
+
+func NewWriter(c *Config) (Writer, error) {
+	if err := c.Valid(); err != nil {
+		return nil, err
+	}
+
+	switch c.Type {
+	case SinkTypeFile:
+		return NewFileWriter(c.Path)
+	case SinkTypeWebhook:
+		return NewWebhookWriter(c.Url, c.Headers, c.TimeoutMs), nil
+	default:
+		return nil, xerror.Errorf(xerror.Normal, "sink type %s has no writer", c.Type)
+	}
+}
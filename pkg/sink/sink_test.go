@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{"nil is doris", nil, false},
+		{"empty type is doris", &Config{}, false},
+		{"file", &Config{Type: SinkTypeFile, Path: "/tmp/a.jsonl"}, false},
+		{"file without path", &Config{Type: SinkTypeFile}, true},
+		{"webhook", &Config{Type: SinkTypeWebhook, Url: "http://127.0.0.1"}, false},
+		{"webhook without url", &Config{Type: SinkTypeWebhook}, true},
+		{"unknown", &Config{Type: "kafka"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sink", "events.jsonl")
+	events := []*Event{
+		{JobName: "job", CommitSeq: 1, Type: "CREATE_TABLE", Record: map[string]any{"sql": "create table t1"}},
+		{JobName: "job", CommitSeq: 2, Type: "UPSERT", TableIds: []int64{10}},
+	}
+
+	writer, err := NewWriter(&Config{Type: SinkTypeFile, Path: path})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	for _, event := range events[:1] {
+		if err := writer.Write(event); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// reopen and append
+	writer, err = NewWriter(&Config{Type: SinkTypeFile, Path: path})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := writer.Write(events[1]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	writer.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open sink file error = %v", err)
+	}
+	defer file.Close()
+
+	var got []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("unmarshal line %s error = %v", scanner.Text(), err)
+		}
+		got = append(got, event)
+	}
+	if len(got) != len(events) {
+		t.Fatalf("got %d events, want %d", len(got), len(events))
+	}
+	for i, event := range got {
+		if event.CommitSeq != events[i].CommitSeq || event.Type != events[i].Type {
+			t.Errorf("event %d = %+v, want %+v", i, event, events[i])
+		}
+	}
+}
+
+func TestWebhookWriter(t *testing.T) {
+	var bodies []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "token" {
+			t.Errorf("header X-Token = %s", r.Header.Get("X-Token"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	writer, err := NewWriter(&Config{Type: SinkTypeWebhook, Url: server.URL, Headers: map[string]string{"X-Token": "token"}})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer writer.Close()
+
+	if err := writer.Write(&Event{JobName: "job", CommitSeq: 1, Type: "DROP_TABLE"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("got %d requests, want 1", len(bodies))
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil || event.CommitSeq != 1 {
+		t.Errorf("unexpected body %s, err: %v", bodies[0], err)
+	}
+
+	status = http.StatusInternalServerError
+	if err := writer.Write(&Event{JobName: "job", CommitSeq: 2}); err == nil {
+		t.Errorf("Write() expect error when webhook returns %d", status)
+	}
+}
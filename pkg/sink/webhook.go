@@ -0,0 +1,68 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookWriter posts each event to a http endpoint as a JSON line.
+type WebhookWriter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhookWriter(url string, headers map[string]string, timeoutMs int64) *WebhookWriter {
+	timeout := defaultWebhookTimeout
+	if timeoutMs > 0 {
+		timeout = time.Duration(timeoutMs) * time.Millisecond
+	}
+
+	return &WebhookWriter{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *WebhookWriter) Write(event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "marshal event failed, commit seq: %d", event.CommitSeq)
+	}
+	data = append(data, '\n')
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "new webhook sink request failed, url: %s", w.url)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.Normal, "post event to webhook sink failed, url: %s", w.url)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return xerror.Errorf(xerror.Normal, "post event to webhook sink failed, url: %s, status: %s",
+			w.url, resp.Status)
+	}
+	return nil
+}
+
+func (w *WebhookWriter) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
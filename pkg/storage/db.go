@@ -5,6 +5,10 @@ import "errors"
 var (
 	ErrJobExists    = errors.New("job exists")
 	ErrJobNotExists = errors.New("job not exists")
+	// The ownership epoch of the job is changed, the job is dispatched to another syncer.
+	ErrJobFenced = errors.New("job ownership epoch changed")
+	// The meta db is migrated by a newer syncer, see SchemaVersion.
+	ErrSchemaTooNew = errors.New("schema of meta db is newer than the syncer")
 )
 
 const (
@@ -25,9 +29,15 @@ type DB interface {
 	GetJobInfo(jobName string) (string, error)
 	// Get job_belong
 	GetJobBelong(jobName string) (string, error)
+	// Get job_belong and the ownership epoch of the job
+	GetJobOwnership(jobName string) (string, int64, error)
+	// Update ccr job if the ownership epoch is not changed, otherwise ErrJobFenced
+	UpdateJobWithEpoch(jobName string, jobInfo string, epoch int64) error
 
 	// Update ccr sync progress
 	UpdateProgress(jobName string, progress string) error
+	// Update ccr sync progress if the ownership epoch of the owner job is not changed, otherwise ErrJobFenced
+	UpdateProgressWithEpoch(jobName string, progress string, owner string, epoch int64) error
 	// IsProgressExist
 	IsProgressExist(jobName string) (bool, error)
 	// Get ccr sync progress
@@ -41,9 +51,37 @@ type DB interface {
 	GetStampAndJobs(hostInfo string) (int64, []string, error)
 	// GetOrphanJobs
 	GetDeadSyncers(expiredTime int64) ([]string, error)
-	// rebalance load
-	RebalanceLoadFromDeadSyncers(syncers []string) error
+	// rebalance load, returns the dead syncers removed by this call, the dead syncers removed by
+	// the other syncers concurrently are not included
+	RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error)
+	// Mark the syncer draining or not, the jobs are not dispatched to the draining syncers
+	DrainSyncer(hostInfo string, draining bool) error
+	// Get the draining syncers
+	GetDrainingSyncers() ([]string, error)
+
+	// Request to hand off the jobs, the pending handoff of the same job is kept
+	AddJobHandoffs(handoffs []*JobHandoff) error
+	// Get the pending handoffs from the syncer, all handoffs if the hostInfo is empty
+	GetJobHandoffs(hostInfo string) ([]*JobHandoff, error)
+	// Remove the pending handoff of the job
+	RemoveJobHandoff(jobName string) error
+	// Dispatch the job to the target and increase its epoch, if the epoch is not changed, otherwise ErrJobFenced
+	TransferJob(jobName string, epoch int64, target string) error
 
 	// GetAllData
 	GetAllData() (map[string][]string, error)
+
+	// Append a event into the job history
+	AddJobEvent(event *JobEvent) error
+	// Get the job events matched the filter, the latest events come first
+	GetJobEvents(filter *JobEventFilter) ([]*JobEvent, error)
+	// Remove the job events before the timestamp (unix milliseconds)
+	RemoveJobEventsBefore(timestamp int64) (int64, error)
+
+	// Save a data verification report of the job
+	AddVerifyReport(report *VerifyReport) error
+	// Get the latest verification reports of the job, the latest reports come first
+	GetVerifyReports(jobName string, limit int) ([]*VerifyReport, error)
+	// Remove the verification reports before the timestamp (unix milliseconds)
+	RemoveVerifyReportsBefore(timestamp int64) (int64, error)
 }
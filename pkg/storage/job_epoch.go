@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The ownership epoch of the job is increased each time the job is dispatched to another syncer,
+// the writes of the job and its progress are conditioned on the epoch, so a syncer which still
+// runs the job after it is rebalanced (e.g. a long GC pause or network partition) is fenced.
+const InitialJobEpoch int64 = 0
+
+// checkJobEpoch tells why the update conditioned on the epoch of the job matched no rows. MySQL
+// reports zero affected rows if the values are not changed, so the epoch might still be valid.
+func checkJobEpoch(row *sql.Row, jobName string, epoch int64) error {
+	var current int64
+	if err := row.Scan(&current); errors.Is(err, sql.ErrNoRows) {
+		return ErrJobNotExists
+	} else if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "query epoch of job %s failed", jobName)
+	}
+
+	if current != epoch {
+		return ErrJobFenced
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Two syncers share the meta db, the job of syncer a is dispatched to syncer b after a is
+// considered dead, but a still runs it.
+func TestSQLiteDB_JobEpochFencing(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	syncerA, syncerB := "127.0.0.1:9190", "127.0.0.1:9191"
+	require.NoError(t, db.AddSyncer(syncerA))
+	require.NoError(t, db.AddSyncer(syncerB))
+	require.NoError(t, db.AddJob("job", "info", syncerA))
+
+	belong, epochA, err := db.GetJobOwnership("job")
+	require.NoError(t, err)
+	assert.Equal(t, syncerA, belong)
+	assert.Equal(t, InitialJobEpoch, epochA)
+
+	require.NoError(t, db.UpdateJobWithEpoch("job", "info a", epochA))
+	require.NoError(t, db.UpdateJobWithEpoch("job", "info a", epochA))
+	require.NoError(t, db.UpdateProgressWithEpoch("job", "progress a", "job", epochA))
+	// The progress of the fan-out child is fenced by the parent.
+	require.NoError(t, db.UpdateProgressWithEpoch("job_dest0", "progress a", "job", epochA))
+
+	removed, err := db.RebalanceLoadFromDeadSyncers([]string{syncerA})
+	require.NoError(t, err)
+	assert.Equal(t, []string{syncerA}, removed)
+	// The dead syncer is removed by the other syncer concurrently.
+	removed, err = db.RebalanceLoadFromDeadSyncers([]string{syncerA})
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	belong, epochB, err := db.GetJobOwnership("job")
+	require.NoError(t, err)
+	assert.Equal(t, syncerB, belong)
+	assert.Equal(t, epochA+1, epochB)
+
+	// Syncer a is fenced.
+	assert.ErrorIs(t, db.UpdateJobWithEpoch("job", "info stale", epochA), ErrJobFenced)
+	assert.ErrorIs(t, db.UpdateProgressWithEpoch("job", "progress stale", "job", epochA), ErrJobFenced)
+	assert.ErrorIs(t, db.UpdateProgressWithEpoch("job_dest0", "progress stale", "job", epochA), ErrJobFenced)
+
+	jobInfo, err := db.GetJobInfo("job")
+	require.NoError(t, err)
+	assert.Equal(t, "info a", jobInfo)
+	progress, err := db.GetProgress("job")
+	require.NoError(t, err)
+	assert.Equal(t, "progress a", progress)
+
+	// Syncer b owns the job now.
+	require.NoError(t, db.UpdateJobWithEpoch("job", "info b", epochB))
+	require.NoError(t, db.UpdateProgressWithEpoch("job", "progress b", "job", epochB))
+	progress, err = db.GetProgress("job")
+	require.NoError(t, err)
+	assert.Equal(t, "progress b", progress)
+
+	assert.ErrorIs(t, db.UpdateJobWithEpoch("missing", "info", epochB), ErrJobNotExists)
+	assert.ErrorIs(t, db.UpdateProgressWithEpoch("missing", "progress", "missing", epochB), ErrJobNotExists)
+}
+
+func TestSQLiteDB_AddEpochColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccr.db")
+	old, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = old.Exec("CREATE TABLE jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT)")
+	require.NoError(t, err)
+	_, err = old.Exec("INSERT INTO jobs VALUES ('job', 'info', '127.0.0.1:9190')")
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	db, err := NewSQLiteDB(path)
+	require.NoError(t, err)
+	_, epoch, err := db.GetJobOwnership("job")
+	require.NoError(t, err)
+	assert.Equal(t, InitialJobEpoch, epoch)
+	require.NoError(t, db.UpdateJobWithEpoch("job", "info", epoch))
+
+	// Open again after the column is added.
+	_, err = NewSQLiteDB(path)
+	require.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultJobEventsLimit = 100
+	maxJobEventsLimit     = 10000
+)
+
+// JobEvent is a entry of the append-only job history.
+type JobEvent struct {
+	Id        int64  `json:"id"`
+	JobName   string `json:"job_name"`
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"` // unix milliseconds
+	Message   string `json:"message"`
+}
+
+// JobEventFilter filters the job events, the zero value of each field means no filter.
+type JobEventFilter struct {
+	JobName string
+	Types   []string
+	Since   int64 // unix milliseconds, inclusive
+	Until   int64 // unix milliseconds, exclusive
+	Limit   int
+}
+
+func (f *JobEventFilter) limit() int {
+	if f.Limit <= 0 {
+		return defaultJobEventsLimit
+	} else if f.Limit > maxJobEventsLimit {
+		return maxJobEventsLimit
+	}
+	return f.Limit
+}
+
+// buildJobEventsQuery returns the query of the filter and its args, the latest events come
+// first. The placeholder returns the bind var of the i-th (1-based) arg.
+func buildJobEventsQuery(table string, filter *JobEventFilter, placeholder func(int) string) (string, []interface{}) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	bind := func(value interface{}) string {
+		args = append(args, value)
+		return placeholder(len(args))
+	}
+
+	if filter.JobName != "" {
+		conds = append(conds, "job_name = "+bind(filter.JobName))
+	}
+	if len(filter.Types) > 0 {
+		vars := make([]string, 0, len(filter.Types))
+		for _, eventType := range filter.Types {
+			vars = append(vars, bind(eventType))
+		}
+		conds = append(conds, fmt.Sprintf("event_type IN (%s)", strings.Join(vars, ", ")))
+	}
+	if filter.Since > 0 {
+		conds = append(conds, "timestamp >= "+bind(filter.Since))
+	}
+	if filter.Until > 0 {
+		conds = append(conds, "timestamp < "+bind(filter.Until))
+	}
+
+	query := fmt.Sprintf("SELECT id, job_name, event_type, timestamp, message FROM %s", table)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d", filter.limit())
+	return query, args
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildJobEventsQuery(t *testing.T) {
+	filter := &JobEventFilter{
+		JobName: "job",
+		Types:   []string{"error", "rollback"},
+		Since:   100,
+		Until:   200,
+		Limit:   10,
+	}
+	query, args := buildJobEventsQuery("ccr.job_events", filter, func(i int) string { return fmt.Sprintf("$%d", i) })
+	assert.Equal(t, "SELECT id, job_name, event_type, timestamp, message FROM ccr.job_events "+
+		"WHERE job_name = $1 AND event_type IN ($2, $3) AND timestamp >= $4 AND timestamp < $5 "+
+		"ORDER BY id DESC LIMIT 10", query)
+	assert.Equal(t, []interface{}{"job", "error", "rollback", int64(100), int64(200)}, args)
+
+	query, args = buildJobEventsQuery("job_events", &JobEventFilter{}, func(int) string { return "?" })
+	assert.Equal(t, "SELECT id, job_name, event_type, timestamp, message FROM job_events ORDER BY id DESC LIMIT 100", query)
+	assert.Empty(t, args)
+}
+
+func TestSQLiteDB_JobEvents(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	events := []*JobEvent{
+		{JobName: "a", Type: "pause", Timestamp: 1000, Message: "pause job"},
+		{JobName: "a", Type: "error", Timestamp: 2000, Message: "it's a 'quoted' error"},
+		{JobName: "b", Type: "error", Timestamp: 3000, Message: "error"},
+	}
+	for _, event := range events {
+		require.NoError(t, db.AddJobEvent(event))
+	}
+
+	got, err := db.GetJobEvents(&JobEventFilter{JobName: "a"})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "error", got[0].Type)
+	assert.Equal(t, "it's a 'quoted' error", got[0].Message)
+	assert.Equal(t, "pause", got[1].Type)
+
+	got, err = db.GetJobEvents(&JobEventFilter{Types: []string{"error"}, Since: 2500})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "b", got[0].JobName)
+
+	removed, err := db.RemoveJobEventsBefore(2500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	got, err = db.GetJobEvents(&JobEventFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, int64(3000), got[0].Timestamp)
+}
@@ -0,0 +1,121 @@
+package storage
+
+import "sort"
+
+// JobHandoff is a request to move a job from a live syncer to another. The owner stops the job
+// after the binlog in handling and transfers it to the target, see DB.TransferJob.
+type JobHandoff struct {
+	JobName   string `json:"job_name"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Timestamp int64  `json:"timestamp"` // unix milliseconds
+}
+
+// JobLoad is the load of a job in the syncer owns it.
+type JobLoad struct {
+	JobName string
+	Syncer  string
+	Weight  int
+	// The job is handing off to the syncer already, it could not be moved again.
+	Pinned bool
+}
+
+// PlanJobHandoffs moves the jobs out of the syncers not in the targets (e.g. draining), and then
+// moves the jobs from the most loaded target to the least loaded one while it makes the load
+// more balanced. At most maxMoves jobs are moved, 0 means unlimited.
+func PlanJobHandoffs(jobs []JobLoad, targets []string, maxMoves int) []*JobHandoff {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	loads := make(map[string]int, len(targets))
+	for _, target := range targets {
+		loads[target] = 0
+	}
+	jobs = append([]JobLoad(nil), jobs...)
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].Weight != jobs[j].Weight {
+			return jobs[i].Weight > jobs[j].Weight
+		}
+		return jobs[i].JobName < jobs[j].JobName
+	})
+
+	var evicted []int
+	for i := range jobs {
+		if _, ok := loads[jobs[i].Syncer]; ok {
+			loads[jobs[i].Syncer] += jobs[i].Weight
+		} else if !jobs[i].Pinned {
+			evicted = append(evicted, i)
+		}
+	}
+
+	leastLoaded := func() string {
+		least := ""
+		for _, target := range targets {
+			if least == "" || loads[target] < loads[least] || (loads[target] == loads[least] && target < least) {
+				least = target
+			}
+		}
+		return least
+	}
+	mostLoaded := func() string {
+		most := ""
+		for _, target := range targets {
+			if most == "" || loads[target] > loads[most] || (loads[target] == loads[most] && target < most) {
+				most = target
+			}
+		}
+		return most
+	}
+
+	handoffs := make([]*JobHandoff, 0)
+	move := func(job *JobLoad, to string) {
+		handoffs = append(handoffs, &JobHandoff{JobName: job.JobName, From: job.Syncer, To: to})
+		if _, ok := loads[job.Syncer]; ok {
+			loads[job.Syncer] -= job.Weight
+		}
+		loads[to] += job.Weight
+		job.Syncer = to
+		job.Pinned = true
+	}
+
+	// The heaviest jobs are moved first.
+	for _, i := range evicted {
+		if maxMoves > 0 && len(handoffs) >= maxMoves {
+			return handoffs
+		}
+		move(&jobs[i], leastLoaded())
+	}
+
+	for maxMoves <= 0 || len(handoffs) < maxMoves {
+		most, least := mostLoaded(), leastLoaded()
+		diff := loads[most] - loads[least]
+
+		// Move the job makes the two syncers closest, the lighter one if tied. Only the move
+		// reduces the difference is made, so the jobs never move back and forth.
+		var candidate *JobLoad
+		for i := range jobs {
+			job := &jobs[i]
+			if job.Syncer != most || job.Pinned || job.Weight >= diff {
+				continue
+			}
+			if candidate == nil {
+				candidate = job
+			} else if d, c := abs(diff-2*job.Weight), abs(diff-2*candidate.Weight); d < c || (d == c && job.Weight < candidate.Weight) {
+				candidate = job
+			}
+		}
+		if candidate == nil {
+			break
+		}
+		move(candidate, least)
+	}
+	return handoffs
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func handoffJobs(handoffs []*JobHandoff) map[string]string {
+	moved := make(map[string]string)
+	for _, handoff := range handoffs {
+		moved[handoff.JobName] = handoff.From + "->" + handoff.To
+	}
+	return moved
+}
+
+func TestPlanJobHandoffs(t *testing.T) {
+	// A new syncer c joins.
+	jobs := []JobLoad{
+		{JobName: "a1", Syncer: "a", Weight: 1},
+		{JobName: "a2", Syncer: "a", Weight: 1},
+		{JobName: "a3", Syncer: "a", Weight: 1},
+		{JobName: "b1", Syncer: "b", Weight: 1},
+		{JobName: "b2", Syncer: "b", Weight: 1},
+		{JobName: "b3", Syncer: "b", Weight: 1},
+	}
+	handoffs := PlanJobHandoffs(jobs, []string{"a", "b", "c"}, 0)
+	assert.Equal(t, map[string]string{"a1": "a->c", "b1": "b->c"}, handoffJobs(handoffs))
+
+	// At most maxMoves jobs are moved.
+	handoffs = PlanJobHandoffs(jobs, []string{"a", "b", "c"}, 1)
+	assert.Equal(t, map[string]string{"a1": "a->c"}, handoffJobs(handoffs))
+
+	// Balanced, a move doesn't reduce the difference.
+	handoffs = PlanJobHandoffs(jobs[:5], []string{"a", "b"}, 0)
+	assert.Empty(t, handoffs)
+
+	// The weight of the db sync is 3, the pinned job is not moved.
+	jobs = []JobLoad{
+		{JobName: "db", Syncer: "a", Weight: 3},
+		{JobName: "t1", Syncer: "a", Weight: 1, Pinned: true},
+		{JobName: "t2", Syncer: "a", Weight: 1},
+		{JobName: "t3", Syncer: "b", Weight: 1},
+	}
+	handoffs = PlanJobHandoffs(jobs, []string{"a", "b"}, 0)
+	assert.Equal(t, map[string]string{"t2": "a->b"}, handoffJobs(handoffs))
+
+	// Drain syncer a, the heaviest job goes to the least loaded syncer first.
+	handoffs = PlanJobHandoffs(jobs, []string{"b", "c"}, 0)
+	assert.Equal(t, map[string]string{"db": "a->c", "t2": "a->b"}, handoffJobs(handoffs))
+
+	assert.Empty(t, PlanJobHandoffs(jobs, nil, 0))
+}
+
+// Syncer a hands off its job to the new syncer b.
+func TestSQLiteDB_JobHandoff(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	syncerA, syncerB := "127.0.0.1:9190", "127.0.0.1:9191"
+	require.NoError(t, db.AddSyncer(syncerA))
+	require.NoError(t, db.AddSyncer(syncerB))
+	require.NoError(t, db.AddJob("job1", "info", syncerA))
+	require.NoError(t, db.AddJob("job2", "info", syncerA))
+
+	handoff := &JobHandoff{JobName: "job1", From: syncerA, To: syncerB, Timestamp: 1000}
+	require.NoError(t, db.AddJobHandoffs([]*JobHandoff{handoff}))
+	// The pending handoff is kept.
+	require.NoError(t, db.AddJobHandoffs([]*JobHandoff{{JobName: "job1", From: syncerA, To: "other", Timestamp: 2000}}))
+
+	handoffs, err := db.GetJobHandoffs(syncerA)
+	require.NoError(t, err)
+	assert.Equal(t, []*JobHandoff{handoff}, handoffs)
+	handoffs, err = db.GetJobHandoffs(syncerB)
+	require.NoError(t, err)
+	assert.Empty(t, handoffs)
+
+	// The refresh of the target fails after the job is transferred, so it recovers the job.
+	stamp, _, err := db.GetStampAndJobs(syncerB)
+	require.NoError(t, err)
+	require.NoError(t, db.TransferJob("job1", InitialJobEpoch, syncerB))
+	newStamp, err := db.RefreshSyncer(syncerB, stamp)
+	require.NoError(t, err)
+	assert.Equal(t, InvalidCheckTimestamp, newStamp)
+
+	belong, epoch, err := db.GetJobOwnership("job1")
+	require.NoError(t, err)
+	assert.Equal(t, syncerB, belong)
+	assert.Equal(t, InitialJobEpoch+1, epoch)
+	handoffs, err = db.GetJobHandoffs("")
+	require.NoError(t, err)
+	assert.Empty(t, handoffs)
+
+	// The stale owner could not transfer it again.
+	assert.ErrorIs(t, db.TransferJob("job1", InitialJobEpoch, syncerA), ErrJobFenced)
+	assert.ErrorIs(t, db.TransferJob("missing", InitialJobEpoch, syncerA), ErrJobNotExists)
+
+	// The jobs of the dead syncer are not dispatched to the draining syncer.
+	syncerC := "127.0.0.1:9192"
+	require.NoError(t, db.AddSyncer(syncerC))
+	require.NoError(t, db.DrainSyncer(syncerB, true))
+	require.NoError(t, db.DrainSyncer(syncerB, true))
+	draining, err := db.GetDrainingSyncers()
+	require.NoError(t, err)
+	assert.Equal(t, []string{syncerB}, draining)
+
+	require.NoError(t, db.AddJobHandoffs([]*JobHandoff{{JobName: "job2", From: syncerA, To: syncerC}}))
+	_, err = db.RebalanceLoadFromDeadSyncers([]string{syncerA})
+	require.NoError(t, err)
+	belong, _, err = db.GetJobOwnership("job2")
+	require.NoError(t, err)
+	assert.Equal(t, syncerC, belong)
+	handoffs, err = db.GetJobHandoffs("")
+	require.NoError(t, err)
+	assert.Empty(t, handoffs)
+
+	// The syncer is undrained after restarted.
+	require.NoError(t, db.AddSyncer(syncerB))
+	draining, err = db.GetDrainingSyncers()
+	require.NoError(t, err)
+	assert.Empty(t, draining)
+
+	require.NoError(t, db.AddJobHandoffs([]*JobHandoff{{JobName: "job2", From: syncerC, To: syncerB}}))
+	require.NoError(t, db.RemoveJob("job2"))
+	handoffs, err = db.GetJobHandoffs("")
+	require.NoError(t, err)
+	assert.Empty(t, handoffs)
+}
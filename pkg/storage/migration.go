@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	log "github.com/sirupsen/logrus"
+)
+
+// SchemaVersion is the version of the meta db schema known by this syncer, it is the version of
+// the last migration of each dialect.
+const SchemaVersion = 5
+
+const (
+	// The GET_LOCK name of mysql and the pg_advisory_lock key of postgresql, held by the syncer
+	// which is migrating the meta db.
+	migrationLockName          = "ccr_syncer_schema_migration"
+	migrationLockKey     int64 = 0x636372736d // "ccrsm"
+	migrationLockTimeout       = 60 * time.Second
+)
+
+// migration upgrades the meta db schema from version-1 to version. MySQL commits the DDL
+// implicitly, a migration interrupted is run again at the next start, so the statements must be
+// idempotent.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return xerror.Wrapf(err, xerror.DB, "exec %s failed", statement)
+			}
+		}
+		return nil
+	}
+}
+
+// addColumnIfNotExists adds the column unless the query of the column count says it is exists.
+func addColumnIfNotExists(countQuery string, addColumn string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRow(countQuery).Scan(&count); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "query %s failed", countQuery)
+		}
+		if count > 0 {
+			return nil
+		}
+		return execStatements(addColumn)(tx)
+	}
+}
+
+// schemaDialect is the schema_version table and the ordered migrations of a db type.
+type schemaDialect struct {
+	name         string
+	versionTable string
+	// Serialize the migrations of the syncers sharing the meta db.
+	lock       func(conn *sql.Conn) error
+	unlock     func(conn *sql.Conn) error
+	migrations []migration
+}
+
+var sqliteDialect = &schemaDialect{
+	name:         "sqlite",
+	versionTable: "schema_version",
+	migrations: []migration{
+		{1, "create jobs, progresses and syncers", execStatements(
+			"CREATE TABLE IF NOT EXISTS jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT)",
+			"CREATE TABLE IF NOT EXISTS progresses (job_name TEXT PRIMARY KEY, progress TEXT)",
+			"CREATE TABLE IF NOT EXISTS syncers (host_info TEXT PRIMARY KEY, timestamp INTEGER)",
+		)},
+		{2, "create job_events", execStatements(
+			"CREATE TABLE IF NOT EXISTS job_events (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, event_type TEXT, timestamp INTEGER, message TEXT)",
+			"CREATE INDEX IF NOT EXISTS idx_job_events_job_name ON job_events (job_name, timestamp)",
+		)},
+		{3, "create verify_reports", execStatements(
+			"CREATE TABLE IF NOT EXISTS verify_reports (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, commit_seq INTEGER, timestamp INTEGER, matched INTEGER, report TEXT)",
+			"CREATE INDEX IF NOT EXISTS idx_verify_reports_job_name ON verify_reports (job_name, timestamp)",
+		)},
+		{4, "add epoch to jobs", addColumnIfNotExists(
+			"SELECT COUNT(*) FROM pragma_table_info('jobs') WHERE name = 'epoch'",
+			"ALTER TABLE jobs ADD COLUMN epoch INTEGER DEFAULT 0",
+		)},
+		{5, "create job_handoffs and draining_syncers", execStatements(
+			"CREATE TABLE IF NOT EXISTS job_handoffs (job_name TEXT PRIMARY KEY, from_syncer TEXT, to_syncer TEXT, timestamp INTEGER)",
+			"CREATE TABLE IF NOT EXISTS draining_syncers (host_info TEXT PRIMARY KEY, timestamp INTEGER)",
+		)},
+	},
+}
+
+var mysqlDialect = &schemaDialect{
+	name:         "mysql",
+	versionTable: "schema_version",
+	lock: func(conn *sql.Conn) error {
+		var locked sql.NullInt64
+		query := fmt.Sprintf("SELECT GET_LOCK('%s', %d)", migrationLockName, int(migrationLockTimeout.Seconds()))
+		if err := conn.QueryRowContext(context.Background(), query).Scan(&locked); err != nil {
+			return xerror.Wrap(err, xerror.DB, "get migration lock failed")
+		}
+		if locked.Int64 != 1 {
+			return xerror.Errorf(xerror.DB, "get migration lock timeout after %s", migrationLockTimeout)
+		}
+		return nil
+	},
+	unlock: func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(context.Background(), fmt.Sprintf("SELECT RELEASE_LOCK('%s')", migrationLockName))
+		return err
+	},
+	migrations: []migration{
+		{1, "create jobs, progresses and syncers", execStatements(
+			"CREATE TABLE IF NOT EXISTS jobs (`job_name` VARCHAR(512) PRIMARY KEY, `job_info` TEXT, `belong_to` VARCHAR(96))",
+			"CREATE TABLE IF NOT EXISTS progresses (`job_name` VARCHAR(512) PRIMARY KEY, `progress` LONGTEXT)",
+			"CREATE TABLE IF NOT EXISTS syncers (`host_info` VARCHAR(96) PRIMARY KEY, `timestamp` BIGINT)",
+		)},
+		{2, "create job_events", execStatements(
+			"CREATE TABLE IF NOT EXISTS job_events (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `job_name` VARCHAR(512), `event_type` VARCHAR(64), `timestamp` BIGINT, `message` TEXT, INDEX idx_job_events_job_name (`job_name`, `timestamp`))",
+		)},
+		{3, "create verify_reports", execStatements(
+			"CREATE TABLE IF NOT EXISTS verify_reports (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `job_name` VARCHAR(512), `commit_seq` BIGINT, `timestamp` BIGINT, `matched` BOOLEAN, `report` LONGTEXT, INDEX idx_verify_reports_job_name (`job_name`, `timestamp`))",
+		)},
+		{4, "add epoch to jobs", addColumnIfNotExists(
+			fmt.Sprintf("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = '%s' AND table_name = 'jobs' AND column_name = 'epoch'", remoteDBName),
+			"ALTER TABLE jobs ADD COLUMN `epoch` BIGINT DEFAULT 0",
+		)},
+		{5, "create job_handoffs and draining_syncers", execStatements(
+			"CREATE TABLE IF NOT EXISTS job_handoffs (`job_name` VARCHAR(512) PRIMARY KEY, `from_syncer` VARCHAR(96), `to_syncer` VARCHAR(96), `timestamp` BIGINT)",
+			"CREATE TABLE IF NOT EXISTS draining_syncers (`host_info` VARCHAR(96) PRIMARY KEY, `timestamp` BIGINT)",
+		)},
+	},
+}
+
+var postgresqlDialect = &schemaDialect{
+	name:         "postgresql",
+	versionTable: remoteDBName + ".schema_version",
+	lock: func(conn *sql.Conn) error {
+		ctx, cancel := context.WithTimeout(context.Background(), migrationLockTimeout)
+		defer cancel()
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockKey)); err != nil {
+			return xerror.Wrap(err, xerror.DB, "get migration lock failed")
+		}
+		return nil
+	},
+	unlock: func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(context.Background(), fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockKey))
+		return err
+	},
+	migrations: []migration{
+		{1, "create jobs, progresses and syncers", execStatements(
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.jobs (job_name VARCHAR(512) PRIMARY KEY, job_info TEXT, belong_to VARCHAR(96))", remoteDBName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.progresses (job_name VARCHAR(512) PRIMARY KEY, progress TEXT)", remoteDBName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.syncers (host_info VARCHAR(96) PRIMARY KEY, timestamp BIGINT)", remoteDBName),
+		)},
+		{2, "create job_events", execStatements(
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.job_events (id BIGSERIAL PRIMARY KEY, job_name VARCHAR(512), event_type VARCHAR(64), timestamp BIGINT, message TEXT)", remoteDBName),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_job_events_job_name ON %s.job_events (job_name, timestamp)", remoteDBName),
+		)},
+		{3, "create verify_reports", execStatements(
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.verify_reports (id BIGSERIAL PRIMARY KEY, job_name VARCHAR(512), commit_seq BIGINT, timestamp BIGINT, matched BOOLEAN, report TEXT)", remoteDBName),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_verify_reports_job_name ON %s.verify_reports (job_name, timestamp)", remoteDBName),
+		)},
+		{4, "add epoch to jobs", execStatements(
+			fmt.Sprintf("ALTER TABLE %s.jobs ADD COLUMN IF NOT EXISTS epoch BIGINT DEFAULT 0", remoteDBName),
+		)},
+		{5, "create job_handoffs and draining_syncers", execStatements(
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.job_handoffs (job_name VARCHAR(512) PRIMARY KEY, from_syncer VARCHAR(96), to_syncer VARCHAR(96), timestamp BIGINT)", remoteDBName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.draining_syncers (host_info VARCHAR(96) PRIMARY KEY, timestamp BIGINT)", remoteDBName),
+		)},
+	},
+}
+
+// migrate upgrades the meta db to SchemaVersion. The tables created by the versions before the
+// schema_version table are treated as version 0, all migrations are idempotent to them.
+func migrate(db *sql.DB, dialect *schemaDialect) error {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "%s: get connection for migration failed", dialect.name)
+	}
+	defer conn.Close()
+
+	if dialect.lock != nil {
+		if err := dialect.lock(conn); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "%s: lock for migration failed", dialect.name)
+		}
+		defer func() {
+			if err := dialect.unlock(conn); err != nil {
+				log.Warnf("%s: release migration lock failed: %+v", dialect.name, err)
+			}
+		}()
+	}
+
+	if _, err := conn.ExecContext(context.Background(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version INTEGER PRIMARY KEY, description VARCHAR(256), applied_at BIGINT)", dialect.versionTable)); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "%s: create table schema_version failed", dialect.name)
+	}
+
+	version, err := schemaVersion(conn, dialect)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return xerror.Wrapf(ErrSchemaTooNew, xerror.DB, "%s: the schema version of the meta db is %d, this syncer knows %d, upgrade the syncer", dialect.name, version, SchemaVersion)
+	}
+
+	for _, m := range dialect.migrations {
+		if m.version <= version {
+			continue
+		}
+
+		log.Infof("%s: migrate the meta db to schema version %d: %s", dialect.name, m.version, m.description)
+		if err := applyMigration(conn, dialect, &m); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "%s: migrate to schema version %d failed", dialect.name, m.version)
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns the version of the last applied migration, 0 if there is none.
+func schemaVersion(conn *sql.Conn, dialect *schemaDialect) (int, error) {
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", dialect.versionTable)
+	if err := conn.QueryRowContext(context.Background(), query).Scan(&version); err != nil {
+		return 0, xerror.Wrapf(err, xerror.DB, "%s: query schema version failed", dialect.name)
+	}
+	return version, nil
+}
+
+func applyMigration(conn *sql.Conn, dialect *schemaDialect, m *migration) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return xerror.Wrap(err, xerror.DB, "begin transaction failed")
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	insertSql := fmt.Sprintf("INSERT INTO %s (version, description, applied_at) VALUES (%d, '%s', %d)",
+		dialect.versionTable, m.version, m.description, time.Now().UnixMilli())
+	if _, err := tx.Exec(insertSql); err != nil {
+		return xerror.Wrap(err, xerror.DB, "insert schema version failed")
+	}
+	if err := tx.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "commit failed")
+	}
+	return nil
+}
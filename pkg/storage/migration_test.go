@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDialects(t *testing.T) {
+	for _, dialect := range []*schemaDialect{sqliteDialect, mysqlDialect, postgresqlDialect} {
+		require.Len(t, dialect.migrations, SchemaVersion, dialect.name)
+		for i, m := range dialect.migrations {
+			assert.Equal(t, i+1, m.version, "%s: migrations must be ordered", dialect.name)
+		}
+	}
+}
+
+func TestSQLiteDB_Migrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccr.db")
+	_, err := NewSQLiteDB(path)
+	require.NoError(t, err)
+
+	versions := func() []int {
+		db, err := sql.Open("sqlite3", path)
+		require.NoError(t, err)
+		defer db.Close()
+		rows, err := db.Query("SELECT version FROM schema_version ORDER BY version")
+		require.NoError(t, err)
+		defer rows.Close()
+		var versions []int
+		for rows.Next() {
+			var version int
+			require.NoError(t, rows.Scan(&version))
+			versions = append(versions, version)
+		}
+		return versions
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, versions())
+
+	// Open again, nothing to migrate.
+	_, err = NewSQLiteDB(path)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, versions())
+}
+
+func TestSQLiteDB_MigrateUnversioned(t *testing.T) {
+	// The meta db created before the schema_version table.
+	path := filepath.Join(t.TempDir(), "ccr.db")
+	old, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = old.Exec("CREATE TABLE jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT, epoch INTEGER DEFAULT 0)")
+	require.NoError(t, err)
+	_, err = old.Exec("CREATE TABLE job_events (id INTEGER PRIMARY KEY AUTOINCREMENT, job_name TEXT, event_type TEXT, timestamp INTEGER, message TEXT)")
+	require.NoError(t, err)
+	_, err = old.Exec("INSERT INTO jobs VALUES ('job', 'info', '127.0.0.1:9190', 3)")
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	db, err := NewSQLiteDB(path)
+	require.NoError(t, err)
+	_, epoch, err := db.GetJobOwnership("job")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), epoch)
+	require.NoError(t, db.DrainSyncer("127.0.0.1:9190", true))
+}
+
+func TestSQLiteDB_SchemaTooNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccr.db")
+	_, err := NewSQLiteDB(path)
+	require.NoError(t, err)
+
+	newer, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = newer.Exec("INSERT INTO schema_version VALUES (?, 'from the future', 0)", SchemaVersion+1)
+	require.NoError(t, err)
+	require.NoError(t, newer.Close())
+
+	_, err = NewSQLiteDB(path)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
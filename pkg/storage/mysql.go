@@ -28,9 +28,14 @@ type MysqlDB struct {
 }
 
 func NewMysqlDB(host string, port int, user string, password string) (DB, error) {
-	dbForDDL, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/?maxAllowedPacket=%d", user, password, host, port, maxAllowedPacket))
+	tlsParams, err := mysqlTLSParams()
 	if err != nil {
-		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open %s@tcp(%s:%s) failed", user, host, password)
+		return nil, err
+	}
+
+	dbForDDL, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/?maxAllowedPacket=%d%s", user, password, host, port, maxAllowedPacket, tlsParams))
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open %s@tcp(%s:%d) failed", user, host, port)
 	}
 
 	if _, err := dbForDDL.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s", remoteDBName)); err != nil {
@@ -38,21 +43,14 @@ func NewMysqlDB(host string, port int, user string, password string) (DB, error)
 	}
 	dbForDDL.Close()
 
-	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?maxAllowedPacket=%d", user, password, host, port, remoteDBName, maxAllowedPacket))
+	db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?maxAllowedPacket=%d%s", user, password, host, port, remoteDBName, maxAllowedPacket, tlsParams))
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.DB, "mysql: open mysql in db %s@tcp(%s:%d)/%s failed", user, host, port, remoteDBName)
 	}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS jobs (`job_name` VARCHAR(512) PRIMARY KEY, `job_info` TEXT, `belong_to` VARCHAR(96))"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table jobs failed")
-	}
-
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS progresses (`job_name` VARCHAR(512) PRIMARY KEY, `progress` LONGTEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table progresses failed")
-	}
-
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS syncers (`host_info` VARCHAR(96) PRIMARY KEY, `timestamp` BIGINT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "mysql: create table syncers failed")
+	if err = migrate(db, mysqlDialect); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return &MysqlDB{db: db}, nil
@@ -121,6 +119,13 @@ func (s *MysqlDB) RemoveJob(jobName string) error {
 		return xerror.Wrapf(err, xerror.DB, "mysql: remove progresses failed, name: %s", jobName)
 	}
 
+	if _, err := txn.Exec(fmt.Sprintf("DELETE FROM job_handoffs WHERE job_name = '%s'", jobName)); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: remove job handoff failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "mysql: remove job handoff failed, name: %s", jobName)
+	}
+
 	if err := txn.Commit(); err != nil {
 		return xerror.Wrapf(err, xerror.DB, "mysql: remove job txn commit failed.")
 	}
@@ -153,6 +158,29 @@ func (s *MysqlDB) GetJobBelong(jobName string) (string, error) {
 	return belong, nil
 }
 
+func (s *MysqlDB) GetJobOwnership(jobName string) (string, int64, error) {
+	var belong string
+	var epoch int64
+	if err := s.db.QueryRow(fmt.Sprintf("SELECT belong_to, epoch FROM jobs WHERE job_name = '%s'", jobName)).Scan(&belong, &epoch); err != nil {
+		return "", 0, xerror.Wrapf(err, xerror.DB, "mysql: get job ownership failed, name: %s", jobName)
+	}
+	return belong, epoch, nil
+}
+
+func (s *MysqlDB) UpdateJobWithEpoch(jobName string, jobInfo string, epoch int64) error {
+	result, err := s.db.Exec(fmt.Sprintf("UPDATE jobs SET job_info = '%s' WHERE job_name = '%s' AND epoch = %d", jobInfo, jobName, epoch))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: update job name %s failed", jobName)
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: update job name %s get affected rows failed", jobName)
+	} else if rowNum == 0 {
+		return checkJobEpoch(s.db.QueryRow(fmt.Sprintf("SELECT epoch FROM jobs WHERE job_name = '%s'", jobName)), jobName, epoch)
+	}
+	return nil
+}
+
 func (s *MysqlDB) UpdateProgress(jobName string, progress string) error {
 	// quoteProgress := strings.ReplaceAll(progress, "\"", "\\\"")
 	encodeProgress := base64.StdEncoding.EncodeToString([]byte(progress))
@@ -168,6 +196,38 @@ func (s *MysqlDB) UpdateProgress(jobName string, progress string) error {
 	return nil
 }
 
+func (s *MysqlDB) UpdateProgressWithEpoch(jobName string, progress string, owner string, epoch int64) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: update progress begin transaction failed, name: %s", jobName)
+	}
+
+	// Lock the job row, so the job is not dispatched before the progress is updated.
+	if err := checkJobEpoch(txn.QueryRow(fmt.Sprintf("SELECT epoch FROM jobs WHERE job_name = '%s' FOR UPDATE", owner)), owner, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: update progress rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	encodeProgress := base64.StdEncoding.EncodeToString([]byte(progress))
+	updateSql := fmt.Sprintf("INSERT INTO progresses (job_name, progress) VALUES ('%s', '%s') ON DUPLICATE KEY UPDATE progress = VALUES(progress)", jobName, encodeProgress)
+	if _, err := txn.Exec(updateSql); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: update progress failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "mysql: update progress failed, name: %s", jobName)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "mysql: update progress txn commit failed.")
+	}
+	return nil
+}
+
 func (s *MysqlDB) IsProgressExist(jobName string) (bool, error) {
 	var count int
 	if err := s.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM progresses WHERE job_name = '%s'", jobName)).Scan(&count); err != nil {
@@ -189,6 +249,11 @@ func (s *MysqlDB) GetProgress(jobName string) (string, error) {
 }
 
 func (s *MysqlDB) AddSyncer(hostInfo string) error {
+	// The syncer is restarted after the maintenance.
+	if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM draining_syncers WHERE host_info = '%s'", hostInfo)); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: undrain syncer %s failed", hostInfo)
+	}
+
 	timestamp := time.Now().UnixNano()
 	addSql := fmt.Sprintf("INSERT INTO syncers (host_info, timestamp) VALUES ('%s', %d) ON DUPLICATE KEY UPDATE timestamp = VALUES(timestamp)", hostInfo, timestamp)
 	if result, err := s.db.Exec(addSql); err != nil {
@@ -271,34 +336,48 @@ func (s *MysqlDB) GetDeadSyncers(expiredTime int64) ([]string, error) {
 	return deadSyncers, nil
 }
 
-func (s *MysqlDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, error) {
+func (s *MysqlDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, []string, error) {
 	orphanJobs := make([]string, 0)
+	removedSyncers := make([]string, 0)
 	for _, deadSyncer := range syncers {
 		rows, err := txn.Query(fmt.Sprintf("SELECT job_name FROM jobs WHERE belong_to = '%s'", deadSyncer))
 		if err != nil {
-			return nil, xerror.Wrapf(err, xerror.DB, "mysql: get orphan jobs failed.")
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: get orphan jobs failed.")
 		}
 
 		for rows.Next() {
 			var jobName string
 			if err := rows.Scan(&jobName); err != nil {
-				return nil, xerror.Wrapf(err, xerror.DB, "mysql: scan orphan job name failed.")
+				return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: scan orphan job name failed.")
 			}
 			orphanJobs = append(orphanJobs, jobName)
 		}
 		rows.Close()
 
-		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM syncers WHERE host_info = '%s'", deadSyncer)); err != nil {
-			return nil, xerror.Wrapf(err, xerror.DB, "mysql: delete dead syncer failed, name: %s", deadSyncer)
+		result, err := txn.Exec(fmt.Sprintf("DELETE FROM syncers WHERE host_info = '%s'", deadSyncer))
+		if err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: delete dead syncer failed, name: %s", deadSyncer)
+		}
+		// The dead syncer might be removed by the other syncers concurrently.
+		if removed, err := result.RowsAffected(); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: get the removed dead syncer failed, name: %s", deadSyncer)
+		} else if removed > 0 {
+			removedSyncers = append(removedSyncers, deadSyncer)
+		}
+		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM job_handoffs WHERE from_syncer = '%s'", deadSyncer)); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: delete job handoffs of dead syncer failed, name: %s", deadSyncer)
+		}
+		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM draining_syncers WHERE host_info = '%s'", deadSyncer)); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "mysql: delete draining dead syncer failed, name: %s", deadSyncer)
 		}
 	}
-	return orphanJobs, nil
+	return orphanJobs, removedSyncers, nil
 }
 
 func (s *MysqlDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 	load := make(LoadSlice, 0)
 	sumLoad := 0
-	host_rows, err := txn.Query("SELECT host_info FROM syncers")
+	host_rows, err := txn.Query("SELECT host_info FROM syncers WHERE host_info NOT IN (SELECT host_info FROM draining_syncers)")
 	if err != nil {
 		return nil, -1, xerror.Wrapf(err, xerror.DB, "mysql: get all syncers failed.")
 	}
@@ -323,7 +402,7 @@ func (s *MysqlDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 
 func (s *MysqlDB) dispatchJobs(txn *sql.Tx, hostInfo string, additionalJobs []string) error {
 	for _, jobName := range additionalJobs {
-		if _, err := txn.Exec(fmt.Sprintf("UPDATE jobs SET belong_to = '%s' WHERE job_name = '%s'", hostInfo, jobName)); err != nil {
+		if _, err := txn.Exec(fmt.Sprintf("UPDATE jobs SET belong_to = '%s', epoch = epoch + 1 WHERE job_name = '%s'", hostInfo, jobName)); err != nil {
 			return xerror.Wrapf(err, xerror.DB, "mysql: update job belong_to failed, name: %s", jobName)
 		}
 	}
@@ -333,45 +412,161 @@ func (s *MysqlDB) dispatchJobs(txn *sql.Tx, hostInfo string, additionalJobs []st
 	return nil
 }
 
-func (s *MysqlDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
+func (s *MysqlDB) RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error) {
 	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 		ReadOnly:  false,
 	})
 	if err != nil {
-		return xerror.Wrap(err, xerror.DB, "mysql: rebalance load begin txn failed")
+		return nil, xerror.Wrap(err, xerror.DB, "mysql: rebalance load begin txn failed")
 	}
 
-	orphanJobs, err := s.getOrphanJobs(txn, syncers)
+	orphanJobs, removedSyncers, err := s.getOrphanJobs(txn, syncers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	additionalLoad := len(orphanJobs)
+	if additionalLoad == 0 {
+		// The dead syncers have no jobs, or they are rebalanced by the other syncers.
+		if err := txn.Commit(); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "mysql: rebalance txn commit failed.")
+		}
+		return removedSyncers, nil
+	}
+
 	loadList, currentLoad, err := s.getLoadInfo(txn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	loadList, err = RebalanceLoad(additionalLoad, currentLoad, loadList)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for i := range loadList {
 		beginIdx := additionalLoad - loadList[i].AddedLoad
 		if err := s.dispatchJobs(txn, loadList[i].HostInfo, orphanJobs[beginIdx:additionalLoad]); err != nil {
 			if err := txn.Rollback(); err != nil {
-				return xerror.Wrap(err, xerror.DB, "mysql: rebalance rollback failed.")
+				return nil, xerror.Wrap(err, xerror.DB, "mysql: rebalance rollback failed.")
 			}
-			return err
+			return nil, err
 		}
 		additionalLoad = beginIdx
 	}
 
 	if err := txn.Commit(); err != nil {
-		return xerror.Wrap(err, xerror.DB, "mysql: rebalance txn commit failed.")
+		return nil, xerror.Wrap(err, xerror.DB, "mysql: rebalance txn commit failed.")
+	}
+
+	return removedSyncers, nil
+}
+
+func (s *MysqlDB) DrainSyncer(hostInfo string, draining bool) error {
+	var err error
+	if draining {
+		_, err = s.db.Exec(fmt.Sprintf("INSERT IGNORE INTO draining_syncers (host_info, timestamp) VALUES ('%s', %d)", hostInfo, time.Now().UnixMilli()))
+	} else {
+		_, err = s.db.Exec(fmt.Sprintf("DELETE FROM draining_syncers WHERE host_info = '%s'", hostInfo))
+	}
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: drain syncer %s failed, draining: %v", hostInfo, draining)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetDrainingSyncers() ([]string, error) {
+	rows, err := s.db.Query("SELECT host_info FROM draining_syncers")
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "mysql: get draining syncers failed.")
+	}
+	defer rows.Close()
+
+	syncers := make([]string, 0)
+	for rows.Next() {
+		var hostInfo string
+		if err := rows.Scan(&hostInfo); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "mysql: scan draining syncers row failed.")
+		}
+		syncers = append(syncers, hostInfo)
 	}
+	return syncers, nil
+}
 
+func (s *MysqlDB) AddJobHandoffs(handoffs []*JobHandoff) error {
+	for _, handoff := range handoffs {
+		insertSql := fmt.Sprintf("INSERT IGNORE INTO job_handoffs (job_name, from_syncer, to_syncer, timestamp) VALUES ('%s', '%s', '%s', %d)",
+			handoff.JobName, handoff.From, handoff.To, handoff.Timestamp)
+		if _, err := s.db.Exec(insertSql); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: add job handoff failed, name: %s", handoff.JobName)
+		}
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetJobHandoffs(hostInfo string) ([]*JobHandoff, error) {
+	query := "SELECT job_name, from_syncer, to_syncer, timestamp FROM job_handoffs"
+	if hostInfo != "" {
+		query += fmt.Sprintf(" WHERE from_syncer = '%s'", hostInfo)
+	}
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "mysql: get job handoffs failed.")
+	}
+	defer rows.Close()
+
+	handoffs := make([]*JobHandoff, 0)
+	for rows.Next() {
+		var handoff JobHandoff
+		if err := rows.Scan(&handoff.JobName, &handoff.From, &handoff.To, &handoff.Timestamp); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "mysql: scan job handoffs row failed.")
+		}
+		handoffs = append(handoffs, &handoff)
+	}
+	return handoffs, nil
+}
+
+func (s *MysqlDB) RemoveJobHandoff(jobName string) error {
+	if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM job_handoffs WHERE job_name = '%s'", jobName)); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: remove job handoff failed, name: %s", jobName)
+	}
+	return nil
+}
+
+func (s *MysqlDB) TransferJob(jobName string, epoch int64, target string) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: transfer job begin transaction failed, name: %s", jobName)
+	}
+
+	if err := checkJobEpoch(txn.QueryRow(fmt.Sprintf("SELECT epoch FROM jobs WHERE job_name = '%s' FOR UPDATE", jobName)), jobName, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "mysql: transfer job rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	// Update the timestamp of the target, so it refreshes failed and recovers the jobs.
+	stmts := []string{
+		fmt.Sprintf("UPDATE jobs SET belong_to = '%s', epoch = epoch + 1 WHERE job_name = '%s'", target, jobName),
+		fmt.Sprintf("UPDATE syncers SET timestamp = %d WHERE host_info = '%s'", time.Now().UnixNano(), target),
+		fmt.Sprintf("DELETE FROM job_handoffs WHERE job_name = '%s'", jobName),
+	}
+	for _, stmt := range stmts {
+		if _, err := txn.Exec(stmt); err != nil {
+			if err := txn.Rollback(); err != nil {
+				return xerror.Wrapf(err, xerror.DB, "mysql: transfer job failed, name: %s, and rollback failed too", jobName)
+			}
+			return xerror.Wrapf(err, xerror.DB, "mysql: transfer job failed, name: %s", jobName)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "mysql: transfer job txn commit failed.")
+	}
 	return nil
 }
 
@@ -413,3 +608,85 @@ func (s *MysqlDB) GetAllData() (map[string][]string, error) {
 
 	return ans, nil
 }
+
+// The job events are written with bind vars, since the message is arbitrary text.
+func (s *MysqlDB) AddJobEvent(event *JobEvent) error {
+	if _, err := s.db.Exec("INSERT INTO job_events (job_name, event_type, timestamp, message) VALUES (?, ?, ?, ?)",
+		event.JobName, event.Type, event.Timestamp, event.Message); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: add job event failed, name: %s", event.JobName)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetJobEvents(filter *JobEventFilter) ([]*JobEvent, error) {
+	query, args := buildJobEventsQuery("job_events", filter, func(int) string { return "?" })
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "mysql: get job events failed.")
+	}
+	defer rows.Close()
+
+	events := make([]*JobEvent, 0)
+	for rows.Next() {
+		var event JobEvent
+		if err := rows.Scan(&event.Id, &event.JobName, &event.Type, &event.Timestamp, &event.Message); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "mysql: scan job events row failed.")
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (s *MysqlDB) RemoveJobEventsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM job_events WHERE timestamp < ?", timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "mysql: remove job events failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "mysql: remove job events get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
+
+func (s *MysqlDB) AddVerifyReport(report *VerifyReport) error {
+	insertSql := "INSERT INTO verify_reports (job_name, commit_seq, timestamp, matched, report) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, report.JobName, report.CommitSeq, report.Timestamp, report.Matched, report.Report); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "mysql: add verify report failed, name: %s", report.JobName)
+	}
+	return nil
+}
+
+func (s *MysqlDB) GetVerifyReports(jobName string, limit int) ([]*VerifyReport, error) {
+	query := fmt.Sprintf("SELECT id, job_name, commit_seq, timestamp, matched, report FROM verify_reports WHERE job_name = ? ORDER BY id DESC LIMIT %d",
+		verifyReportsLimit(limit))
+	rows, err := s.db.Query(query, jobName)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "mysql: get verify reports failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	reports := make([]*VerifyReport, 0)
+	for rows.Next() {
+		var report VerifyReport
+		if err := rows.Scan(&report.Id, &report.JobName, &report.CommitSeq, &report.Timestamp, &report.Matched, &report.Report); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "mysql: scan verify reports row failed.")
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+func (s *MysqlDB) RemoveVerifyReportsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM verify_reports WHERE timestamp < ?", timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "mysql: remove verify reports failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "mysql: remove verify reports get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
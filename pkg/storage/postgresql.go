@@ -16,7 +16,7 @@ type PostgresqlDB struct {
 }
 
 func NewPostgresqlDB(host string, port int, user string, password string) (DB, error) {
-	url := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", user, password, host, port, "postgres")
+	url := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?%s", user, password, host, port, "postgres", postgresqlSSLParams())
 	db, err := sql.Open("postgres", url)
 	if err != nil {
 		return nil, xerror.Wrapf(err, xerror.DB, "postgresql: open %s:%d failed", host, port)
@@ -26,16 +26,9 @@ func NewPostgresqlDB(host string, port int, user string, password string) (DB, e
 		return nil, xerror.Wrapf(err, xerror.DB, "postgresql: create schema %s failed", remoteDBName)
 	}
 
-	if _, err = db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.jobs (job_name VARCHAR(512) PRIMARY KEY, job_info TEXT, belong_to VARCHAR(96))", remoteDBName)); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "postgresql: create table jobs failed")
-	}
-
-	if _, err = db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.progresses (job_name VARCHAR(512) PRIMARY KEY, progress TEXT)", remoteDBName)); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "postgresql: create table progresses failed")
-	}
-
-	if _, err = db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.syncers (host_info VARCHAR(96) PRIMARY KEY, timestamp BIGINT)", remoteDBName)); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "postgresql: create table syncers failed")
+	if err = migrate(db, postgresqlDialect); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return &PostgresqlDB{db: db}, nil
@@ -104,6 +97,13 @@ func (s *PostgresqlDB) RemoveJob(jobName string) error {
 		return xerror.Wrapf(err, xerror.DB, "postgresql: remove progresses failed, name: %s", jobName)
 	}
 
+	if _, err := txn.Exec(fmt.Sprintf("DELETE FROM %s.job_handoffs WHERE job_name = '%s'", remoteDBName, jobName)); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "postgresql: remove job handoff failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "postgresql: remove job handoff failed, name: %s", jobName)
+	}
+
 	if err := txn.Commit(); err != nil {
 		return xerror.Wrapf(err, xerror.DB, "postgresql: remove job txn commit failed.")
 	}
@@ -136,6 +136,29 @@ func (s *PostgresqlDB) GetJobBelong(jobName string) (string, error) {
 	return belong, nil
 }
 
+func (s *PostgresqlDB) GetJobOwnership(jobName string) (string, int64, error) {
+	var belong string
+	var epoch int64
+	if err := s.db.QueryRow(fmt.Sprintf("SELECT belong_to, epoch FROM %s.jobs WHERE job_name = '%s'", remoteDBName, jobName)).Scan(&belong, &epoch); err != nil {
+		return "", 0, xerror.Wrapf(err, xerror.DB, "postgresql: get job ownership failed, name: %s", jobName)
+	}
+	return belong, epoch, nil
+}
+
+func (s *PostgresqlDB) UpdateJobWithEpoch(jobName string, jobInfo string, epoch int64) error {
+	result, err := s.db.Exec(fmt.Sprintf("UPDATE %s.jobs SET job_info = '%s' WHERE job_name = '%s' AND epoch = %d", remoteDBName, jobInfo, jobName, epoch))
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: update job name %s failed", jobName)
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: update job name %s get affected rows failed", jobName)
+	} else if rowNum == 0 {
+		return checkJobEpoch(s.db.QueryRow(fmt.Sprintf("SELECT epoch FROM %s.jobs WHERE job_name = '%s'", remoteDBName, jobName)), jobName, epoch)
+	}
+	return nil
+}
+
 func (s *PostgresqlDB) UpdateProgress(jobName string, progress string) error {
 	// quoteProgress := strings.ReplaceAll(progress, "\"", "\\\"")
 	encodeProgress := base64.StdEncoding.EncodeToString([]byte(progress))
@@ -151,6 +174,38 @@ func (s *PostgresqlDB) UpdateProgress(jobName string, progress string) error {
 	return nil
 }
 
+func (s *PostgresqlDB) UpdateProgressWithEpoch(jobName string, progress string, owner string, epoch int64) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: update progress begin transaction failed, name: %s", jobName)
+	}
+
+	// Lock the job row, so the job is not dispatched before the progress is updated.
+	if err := checkJobEpoch(txn.QueryRow(fmt.Sprintf("SELECT epoch FROM %s.jobs WHERE job_name = '%s' FOR UPDATE", remoteDBName, owner)), owner, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "postgresql: update progress rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	encodeProgress := base64.StdEncoding.EncodeToString([]byte(progress))
+	updateSql := fmt.Sprintf("INSERT INTO %s.progresses (job_name, progress) VALUES ('%s', '%s') ON CONFLICT (job_name) DO UPDATE SET progress = EXCLUDED.progress", remoteDBName, jobName, encodeProgress)
+	if _, err := txn.Exec(updateSql); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "postgresql: update progress failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "postgresql: update progress failed, name: %s", jobName)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "postgresql: update progress txn commit failed.")
+	}
+	return nil
+}
+
 func (s *PostgresqlDB) IsProgressExist(jobName string) (bool, error) {
 	var count int
 	if err := s.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s.progresses WHERE job_name = '%s'", remoteDBName, jobName)).Scan(&count); err != nil {
@@ -174,6 +229,11 @@ func (s *PostgresqlDB) GetProgress(jobName string) (string, error) {
 }
 
 func (s *PostgresqlDB) AddSyncer(hostInfo string) error {
+	// The syncer is restarted after the maintenance.
+	if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s.draining_syncers WHERE host_info = '%s'", remoteDBName, hostInfo)); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: undrain syncer %s failed", hostInfo)
+	}
+
 	timestamp := time.Now().UnixNano()
 	addSql := fmt.Sprintf("INSERT INTO %s.syncers (host_info, timestamp) VALUES ('%s', %d) ON CONFLICT (host_info) DO UPDATE SET timestamp = EXCLUDED.timestamp", remoteDBName, hostInfo, timestamp)
 	if result, err := s.db.Exec(addSql); err != nil {
@@ -258,35 +318,49 @@ func (s *PostgresqlDB) GetDeadSyncers(expiredTime int64) ([]string, error) {
 	return deadSyncers, nil
 }
 
-func (s *PostgresqlDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, error) {
+func (s *PostgresqlDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, []string, error) {
 	orphanJobs := make([]string, 0)
+	removedSyncers := make([]string, 0)
 	for _, deadSyncer := range syncers {
 		rows, err := txn.Query(fmt.Sprintf("SELECT job_name FROM %s.jobs WHERE belong_to = '%s'", remoteDBName, deadSyncer))
 		if err != nil {
-			return nil, xerror.Wrapf(err, xerror.DB, "postgresql: get orphan jobs failed.")
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: get orphan jobs failed.")
 		}
 
 		for rows.Next() {
 			var jobName string
 			if err := rows.Scan(&jobName); err != nil {
-				return nil, xerror.Wrapf(err, xerror.DB, "postgresql: scan orphan job name failed.")
+				return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: scan orphan job name failed.")
 			}
 			orphanJobs = append(orphanJobs, jobName)
 		}
 		rows.Close()
 
-		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM %s.syncers WHERE host_info = '%s'", remoteDBName, deadSyncer)); err != nil {
-			return nil, xerror.Wrapf(err, xerror.DB, "postgresql: delete dead syncer failed, name: %s", deadSyncer)
+		result, err := txn.Exec(fmt.Sprintf("DELETE FROM %s.syncers WHERE host_info = '%s'", remoteDBName, deadSyncer))
+		if err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: delete dead syncer failed, name: %s", deadSyncer)
+		}
+		// The dead syncer might be removed by the other syncers concurrently.
+		if removed, err := result.RowsAffected(); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: get the removed dead syncer failed, name: %s", deadSyncer)
+		} else if removed > 0 {
+			removedSyncers = append(removedSyncers, deadSyncer)
+		}
+		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM %s.job_handoffs WHERE from_syncer = '%s'", remoteDBName, deadSyncer)); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: delete job handoffs of dead syncer failed, name: %s", deadSyncer)
+		}
+		if _, err := txn.Exec(fmt.Sprintf("DELETE FROM %s.draining_syncers WHERE host_info = '%s'", remoteDBName, deadSyncer)); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "postgresql: delete draining dead syncer failed, name: %s", deadSyncer)
 		}
 	}
 
-	return orphanJobs, nil
+	return orphanJobs, removedSyncers, nil
 }
 
 func (s *PostgresqlDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 	load := make(LoadSlice, 0)
 	sumLoad := 0
-	host_rows, err := txn.Query(fmt.Sprintf("SELECT host_info FROM %s.syncers", remoteDBName))
+	host_rows, err := txn.Query(fmt.Sprintf("SELECT host_info FROM %s.syncers WHERE host_info NOT IN (SELECT host_info FROM %s.draining_syncers)", remoteDBName, remoteDBName))
 	if err != nil {
 		return nil, -1, xerror.Wrapf(err, xerror.DB, "postgresql: get all syncers failed.")
 	}
@@ -311,7 +385,7 @@ func (s *PostgresqlDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 
 func (s *PostgresqlDB) dispatchJobs(txn *sql.Tx, hostInfo string, additionalJobs []string) error {
 	for _, jobName := range additionalJobs {
-		if _, err := txn.Exec(fmt.Sprintf("UPDATE %s.jobs SET belong_to = '%s' WHERE job_name = '%s'", remoteDBName, hostInfo, jobName)); err != nil {
+		if _, err := txn.Exec(fmt.Sprintf("UPDATE %s.jobs SET belong_to = '%s', epoch = epoch + 1 WHERE job_name = '%s'", remoteDBName, hostInfo, jobName)); err != nil {
 			return xerror.Wrapf(err, xerror.DB, "postgresql: update job belong_to failed, name: %s", jobName)
 		}
 	}
@@ -322,45 +396,161 @@ func (s *PostgresqlDB) dispatchJobs(txn *sql.Tx, hostInfo string, additionalJobs
 	return nil
 }
 
-func (s *PostgresqlDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
+func (s *PostgresqlDB) RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error) {
 	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
 		ReadOnly:  false,
 	})
 	if err != nil {
-		return xerror.Wrap(err, xerror.DB, "postgresql: rebalance load begin txn failed")
+		return nil, xerror.Wrap(err, xerror.DB, "postgresql: rebalance load begin txn failed")
 	}
 
-	orphanJobs, err := s.getOrphanJobs(txn, syncers)
+	orphanJobs, removedSyncers, err := s.getOrphanJobs(txn, syncers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	additionalLoad := len(orphanJobs)
+	if additionalLoad == 0 {
+		// The dead syncers have no jobs, or they are rebalanced by the other syncers.
+		if err := txn.Commit(); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "postgresql: rebalance txn commit failed.")
+		}
+		return removedSyncers, nil
+	}
+
 	loadList, currentLoad, err := s.getLoadInfo(txn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	loadList, err = RebalanceLoad(additionalLoad, currentLoad, loadList)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for i := range loadList {
 		beginIdx := additionalLoad - loadList[i].AddedLoad
 		if err := s.dispatchJobs(txn, loadList[i].HostInfo, orphanJobs[beginIdx:additionalLoad]); err != nil {
 			if err := txn.Rollback(); err != nil {
-				return xerror.Wrap(err, xerror.DB, "postgresql: rebalance rollback failed.")
+				return nil, xerror.Wrap(err, xerror.DB, "postgresql: rebalance rollback failed.")
 			}
-			return err
+			return nil, err
 		}
 		additionalLoad = beginIdx
 	}
 
 	if err := txn.Commit(); err != nil {
-		return xerror.Wrap(err, xerror.DB, "postgresql: rebalance txn commit failed.")
+		return nil, xerror.Wrap(err, xerror.DB, "postgresql: rebalance txn commit failed.")
+	}
+
+	return removedSyncers, nil
+}
+
+func (s *PostgresqlDB) DrainSyncer(hostInfo string, draining bool) error {
+	var err error
+	if draining {
+		_, err = s.db.Exec(fmt.Sprintf("INSERT INTO %s.draining_syncers (host_info, timestamp) VALUES ('%s', %d) ON CONFLICT (host_info) DO NOTHING", remoteDBName, hostInfo, time.Now().UnixMilli()))
+	} else {
+		_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s.draining_syncers WHERE host_info = '%s'", remoteDBName, hostInfo))
+	}
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: drain syncer %s failed, draining: %v", hostInfo, draining)
+	}
+	return nil
+}
+
+func (s *PostgresqlDB) GetDrainingSyncers() ([]string, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT host_info FROM %s.draining_syncers", remoteDBName))
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "postgresql: get draining syncers failed.")
+	}
+	defer rows.Close()
+
+	syncers := make([]string, 0)
+	for rows.Next() {
+		var hostInfo string
+		if err := rows.Scan(&hostInfo); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "postgresql: scan draining syncers row failed.")
+		}
+		syncers = append(syncers, hostInfo)
+	}
+	return syncers, nil
+}
+
+func (s *PostgresqlDB) AddJobHandoffs(handoffs []*JobHandoff) error {
+	for _, handoff := range handoffs {
+		insertSql := fmt.Sprintf("INSERT INTO %s.job_handoffs (job_name, from_syncer, to_syncer, timestamp) VALUES ('%s', '%s', '%s', %d) ON CONFLICT (job_name) DO NOTHING",
+			remoteDBName, handoff.JobName, handoff.From, handoff.To, handoff.Timestamp)
+		if _, err := s.db.Exec(insertSql); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "postgresql: add job handoff failed, name: %s", handoff.JobName)
+		}
 	}
+	return nil
+}
+
+func (s *PostgresqlDB) GetJobHandoffs(hostInfo string) ([]*JobHandoff, error) {
+	query := fmt.Sprintf("SELECT job_name, from_syncer, to_syncer, timestamp FROM %s.job_handoffs", remoteDBName)
+	if hostInfo != "" {
+		query += fmt.Sprintf(" WHERE from_syncer = '%s'", hostInfo)
+	}
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "postgresql: get job handoffs failed.")
+	}
+	defer rows.Close()
+
+	handoffs := make([]*JobHandoff, 0)
+	for rows.Next() {
+		var handoff JobHandoff
+		if err := rows.Scan(&handoff.JobName, &handoff.From, &handoff.To, &handoff.Timestamp); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "postgresql: scan job handoffs row failed.")
+		}
+		handoffs = append(handoffs, &handoff)
+	}
+	return handoffs, nil
+}
 
+func (s *PostgresqlDB) RemoveJobHandoff(jobName string) error {
+	if _, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s.job_handoffs WHERE job_name = '%s'", remoteDBName, jobName)); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: remove job handoff failed, name: %s", jobName)
+	}
+	return nil
+}
+
+func (s *PostgresqlDB) TransferJob(jobName string, epoch int64, target string) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: transfer job begin transaction failed, name: %s", jobName)
+	}
+
+	if err := checkJobEpoch(txn.QueryRow(fmt.Sprintf("SELECT epoch FROM %s.jobs WHERE job_name = '%s' FOR UPDATE", remoteDBName, jobName)), jobName, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "postgresql: transfer job rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	// Update the timestamp of the target, so it refreshes failed and recovers the jobs.
+	stmts := []string{
+		fmt.Sprintf("UPDATE %s.jobs SET belong_to = '%s', epoch = epoch + 1 WHERE job_name = '%s'", remoteDBName, target, jobName),
+		fmt.Sprintf("UPDATE %s.syncers SET timestamp = %d WHERE host_info = '%s'", remoteDBName, time.Now().UnixNano(), target),
+		fmt.Sprintf("DELETE FROM %s.job_handoffs WHERE job_name = '%s'", remoteDBName, jobName),
+	}
+	for _, stmt := range stmts {
+		if _, err := txn.Exec(stmt); err != nil {
+			if err := txn.Rollback(); err != nil {
+				return xerror.Wrapf(err, xerror.DB, "postgresql: transfer job failed, name: %s, and rollback failed too", jobName)
+			}
+			return xerror.Wrapf(err, xerror.DB, "postgresql: transfer job failed, name: %s", jobName)
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "postgresql: transfer job txn commit failed.")
+	}
 	return nil
 }
 
@@ -402,3 +592,86 @@ func (s *PostgresqlDB) GetAllData() (map[string][]string, error) {
 
 	return ans, nil
 }
+
+// The job events are written with bind vars, since the message is arbitrary text.
+func (s *PostgresqlDB) AddJobEvent(event *JobEvent) error {
+	insertSql := fmt.Sprintf("INSERT INTO %s.job_events (job_name, event_type, timestamp, message) VALUES ($1, $2, $3, $4)", remoteDBName)
+	if _, err := s.db.Exec(insertSql, event.JobName, event.Type, event.Timestamp, event.Message); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: add job event failed, name: %s", event.JobName)
+	}
+	return nil
+}
+
+func (s *PostgresqlDB) GetJobEvents(filter *JobEventFilter) ([]*JobEvent, error) {
+	table := fmt.Sprintf("%s.job_events", remoteDBName)
+	query, args := buildJobEventsQuery(table, filter, func(i int) string { return fmt.Sprintf("$%d", i) })
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "postgresql: get job events failed.")
+	}
+	defer rows.Close()
+
+	events := make([]*JobEvent, 0)
+	for rows.Next() {
+		var event JobEvent
+		if err := rows.Scan(&event.Id, &event.JobName, &event.Type, &event.Timestamp, &event.Message); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "postgresql: scan job events row failed.")
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (s *PostgresqlDB) RemoveJobEventsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s.job_events WHERE timestamp < $1", remoteDBName), timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "postgresql: remove job events failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "postgresql: remove job events get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
+
+func (s *PostgresqlDB) AddVerifyReport(report *VerifyReport) error {
+	insertSql := fmt.Sprintf("INSERT INTO %s.verify_reports (job_name, commit_seq, timestamp, matched, report) VALUES ($1, $2, $3, $4, $5)", remoteDBName)
+	if _, err := s.db.Exec(insertSql, report.JobName, report.CommitSeq, report.Timestamp, report.Matched, report.Report); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "postgresql: add verify report failed, name: %s", report.JobName)
+	}
+	return nil
+}
+
+func (s *PostgresqlDB) GetVerifyReports(jobName string, limit int) ([]*VerifyReport, error) {
+	query := fmt.Sprintf("SELECT id, job_name, commit_seq, timestamp, matched, report FROM %s.verify_reports WHERE job_name = $1 ORDER BY id DESC LIMIT %d",
+		remoteDBName, verifyReportsLimit(limit))
+	rows, err := s.db.Query(query, jobName)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "postgresql: get verify reports failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	reports := make([]*VerifyReport, 0)
+	for rows.Next() {
+		var report VerifyReport
+		if err := rows.Scan(&report.Id, &report.JobName, &report.CommitSeq, &report.Timestamp, &report.Matched, &report.Report); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "postgresql: scan verify reports row failed.")
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+func (s *PostgresqlDB) RemoveVerifyReportsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s.verify_reports WHERE timestamp < $1", remoteDBName), timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "postgresql: remove verify reports failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "postgresql: remove verify reports get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	raftApplyTimeout   = 10 * time.Second
+	raftRetryInterval  = 100 * time.Millisecond
+	raftSnapshotRetain = 2
+)
+
+// The write is not appended to the raft log, it is safe to retry.
+var errRaftNoLeader = errors.New("raft: no leader")
+
+// The errors of the writes are returned as is, the callers check them by errors.Is.
+var raftErrorCodes = map[string]error{
+	"job_exists":     ErrJobExists,
+	"job_not_exists": ErrJobNotExists,
+	"job_fenced":     ErrJobFenced,
+}
+
+type RaftConfig struct {
+	// The dir of the raft log, the snapshots and the replica of the meta db.
+	Dir string
+	// The address to bind and advertise, it is the id of the node too.
+	Addr string
+	// The addresses of all nodes, the cluster is bootstrapped with them at the first start, and
+	// only the connections from their hosts are accepted.
+	Peers []string
+	// The mutual tls of the raft connections, it is disabled if nil. The certificate is used as
+	// both the server and the client certificate, the CA file verifies the peers.
+	TLS *utils.TLSOptions
+}
+
+// RaftDB replicates the meta db among a small group of syncers by the raft log, so the syncers
+// need no external database for HA.
+//
+// The writes are forwarded to the leader and applied to the sqlite db of each node in the order
+// of the raft log, a write returns after it is applied to the local node. The reads are served
+// by the local node, they might be stale in the followers but never earlier than the writes of
+// the same node.
+type RaftDB struct {
+	addr   string
+	fsm    *raftFSM
+	raft   *raft.Raft
+	store  *raftboltdb.BoltStore
+	stream *raftStreamLayer
+}
+
+func NewRaftDB(config *RaftConfig) (DB, error) {
+	db, err := openRaftDB(config)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func openRaftDB(config *RaftConfig) (*RaftDB, error) {
+	if config.Addr == "" {
+		return nil, xerror.New(xerror.Normal, "raft: addr is empty")
+	}
+	peers := config.Peers
+	if len(peers) == 0 {
+		peers = []string{config.Addr}
+	}
+	servers := make([]raft.Server, 0, len(peers))
+	hasSelf := false
+	for _, peer := range peers {
+		hasSelf = hasSelf || peer == config.Addr
+		servers = append(servers, raft.Server{ID: raft.ServerID(peer), Address: raft.ServerAddress(peer)})
+	}
+	if !hasSelf {
+		return nil, xerror.Errorf(xerror.Normal, "raft: addr %s is not one of the peers %v", config.Addr, peers)
+	}
+
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "raft: create dir %s failed", config.Dir)
+	}
+	fsm, err := newRaftFSM(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	store, err := raftboltdb.NewBoltStore(filepath.Join(config.Dir, "raft.db"))
+	if err != nil {
+		fsm.close()
+		return nil, xerror.Wrap(err, xerror.DB, "raft: open log store failed")
+	}
+
+	db := &RaftDB{addr: config.Addr, fsm: fsm, store: store}
+	logOutput := log.StandardLogger().Writer()
+	snapshots, err := raft.NewFileSnapshotStore(config.Dir, raftSnapshotRetain, logOutput)
+	if err != nil {
+		db.closeStores()
+		return nil, xerror.Wrap(err, xerror.DB, "raft: open snapshot store failed")
+	}
+	if db.stream, err = newRaftStreamLayer(config.Addr, peers, config.TLS, db.handleForward); err != nil {
+		db.closeStores()
+		return nil, err
+	}
+	transport := raft.NewNetworkTransport(db.stream, 3, raftDialTimeout, logOutput)
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(config.Addr)
+	raftConfig.LogOutput = logOutput
+	raftConfig.LogLevel = "INFO"
+
+	if exists, err := raft.HasExistingState(store, store, snapshots); err != nil {
+		transport.Close()
+		db.closeStores()
+		return nil, xerror.Wrap(err, xerror.DB, "raft: check existing state failed")
+	} else if !exists {
+		// It is safe to bootstrap all nodes with the same configuration.
+		log.Infof("raft: bootstrap cluster with peers %v", peers)
+		err := raft.BootstrapCluster(raftConfig, store, store, snapshots, transport, raft.Configuration{Servers: servers})
+		if err != nil {
+			transport.Close()
+			db.closeStores()
+			return nil, xerror.Wrap(err, xerror.DB, "raft: bootstrap cluster failed")
+		}
+	}
+
+	if db.raft, err = raft.NewRaft(raftConfig, fsm, store, store, snapshots, transport); err != nil {
+		transport.Close()
+		db.closeStores()
+		return nil, xerror.Wrap(err, xerror.DB, "raft: start raft failed")
+	}
+	return db, nil
+}
+
+func (r *RaftDB) closeStores() {
+	r.store.Close()
+	r.fsm.close()
+}
+
+// Close stops the raft node, the replica is rebuilt from the raft log at the next start.
+func (r *RaftDB) Close() error {
+	if err := r.raft.Shutdown().Error(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "raft: shutdown failed")
+	}
+	r.closeStores()
+	return nil
+}
+
+// apply replicates the write and waits for it is applied to this node.
+func (r *RaftDB) apply(op string, args *raftArgs) (int64, error) {
+	cmd := &raftCommand{Op: op, Now: time.Now().UnixNano(), Args: args}
+	deadline := time.Now().Add(raftApplyTimeout)
+	for {
+		var index uint64
+		var result *raftResult
+		var err error
+		if r.raft.State() == raft.Leader {
+			index, result, err = r.applyLocal(cmd)
+		} else {
+			index, result, err = r.forward(cmd)
+		}
+
+		if err == nil {
+			if err := r.waitApplied(index, deadline); err != nil {
+				return 0, err
+			}
+			return result.Value, result.Err
+		}
+		if !isRaftRetryable(err) || time.Now().After(deadline) {
+			return 0, xerror.Wrapf(err, xerror.DB, "raft: apply %s failed", op)
+		}
+		time.Sleep(raftRetryInterval)
+	}
+}
+
+func isRaftRetryable(err error) bool {
+	return errors.Is(err, errRaftNoLeader) || errors.Is(err, raft.ErrNotLeader)
+}
+
+func (r *RaftDB) applyLocal(cmd *raftCommand) (uint64, *raftResult, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return 0, nil, xerror.Wrap(err, xerror.DB, "raft: marshal command failed")
+	}
+
+	future := r.raft.Apply(data, raftApplyTimeout)
+	if err := future.Error(); err != nil {
+		return 0, nil, err
+	}
+	return future.Index(), future.Response().(*raftResult), nil
+}
+
+func (r *RaftDB) waitApplied(index uint64, deadline time.Time) error {
+	for r.raft.AppliedIndex() < index {
+		if time.Now().After(deadline) {
+			return xerror.Errorf(xerror.DB, "raft: wait for index %d applied timeout, applied: %d", index, r.raft.AppliedIndex())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return nil
+}
+
+type raftForwardResponse struct {
+	Index uint64 `json:"index"`
+	Value int64  `json:"value"`
+	// The command is not applied.
+	ApplyError string `json:"apply_error,omitempty"`
+	NotLeader  bool   `json:"not_leader,omitempty"`
+	// The error returned by the write.
+	Error     string `json:"error,omitempty"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// forward sends the write to the leader.
+func (r *RaftDB) forward(cmd *raftCommand) (uint64, *raftResult, error) {
+	leader, _ := r.raft.LeaderWithID()
+	if leader == "" {
+		return 0, nil, errRaftNoLeader
+	}
+
+	conn, err := r.stream.dial(string(leader), raftConnForward, raftDialTimeout)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: dial leader %s: %v", errRaftNoLeader, leader, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(raftApplyTimeout + raftDialTimeout))
+
+	if err := json.NewEncoder(conn).Encode(cmd); err != nil {
+		return 0, nil, xerror.Wrapf(err, xerror.DB, "raft: forward to leader %s failed", leader)
+	}
+	var resp raftForwardResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return 0, nil, xerror.Wrapf(err, xerror.DB, "raft: read the response of leader %s failed", leader)
+	}
+
+	if resp.NotLeader {
+		return 0, nil, fmt.Errorf("%w: %s is not the leader", errRaftNoLeader, leader)
+	} else if resp.ApplyError != "" {
+		return 0, nil, xerror.Errorf(xerror.DB, "raft: leader %s apply failed: %s", leader, resp.ApplyError)
+	}
+
+	result := &raftResult{Value: resp.Value}
+	if sentinel, ok := raftErrorCodes[resp.ErrorCode]; ok {
+		result.Err = sentinel
+	} else if resp.Error != "" {
+		result.Err = xerror.New(xerror.DB, resp.Error)
+	}
+	return resp.Index, result, nil
+}
+
+func (r *RaftDB) handleForward(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(raftApplyTimeout + raftDialTimeout))
+
+	var cmd raftCommand
+	if err := json.NewDecoder(conn).Decode(&cmd); err != nil {
+		log.Warnf("raft: read the forwarded command from %s failed: %+v", conn.RemoteAddr(), err)
+		return
+	}
+
+	var resp raftForwardResponse
+	if _, ok := raftOps[cmd.Op]; !ok || cmd.Args == nil {
+		log.Warnf("raft: reject the forwarded command %q from %s, it is unknown", cmd.Op, conn.RemoteAddr())
+		resp.ApplyError = fmt.Sprintf("unknown command %q", cmd.Op)
+	} else if index, result, err := r.applyLocal(&cmd); errors.Is(err, raft.ErrNotLeader) {
+		resp.NotLeader = true
+	} else if err != nil {
+		resp.ApplyError = err.Error()
+	} else {
+		resp.Index, resp.Value = index, result.Value
+		if result.Err != nil {
+			resp.Error = result.Err.Error()
+			for code, sentinel := range raftErrorCodes {
+				if errors.Is(result.Err, sentinel) {
+					resp.ErrorCode = code
+				}
+			}
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(&resp); err != nil {
+		log.Warnf("raft: write the response to %s failed: %+v", conn.RemoteAddr(), err)
+	}
+}
+
+func (r *RaftDB) AddJob(jobName string, jobInfo string, hostInfo string) error {
+	_, err := r.apply("AddJob", &raftArgs{JobName: jobName, JobInfo: jobInfo, HostInfo: hostInfo})
+	return err
+}
+
+func (r *RaftDB) UpdateJob(jobName string, jobInfo string) error {
+	_, err := r.apply("UpdateJob", &raftArgs{JobName: jobName, JobInfo: jobInfo})
+	return err
+}
+
+func (r *RaftDB) RemoveJob(jobName string) error {
+	_, err := r.apply("RemoveJob", &raftArgs{JobName: jobName})
+	return err
+}
+
+func (r *RaftDB) IsJobExist(jobName string) (bool, error) {
+	var exists bool
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		exists, err = db.IsJobExist(jobName)
+		return
+	})
+	return exists, err
+}
+
+func (r *RaftDB) GetJobInfo(jobName string) (string, error) {
+	var jobInfo string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		jobInfo, err = db.GetJobInfo(jobName)
+		return
+	})
+	return jobInfo, err
+}
+
+func (r *RaftDB) GetJobBelong(jobName string) (string, error) {
+	var belong string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		belong, err = db.GetJobBelong(jobName)
+		return
+	})
+	return belong, err
+}
+
+func (r *RaftDB) GetJobOwnership(jobName string) (string, int64, error) {
+	var belong string
+	var epoch int64
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		belong, epoch, err = db.GetJobOwnership(jobName)
+		return
+	})
+	return belong, epoch, err
+}
+
+func (r *RaftDB) UpdateJobWithEpoch(jobName string, jobInfo string, epoch int64) error {
+	_, err := r.apply("UpdateJobWithEpoch", &raftArgs{JobName: jobName, JobInfo: jobInfo, Epoch: epoch})
+	return err
+}
+
+func (r *RaftDB) UpdateProgress(jobName string, progress string) error {
+	_, err := r.apply("UpdateProgress", &raftArgs{JobName: jobName, Progress: progress})
+	return err
+}
+
+func (r *RaftDB) UpdateProgressWithEpoch(jobName string, progress string, owner string, epoch int64) error {
+	_, err := r.apply("UpdateProgressWithEpoch", &raftArgs{JobName: jobName, Progress: progress, Owner: owner, Epoch: epoch})
+	return err
+}
+
+func (r *RaftDB) IsProgressExist(jobName string) (bool, error) {
+	var exists bool
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		exists, err = db.IsProgressExist(jobName)
+		return
+	})
+	return exists, err
+}
+
+func (r *RaftDB) GetProgress(jobName string) (string, error) {
+	var progress string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		progress, err = db.GetProgress(jobName)
+		return
+	})
+	return progress, err
+}
+
+func (r *RaftDB) AddSyncer(hostInfo string) error {
+	_, err := r.apply("AddSyncer", &raftArgs{HostInfo: hostInfo})
+	return err
+}
+
+func (r *RaftDB) RefreshSyncer(hostInfo string, lastStamp int64) (int64, error) {
+	stamp, err := r.apply("RefreshSyncer", &raftArgs{HostInfo: hostInfo, Timestamp: lastStamp})
+	if err != nil {
+		return -1, err
+	}
+	return stamp, nil
+}
+
+func (r *RaftDB) GetStampAndJobs(hostInfo string) (int64, []string, error) {
+	var stamp int64
+	var jobs []string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		stamp, jobs, err = db.GetStampAndJobs(hostInfo)
+		return
+	})
+	return stamp, jobs, err
+}
+
+func (r *RaftDB) GetDeadSyncers(expiredTime int64) ([]string, error) {
+	var syncers []string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		syncers, err = db.GetDeadSyncers(expiredTime)
+		return
+	})
+	return syncers, err
+}
+
+// RebalanceLoadFromDeadSyncers rebalances the dead syncers one by one, so the removed ones are
+// known by the number of the removed syncers of each command.
+func (r *RaftDB) RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error) {
+	removedSyncers := make([]string, 0)
+	for _, syncer := range syncers {
+		removed, err := r.apply("RebalanceLoadFromDeadSyncers", &raftArgs{Syncers: []string{syncer}})
+		if err != nil {
+			return removedSyncers, err
+		}
+		if removed > 0 {
+			removedSyncers = append(removedSyncers, syncer)
+		}
+	}
+	return removedSyncers, nil
+}
+
+func (r *RaftDB) DrainSyncer(hostInfo string, draining bool) error {
+	_, err := r.apply("DrainSyncer", &raftArgs{HostInfo: hostInfo, Draining: draining})
+	return err
+}
+
+func (r *RaftDB) GetDrainingSyncers() ([]string, error) {
+	var syncers []string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		syncers, err = db.GetDrainingSyncers()
+		return
+	})
+	return syncers, err
+}
+
+func (r *RaftDB) AddJobHandoffs(handoffs []*JobHandoff) error {
+	_, err := r.apply("AddJobHandoffs", &raftArgs{Handoffs: handoffs})
+	return err
+}
+
+func (r *RaftDB) GetJobHandoffs(hostInfo string) ([]*JobHandoff, error) {
+	var handoffs []*JobHandoff
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		handoffs, err = db.GetJobHandoffs(hostInfo)
+		return
+	})
+	return handoffs, err
+}
+
+func (r *RaftDB) RemoveJobHandoff(jobName string) error {
+	_, err := r.apply("RemoveJobHandoff", &raftArgs{JobName: jobName})
+	return err
+}
+
+func (r *RaftDB) TransferJob(jobName string, epoch int64, target string) error {
+	_, err := r.apply("TransferJob", &raftArgs{JobName: jobName, Epoch: epoch, Target: target})
+	return err
+}
+
+func (r *RaftDB) GetAllData() (map[string][]string, error) {
+	var data map[string][]string
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		data, err = db.GetAllData()
+		return
+	})
+	return data, err
+}
+
+func (r *RaftDB) AddJobEvent(event *JobEvent) error {
+	_, err := r.apply("AddJobEvent", &raftArgs{Event: event})
+	return err
+}
+
+func (r *RaftDB) GetJobEvents(filter *JobEventFilter) ([]*JobEvent, error) {
+	var events []*JobEvent
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		events, err = db.GetJobEvents(filter)
+		return
+	})
+	return events, err
+}
+
+func (r *RaftDB) RemoveJobEventsBefore(timestamp int64) (int64, error) {
+	return r.apply("RemoveJobEventsBefore", &raftArgs{Timestamp: timestamp})
+}
+
+func (r *RaftDB) AddVerifyReport(report *VerifyReport) error {
+	_, err := r.apply("AddVerifyReport", &raftArgs{Report: report})
+	return err
+}
+
+func (r *RaftDB) GetVerifyReports(jobName string, limit int) ([]*VerifyReport, error) {
+	var reports []*VerifyReport
+	err := r.fsm.read(func(db *SQLiteDB) (err error) {
+		reports, err = db.GetVerifyReports(jobName, limit)
+		return
+	})
+	return reports, err
+}
+
+func (r *RaftDB) RemoveVerifyReportsBefore(timestamp int64) (int64, error) {
+	return r.apply("RemoveVerifyReportsBefore", &raftArgs{Timestamp: timestamp})
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// raftCommand is a write of the DB replicated by the raft log, it is applied to the sqlite db
+// of each node by the method of the same name.
+type raftCommand struct {
+	Op string `json:"op"`
+	// The time of the command in unix nanoseconds, all nodes write the same timestamps.
+	Now  int64     `json:"now"`
+	Args *raftArgs `json:"args"`
+}
+
+// raftArgs is the union of the arguments of the writes.
+type raftArgs struct {
+	JobName   string        `json:"job_name,omitempty"`
+	JobInfo   string        `json:"job_info,omitempty"`
+	HostInfo  string        `json:"host_info,omitempty"`
+	Progress  string        `json:"progress,omitempty"`
+	Owner     string        `json:"owner,omitempty"`
+	Target    string        `json:"target,omitempty"`
+	Epoch     int64         `json:"epoch,omitempty"`
+	Timestamp int64         `json:"timestamp,omitempty"`
+	Draining  bool          `json:"draining,omitempty"`
+	Syncers   []string      `json:"syncers,omitempty"`
+	Handoffs  []*JobHandoff `json:"handoffs,omitempty"`
+	Event     *JobEvent     `json:"event,omitempty"`
+	Report    *VerifyReport `json:"report,omitempty"`
+}
+
+// raftResult is the result of a applied command.
+type raftResult struct {
+	Value int64
+	Err   error
+}
+
+type raftOp func(db *SQLiteDB, args *raftArgs) (int64, error)
+
+var raftOps = map[string]raftOp{
+	"AddJob": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.AddJob(args.JobName, args.JobInfo, args.HostInfo)
+	},
+	"UpdateJob": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.UpdateJob(args.JobName, args.JobInfo)
+	},
+	"RemoveJob": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.RemoveJob(args.JobName)
+	},
+	"UpdateJobWithEpoch": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.UpdateJobWithEpoch(args.JobName, args.JobInfo, args.Epoch)
+	},
+	"UpdateProgress": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.UpdateProgress(args.JobName, args.Progress)
+	},
+	"UpdateProgressWithEpoch": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.UpdateProgressWithEpoch(args.JobName, args.Progress, args.Owner, args.Epoch)
+	},
+	"AddSyncer": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.AddSyncer(args.HostInfo)
+	},
+	"RefreshSyncer": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return db.RefreshSyncer(args.HostInfo, args.Timestamp)
+	},
+	"RebalanceLoadFromDeadSyncers": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		removedSyncers, err := db.RebalanceLoadFromDeadSyncers(args.Syncers)
+		return int64(len(removedSyncers)), err
+	},
+	"DrainSyncer": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.DrainSyncer(args.HostInfo, args.Draining)
+	},
+	"AddJobHandoffs": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.AddJobHandoffs(args.Handoffs)
+	},
+	"RemoveJobHandoff": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.RemoveJobHandoff(args.JobName)
+	},
+	"TransferJob": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.TransferJob(args.JobName, args.Epoch, args.Target)
+	},
+	"AddJobEvent": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.AddJobEvent(args.Event)
+	},
+	"RemoveJobEventsBefore": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return db.RemoveJobEventsBefore(args.Timestamp)
+	},
+	"AddVerifyReport": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return 0, db.AddVerifyReport(args.Report)
+	},
+	"RemoveVerifyReportsBefore": func(db *SQLiteDB, args *raftArgs) (int64, error) {
+		return db.RemoveVerifyReportsBefore(args.Timestamp)
+	},
+}
+
+// raftFSM applies the commands to a sqlite db, which is rebuilt from the snapshot and the raft
+// log each time the node is started.
+type raftFSM struct {
+	path string
+
+	// Held for writing while the db is replaced by a snapshot.
+	lock sync.RWMutex
+	db   *SQLiteDB
+	// The time of the command in applying, only accessed by the apply goroutine.
+	applyTime int64
+}
+
+func newRaftFSM(dir string) (*raftFSM, error) {
+	f := &raftFSM{path: filepath.Join(dir, "meta.db")}
+	if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, xerror.Wrapf(err, xerror.DB, "raft: remove the stale meta db %s failed", f.path)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *raftFSM) open() error {
+	// The reads wait for the apply instead of failing with SQLITE_BUSY, and vice versa, the
+	// apply must not fail by chance, or the nodes diverge.
+	db, err := openSQLiteDB("file:" + f.path + "?_busy_timeout=10000")
+	if err != nil {
+		return err
+	}
+	db.now = func() time.Time { return time.Unix(0, f.applyTime) }
+	f.db = db
+	return nil
+}
+
+// read runs the read of the db, the reads might be stale in the followers.
+func (f *raftFSM) read(fn func(db *SQLiteDB) error) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return fn(f.db)
+}
+
+func (f *raftFSM) Apply(log *raft.Log) interface{} {
+	var cmd raftCommand
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return &raftResult{Err: xerror.Wrapf(err, xerror.DB, "raft: unmarshal command at index %d failed", log.Index)}
+	}
+	op, ok := raftOps[cmd.Op]
+	if !ok || cmd.Args == nil {
+		return &raftResult{Err: xerror.Errorf(xerror.DB, "raft: unknown command %s at index %d", cmd.Op, log.Index)}
+	}
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	f.applyTime = cmd.Now
+	value, err := op(f.db, cmd.Args)
+	return &raftResult{Value: value, Err: err}
+}
+
+// Snapshot copies the db, the meta db is small so it is fast enough to do it in the apply
+// goroutine.
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	file, err := os.CreateTemp(filepath.Dir(f.path), "snapshot-*.db")
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "raft: create snapshot file failed")
+	}
+	path := file.Name()
+	file.Close()
+	// VACUUM INTO requires the file is not exists.
+	os.Remove(path)
+
+	if _, err := f.db.db.Exec("VACUUM INTO ?", path); err != nil {
+		os.Remove(path)
+		return nil, xerror.Wrap(err, xerror.DB, "raft: copy meta db failed")
+	}
+	return &raftSnapshot{path: path}, nil
+}
+
+func (f *raftFSM) Restore(snapshot io.ReadCloser) error {
+	defer snapshot.Close()
+
+	file, err := os.CreateTemp(filepath.Dir(f.path), "restore-*.db")
+	if err != nil {
+		return xerror.Wrap(err, xerror.DB, "raft: create restore file failed")
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, snapshot); err != nil {
+		file.Close()
+		return xerror.Wrap(err, xerror.DB, "raft: read snapshot failed")
+	}
+	if err := file.Close(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "raft: write restore file failed")
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if err := f.db.db.Close(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "raft: close meta db failed")
+	}
+	if err := os.Rename(file.Name(), f.path); err != nil {
+		return xerror.Wrap(err, xerror.DB, "raft: replace meta db failed")
+	}
+	return f.open()
+}
+
+func (f *raftFSM) close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.db.db.Close()
+}
+
+type raftSnapshot struct {
+	path string
+}
+
+func (s *raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		sink.Cancel()
+		return xerror.Wrap(err, xerror.DB, "raft: open snapshot file failed")
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(sink, file); err != nil {
+		sink.Cancel()
+		return xerror.Wrap(err, xerror.DB, "raft: write snapshot failed")
+	}
+	return sink.Close()
+}
+
+func (s *raftSnapshot) Release() {
+	os.Remove(s.path)
+}
@@ -0,0 +1,373 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+)
+
+func freeRaftAddrs(t *testing.T, n int) []string {
+	addrs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen failed: %v", err)
+		}
+		addrs = append(addrs, listener.Addr().String())
+		listener.Close()
+	}
+	return addrs
+}
+
+func waitRaftLeader(t *testing.T, nodes []*RaftDB) *RaftDB {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, node := range nodes {
+			if node.raft.State() == raft.Leader {
+				return node
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("no leader elected")
+	return nil
+}
+
+func waitRaftJobInfo(t *testing.T, node *RaftDB, jobName, expected string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if jobInfo, err := node.GetJobInfo(jobName); err == nil && jobInfo == expected {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("job %s info of node %s is not %s", jobName, node.addr, expected)
+}
+
+func TestRaftDB_ThreeNodes(t *testing.T) {
+	addrs := freeRaftAddrs(t, 3)
+	nodes := make([]*RaftDB, 0, len(addrs))
+	for _, addr := range addrs {
+		node, err := openRaftDB(&RaftConfig{Dir: filepath.Join(t.TempDir(), "raft"), Addr: addr, Peers: addrs})
+		if err != nil {
+			t.Fatalf("open raft db %s failed: %v", addr, err)
+		}
+		nodes = append(nodes, node)
+	}
+	closed := make(map[*RaftDB]bool)
+	defer func() {
+		for _, node := range nodes {
+			if !closed[node] {
+				node.Close()
+			}
+		}
+	}()
+
+	leader := waitRaftLeader(t, nodes)
+	var follower *RaftDB
+	for _, node := range nodes {
+		if node != leader {
+			follower = node
+			break
+		}
+	}
+
+	// The writes of the follower are forwarded and could be read at once.
+	if err := follower.AddSyncer("127.0.0.1:9190"); err != nil {
+		t.Fatalf("add syncer failed: %v", err)
+	}
+	if err := follower.AddJob("job", "info", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+	if jobInfo, err := follower.GetJobInfo("job"); err != nil || jobInfo != "info" {
+		t.Fatalf("get job info from follower: %s, err: %v", jobInfo, err)
+	}
+	if err := follower.AddJob("job", "info", "127.0.0.1:9190"); !errors.Is(err, ErrJobExists) {
+		t.Fatalf("add job again should be ErrJobExists, err: %v", err)
+	}
+	for _, node := range nodes {
+		waitRaftJobInfo(t, node, "job", "info")
+	}
+
+	// The timestamps are the same in all nodes.
+	stamp, _, err := follower.GetStampAndJobs("127.0.0.1:9190")
+	if err != nil {
+		t.Fatalf("get stamp failed: %v", err)
+	}
+	if newStamp, err := follower.RefreshSyncer("127.0.0.1:9190", stamp); err != nil || newStamp <= 0 {
+		t.Fatalf("refresh syncer: %d, err: %v", newStamp, err)
+	}
+	if newStamp, err := follower.RefreshSyncer("127.0.0.1:9190", stamp); err != nil || newStamp != -1 {
+		t.Fatalf("refresh syncer with the stale stamp: %d, err: %v", newStamp, err)
+	}
+	leaderStamp, _, _ := leader.GetStampAndJobs("127.0.0.1:9190")
+	followerStamp, _, _ := follower.GetStampAndJobs("127.0.0.1:9190")
+	if leaderStamp != followerStamp {
+		t.Fatalf("the stamps are different, leader: %d, follower: %d", leaderStamp, followerStamp)
+	}
+
+	if err := follower.UpdateJobWithEpoch("job", "info2", InitialJobEpoch+1); !errors.Is(err, ErrJobFenced) {
+		t.Fatalf("update job with the stale epoch should be fenced, err: %v", err)
+	}
+
+	// Only the first syncer rebalancing the dead syncer removes it.
+	if err := leader.AddSyncer("127.0.0.1:9191"); err != nil {
+		t.Fatalf("add syncer failed: %v", err)
+	}
+	if removed, err := follower.RebalanceLoadFromDeadSyncers([]string{"127.0.0.1:9190"}); err != nil || len(removed) != 1 {
+		t.Fatalf("rebalance the dead syncer: %v, err: %v", removed, err)
+	}
+	if removed, err := leader.RebalanceLoadFromDeadSyncers([]string{"127.0.0.1:9190"}); err != nil || len(removed) != 0 {
+		t.Fatalf("rebalance the removed dead syncer: %v, err: %v", removed, err)
+	}
+
+	// The remaining nodes elect a new leader and accept the writes.
+	if err := leader.Close(); err != nil {
+		t.Fatalf("close leader failed: %v", err)
+	}
+	closed[leader] = true
+	survivors := make([]*RaftDB, 0, 2)
+	for _, node := range nodes {
+		if node != leader {
+			survivors = append(survivors, node)
+		}
+	}
+	waitRaftLeader(t, survivors)
+	if err := survivors[0].UpdateJob("job", "info3"); err != nil {
+		t.Fatalf("update job after the leader is closed failed: %v", err)
+	}
+	for _, node := range survivors {
+		waitRaftJobInfo(t, node, "job", "info3")
+	}
+}
+
+// writeRaftCert writes a self signed certificate of 127.0.0.1 and its key, it is the CA too.
+func writeRaftCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ccr_syncer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate failed: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("write certificate failed: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("write key failed: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// forwardRaw sends the command to the forward handler of the address by the conn, and returns
+// the response, or the error if the conn is closed.
+func forwardRaw(conn net.Conn, cmd string) (*raftForwardResponse, error) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(cmd + "\n")); err != nil {
+		return nil, err
+	}
+	var resp raftForwardResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func TestRaftDB_MutualTLS(t *testing.T) {
+	certFile, keyFile := writeRaftCert(t)
+	options := &utils.TLSOptions{Enable: true, CaFile: certFile, CertFile: certFile, KeyFile: keyFile}
+
+	addrs := freeRaftAddrs(t, 2)
+	nodes := make([]*RaftDB, 0, len(addrs))
+	for _, addr := range addrs {
+		node, err := openRaftDB(&RaftConfig{Dir: filepath.Join(t.TempDir(), "raft"), Addr: addr, Peers: addrs, TLS: options})
+		if err != nil {
+			t.Fatalf("open raft db %s failed: %v", addr, err)
+		}
+		defer node.Close()
+		nodes = append(nodes, node)
+	}
+
+	// The raft rpc and the forwarded writes are in tls.
+	leader := waitRaftLeader(t, nodes)
+	follower := nodes[0]
+	if follower == leader {
+		follower = nodes[1]
+	}
+	if err := follower.AddJob("job", "info", "127.0.0.1:9190"); err != nil {
+		t.Fatalf("add job failed: %v", err)
+	}
+	waitRaftJobInfo(t, leader, "job", "info")
+
+	// The unknown commands are not applied.
+	conn, err := follower.stream.dial(leader.addr, raftConnForward, raftDialTimeout)
+	if err != nil {
+		t.Fatalf("dial leader failed: %v", err)
+	}
+	resp, err := forwardRaw(conn, `{"op":"DropAll","args":{}}`)
+	if err != nil || !strings.Contains(resp.ApplyError, "unknown command") {
+		t.Fatalf("forward the unknown command: %+v, err: %v", resp, err)
+	}
+
+	// The connections without the certificate are rejected.
+	conn, err = net.DialTimeout("tcp", leader.addr, raftDialTimeout)
+	if err != nil {
+		t.Fatalf("dial leader failed: %v", err)
+	}
+	conn.Write([]byte{raftConnForward})
+	if resp, err := forwardRaw(conn, `{"op":"RemoveJob","args":{"job_name":"job"}}`); err == nil {
+		t.Fatalf("the plain connection is accepted: %+v", resp)
+	}
+	if exists, err := leader.IsJobExist("job"); err != nil || !exists {
+		t.Fatalf("the job is removed by the plain connection, err: %v", err)
+	}
+
+	// The mutual tls requires the peer CA.
+	if _, err := openRaftDB(&RaftConfig{Dir: filepath.Join(t.TempDir(), "raft"), Addr: addrs[0], Peers: addrs,
+		TLS: &utils.TLSOptions{Enable: true, CertFile: certFile, KeyFile: keyFile}}); err == nil {
+		t.Fatalf("open raft db without the peer CA succeeded")
+	}
+}
+
+func TestRaftStreamLayer_Peers(t *testing.T) {
+	forwarded := make(chan struct{}, 1)
+	stream, err := newRaftStreamLayer("127.0.0.1:0", []string{"10.0.0.1:9290", "10.0.0.2:9290"}, nil,
+		func(conn net.Conn) {
+			forwarded <- struct{}{}
+			conn.Close()
+		})
+	if err != nil {
+		t.Fatalf("new stream layer failed: %v", err)
+	}
+	defer stream.Close()
+
+	// The connection from the host out of the peers is closed without handled.
+	conn, err := stream.dial(stream.Addr().String(), raftConnForward, raftDialTimeout)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if _, err := forwardRaw(conn, `{"op":"RemoveJob","args":{"job_name":"job"}}`); err == nil {
+		t.Fatalf("the connection from the non peer is accepted")
+	}
+	select {
+	case <-forwarded:
+		t.Fatalf("the connection from the non peer is forwarded")
+	default:
+	}
+
+	tests := []struct {
+		peers  []string
+		remote string
+		isPeer bool
+	}{
+		{[]string{"10.0.0.1:9290"}, "10.0.0.1:52000", true},
+		{[]string{"10.0.0.1:9290"}, "10.0.0.2:52000", false},
+		{[]string{"localhost:9290"}, "127.0.0.1:52000", true},
+		{[]string{"[::1]:9290"}, "[::1]:52000", true},
+		{nil, "127.0.0.1:52000", false},
+	}
+	for _, test := range tests {
+		stream := &raftStreamLayer{peers: test.peers}
+		remote, err := net.ResolveTCPAddr("tcp", test.remote)
+		if err != nil {
+			t.Fatalf("resolve %s failed: %v", test.remote, err)
+		}
+		if isPeer := stream.isPeer(remote); isPeer != test.isPeer {
+			t.Errorf("%s is peer of %v: %t, expect %t", test.remote, test.peers, isPeer, test.isPeer)
+		}
+	}
+}
+
+type bufferSnapshotSink struct {
+	bytes.Buffer
+}
+
+func (s *bufferSnapshotSink) ID() string    { return "buffer" }
+func (s *bufferSnapshotSink) Cancel() error { return nil }
+func (s *bufferSnapshotSink) Close() error  { return nil }
+
+func TestRaftFSM_SnapshotRestore(t *testing.T) {
+	fsm, err := newRaftFSM(t.TempDir())
+	if err != nil {
+		t.Fatalf("new fsm failed: %v", err)
+	}
+	defer fsm.close()
+
+	apply := func(index uint64, cmd *raftCommand) {
+		data, _ := json.Marshal(cmd)
+		if result := fsm.Apply(&raft.Log{Index: index, Data: data}).(*raftResult); result.Err != nil {
+			t.Fatalf("apply %s failed: %v", cmd.Op, result.Err)
+		}
+	}
+	apply(1, &raftCommand{Op: "AddSyncer", Now: 100, Args: &raftArgs{HostInfo: "127.0.0.1:9190"}})
+	apply(2, &raftCommand{Op: "AddJob", Now: 200, Args: &raftArgs{JobName: "job", JobInfo: "info", HostInfo: "127.0.0.1:9190"}})
+	apply(3, &raftCommand{Op: "UpdateProgress", Now: 300, Args: &raftArgs{JobName: "job", Progress: "progress"}})
+
+	snapshot, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot failed: %v", err)
+	}
+	defer snapshot.Release()
+	sink := &bufferSnapshotSink{}
+	if err := snapshot.Persist(sink); err != nil {
+		t.Fatalf("persist snapshot failed: %v", err)
+	}
+
+	restored, err := newRaftFSM(t.TempDir())
+	if err != nil {
+		t.Fatalf("new fsm failed: %v", err)
+	}
+	defer restored.close()
+	if err := restored.Restore(io.NopCloser(&sink.Buffer)); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	var expected, actual map[string][]string
+	fsm.read(func(db *SQLiteDB) (err error) { expected, err = db.GetAllData(); return })
+	restored.read(func(db *SQLiteDB) (err error) { actual, err = db.GetAllData(); return })
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("restored data %v, expected %v", actual, expected)
+	}
+	if !reflect.DeepEqual(actual["syncers"], []string{"127.0.0.1:9190, 100"}) {
+		t.Fatalf("the timestamp of the syncer is not the time of the command: %v", actual["syncers"])
+	}
+}
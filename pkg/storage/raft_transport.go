@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+	log "github.com/sirupsen/logrus"
+)
+
+// The raft rpc and the writes forwarded to the leader share the raft address, the first byte of
+// a connection tells its kind.
+const (
+	raftConnRaft    byte = 'r'
+	raftConnForward byte = 'f'
+
+	raftDialTimeout = 5 * time.Second
+)
+
+// raftStreamLayer is the raft.StreamLayer which hands the forwarded writes to the handler.
+//
+// Only the connections from the hosts of the peers are accepted, and the connections are in
+// mutual tls if the tls is enabled, both sides verify the certificates by the peer CA.
+type raftStreamLayer struct {
+	net.Listener
+
+	peers     []string
+	serverTLS *tls.Config
+	clientTLS *tls.Config
+
+	conns     chan net.Conn
+	forward   func(conn net.Conn)
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newRaftStreamLayer(addr string, peers []string, tlsOptions *utils.TLSOptions,
+	forward func(conn net.Conn)) (*raftStreamLayer, error) {
+	s := &raftStreamLayer{
+		peers:   peers,
+		conns:   make(chan net.Conn),
+		forward: forward,
+		closed:  make(chan struct{}),
+	}
+	if tlsOptions != nil {
+		if tlsOptions.CaFile == "" {
+			return nil, xerror.New(xerror.Normal, "raft: the peer CA file is required to verify the certificates of the peers")
+		}
+		var err error
+		if s.serverTLS, err = utils.NewServerTLSConfig(tlsOptions.CertFile, tlsOptions.KeyFile, tlsOptions.CaFile); err != nil {
+			return nil, err
+		}
+		clientOptions := *tlsOptions
+		clientOptions.Enable = true
+		if s.clientTLS, err = clientOptions.ClientConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "raft: listen %s failed", addr)
+	}
+	s.Listener = listener
+	go s.serve()
+	return s, nil
+}
+
+func (s *raftStreamLayer) serve() {
+	for {
+		conn, err := s.Listener.Accept()
+		if err != nil {
+			select {
+			case <-s.closed:
+				return
+			default:
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go s.dispatch(conn)
+	}
+}
+
+// isPeer checks whether the remote address is the host of one of the peers, the hostnames of
+// the peers are resolved each time, so the changed ips are accepted.
+func (s *raftStreamLayer) isPeer(remoteAddr net.Addr) bool {
+	remoteHost, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		return false
+	}
+	remoteIP := net.ParseIP(remoteHost)
+	if remoteIP == nil {
+		return false
+	}
+
+	for _, peer := range s.peers {
+		host, _, err := net.SplitHostPort(peer)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			if ip.Equal(remoteIP) {
+				return true
+			}
+			continue
+		}
+		addrs, err := net.LookupHost(host)
+		if err != nil {
+			log.Warnf("raft: resolve the peer %s failed: %+v", peer, err)
+			continue
+		}
+		for _, addr := range addrs {
+			if ip := net.ParseIP(addr); ip != nil && ip.Equal(remoteIP) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *raftStreamLayer) dispatch(conn net.Conn) {
+	if !s.isPeer(conn.RemoteAddr()) {
+		log.Warnf("raft: reject the connection from %s, it is not one of the peers %v", conn.RemoteAddr(), s.peers)
+		conn.Close()
+		return
+	}
+	if s.serverTLS != nil {
+		conn = tls.Server(conn, s.serverTLS)
+	}
+
+	// The tls handshake is done by the first read.
+	var kind [1]byte
+	conn.SetReadDeadline(time.Now().Add(raftDialTimeout))
+	if _, err := io.ReadFull(conn, kind[:]); err != nil {
+		if s.serverTLS != nil {
+			log.Warnf("raft: read the connection from %s failed: %+v", conn.RemoteAddr(), err)
+		}
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	switch kind[0] {
+	case raftConnRaft:
+		select {
+		case s.conns <- conn:
+		case <-s.closed:
+			conn.Close()
+		}
+	case raftConnForward:
+		s.forward(conn)
+	default:
+		conn.Close()
+	}
+}
+
+func (s *raftStreamLayer) Accept() (net.Conn, error) {
+	select {
+	case conn := <-s.conns:
+		return conn, nil
+	case <-s.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (s *raftStreamLayer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		err = s.Listener.Close()
+	})
+	return err
+}
+
+func (s *raftStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	return s.dial(string(address), raftConnRaft, timeout)
+}
+
+// dial connects to the peer, the server certificate is verified by the host of the address.
+func (s *raftStreamLayer) dial(address string, kind byte, timeout time.Duration) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if s.clientTLS != nil {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, s.clientTLS)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{kind}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
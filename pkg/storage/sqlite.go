@@ -12,29 +12,31 @@ import (
 
 type SQLiteDB struct {
 	db *sql.DB
+	// The clock of the timestamps written, the raft db replays the writes at the time of the
+	// leader, see raftFSM.
+	now func() time.Time
 }
 
 func NewSQLiteDB(dbPath string) (DB, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := openSQLiteDB(dbPath)
 	if err != nil {
-		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: open sqlite3 path %s failed", dbPath)
-	}
-
-	// create table info && progress, if not exists
-	// all is tuple (string, string)
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS jobs (job_name TEXT PRIMARY KEY, job_info TEXT, belong_to TEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table jobs failed")
+		return nil, err
 	}
+	return db, nil
+}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS progresses (job_name TEXT PRIMARY KEY, progress TEXT)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table progresses failed")
+func openSQLiteDB(dbPath string) (*SQLiteDB, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: open sqlite3 path %s failed", dbPath)
 	}
 
-	if _, err = db.Exec("CREATE TABLE IF NOT EXISTS syncers (host_info TEXT PRIMARY KEY, timestamp INTEGER)"); err != nil {
-		return nil, xerror.Wrap(err, xerror.DB, "sqlite: create table syncers failed")
+	if err = migrate(db, sqliteDialect); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &SQLiteDB{db: db}, nil
+	return &SQLiteDB{db: db, now: time.Now}, nil
 }
 
 func (s *SQLiteDB) AddJob(jobName string, jobInfo string, hostInfo string) error {
@@ -99,6 +101,13 @@ func (s *SQLiteDB) RemoveJob(jobName string) error {
 		return xerror.Wrapf(err, xerror.DB, "sqlite: remove progresses failed, name: %s", jobName)
 	}
 
+	if _, err := txn.Exec("DELETE FROM job_handoffs WHERE job_name = ?", jobName); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: remove job handoff failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "sqlite: remove job handoff failed, name: %s", jobName)
+	}
+
 	if err := txn.Commit(); err != nil {
 		return xerror.Wrap(err, xerror.DB, "sqlite: remove job txn commit failed.")
 	}
@@ -131,6 +140,29 @@ func (s *SQLiteDB) GetJobBelong(jobName string) (string, error) {
 	return belong, nil
 }
 
+func (s *SQLiteDB) GetJobOwnership(jobName string) (string, int64, error) {
+	var belong string
+	var epoch int64
+	if err := s.db.QueryRow("SELECT belong_to, epoch FROM jobs WHERE job_name = ?", jobName).Scan(&belong, &epoch); err != nil {
+		return "", 0, xerror.Wrapf(err, xerror.DB, "sqlite: get job ownership failed, name: %s", jobName)
+	}
+	return belong, epoch, nil
+}
+
+func (s *SQLiteDB) UpdateJobWithEpoch(jobName string, jobInfo string, epoch int64) error {
+	result, err := s.db.Exec("UPDATE jobs SET job_info = ? WHERE job_name = ? AND epoch = ?", jobInfo, jobName, epoch)
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: update job name %s failed", jobName)
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: update job name %s get affected rows failed", jobName)
+	} else if rowNum == 0 {
+		return checkJobEpoch(s.db.QueryRow("SELECT epoch FROM jobs WHERE job_name = ?", jobName), jobName, epoch)
+	}
+	return nil
+}
+
 func (s *SQLiteDB) UpdateProgress(jobName string, progress string) error {
 	if result, err := s.db.Exec("INSERT INTO progresses VALUES (?, ?) ON CONFLICT (job_name) DO UPDATE SET progress = ?", jobName, progress, progress); err != nil {
 		return xerror.Wrap(err, xerror.DB, "sqlite: update progress failed")
@@ -143,6 +175,35 @@ func (s *SQLiteDB) UpdateProgress(jobName string, progress string) error {
 	return nil
 }
 
+func (s *SQLiteDB) UpdateProgressWithEpoch(jobName string, progress string, owner string, epoch int64) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: update progress begin transaction failed, name: %s", jobName)
+	}
+
+	if err := checkJobEpoch(txn.QueryRow("SELECT epoch FROM jobs WHERE job_name = ?", owner), owner, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: update progress rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	if _, err := txn.Exec("INSERT INTO progresses VALUES (?, ?) ON CONFLICT (job_name) DO UPDATE SET progress = ?", jobName, progress, progress); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: update progress failed, name: %s, and rollback failed too", jobName)
+		}
+		return xerror.Wrapf(err, xerror.DB, "sqlite: update progress failed, name: %s", jobName)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "sqlite: update progress txn commit failed.")
+	}
+	return nil
+}
+
 func (s *SQLiteDB) IsProgressExist(jobName string) (bool, error) {
 	var count int
 	if err := s.db.QueryRow("SELECT COUNT(*) FROM progresses WHERE job_name = ?", jobName).Scan(&count); err != nil {
@@ -160,7 +221,12 @@ func (s *SQLiteDB) GetProgress(jobName string) (string, error) {
 }
 
 func (s *SQLiteDB) AddSyncer(hostInfo string) error {
-	timestamp := time.Now().UnixNano()
+	// The syncer is restarted after the maintenance.
+	if _, err := s.db.Exec("DELETE FROM draining_syncers WHERE host_info = ?", hostInfo); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: undrain syncer %s failed", hostInfo)
+	}
+
+	timestamp := s.now().UnixNano()
 	if result, err := s.db.Exec("INSERT INTO syncers VALUES (?, ?) ON CONFLICT (host_info) DO UPDATE SET timestamp = ?", hostInfo, timestamp, timestamp); err != nil {
 		return xerror.Wrap(err, xerror.DB, "sqlite: add syncer failed")
 	} else if rowNum, err := result.RowsAffected(); err != nil {
@@ -173,7 +239,7 @@ func (s *SQLiteDB) AddSyncer(hostInfo string) error {
 }
 
 func (s *SQLiteDB) RefreshSyncer(hostInfo string, lastStamp int64) (int64, error) {
-	nowTime := time.Now().UnixNano()
+	nowTime := s.now().UnixNano()
 	result, err := s.db.Exec("UPDATE syncers SET timestamp = ? WHERE host_info = ? AND timestamp = ?", nowTime, hostInfo, lastStamp)
 	if err != nil {
 		return -1, xerror.Wrap(err, xerror.DB, "sqlite: refresh syncer failed.")
@@ -241,34 +307,48 @@ func (s *SQLiteDB) GetDeadSyncers(expiredTime int64) ([]string, error) {
 	return deadSyncers, nil
 }
 
-func (s *SQLiteDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, error) {
+func (s *SQLiteDB) getOrphanJobs(txn *sql.Tx, syncers []string) ([]string, []string, error) {
 	orphanJobs := make([]string, 0)
+	removedSyncers := make([]string, 0)
 	for _, deadSyncer := range syncers {
 		rows, err := txn.Query("SELECT job_name FROM jobs WHERE belong_to = ?", deadSyncer)
 		if err != nil {
-			return nil, xerror.Wrap(err, xerror.DB, "sqlite: get orphan jobs failed.")
+			return nil, nil, xerror.Wrap(err, xerror.DB, "sqlite: get orphan jobs failed.")
 		}
 
 		for rows.Next() {
 			var jobName string
 			if err := rows.Scan(&jobName); err != nil {
-				return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan orphan job name failed.")
+				return nil, nil, xerror.Wrap(err, xerror.DB, "sqlite: scan orphan job name failed.")
 			}
 			orphanJobs = append(orphanJobs, jobName)
 		}
 		rows.Close()
 
-		if _, err := txn.Exec("DELETE FROM syncers WHERE host_info = ?", deadSyncer); err != nil {
-			return nil, xerror.Wrapf(err, xerror.DB, "sqlite: delete dead syncer failed, name: %s", deadSyncer)
+		result, err := txn.Exec("DELETE FROM syncers WHERE host_info = ?", deadSyncer)
+		if err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "sqlite: delete dead syncer failed, name: %s", deadSyncer)
+		}
+		// The dead syncer might be removed by the other syncers concurrently.
+		if removed, err := result.RowsAffected(); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "sqlite: get the removed dead syncer failed, name: %s", deadSyncer)
+		} else if removed > 0 {
+			removedSyncers = append(removedSyncers, deadSyncer)
+		}
+		if _, err := txn.Exec("DELETE FROM job_handoffs WHERE from_syncer = ?", deadSyncer); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "sqlite: delete job handoffs of dead syncer failed, name: %s", deadSyncer)
+		}
+		if _, err := txn.Exec("DELETE FROM draining_syncers WHERE host_info = ?", deadSyncer); err != nil {
+			return nil, nil, xerror.Wrapf(err, xerror.DB, "sqlite: delete draining dead syncer failed, name: %s", deadSyncer)
 		}
 	}
-	return orphanJobs, nil
+	return orphanJobs, removedSyncers, nil
 }
 
 func (s *SQLiteDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 	load := make(LoadSlice, 0)
 	sumLoad := 0
-	host_rows, err := txn.Query("SELECT host_info FROM syncers")
+	host_rows, err := txn.Query("SELECT host_info FROM syncers WHERE host_info NOT IN (SELECT host_info FROM draining_syncers)")
 	if err != nil {
 		return nil, -1, xerror.Wrap(err, xerror.DB, "sqlite: get all syncers failed.")
 	}
@@ -290,55 +370,173 @@ func (s *SQLiteDB) getLoadInfo(txn *sql.Tx) (LoadSlice, int, error) {
 
 func (s *SQLiteDB) dispatchJobs(txn *sql.Tx, hostInfo string, additionalJobs []string) error {
 	for _, jobName := range additionalJobs {
-		if _, err := txn.Exec("UPDATE jobs SET belong_to = ? WHERE job_name = ?", hostInfo, jobName); err != nil {
+		if _, err := txn.Exec("UPDATE jobs SET belong_to = ?, epoch = epoch + 1 WHERE job_name = ?", hostInfo, jobName); err != nil {
 			return xerror.Wrapf(err, xerror.DB, "sqlite: update job belong_to failed, name: %s", jobName)
 		}
 	}
-	if _, err := txn.Exec("UPDATE syncers SET timestamp = ? WHERE host_info = ?", time.Now().UnixNano(), hostInfo); err != nil {
+	if _, err := txn.Exec("UPDATE syncers SET timestamp = ? WHERE host_info = ?", s.now().UnixNano(), hostInfo); err != nil {
 		return xerror.Wrapf(err, xerror.DB, "sqlite: update syncer timestamp failed, host: %s", hostInfo)
 	}
 	return nil
 }
 
-func (s *SQLiteDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
+func (s *SQLiteDB) RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error) {
 	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
 		ReadOnly:  false,
 	})
 	if err != nil {
-		return xerror.Wrap(err, xerror.DB, "sqlite: rebalance load begin txn failed")
+		return nil, xerror.Wrap(err, xerror.DB, "sqlite: rebalance load begin txn failed")
 	}
 
-	orphanJobs, err := s.getOrphanJobs(txn, syncers)
+	orphanJobs, removedSyncers, err := s.getOrphanJobs(txn, syncers)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	additionalLoad := len(orphanJobs)
+	if additionalLoad == 0 {
+		// The dead syncers have no jobs, or they are rebalanced by the other syncers.
+		if err := txn.Commit(); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: rebalance txn commit failed.")
+		}
+		return removedSyncers, nil
+	}
+
 	loadList, currentLoad, err := s.getLoadInfo(txn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	loadList, err = RebalanceLoad(additionalLoad, currentLoad, loadList)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for i := range loadList {
 		beginIdx := additionalLoad - loadList[i].AddedLoad
 		if err := s.dispatchJobs(txn, loadList[i].HostInfo, orphanJobs[beginIdx:additionalLoad]); err != nil {
 			if err := txn.Rollback(); err != nil {
-				return xerror.Wrap(err, xerror.DB, "sqlite: rebalance rollback failed.")
+				return nil, xerror.Wrap(err, xerror.DB, "sqlite: rebalance rollback failed.")
 			}
-			return err
+			return nil, err
 		}
 		additionalLoad = beginIdx
 	}
 
 	if err := txn.Commit(); err != nil {
-		return xerror.Wrap(err, xerror.DB, "sqlite: rebalance txn commit failed.")
+		return nil, xerror.Wrap(err, xerror.DB, "sqlite: rebalance txn commit failed.")
+	}
+
+	return removedSyncers, nil
+}
+
+func (s *SQLiteDB) DrainSyncer(hostInfo string, draining bool) error {
+	var err error
+	if draining {
+		_, err = s.db.Exec("INSERT INTO draining_syncers VALUES (?, ?) ON CONFLICT (host_info) DO NOTHING", hostInfo, s.now().UnixMilli())
+	} else {
+		_, err = s.db.Exec("DELETE FROM draining_syncers WHERE host_info = ?", hostInfo)
+	}
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: drain syncer %s failed, draining: %v", hostInfo, draining)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetDrainingSyncers() ([]string, error) {
+	rows, err := s.db.Query("SELECT host_info FROM draining_syncers")
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "sqlite: get draining syncers failed.")
+	}
+	defer rows.Close()
+
+	syncers := make([]string, 0)
+	for rows.Next() {
+		var hostInfo string
+		if err := rows.Scan(&hostInfo); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan draining syncers row failed.")
+		}
+		syncers = append(syncers, hostInfo)
+	}
+	return syncers, nil
+}
+
+func (s *SQLiteDB) AddJobHandoffs(handoffs []*JobHandoff) error {
+	for _, handoff := range handoffs {
+		if _, err := s.db.Exec("INSERT INTO job_handoffs VALUES (?, ?, ?, ?) ON CONFLICT (job_name) DO NOTHING",
+			handoff.JobName, handoff.From, handoff.To, handoff.Timestamp); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: add job handoff failed, name: %s", handoff.JobName)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetJobHandoffs(hostInfo string) ([]*JobHandoff, error) {
+	query, args := "SELECT job_name, from_syncer, to_syncer, timestamp FROM job_handoffs", []interface{}{}
+	if hostInfo != "" {
+		query, args = query+" WHERE from_syncer = ?", append(args, hostInfo)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "sqlite: get job handoffs failed.")
+	}
+	defer rows.Close()
+
+	handoffs := make([]*JobHandoff, 0)
+	for rows.Next() {
+		var handoff JobHandoff
+		if err := rows.Scan(&handoff.JobName, &handoff.From, &handoff.To, &handoff.Timestamp); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan job handoffs row failed.")
+		}
+		handoffs = append(handoffs, &handoff)
+	}
+	return handoffs, nil
+}
+
+func (s *SQLiteDB) RemoveJobHandoff(jobName string) error {
+	if _, err := s.db.Exec("DELETE FROM job_handoffs WHERE job_name = ?", jobName); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: remove job handoff failed, name: %s", jobName)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) TransferJob(jobName string, epoch int64, target string) error {
+	txn, err := s.db.BeginTx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  false,
+	})
+	if err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: transfer job begin transaction failed, name: %s", jobName)
+	}
+
+	if err := checkJobEpoch(txn.QueryRow("SELECT epoch FROM jobs WHERE job_name = ?", jobName), jobName, epoch); err != nil {
+		if err := txn.Rollback(); err != nil {
+			return xerror.Wrapf(err, xerror.DB, "sqlite: transfer job rollback failed, name: %s", jobName)
+		}
+		return err
+	}
+
+	// Update the timestamp of the target, so it refreshes failed and recovers the jobs.
+	stmts := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE jobs SET belong_to = ?, epoch = epoch + 1 WHERE job_name = ?", []interface{}{target, jobName}},
+		{"UPDATE syncers SET timestamp = ? WHERE host_info = ?", []interface{}{s.now().UnixNano(), target}},
+		{"DELETE FROM job_handoffs WHERE job_name = ?", []interface{}{jobName}},
+	}
+	for _, stmt := range stmts {
+		if _, err := txn.Exec(stmt.query, stmt.args...); err != nil {
+			if err := txn.Rollback(); err != nil {
+				return xerror.Wrapf(err, xerror.DB, "sqlite: transfer job failed, name: %s, and rollback failed too", jobName)
+			}
+			return xerror.Wrapf(err, xerror.DB, "sqlite: transfer job failed, name: %s", jobName)
+		}
 	}
 
+	if err := txn.Commit(); err != nil {
+		return xerror.Wrap(err, xerror.DB, "sqlite: transfer job txn commit failed.")
+	}
 	return nil
 }
 
@@ -379,3 +577,84 @@ func (s *SQLiteDB) GetAllData() (map[string][]string, error) {
 
 	return ans, nil
 }
+
+func (s *SQLiteDB) AddJobEvent(event *JobEvent) error {
+	if _, err := s.db.Exec("INSERT INTO job_events (job_name, event_type, timestamp, message) VALUES (?, ?, ?, ?)",
+		event.JobName, event.Type, event.Timestamp, event.Message); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: add job event failed, name: %s", event.JobName)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetJobEvents(filter *JobEventFilter) ([]*JobEvent, error) {
+	query, args := buildJobEventsQuery("job_events", filter, func(int) string { return "?" })
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, xerror.Wrap(err, xerror.DB, "sqlite: get job events failed.")
+	}
+	defer rows.Close()
+
+	events := make([]*JobEvent, 0)
+	for rows.Next() {
+		var event JobEvent
+		if err := rows.Scan(&event.Id, &event.JobName, &event.Type, &event.Timestamp, &event.Message); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan job events row failed.")
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (s *SQLiteDB) RemoveJobEventsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM job_events WHERE timestamp < ?", timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "sqlite: remove job events failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "sqlite: remove job events get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
+
+func (s *SQLiteDB) AddVerifyReport(report *VerifyReport) error {
+	insertSql := "INSERT INTO verify_reports (job_name, commit_seq, timestamp, matched, report) VALUES (?, ?, ?, ?, ?)"
+	if _, err := s.db.Exec(insertSql, report.JobName, report.CommitSeq, report.Timestamp, report.Matched, report.Report); err != nil {
+		return xerror.Wrapf(err, xerror.DB, "sqlite: add verify report failed, name: %s", report.JobName)
+	}
+	return nil
+}
+
+func (s *SQLiteDB) GetVerifyReports(jobName string, limit int) ([]*VerifyReport, error) {
+	query := fmt.Sprintf("SELECT id, job_name, commit_seq, timestamp, matched, report FROM verify_reports WHERE job_name = ? ORDER BY id DESC LIMIT %d",
+		verifyReportsLimit(limit))
+	rows, err := s.db.Query(query, jobName)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.DB, "sqlite: get verify reports failed, name: %s", jobName)
+	}
+	defer rows.Close()
+
+	reports := make([]*VerifyReport, 0)
+	for rows.Next() {
+		var report VerifyReport
+		if err := rows.Scan(&report.Id, &report.JobName, &report.CommitSeq, &report.Timestamp, &report.Matched, &report.Report); err != nil {
+			return nil, xerror.Wrap(err, xerror.DB, "sqlite: scan verify reports row failed.")
+		}
+		reports = append(reports, &report)
+	}
+	return reports, nil
+}
+
+func (s *SQLiteDB) RemoveVerifyReportsBefore(timestamp int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM verify_reports WHERE timestamp < ?", timestamp)
+	if err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "sqlite: remove verify reports failed.")
+	}
+
+	if rowNum, err := result.RowsAffected(); err != nil {
+		return 0, xerror.Wrap(err, xerror.DB, "sqlite: remove verify reports get affected rows failed.")
+	} else {
+		return rowNum, nil
+	}
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"flag"
+	"net/url"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/selectdb/ccr_syncer/pkg/utils"
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// The name of the tls config registered to the mysql driver.
+const mysqlTLSConfigName = "ccr_meta_db"
+
+var metaDBTLS utils.TLSOptions
+
+func init() {
+	flag.BoolVar(&metaDBTLS.Enable, "meta_db_tls", false, "Connect to the mysql/postgresql meta db with tls")
+	flag.StringVar(&metaDBTLS.CaFile, "meta_db_tls_ca_file", "",
+		"The CA file to verify the meta db server certificate, the system CAs are used if it is empty")
+	flag.StringVar(&metaDBTLS.CertFile, "meta_db_tls_cert_file", "", "The client certificate file sent to the meta db")
+	flag.StringVar(&metaDBTLS.KeyFile, "meta_db_tls_key_file", "", "The client key file sent to the meta db")
+	flag.BoolVar(&metaDBTLS.SkipVerify, "meta_db_tls_skip_verify", false, "Skip the verification of the meta db server certificate")
+}
+
+// mysqlTLSParams registers the tls config to the mysql driver, and returns the dsn params to use it.
+func mysqlTLSParams() (string, error) {
+	config, err := metaDBTLS.ClientConfig()
+	if err != nil || config == nil {
+		return "", err
+	}
+
+	if err := mysql.RegisterTLSConfig(mysqlTLSConfigName, config); err != nil {
+		return "", xerror.Wrap(err, xerror.Normal, "mysql: register tls config failed")
+	}
+	return "&tls=" + mysqlTLSConfigName, nil
+}
+
+// postgresqlSSLParams returns the url params of the ssl mode and certificates.
+func postgresqlSSLParams() string {
+	params := url.Values{}
+	switch {
+	case !metaDBTLS.Enable:
+		params.Set("sslmode", "disable")
+	case metaDBTLS.SkipVerify:
+		params.Set("sslmode", "require")
+	default:
+		params.Set("sslmode", "verify-full")
+		if metaDBTLS.CaFile != "" {
+			params.Set("sslrootcert", metaDBTLS.CaFile)
+		}
+	}
+	if metaDBTLS.Enable && metaDBTLS.CertFile != "" {
+		params.Set("sslcert", metaDBTLS.CertFile)
+		params.Set("sslkey", metaDBTLS.KeyFile)
+	}
+	return params.Encode()
+}
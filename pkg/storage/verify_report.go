@@ -0,0 +1,25 @@
+package storage
+
+const (
+	defaultVerifyReportsLimit = 10
+	maxVerifyReportsLimit     = 1000
+)
+
+// VerifyReport is a data verification report of a job, see pkg/verify.
+type VerifyReport struct {
+	Id        int64  `json:"id"`
+	JobName   string `json:"job_name"`
+	CommitSeq int64  `json:"commit_seq"`
+	Timestamp int64  `json:"timestamp"` // unix milliseconds
+	Matched   bool   `json:"matched"`
+	Report    string `json:"report"` // the json of verify.Report
+}
+
+func verifyReportsLimit(limit int) int {
+	if limit <= 0 {
+		return defaultVerifyReportsLimit
+	} else if limit > maxVerifyReportsLimit {
+		return maxVerifyReportsLimit
+	}
+	return limit
+}
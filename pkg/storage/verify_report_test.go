@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteDB_VerifyReports(t *testing.T) {
+	db, err := NewSQLiteDB(filepath.Join(t.TempDir(), "ccr.db"))
+	require.NoError(t, err)
+
+	reports := []*VerifyReport{
+		{JobName: "a", CommitSeq: 10, Timestamp: 1000, Matched: true, Report: `{"matched":true}`},
+		{JobName: "a", CommitSeq: 20, Timestamp: 2000, Matched: false, Report: `{"matched":false}`},
+		{JobName: "b", CommitSeq: 30, Timestamp: 3000, Matched: true, Report: `{}`},
+	}
+	for _, report := range reports {
+		require.NoError(t, db.AddVerifyReport(report))
+	}
+
+	got, err := db.GetVerifyReports("a", 0)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, int64(20), got[0].CommitSeq)
+	assert.False(t, got[0].Matched)
+	assert.Equal(t, `{"matched":false}`, got[0].Report)
+	assert.True(t, got[1].Matched)
+
+	got, err = db.GetVerifyReports("a", 1)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	removed, err := db.RemoveVerifyReportsBefore(2500)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), removed)
+
+	got, err = db.GetVerifyReports("a", 0)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -7,6 +7,7 @@ package test_util
 import (
 	reflect "reflect"
 
+	storage "github.com/selectdb/ccr_syncer/pkg/storage"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -47,6 +48,34 @@ func (mr *MockDBMockRecorder) AddJob(jobName, jobInfo, hostInfo interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddJob", reflect.TypeOf((*MockDB)(nil).AddJob), jobName, jobInfo, hostInfo)
 }
 
+// AddJobEvent mocks base method.
+func (m *MockDB) AddJobEvent(event *storage.JobEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddJobEvent", event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddJobEvent indicates an expected call of AddJobEvent.
+func (mr *MockDBMockRecorder) AddJobEvent(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddJobEvent", reflect.TypeOf((*MockDB)(nil).AddJobEvent), event)
+}
+
+// AddJobHandoffs mocks base method.
+func (m *MockDB) AddJobHandoffs(handoffs []*storage.JobHandoff) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddJobHandoffs", handoffs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddJobHandoffs indicates an expected call of AddJobHandoffs.
+func (mr *MockDBMockRecorder) AddJobHandoffs(handoffs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddJobHandoffs", reflect.TypeOf((*MockDB)(nil).AddJobHandoffs), handoffs)
+}
+
 // AddSyncer mocks base method.
 func (m *MockDB) AddSyncer(hostInfo string) error {
 	m.ctrl.T.Helper()
@@ -61,6 +90,34 @@ func (mr *MockDBMockRecorder) AddSyncer(hostInfo interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSyncer", reflect.TypeOf((*MockDB)(nil).AddSyncer), hostInfo)
 }
 
+// AddVerifyReport mocks base method.
+func (m *MockDB) AddVerifyReport(report *storage.VerifyReport) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddVerifyReport", report)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddVerifyReport indicates an expected call of AddVerifyReport.
+func (mr *MockDBMockRecorder) AddVerifyReport(report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVerifyReport", reflect.TypeOf((*MockDB)(nil).AddVerifyReport), report)
+}
+
+// DrainSyncer mocks base method.
+func (m *MockDB) DrainSyncer(hostInfo string, draining bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DrainSyncer", hostInfo, draining)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DrainSyncer indicates an expected call of DrainSyncer.
+func (mr *MockDBMockRecorder) DrainSyncer(hostInfo, draining interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrainSyncer", reflect.TypeOf((*MockDB)(nil).DrainSyncer), hostInfo, draining)
+}
+
 // GetAllData mocks base method.
 func (m *MockDB) GetAllData() (map[string][]string, error) {
 	m.ctrl.T.Helper()
@@ -91,6 +148,21 @@ func (mr *MockDBMockRecorder) GetDeadSyncers(expiredTime interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeadSyncers", reflect.TypeOf((*MockDB)(nil).GetDeadSyncers), expiredTime)
 }
 
+// GetDrainingSyncers mocks base method.
+func (m *MockDB) GetDrainingSyncers() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDrainingSyncers")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDrainingSyncers indicates an expected call of GetDrainingSyncers.
+func (mr *MockDBMockRecorder) GetDrainingSyncers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDrainingSyncers", reflect.TypeOf((*MockDB)(nil).GetDrainingSyncers))
+}
+
 // GetJobBelong mocks base method.
 func (m *MockDB) GetJobBelong(jobName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -106,6 +178,36 @@ func (mr *MockDBMockRecorder) GetJobBelong(jobName interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobBelong", reflect.TypeOf((*MockDB)(nil).GetJobBelong), jobName)
 }
 
+// GetJobEvents mocks base method.
+func (m *MockDB) GetJobEvents(filter *storage.JobEventFilter) ([]*storage.JobEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobEvents", filter)
+	ret0, _ := ret[0].([]*storage.JobEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobEvents indicates an expected call of GetJobEvents.
+func (mr *MockDBMockRecorder) GetJobEvents(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobEvents", reflect.TypeOf((*MockDB)(nil).GetJobEvents), filter)
+}
+
+// GetJobHandoffs mocks base method.
+func (m *MockDB) GetJobHandoffs(hostInfo string) ([]*storage.JobHandoff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobHandoffs", hostInfo)
+	ret0, _ := ret[0].([]*storage.JobHandoff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobHandoffs indicates an expected call of GetJobHandoffs.
+func (mr *MockDBMockRecorder) GetJobHandoffs(hostInfo interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobHandoffs", reflect.TypeOf((*MockDB)(nil).GetJobHandoffs), hostInfo)
+}
+
 // GetJobInfo mocks base method.
 func (m *MockDB) GetJobInfo(jobName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -121,6 +223,22 @@ func (mr *MockDBMockRecorder) GetJobInfo(jobName interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobInfo", reflect.TypeOf((*MockDB)(nil).GetJobInfo), jobName)
 }
 
+// GetJobOwnership mocks base method.
+func (m *MockDB) GetJobOwnership(jobName string) (string, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobOwnership", jobName)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetJobOwnership indicates an expected call of GetJobOwnership.
+func (mr *MockDBMockRecorder) GetJobOwnership(jobName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobOwnership", reflect.TypeOf((*MockDB)(nil).GetJobOwnership), jobName)
+}
+
 // GetProgress mocks base method.
 func (m *MockDB) GetProgress(jobName string) (string, error) {
 	m.ctrl.T.Helper()
@@ -152,6 +270,21 @@ func (mr *MockDBMockRecorder) GetStampAndJobs(hostInfo interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStampAndJobs", reflect.TypeOf((*MockDB)(nil).GetStampAndJobs), hostInfo)
 }
 
+// GetVerifyReports mocks base method.
+func (m *MockDB) GetVerifyReports(jobName string, limit int) ([]*storage.VerifyReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVerifyReports", jobName, limit)
+	ret0, _ := ret[0].([]*storage.VerifyReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVerifyReports indicates an expected call of GetVerifyReports.
+func (mr *MockDBMockRecorder) GetVerifyReports(jobName, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVerifyReports", reflect.TypeOf((*MockDB)(nil).GetVerifyReports), jobName, limit)
+}
+
 // IsJobExist mocks base method.
 func (m *MockDB) IsJobExist(jobName string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -183,11 +316,12 @@ func (mr *MockDBMockRecorder) IsProgressExist(jobName interface{}) *gomock.Call
 }
 
 // RebalanceLoadFromDeadSyncers mocks base method.
-func (m *MockDB) RebalanceLoadFromDeadSyncers(syncers []string) error {
+func (m *MockDB) RebalanceLoadFromDeadSyncers(syncers []string) ([]string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "RebalanceLoadFromDeadSyncers", syncers)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // RebalanceLoadFromDeadSyncers indicates an expected call of RebalanceLoadFromDeadSyncers.
@@ -225,6 +359,64 @@ func (mr *MockDBMockRecorder) RemoveJob(jobName interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveJob", reflect.TypeOf((*MockDB)(nil).RemoveJob), jobName)
 }
 
+// RemoveJobEventsBefore mocks base method.
+func (m *MockDB) RemoveJobEventsBefore(timestamp int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveJobEventsBefore", timestamp)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveJobEventsBefore indicates an expected call of RemoveJobEventsBefore.
+func (mr *MockDBMockRecorder) RemoveJobEventsBefore(timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveJobEventsBefore", reflect.TypeOf((*MockDB)(nil).RemoveJobEventsBefore), timestamp)
+}
+
+// RemoveJobHandoff mocks base method.
+func (m *MockDB) RemoveJobHandoff(jobName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveJobHandoff", jobName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveJobHandoff indicates an expected call of RemoveJobHandoff.
+func (mr *MockDBMockRecorder) RemoveJobHandoff(jobName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveJobHandoff", reflect.TypeOf((*MockDB)(nil).RemoveJobHandoff), jobName)
+}
+
+// RemoveVerifyReportsBefore mocks base method.
+func (m *MockDB) RemoveVerifyReportsBefore(timestamp int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveVerifyReportsBefore", timestamp)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveVerifyReportsBefore indicates an expected call of RemoveVerifyReportsBefore.
+func (mr *MockDBMockRecorder) RemoveVerifyReportsBefore(timestamp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveVerifyReportsBefore", reflect.TypeOf((*MockDB)(nil).RemoveVerifyReportsBefore), timestamp)
+}
+
+// TransferJob mocks base method.
+func (m *MockDB) TransferJob(jobName string, epoch int64, target string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferJob", jobName, epoch, target)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferJob indicates an expected call of TransferJob.
+func (mr *MockDBMockRecorder) TransferJob(jobName, epoch, target interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferJob", reflect.TypeOf((*MockDB)(nil).TransferJob), jobName, epoch, target)
+}
+
 // UpdateJob mocks base method.
 func (m *MockDB) UpdateJob(jobName, jobInfo string) error {
 	m.ctrl.T.Helper()
@@ -239,6 +431,20 @@ func (mr *MockDBMockRecorder) UpdateJob(jobName, jobInfo interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockDB)(nil).UpdateJob), jobName, jobInfo)
 }
 
+// UpdateJobWithEpoch mocks base method.
+func (m *MockDB) UpdateJobWithEpoch(jobName, jobInfo string, epoch int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJobWithEpoch", jobName, jobInfo, epoch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJobWithEpoch indicates an expected call of UpdateJobWithEpoch.
+func (mr *MockDBMockRecorder) UpdateJobWithEpoch(jobName, jobInfo, epoch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJobWithEpoch", reflect.TypeOf((*MockDB)(nil).UpdateJobWithEpoch), jobName, jobInfo, epoch)
+}
+
 // UpdateProgress mocks base method.
 func (m *MockDB) UpdateProgress(jobName, progress string) error {
 	m.ctrl.T.Helper()
@@ -252,3 +458,17 @@ func (mr *MockDBMockRecorder) UpdateProgress(jobName, progress interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProgress", reflect.TypeOf((*MockDB)(nil).UpdateProgress), jobName, progress)
 }
+
+// UpdateProgressWithEpoch mocks base method.
+func (m *MockDB) UpdateProgressWithEpoch(jobName, progress, owner string, epoch int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProgressWithEpoch", jobName, progress, owner, epoch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProgressWithEpoch indicates an expected call of UpdateProgressWithEpoch.
+func (mr *MockDBMockRecorder) UpdateProgressWithEpoch(jobName, progress, owner, epoch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProgressWithEpoch", reflect.TypeOf((*MockDB)(nil).UpdateProgressWithEpoch), jobName, progress, owner, epoch)
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiter, the tokens are refilled at the rate per second and
+// at most one second of tokens are accumulated. A non-positive rate means unlimited.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	// Closed when the rate is changed, so the waiting callers wait again with the new rate.
+	changed chan struct{}
+
+	now func() time.Time // for test
+}
+
+func NewRateLimiter(rate float64) *RateLimiter {
+	l := &RateLimiter{now: time.Now, changed: make(chan struct{})}
+	l.SetRate(rate)
+	return l
+}
+
+func (l *RateLimiter) Rate() float64 {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// SetRate changes the rate, the waiting callers wait again with the new rate.
+func (l *RateLimiter) SetRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if rate < 0 {
+		rate = 0
+	}
+	l.refill()
+	l.rate = rate
+	if l.tokens > l.burst() {
+		l.tokens = l.burst()
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *RateLimiter) burst() float64 {
+	if l.rate < 1 {
+		return 1
+	}
+	return l.rate
+}
+
+func (l *RateLimiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() && now.After(l.last) && l.rate > 0 {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst() {
+			l.tokens = l.burst()
+		}
+	}
+	l.last = now
+}
+
+// reserve takes n tokens and returns the duration to wait until they are available, and the
+// channel closed when the rate is changed.
+func (l *RateLimiter) reserve(n int) (time.Duration, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0, l.changed
+	}
+
+	l.refill()
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0, l.changed
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), l.changed
+}
+
+// refund gives back the n tokens taken by the reservation which is not waited.
+func (l *RateLimiter) refund(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return
+	}
+
+	l.refill()
+	l.tokens += float64(n)
+	if l.tokens > l.burst() {
+		l.tokens = l.burst()
+	}
+}
+
+// Wait blocks until n tokens are available, it returns false if the cancel channel is
+// closed before that. A nil limiter never blocks.
+func (l *RateLimiter) Wait(n int, cancel <-chan struct{}) bool {
+	if l == nil || n <= 0 {
+		return true
+	}
+
+	for {
+		delay, changed := l.reserve(n)
+		if delay <= 0 {
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			return true
+		case <-changed:
+			timer.Stop()
+			l.refund(n)
+		case <-cancel:
+			timer.Stop()
+			l.refund(n)
+			return false
+		}
+	}
+}
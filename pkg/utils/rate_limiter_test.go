@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := &RateLimiter{now: func() time.Time { return now }, changed: make(chan struct{})}
+	l.SetRate(10)
+
+	// the bucket starts empty
+	assert.Equal(t, 100*time.Millisecond, reserveDelay(l, 1))
+
+	// refill one second of tokens, the debt is paid first
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), reserveDelay(l, 9))
+	assert.Equal(t, 100*time.Millisecond, reserveDelay(l, 1))
+
+	// at most one second of tokens are accumulated
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Duration(0), reserveDelay(l, 10))
+	assert.Equal(t, 200*time.Millisecond, reserveDelay(l, 2))
+
+	// unlimited
+	l.SetRate(0)
+	assert.Equal(t, time.Duration(0), reserveDelay(l, 1000))
+	assert.Equal(t, float64(0), l.Rate())
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	var l *RateLimiter
+	assert.True(t, l.Wait(100, nil))
+
+	l = NewRateLimiter(1)
+	reserveDelay(l, 1)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	assert.False(t, l.Wait(1, cancel))
+
+	l.SetRate(1000)
+	start := time.Now()
+	assert.True(t, l.Wait(1, nil))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRateLimiter_WaitCancelRefund(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := &RateLimiter{now: func() time.Time { return now }, changed: make(chan struct{})}
+	l.SetRate(10)
+
+	cancel := make(chan struct{})
+	close(cancel)
+	// the cancelled wait gives back its tokens
+	assert.False(t, l.Wait(5, cancel))
+	assert.Equal(t, 100*time.Millisecond, reserveDelay(l, 1))
+}
+
+func TestRateLimiter_WaitRateChanged(t *testing.T) {
+	l := NewRateLimiter(0.001)
+	reserveDelay(l, 1)
+
+	done := make(chan bool)
+	go func() {
+		done <- l.Wait(1, nil)
+	}()
+
+	// wait the caller to block on the slow rate
+	time.Sleep(50 * time.Millisecond)
+	l.SetRate(0)
+
+	select {
+	case ok := <-done:
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting caller is not woken up by SetRate")
+	}
+}
+
+func reserveDelay(l *RateLimiter, n int) time.Duration {
+	delay, _ := l.reserve(n)
+	return delay
+}
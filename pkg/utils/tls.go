@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/selectdb/ccr_syncer/pkg/xerror"
+)
+
+// TLSOptions describes the tls config of a client connection.
+type TLSOptions struct {
+	Enable bool
+	// The CA file to verify the server certificate, the system CAs are used if it is empty.
+	CaFile string
+	// The client certificate and key, they are sent to the server if both are set.
+	CertFile string
+	KeyFile  string
+	// Skip the verification of the server certificate.
+	SkipVerify bool
+}
+
+// ClientConfig builds the tls config, it returns nil if the tls is not enabled.
+func (o *TLSOptions) ClientConfig() (*tls.Config, error) {
+	if !o.Enable {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: o.SkipVerify,
+	}
+	if o.CaFile != "" {
+		pool, err := LoadCertPool(o.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, xerror.Wrapf(err, xerror.Normal, "load tls key pair %s, %s failed", o.CertFile, o.KeyFile)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// LoadCertPool loads the PEM encoded certificates from the file.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "read tls ca file %s failed", path)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, xerror.Errorf(xerror.Normal, "no certificate found in tls ca file %s", path)
+	}
+	return pool, nil
+}
+
+// NewServerTLSConfig builds the tls config of a server, the client certificates are required
+// and verified if the client CA file is set.
+func NewServerTLSConfig(certFile, keyFile, clientCaFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, xerror.Wrapf(err, xerror.Normal, "load tls key pair %s, %s failed", certFile, keyFile)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientCaFile != "" {
+		pool, err := LoadCertPool(clientCaFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
@@ -0,0 +1,273 @@
+package verify
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+)
+
+// Specer queries the data of the tables, it is implemented by base.Spec.
+type Specer interface {
+	CountTableRows(tableName string) (int64, error)
+	ChecksumPartition(tableName, partitionName string) (*base.PartitionChecksum, error)
+}
+
+// Metaer gets the visible versions of the partitions, partition name -> visible version.
+type Metaer interface {
+	GetPartitionVersions(tableName string) (map[string]int64, error)
+}
+
+// Cluster is the source or the dest cluster to verify.
+type Cluster struct {
+	Spec Specer
+	Meta Metaer
+}
+
+// TablePair is a table to verify, the name in the source and the dest cluster.
+type TablePair struct {
+	Src  string `json:"src"`
+	Dest string `json:"dest"`
+}
+
+type PartitionReport struct {
+	Name         string `json:"name"`
+	SrcVersion   int64  `json:"src_version"`
+	DestVersion  int64  `json:"dest_version"`
+	SrcRowCount  int64  `json:"src_row_count,omitempty"`
+	DestRowCount int64  `json:"dest_row_count,omitempty"`
+	SrcChecksum  string `json:"src_checksum,omitempty"`
+	DestChecksum string `json:"dest_checksum,omitempty"`
+	Matched      bool   `json:"matched"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+type TableReport struct {
+	SrcTable     string             `json:"src_table"`
+	DestTable    string             `json:"dest_table"`
+	SrcRowCount  int64              `json:"src_row_count"`
+	DestRowCount int64              `json:"dest_row_count"`
+	Partitions   []*PartitionReport `json:"partitions,omitempty"`
+	Matched      bool               `json:"matched"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// MismatchedPartitions returns the number of the mismatched partitions of the table.
+func (r *TableReport) MismatchedPartitions() int {
+	num := 0
+	for _, partition := range r.Partitions {
+		if !partition.Matched {
+			num++
+		}
+	}
+	return num
+}
+
+type Report struct {
+	JobName   string `json:"job_name"`
+	CommitSeq int64  `json:"commit_seq"`
+	StartAt   int64  `json:"start_at"`  // unix milliseconds
+	FinishAt  int64  `json:"finish_at"` // unix milliseconds
+	Checksum  bool   `json:"checksum"`
+	// Whether the source is not changed while it is collected, and the dest is compared at the
+	// same commit seq. The mismatches of a inconsistent report might be caused by the binlogs
+	// not synced yet.
+	Consistent           bool           `json:"consistent"`
+	Matched              bool           `json:"matched"`
+	MismatchedTables     int            `json:"mismatched_tables"`
+	MismatchedPartitions int            `json:"mismatched_partitions"`
+	FailedTables         int            `json:"failed_tables"`
+	Tables               []*TableReport `json:"tables"`
+}
+
+type Verifier struct {
+	src      *Cluster
+	dest     *Cluster
+	checksum bool
+}
+
+// NewVerifier creates a verifier, the checksum of each partition is compared if checksum is
+// true, which scans all data of the tables.
+func NewVerifier(src, dest *Cluster, checksum bool) *Verifier {
+	return &Verifier{
+		src:      src,
+		dest:     dest,
+		checksum: checksum,
+	}
+}
+
+// tableData is the data of a table collected from a cluster.
+type tableData struct {
+	rowCount  int64
+	versions  map[string]int64                   // partition name -> visible version
+	checksums map[string]*base.PartitionChecksum // partition name -> checksum, only if checksum is true
+	err       error
+}
+
+// Source is the data of the source tables. It is collected before the dest is synced to the
+// same commit seq, then compared with the dest, see Verifier.Compare.
+type Source struct {
+	startAt int64
+	tables  map[string]*tableData // src table name -> data
+}
+
+// Verify compares the row counts and the partitions of the tables, the failure of a table is
+// recorded in its report.
+func (v *Verifier) Verify(tables []TablePair) *Report {
+	return v.Compare(tables, v.CollectSource(tables))
+}
+
+// CollectSource collects the row counts, the partition versions and the checksums of the
+// source tables.
+func (v *Verifier) CollectSource(tables []TablePair) *Source {
+	source := &Source{
+		startAt: time.Now().UnixMilli(),
+		tables:  make(map[string]*tableData, len(tables)),
+	}
+	for _, table := range tables {
+		source.tables[table.Src] = v.collect(v.src, table.Src)
+	}
+	return source
+}
+
+// Compare collects the dest tables and compares them with the source.
+func (v *Verifier) Compare(tables []TablePair, source *Source) *Report {
+	report := &Report{
+		StartAt:  source.startAt,
+		Checksum: v.checksum,
+		Matched:  true,
+		Tables:   make([]*TableReport, 0, len(tables)),
+	}
+
+	for _, table := range tables {
+		srcData, ok := source.tables[table.Src]
+		if !ok {
+			srcData = &tableData{err: errors.New("src table is not collected")}
+		}
+		tableReport := v.compareTable(table, srcData)
+		report.Tables = append(report.Tables, tableReport)
+		if tableReport.Error != "" {
+			report.FailedTables++
+		} else if !tableReport.Matched {
+			report.MismatchedTables++
+		}
+		report.MismatchedPartitions += tableReport.MismatchedPartitions()
+	}
+
+	report.Matched = report.MismatchedTables == 0 && report.FailedTables == 0
+	report.FinishAt = time.Now().UnixMilli()
+	return report
+}
+
+func (v *Verifier) VerifyTable(table TablePair) *TableReport {
+	return v.compareTable(table, v.collect(v.src, table.Src))
+}
+
+func (v *Verifier) collect(cluster *Cluster, tableName string) *tableData {
+	data := &tableData{}
+	if data.rowCount, data.err = cluster.Spec.CountTableRows(tableName); data.err != nil {
+		return data
+	}
+	if data.versions, data.err = cluster.Meta.GetPartitionVersions(tableName); data.err != nil {
+		return data
+	}
+
+	if v.checksum {
+		data.checksums = make(map[string]*base.PartitionChecksum, len(data.versions))
+		for name := range data.versions {
+			checksum, err := cluster.Spec.ChecksumPartition(tableName, name)
+			if err != nil {
+				data.err = err
+				return data
+			}
+			data.checksums[name] = checksum
+		}
+	}
+	return data
+}
+
+func (v *Verifier) compareTable(table TablePair, srcData *tableData) *TableReport {
+	report := &TableReport{
+		SrcTable:  table.Src,
+		DestTable: table.Dest,
+	}
+	if err := v.compareTableData(report, srcData); err != nil {
+		report.Matched = false
+		report.Error = err.Error()
+	}
+	return report
+}
+
+func (v *Verifier) compareTableData(report *TableReport, srcData *tableData) error {
+	if srcData.err != nil {
+		return srcData.err
+	}
+	destData := v.collect(v.dest, report.DestTable)
+	if destData.err != nil {
+		return destData.err
+	}
+	report.SrcRowCount = srcData.rowCount
+	report.DestRowCount = destData.rowCount
+
+	// The partitions of the dest table have the same names with the source table.
+	names := make([]string, 0, len(srcData.versions))
+	for name := range srcData.versions {
+		names = append(names, name)
+	}
+	for name := range destData.versions {
+		if _, ok := srcData.versions[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	report.Matched = report.SrcRowCount == report.DestRowCount
+	for _, name := range names {
+		partition := v.comparePartition(name, srcData, destData)
+		report.Partitions = append(report.Partitions, partition)
+		if !partition.Matched {
+			report.Matched = false
+		}
+	}
+	return nil
+}
+
+func (v *Verifier) comparePartition(name string, srcData, destData *tableData) *PartitionReport {
+	srcVersion, srcOk := srcData.versions[name]
+	destVersion, destOk := destData.versions[name]
+	report := &PartitionReport{
+		Name:        name,
+		SrcVersion:  srcVersion,
+		DestVersion: destVersion,
+	}
+
+	switch {
+	case !destOk:
+		report.Reason = "partition not exists in dest"
+		return report
+	case !srcOk:
+		report.Reason = "partition not exists in src"
+		return report
+	case srcVersion != destVersion:
+		report.Reason = "visible version mismatched"
+		return report
+	}
+
+	if v.checksum {
+		srcChecksum, destChecksum := srcData.checksums[name], destData.checksums[name]
+		report.SrcRowCount, report.SrcChecksum = srcChecksum.RowCount, srcChecksum.Checksum
+		report.DestRowCount, report.DestChecksum = destChecksum.RowCount, destChecksum.Checksum
+		if report.SrcRowCount != report.DestRowCount {
+			report.Reason = "row count mismatched"
+			return report
+		}
+		if report.SrcChecksum != report.DestChecksum {
+			report.Reason = "checksum mismatched"
+			return report
+		}
+	}
+
+	report.Matched = true
+	return report
+}
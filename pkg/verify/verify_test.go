@@ -0,0 +1,143 @@
+package verify
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/selectdb/ccr_syncer/pkg/ccr/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTable struct {
+	rowCount   int64
+	versions   map[string]int64
+	checksums  map[string]*base.PartitionChecksum
+	countError error
+}
+
+// mockCluster implements both Specer and Metaer.
+type mockCluster struct {
+	tables map[string]*mockTable
+}
+
+func (c *mockCluster) table(name string) (*mockTable, error) {
+	table, ok := c.tables[name]
+	if !ok {
+		return nil, errors.New("table not found: " + name)
+	}
+	return table, nil
+}
+
+func (c *mockCluster) CountTableRows(tableName string) (int64, error) {
+	table, err := c.table(tableName)
+	if err != nil {
+		return 0, err
+	}
+	return table.rowCount, table.countError
+}
+
+func (c *mockCluster) ChecksumPartition(tableName, partitionName string) (*base.PartitionChecksum, error) {
+	table, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.checksums[partitionName], nil
+}
+
+func (c *mockCluster) GetPartitionVersions(tableName string) (map[string]int64, error) {
+	table, err := c.table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return table.versions, nil
+}
+
+func newCluster(tables map[string]*mockTable) *Cluster {
+	c := &mockCluster{tables: tables}
+	return &Cluster{Spec: c, Meta: c}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	src := newCluster(map[string]*mockTable{
+		"t1": {rowCount: 10, versions: map[string]int64{"p1": 3, "p2": 5}},
+		"t2": {rowCount: 5, versions: map[string]int64{"t2": 7}},
+		"t3": {rowCount: 1, versions: map[string]int64{"p1": 2, "p2": 2}},
+		"t4": {rowCount: 1, countError: errors.New("timeout")},
+	})
+	dest := newCluster(map[string]*mockTable{
+		"t1": {rowCount: 10, versions: map[string]int64{"p1": 3, "p2": 5}},
+		"t2": {rowCount: 4, versions: map[string]int64{"t2": 6}},
+		"t3": {rowCount: 1, versions: map[string]int64{"p1": 2, "p3": 2}},
+		"t4": {rowCount: 1},
+	})
+
+	report := NewVerifier(src, dest, false).Verify([]TablePair{
+		{Src: "t1", Dest: "t1"},
+		{Src: "t2", Dest: "t2"},
+		{Src: "t3", Dest: "t3"},
+		{Src: "t4", Dest: "t4"},
+	})
+
+	require.Len(t, report.Tables, 4)
+	assert.False(t, report.Matched)
+	assert.Equal(t, 2, report.MismatchedTables)
+	assert.Equal(t, 1, report.FailedTables)
+	assert.Equal(t, 3, report.MismatchedPartitions)
+
+	assert.True(t, report.Tables[0].Matched)
+	assert.Len(t, report.Tables[0].Partitions, 2)
+
+	t2 := report.Tables[1]
+	assert.False(t, t2.Matched)
+	assert.Equal(t, int64(5), t2.SrcRowCount)
+	assert.Equal(t, int64(4), t2.DestRowCount)
+	assert.Equal(t, "visible version mismatched", t2.Partitions[0].Reason)
+
+	t3 := report.Tables[2]
+	require.Len(t, t3.Partitions, 3)
+	assert.True(t, t3.Partitions[0].Matched)
+	assert.Equal(t, "partition not exists in dest", t3.Partitions[1].Reason)
+	assert.Equal(t, "partition not exists in src", t3.Partitions[2].Reason)
+
+	assert.Equal(t, "timeout", report.Tables[3].Error)
+}
+
+func TestVerifier_Checksum(t *testing.T) {
+	src := newCluster(map[string]*mockTable{
+		"t": {
+			rowCount: 3,
+			versions: map[string]int64{"p1": 2, "p2": 2},
+			checksums: map[string]*base.PartitionChecksum{
+				"p1": {RowCount: 1, Checksum: "100"},
+				"p2": {RowCount: 2, Checksum: "200"},
+			},
+		},
+	})
+	dest := newCluster(map[string]*mockTable{
+		"t_alias": {
+			rowCount: 3,
+			versions: map[string]int64{"p1": 2, "p2": 2},
+			checksums: map[string]*base.PartitionChecksum{
+				"p1": {RowCount: 1, Checksum: "100"},
+				"p2": {RowCount: 2, Checksum: "201"},
+			},
+		},
+	})
+
+	report := NewVerifier(src, dest, true).Verify([]TablePair{{Src: "t", Dest: "t_alias"}})
+	assert.False(t, report.Matched)
+	assert.True(t, report.Checksum)
+	assert.Equal(t, 1, report.MismatchedTables)
+	assert.Equal(t, 1, report.MismatchedPartitions)
+
+	partitions := report.Tables[0].Partitions
+	assert.True(t, partitions[0].Matched)
+	assert.Equal(t, "checksum mismatched", partitions[1].Reason)
+	assert.Equal(t, "200", partitions[1].SrcChecksum)
+	assert.Equal(t, "201", partitions[1].DestChecksum)
+
+	// Without checksum, only the row counts and the versions are compared.
+	report = NewVerifier(src, dest, false).Verify([]TablePair{{Src: "t", Dest: "t_alias"}})
+	assert.True(t, report.Matched)
+}
@@ -68,6 +68,89 @@ func (j *jobMetrics) HandledBinlogNum() IMetricsTag {
 	return j
 }
 
+func (j *jobMetrics) IngestedTabletNum() IMetricsTag {
+	j.tags = append(j.tags, "ingestedTabletNum")
+	return j
+}
+
+func (j *jobMetrics) BinlogRateLimit() IMetricsTag {
+	j.tags = append(j.tags, "binlogRateLimit")
+	return j
+}
+
+func (j *jobMetrics) TabletRateLimit() IMetricsTag {
+	j.tags = append(j.tags, "tabletRateLimit")
+	return j
+}
+
+func (j *jobMetrics) VerifyNum() IMetricsTag {
+	j.tags = append(j.tags, "verifyNum")
+	return j
+}
+
+func (j *jobMetrics) VerifyMismatchedTables() IMetricsTag {
+	j.tags = append(j.tags, "verifyMismatchedTables")
+	return j
+}
+
+func (j *jobMetrics) VerifyMismatchedPartitions() IMetricsTag {
+	j.tags = append(j.tags, "verifyMismatchedPartitions")
+	return j
+}
+
+func (j *jobMetrics) Completed() IMetricsTag {
+	j.tags = append(j.tags, "completed")
+	return j
+}
+
+func (j *jobMetrics) CompletedCommitSeq() IMetricsTag {
+	j.tags = append(j.tags, "completedCommitSeq")
+	return j
+}
+
+// syncer metrics
+type syncerMetrics struct {
+	metricsTag
+	host string
+}
+
+func SyncerMetrics(host string) *syncerMetrics {
+	return &syncerMetrics{
+		metricsTag: metricsTag{[]string{"syncer"}},
+		host:       host,
+	}
+}
+
+func (s *syncerMetrics) Tag() []string {
+	s.tags = append(s.tags, s.host)
+	return s.tags
+}
+
+func (s *syncerMetrics) HeartbeatAge() IMetricsTag {
+	s.tags = append(s.tags, "heartbeatAgeMs")
+	return s
+}
+
+func (s *syncerMetrics) Alive() IMetricsTag {
+	s.tags = append(s.tags, "alive")
+	return s
+}
+
+func (s *syncerMetrics) JobNum() IMetricsTag {
+	s.tags = append(s.tags, "jobNum")
+	return s
+}
+
+func (d *dashboardMetrics) SyncerNum() IMetricsTag {
+	d.tags = append(d.tags, "syncerNum")
+	return d
+}
+
+func (d *dashboardMetrics) AliveSyncerNum() IMetricsTag {
+	d.tags = append(d.tags, "aliveSyncerNum")
+	return d
+}
+
 // error metrics
 type errorMetrics struct {
 	metricsTag
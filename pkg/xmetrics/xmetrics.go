@@ -44,3 +44,41 @@ func ConsumeBinlog(jobName string, commitSeq int64) {
 
 	metrics.IncrCounter(DashboardMetrics().BinlogNum().Tag(), 1)
 }
+
+func IngestTablet(jobName string) {
+	metrics.IncrCounter(JobMetrics(jobName).IngestedTabletNum().Tag(), 1)
+}
+
+// RateLimit sets the current rate limit of the job, 0 means unlimited.
+func RateLimit(jobName string, binlogsPerSecond, tabletsPerSecond float64) {
+	metrics.SetGauge(JobMetrics(jobName).BinlogRateLimit().Tag(), float32(binlogsPerSecond))
+	metrics.SetGauge(JobMetrics(jobName).TabletRateLimit().Tag(), float32(tabletsPerSecond))
+}
+
+// Verify records a consistent verification of the job, the mismatches are kept until the next one.
+func Verify(jobName string, mismatchedTables, mismatchedPartitions int) {
+	metrics.IncrCounter(JobMetrics(jobName).VerifyNum().Tag(), 1)
+	metrics.SetGauge(JobMetrics(jobName).VerifyMismatchedTables().Tag(), float32(mismatchedTables))
+	metrics.SetGauge(JobMetrics(jobName).VerifyMismatchedPartitions().Tag(), float32(mismatchedPartitions))
+}
+
+func JobCompleted(jobName string, commitSeq int64) {
+	metrics.SetGauge(JobMetrics(jobName).Completed().Tag(), 1)
+	metrics.SetGauge(JobMetrics(jobName).CompletedCommitSeq().Tag(), float32(commitSeq))
+}
+
+// Syncer records the membership of a syncer in the cluster.
+func Syncer(host string, heartbeatAgeMs int64, alive bool, jobNum int) {
+	var aliveValue float32
+	if alive {
+		aliveValue = 1
+	}
+	metrics.SetGauge(SyncerMetrics(host).HeartbeatAge().Tag(), float32(heartbeatAgeMs))
+	metrics.SetGauge(SyncerMetrics(host).Alive().Tag(), aliveValue)
+	metrics.SetGauge(SyncerMetrics(host).JobNum().Tag(), float32(jobNum))
+}
+
+func SyncerNum(total, alive int) {
+	metrics.SetGauge(DashboardMetrics().SyncerNum().Tag(), float32(total))
+	metrics.SetGauge(DashboardMetrics().AliveSyncerNum().Tag(), float32(alive))
+}